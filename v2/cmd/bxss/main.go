@@ -1,15 +1,27 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"net/http"
 	"os"
-	"strings"
-	"sync"
+	"os/signal"
+	"sort"
+	"syscall"
 
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
-	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/checkpoint"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/notify"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/openapi"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/payloads"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/progress"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/useragent"
 	"golang.org/x/time/rate"
 )
 
@@ -21,34 +33,320 @@ var (
 func main() {
 
 	// Create the arguments
-	args = arguments.NewArguments()
-	if args == nil {
+	var err error
+	args, err = arguments.NewArguments()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// Set up the output before anything else is printed
+	level := logger.LevelInfo
+	if args.Quiet {
+		level = logger.LevelWarn
+	}
+	if args.Debug {
+		level = logger.LevelDebug
+	}
+	logger.SetDefault(logger.New(os.Stdout, level, args.NoColor || !logger.UseColor(args.Color, os.Stdout)))
+
+	// Dump the built-in payloads for use elsewhere
+	if args.ListPayloads {
+		builtin, err := payloads.Builtin(args.Contexts)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		for _, payload := range builtin {
+			fmt.Println(payload)
+		}
 		return
 	}
 
+	// Ctrl+C cancels the scan, stopping in-flight requests and browser work
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Validate the arguments
 	args.ValidateArgs()
 
+	// The scan stops early when its budget runs out, while Ctrl+C also ends
+	// the wait for late callbacks
+	scanCtx, cancelScan := context.WithCancel(ctx)
+	if args.MaxDuration > 0 {
+		scanCtx, cancelScan = context.WithTimeout(ctx, args.MaxDuration)
+	}
+	defer cancelScan()
+
 	if args.RateLimit > 0 {
 		limiter = rate.NewLimiter(rate.Limit(args.RateLimit), 1)
+	} else if args.AdaptiveRate {
+		// Adaptive limiting needs a ceiling to ramp back up to
+		limiter = rate.NewLimiter(ratelimit.DefaultCeiling, 1)
 	}
 	// Create the payload parser
 	payloadParser := payloads.NewPayload(args)
 	if payloadParser == nil {
-		fmt.Printf(colours.ErrorColor, "Error creating payload parser: "+"Something went wrong")
+		logger.Error("Error creating payload parser: " + "Something went wrong")
+		os.Exit(1)
+	}
+
+	// Stop once the request budget is spent
+	if args.MaxRequests > 0 {
+		payloadParser.Budget = ratelimit.NewBudget(args.MaxRequests, cancelScan)
+	}
+
+	// Share one transport between every HTTP probe, and give the browser the
+	// same proxy, TLS verification and User-Agent
+	netOptions := transport.Options{
+		Insecure:    args.Insecure,
+		CACert:      args.CACert,
+		HTTP1:       args.HTTP1,
+		Proxy:       args.Proxy,
+		UserAgent:   args.UserAgent,
+		Fingerprint: args.TLSFingerprint,
+	}
+	httpTransport, err := transport.New(netOptions)
+	if err != nil {
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
+	args.ChromeFlags = mergeFlags(netOptions.BrowserFlags(), args.ChromeFlags)
+	if args.Insecure {
+		logger.Warn("TLS certificate verification is DISABLED for HTTP requests (-insecure), use only against targets you trust")
+	}
+	if args.CACert != "" {
+		logger.Warn("Trusting extra certificate authorities from " + args.CACert)
+	}
+	if args.Proxy != "" {
+		logger.Info("Sending HTTP requests and the browser through " + args.Proxy + ", trusting its certificate")
+	}
+	payloadParser.Transport = httpTransport
+
+	// Give up on hosts that can't be reached instead of sending them every payload
+	payloadParser.Unreachable = transport.NewUnreachable()
+
+	// Carry the cookies responses set on to later requests, as a logged in browser would
+	var jar http.CookieJar
+	if args.Session {
+		jar, err = transport.NewJar(args.CookieFile)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		payloadParser.Jar = jar
+	}
+
+	// Log in first so the scan runs with the session the login sets up
+	if args.AuthRequestFile != "" {
+		authParser := browser.NewRequestParser(args.AuthRequestFile)
+		authParser.Transport = httpTransport
+		authParser.Jar = jar
+		authParser.UserAgent = args.UserAgent
+		authParser.BaseURL = args.BaseURL
+		if args.HTTPTimeout > 0 {
+			authParser.Timeout = args.HTTPTimeout
+		}
+		authParser.Retries = args.Retries
+		authParser.RetryBackoff = args.RetryBackoff
+		authParser.Scope = args.Scope
+		authParser.ExtraMethods = args.ExtraMethods
+
+		sessionCookies, err := authParser.ExecuteSequence(ctx, args.AuthExtract)
+		if err != nil {
+			logger.Error("Login sequence failed: " + err.Error())
+			os.Exit(1)
+		}
+		logger.Success(fmt.Sprintf("Logged in with %s, %d session cookies", args.AuthRequestFile, len(sessionCookies)))
+		payloadParser.BrowserCookies = sessionCookies
+	}
+
+	// Back off when targets start answering 429 or 503
+	if args.AdaptiveRate {
+		payloadParser.Adaptive = ratelimit.NewAdaptive(limiter)
+	}
+
+	// Pace each host separately, the global limiter stays the overall cap
+	if args.RateLimitPerHost > 0 {
+		payloadParser.HostLimiter = ratelimit.NewHostLimiter(args.RateLimitPerHost)
+	}
+
+	// Rotate the User-Agent of each request through the built-in or given list
+	if args.RotateUA {
+		var agents []string
+		if args.UAFile != "" {
+			agents, err = payloadParser.ReadLines(args.UAFile)
+			if err != nil {
+				logger.Error("Error reading User-Agent file: " + err.Error())
+				os.Exit(1)
+			}
+		}
+		payloadParser.UserAgents = useragent.NewPool(agents)
+		logger.Info(fmt.Sprintf("Rotating through %d User-Agents", payloadParser.UserAgents.Len()))
+	}
+
+	// Open the machine-readable findings output, on stdout unless a file is given
+	var writers []report.Writer
+	if args.Output != "" || args.JSON || args.Format != "" {
+		var writer report.Writer
+		var err error
+		if args.Output != "" {
+			writer, err = report.Open(args.Output, args.Format)
+		} else {
+			writer, err = report.New(os.Stdout, args.Format)
+		}
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		writers = append(writers, writer)
+	}
+
+	// Print a curl command reproducing each finding
+	if args.EmitCurl {
+		writers = append(writers, report.NewCurlWriter(os.Stdout))
+	}
+
+	// Post confirmed findings to a webhook
+	if args.NotifyWebhook != "" {
+		notifier, err := notify.New(args.NotifyWebhook, args.NotifyTemplate)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		writers = append(writers, notifier)
+	}
+
+	// Drop the same finding reported again by retries or -inject-all follow-ups
+	var aggregator *report.Aggregator
+	if len(writers) > 0 {
+		aggregator = report.NewAggregator(report.Multi(writers...))
+	} else if args.StopOnFirstHit {
+		aggregator = report.NewAggregator(nil)
+	}
+	if aggregator != nil {
+		payloadParser.Report = aggregator
+		defer payloadParser.Report.Close()
+	}
+
+	// Stop the scan once a payload is confirmed, after its finding is recorded
+	firstHit := make(chan struct{})
+	if args.StopOnFirstHit {
+		payloadParser.Report = report.OnFirstConfirmed(payloadParser.Report, func(f report.Finding) {
+			close(firstHit)
+			logger.Success(fmt.Sprintf("Confirmed via %s on %s with payload %s, stopping (-stop-on-first-hit)", f.InjectionPoint, f.Target, f.Payload))
+			cancelScan()
+		})
+	}
+
+	// Skip what an interrupted run already finished
+	if args.ResumeFile != "" {
+		log, err := checkpoint.Open(args.ResumeFile)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		defer log.Close()
+		if n := log.Len(); n > 0 {
+			logger.Info(fmt.Sprintf("Resuming, %d completed scans will be skipped", n))
+		}
+		payloadParser.Checkpoint = log
+	}
+
+	// Count callbacks and record correlated ones as confirmed findings
+	onHit := func(hit callback.Hit) {
+		if payloadParser.Progress != nil {
+			payloadParser.Progress.Callback()
+		}
+		if hit.Injection == nil || payloadParser.Report == nil {
+			return
+		}
+		evidence := fmt.Sprintf("callback %s %s from %s", hit.Method, hit.Path, hit.RemoteAddr)
+		if hit.Injection.Point != "" {
+			evidence += " for the " + hit.Injection.Point + " injection"
+		}
+		err := payloadParser.Report.Write(report.Finding{
+			Target:         hit.Injection.URL,
+			Param:          hit.Injection.Param,
+			Header:         hit.Injection.Header,
+			Payload:        hit.Injection.Payload,
+			InjectionPoint: report.PointCallback,
+			Token:          hit.Injection.Token,
+			Source:         hit.Injection.Source,
+			Encodings:      hit.Injection.Encodings,
+			Confirmed:      true,
+			Evidence:       evidence,
+			Timestamp:      hit.Time,
+		})
+		if err != nil {
+			logger.Error("Error writing finding: " + err.Error())
+		}
+	}
+
+	// Start the callback listener so fired payloads can be correlated
+	if args.CallbackListen != "" {
+		callbacks := callback.NewServer(args.CallbackListen, payloadParser.Tokens)
+		callbacks.OnHit = onHit
+		if err := callbacks.Start(); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		defer callbacks.Close()
+		payloadParser.Callbacks = callbacks
+	}
+
+	// Use a public collaborator for {{callback}} when there is no listener of our own
+	if args.InteractshServer != "" {
+		collaborator := callback.NewInteractsh(args.InteractshServer, payloadParser.Tokens)
+		collaborator.Token = args.InteractshToken
+		collaborator.OnHit = onHit
+		if err := collaborator.Register(ctx); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		defer collaborator.Close()
+		payloadParser.Collaborator = collaborator
+	}
+
+	// Re-test the confirmed findings of an earlier run instead of scanning,
+	// exiting with status 1 when any of them still fires
+	if args.ReplayFile != "" {
+		file, err := os.Open(args.ReplayFile)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		findings, err := report.ReadJSONL(file)
+		file.Close()
+		if err != nil {
+			logger.Error("Error reading replay file: " + err.Error())
+			os.Exit(1)
+		}
+
+		logger.Info("Replaying the confirmed findings of " + args.ReplayFile)
+		result := payloadParser.Replay(scanCtx, limiter, findings)
+		logger.Info(fmt.Sprintf("Replay: %d passed, %d failed, %d skipped, %d errors", result.Passed, result.Failed, result.Skipped, result.Errors))
+		if result.Failed > 0 {
+			if payloadParser.Report != nil {
+				payloadParser.Report.Close()
+			}
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Handle custom request file if specified
 	if args.RequestFile != "" {
-		fmt.Printf(colours.InfoColor, "Using custom request file: "+args.RequestFile)
+		logger.Info("Using custom request file: " + args.RequestFile)
 
 		// Create request parser from the payloads package
-		requestParser := payloads.NewRequestParser(args.RequestFile)
+		requestParser := payloads.NewRequestParser(args, args.RequestFile)
 		if requestParser == nil {
-			fmt.Printf(colours.ErrorColor, "Error creating request parser for file: "+args.RequestFile)
+			logger.Error("Error creating request parser for file: " + args.RequestFile)
 			os.Exit(1)
 		}
+		requestParser.Transport = httpTransport
+		requestParser.Jar = jar
 
 		// Process the custom requests
 		var payloadList []string
@@ -56,78 +354,131 @@ func main() {
 			payloadList = []string{args.Payload}
 		}
 
-		err := requestParser.ProcessCustomRequests(limiter, payloadList)
+		err := requestParser.ProcessCustomRequests(scanCtx, limiter, payloadList)
 		if err != nil {
-			fmt.Printf(colours.ErrorColor, "Error processing custom requests: "+err.Error())
+			logger.Error("Error processing custom requests: " + err.Error())
 			os.Exit(1)
 		}
 
 		// Exit after processing custom requests
-		fmt.Printf(colours.SuccessColor, "Custom requests processed successfully.")
+		logger.Success("Custom requests processed successfully.")
 		os.Exit(0)
 	}
 
-	var headers []string
-	if args.HeaderFile != "" {
-		var err error
-		headers, err = payloadParser.ReadLinesFromFile()
+	// Derive the requests to scan from an OpenAPI document
+	var endpoints []openapi.Endpoint
+	if args.OpenAPI != "" {
+		spec, err := openapi.Load(args.OpenAPI)
 		if err != nil {
-			fmt.Printf(colours.ErrorColor, "Error reading header file: "+err.Error())
-			return
+			logger.Error(err.Error())
+			os.Exit(1)
 		}
-	} else if args.Header != "" {
-		headers = []string{args.Header}
-	}
-
-	var payloads []string
-	if args.PayloadFile != "" {
-		var err error
-		payloads, err = payloadParser.ReadLinesFromFile()
+		endpoints, err = spec.Endpoints(args.BaseURL)
 		if err != nil {
-			fmt.Printf(colours.ErrorColor, "Error reading payload file: "+err.Error())
-			return
+			logger.Error(err.Error())
+			os.Exit(1)
 		}
-	} else if args.Payload != "" {
-		payloads = []string{args.Payload}
+		logger.Info(fmt.Sprintf("Scanning %d endpoints from %s", len(endpoints), args.OpenAPI))
 	}
 
-	fmt.Printf(colours.NoticeColor, "Please Be Patient for bxss"+"")
+	headers, err := payloadParser.ReadHeaders()
+	if err != nil {
+		logger.Error("Error reading header file: " + err.Error())
+		os.Exit(1)
+	}
 
-	// Create a channel to send work items to the worker pool
-	workChan := make(chan string)
+	payloads, err := payloadParser.ReadPayloads()
+	if err != nil {
+		logger.Error("Error reading payloads: " + err.Error())
+		os.Exit(1)
+	}
 
-	// Create a worker pool
-	var wg sync.WaitGroup
-	for i := 0; i < args.Concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for link := range workChan {
-				payloadParser.ProcessPayloadsAndHeaders(limiter, link, payloads, headers)
-			}
-		}()
+	logger.Notice("Please Be Patient for bxss" + "")
+
+	// Report progress on stderr, estimating the time left from the rate limit if one is set
+	if args.Progress {
+		payloadParser.Progress = progress.New(args.RateLimit)
+		payloadParser.Progress.Start()
 	}
 
-	// Start sending the work items to the channel
-	go func() {
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // Increase buffer size
-		for scanner.Scan() {
-			link := strings.TrimSpace(scanner.Text())
-			if link == "" {
-				continue // Skip empty lines
-			}
-			workChan <- link
-		}
-		if err := scanner.Err(); err != nil {
-			fmt.Printf(colours.ErrorColor, "Error reading input: "+err.Error())
+	// Scan the endpoints of an OpenAPI document, the links and forms crawled
+	// from targets on stdin, or the targets themselves as they arrive without
+	// waiting for the whole list
+	if args.OpenAPI != "" {
+		err = payloadParser.ProcessEndpoints(scanCtx, limiter, endpoints, payloads, headers)
+	} else if args.Crawl {
+		endpoints, err = payloadParser.Crawl(scanCtx, limiter, os.Stdin)
+		if err == nil {
+			err = payloadParser.ProcessEndpoints(scanCtx, limiter, endpoints, payloads, headers)
 		}
-		close(workChan)
-	}()
+	} else {
+		err = payloadParser.ProcessLinks(scanCtx, limiter, os.Stdin, payloads, headers)
+	}
+	if payloadParser.Progress != nil {
+		payloadParser.Progress.Stop()
+	}
+	if err != nil && scanCtx.Err() == nil {
+		logger.Error("Error reading input: " + err.Error())
+	}
 
-	wg.Wait()
+	if ctx.Err() != nil {
+		logger.Warn("Scan interrupted.")
+		return
+	}
 
 	// Log completion message
-	fmt.Printf(colours.SuccessColor, "Scan completed successfully.")
-	fmt.Println("")
+	if stoppedOnHit(firstHit) {
+		logger.Success("Scan stopped on the first confirmed finding.")
+	} else if scanCtx.Err() != nil {
+		logger.Warn("Scan budget exhausted, stopped early.")
+	} else {
+		logger.Success("Scan completed successfully.")
+	}
+	if unreachable := payloadParser.Unreachable.Hosts(); len(unreachable) > 0 {
+		hosts := make([]string, 0, len(unreachable))
+		for host := range unreachable {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		logger.Warn(fmt.Sprintf("%d unreachable hosts were skipped:", len(hosts)))
+		for _, host := range hosts {
+			logger.Println("  " + host + " (" + unreachable[host] + ")")
+		}
+	}
+	if aggregator != nil {
+		summary := aggregator.Summary()
+		logger.Info(fmt.Sprintf("%d findings, %d confirmed, %d duplicates dropped", summary.Findings, summary.Confirmed, summary.Duplicates))
+	}
+	logger.Println("")
+
+	// Blind payloads can fire long after the scan, so keep listening until interrupted
+	if (payloadParser.Callbacks != nil || payloadParser.Collaborator != nil) && !stoppedOnHit(firstHit) {
+		logger.Notice("Still listening for callbacks, press Ctrl+C to stop")
+		select {
+		case <-ctx.Done():
+		case <-firstHit:
+		}
+	}
+}
+
+// stoppedOnHit reports whether -stop-on-first-hit has closed firstHit
+func stoppedOnHit(firstHit <-chan struct{}) bool {
+	select {
+	case <-firstHit:
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeFlags returns the browser flags in defaults overridden by those in flags
+func mergeFlags(defaults, flags map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(defaults)+len(flags))
+	for name, value := range defaults {
+		merged[name] = value
+	}
+	for name, value := range flags {
+		merged[name] = value
+	}
+	return merged
 }
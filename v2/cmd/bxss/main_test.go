@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs bxss itself instead of the tests when BXSS_RUN_MAIN is set,
+// so run can start it as a process of its own with the arguments given
+func TestMain(m *testing.M) {
+	if os.Getenv("BXSS_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// run runs bxss with args and no input, returning its output and exit status
+func run(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "BXSS_RUN_MAIN=1")
+	cmd.Stdin = strings.NewReader("")
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("running bxss %q: %v", args, err)
+	}
+	return string(out), 0
+}
+
+func TestInvalidFlagExitsNonZero(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-window-size", "abc", "-p", "x"}, "invalid window size"},
+		{[]string{"-cookie", "", "-p", "x"}, "invalid cookie"},
+		{[]string{"-tls-fingerprint", "safari", "-p", "x"}, "invalid -tls-fingerprint"},
+		{[]string{"-match-status", "abc", "-p", "x"}, "invalid -match-status"},
+		{[]string{"-crawl-depth", "-1", "-p", "x"}, "-crawl-depth must not be negative"},
+	} {
+		out, status := run(t, tt.args...)
+		if status != 1 {
+			t.Errorf("bxss %q exited with %d, want 1", tt.args, status)
+		}
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("bxss %q printed %q, want it to mention %q", tt.args, out, tt.want)
+		}
+	}
+}
+
+func TestListPayloadsExitsZero(t *testing.T) {
+	out, status := run(t, "-list-payloads")
+	if status != 0 || out == "" {
+		t.Errorf("bxss -list-payloads exited with %d printing %q, want the payloads and 0", status, out)
+	}
+}
@@ -1,50 +1,259 @@
 package arguments
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/mutate"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/retry"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scan"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
 )
 
 type Arguments struct {
-	Concurrency     int
-	Header          string
-	HeaderFile      string
-	Payload         string
-	PayloadFile     string
-	Method          string
-	AppendMode      bool
-	Parameters      bool
-	Debug           bool
-	RateLimit       float64
-	FollowRedirects bool
-	Trace           bool
-	BrowserType     string
-	BrowserPath     string
-	WorkerPool      int
-	RequestFile     string
+	Concurrency      int
+	Header           string
+	HeaderFile       string
+	Payload          string
+	PayloadFiles     []string
+	Method           string
+	AppendMode       bool
+	Parameters       bool
+	Debug            bool
+	RateLimit        float64
+	FollowRedirects  bool
+	Trace            bool
+	BrowserType      string
+	BrowserPath      string
+	WorkerPool       int
+	RequestFile      string
+	BrowserTimeout   time.Duration
+	Proxy            string
+	Headed           bool
+	ScreenshotDir    string
+	Cookies          []*http.Cookie
+	RemoteBrowser    string
+	ChromeFlags      map[string]interface{}
+	DisableWebSec    bool
+	UserAgent        string
+	WindowWidth      int
+	WindowHeight     int
+	WorkerLifetime   time.Duration
+	LazyWorkers      bool
+	NoSandbox        bool
+	HookEval         bool
+	WaitIdle         int
+	BaseURL          string
+	CallbackListen   string
+	CallbackURL      string
+	KeepDuplicates   bool
+	Encodings        []string
+	PathInject       bool
+	Data             string
+	CookieParams     []string
+	InjectAll        bool
+	Retries          int
+	RetryBackoff     time.Duration
+	Output           string
+	JSON             bool
+	Format           string
+	NotifyWebhook    string
+	NotifyTemplate   string
+	ResumeFile       string
+	RateLimitPerHost float64
+	AdaptiveRate     bool
+	DryRun           bool
+	Progress         bool
+	DefaultScheme    string
+	Insecure         bool
+	CACert           string
+	GlobalHeaders    http.Header
+	NoDedupe         bool
+	Scope            *scope.Scope
+	MaxRequests      int64
+	MaxDuration      time.Duration
+	AcquireTimeout   time.Duration
+	ReleaseTimeout   time.Duration
+	InteractshServer string
+	InteractshToken  string
+	Contexts         []string
+	ListPayloads     bool
+	ReflectCheck     bool
+	ReflectOnly      bool
+	Fragment         bool
+	Jitter           ratelimit.Jitter
+	RotateUA         bool
+	UAFile           string
+	GraphQL          scan.GraphQL
+	Multipart        string
+	MultipartFile    string
+	Quiet            bool
+	NoColor          bool
+	Color            string
+	ExtraMethods     []string
+	Filter           scan.ResponseFilter
+	HTTPTimeout      time.Duration
+	HTTP1            bool
+	Session          bool
+	CookieFile       string
+	AuthRequestFile  string
+	AuthExtract      []browser.Extraction
+	StopOnFirstHit   bool
+	MaxPayloads      int
+	MaxParams        int
+	RedactHeaders    []string
+	EmitCurl         bool
+	JSONPaths        []string
+	Mutators         []string
+	WebSocket        scan.WebSocket
+	ReplayFile       string
+	ReplayWait       time.Duration
+	InjectMode       scan.Mode
+	OpenAPI          string
+	Crawl            bool
+	CrawlDepth       int
+	CrawlRobots      bool
+	RedirectLimit    int
+	TLSFingerprint   string
+	ViewURLs         []string
+}
+
+// stringList is a flag.Value that collects every occurrence of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
 // Flag variables
 var (
-	debug           bool
-	concurrency     int
-	payload         string
-	payloadFile     string
-	method          string
-	header          string
-	headerFile      string
-	appendMode      bool
-	parameters      bool
-	rateLimit       float64
-	followRedirects bool
-	trace           bool
-	browserType     string
-	browserPath     string
-	workerPool      int
-	requestFile     string
+	debug            bool
+	concurrency      int
+	payload          string
+	payloadFiles     stringList
+	method           string
+	header           string
+	headerFile       string
+	appendMode       bool
+	parameters       bool
+	rateLimit        float64
+	followRedirects  bool
+	trace            bool
+	browserType      string
+	browserPath      string
+	workerPool       int
+	requestFile      string
+	browserTimeout   time.Duration
+	proxy            string
+	headed           bool
+	screenshotDir    string
+	cookies          stringList
+	remoteBrowser    string
+	chromeFlags      stringList
+	disableWebSec    bool
+	userAgent        string
+	windowSize       string
+	workerLifetime   time.Duration
+	lazyWorkers      bool
+	noSandbox        bool
+	hookEval         bool
+	waitIdle         int
+	baseURL          string
+	callbackListen   string
+	callbackURL      string
+	keepDuplicates   bool
+	encode           string
+	pathInject       bool
+	data             string
+	cookieParams     stringList
+	injectAll        bool
+	retries          int
+	retryBackoff     time.Duration
+	output           string
+	jsonOutput       bool
+	format           string
+	notifyWebhook    string
+	notifyTemplate   string
+	resumeFile       string
+	rateLimitPerHost float64
+	adaptiveRate     bool
+	dryRun           bool
+	showProgress     bool
+	defaultScheme    string
+	insecure         bool
+	caCert           string
+	globalHeaders    stringList
+	noDedupe         bool
+	scopeAllow       stringList
+	scopeExclude     stringList
+	maxRequests      int64
+	maxDuration      time.Duration
+	acquireTimeout   time.Duration
+	releaseTimeout   time.Duration
+	interactshServer string
+	interactshToken  string
+	payloadContexts  string
+	listPayloads     bool
+	reflectCheck     bool
+	reflectOnly      bool
+	fragment         bool
+	jitter           string
+	rotateUA         bool
+	uaFile           string
+	graphQL          scan.GraphQL
+	multipartFields  string
+	multipartFile    string
+	quiet            bool
+	noColor          bool
+	color            string
+	extraMethods     string
+	matchStatus      string
+	filterStatus     string
+	matchSize        string
+	filterSize       string
+	httpTimeout      time.Duration
+	http1            bool
+	session          bool
+	cookieFile       string
+	authRequestFile  string
+	authExtract      stringList
+	stopOnFirstHit   bool
+	maxPayloads      int
+	maxParams        int
+	redactHeaders    stringList
+	emitCurl         bool
+	jsonPaths        stringList
+	mutators         string
+	webSocket        scan.WebSocket
+	wsSubprotocols   string
+	replayFile       string
+	replayWait       time.Duration
+	injectMode       string
+	openAPI          string
+	crawlTargets     bool
+	crawlDepth       int
+	crawlRobots      bool
+	redirectLimit    int
+	tlsFingerprint   string
+	viewURLs         stringList
 )
 
 // ValidateArgs validates the arguments passed to the program and prints the
@@ -52,7 +261,7 @@ func (a *Arguments) ValidateArgs() {
 	// prints the default help and exits the program with status 1.
 
 	// The banner
-	fmt.Printf(colours.BannerColor, `
+	logger.Printf(colours.BannerColor, `
   ____                     
  | __ )  __  __  ___   ___ 
  |  _ \  \ \/ / / __| / __|
@@ -60,11 +269,11 @@ func (a *Arguments) ValidateArgs() {
  |____/  /_/\_\ |___/ |___/
                                         
 	`, "")
-	fmt.Printf(colours.TextColor, "", "v0.0.3")
-	fmt.Printf("\n")
+	logger.Printf(colours.TextColor, "", "v0.0.3")
+	logger.Printf("\n")
 
-	// Check if at least one header and one payload option is provided
-	if (a.Header == "" && a.HeaderFile == "") && (a.Payload == "" && a.PayloadFile == "") {
+	// Check that something was asked for, the built-in payloads are used when none are given
+	if (a.Header == "" && a.HeaderFile == "") && (a.Payload == "" && len(a.PayloadFiles) == 0 && len(a.Contexts) == 0) && !a.Parameters && a.GraphQL.Query == "" && !a.WebSocket.Enabled && a.ReplayFile == "" && a.OpenAPI == "" && !a.Crawl {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -72,47 +281,512 @@ func (a *Arguments) ValidateArgs() {
 
 // NewArguments parses the command line flags and returns a pointer to an Arguments
 // object. The Arguments object contains the parsed values of the flags, which can
-// be used to configure the program. The function will panic if there is an error
-// parsing the flags.
-func NewArguments() *Arguments {
+// be used to configure the program. An error is returned for the first flag
+// value that is invalid.
+func NewArguments() (*Arguments, error) {
 
 	// Define the flags
 	flag.IntVar(&concurrency, "c", 30, "Set the concurrency level for the scanner")
 	flag.StringVar(&header, "H", "", "Set a single custom header to test for blind XSS")
-	flag.StringVar(&headerFile, "hf", "", "Path to file containing headers to test for blind XSS")
+	flag.StringVar(&headerFile, "hf", "", "Path to file containing headers to test for blind XSS, one 'Name' or 'Name: value' per line (# comments allowed)")
+	flag.StringVar(&headerFile, "headers-file", "", "Same as -hf")
 	flag.StringVar(&payload, "p", "", "The blind XSS payload to test")
-	flag.StringVar(&payloadFile, "pf", "", "Path to file containing payloads to test for blind XSS")
+	flag.Var(&payloadFiles, "pf", "Path to file containing payloads to test for blind XSS, repeatable (findings name the file a payload came from)")
 	flag.BoolVar(&appendMode, "a", false, "Append the payload to the parameter value when testing")
 	flag.BoolVar(&parameters, "t", false, "Test the parameters for blind XSS by appending the payload to the parameter value")
-	flag.StringVar(&method, "X", "", "The HTTP method to use when testing (GET, POST, etc.)")
+	flag.BoolVar(&injectAll, "inject-all", false, "With -t, inject every parameter in a single request, re-testing them one at a time only on URLs that fire")
+	flag.StringVar(&method, "X", "", "The HTTP methods to test with, comma separated (e.g. GET,POST,PUT)")
+	flag.StringVar(&method, "method", "", "Same as -X")
+	flag.StringVar(&extraMethods, "extra-methods", "", "Custom HTTP methods to allow in -X and request files on top of the standard ones, comma separated (e.g. PURGE)")
+	flag.StringVar(&output, "output", "", "Write every injection as a finding to this file, as CSV for .csv, SARIF for .sarif and JSON lines otherwise")
+	flag.StringVar(&format, "format", "", "Findings format for -output or stdout (jsonl, csv, sarif)")
+	flag.StringVar(&notifyWebhook, "notify-webhook", "", "POST confirmed findings to this webhook (Slack and Discord URLs get their own message format)")
+	flag.StringVar(&notifyTemplate, "notify-template", "", "Go template for webhook messages, with the finding's fields (e.g. '{{.Target}}: {{.Payload}}')")
+	flag.BoolVar(&jsonOutput, "json", false, "Write JSON lines findings to stdout")
 	flag.BoolVar(&debug, "v", false, "Enable debug mode to view full request details and debug information")
 	flag.Float64Var(&rateLimit, "rl", 0, "Rate limit in requests per second (optional to prevent abuse)")
+	flag.Float64Var(&rateLimitPerHost, "rate-limit-per-host", 0, "Rate limit in requests per second for each host, within the overall -rl limit")
+	flag.BoolVar(&adaptiveRate, "adaptive-rate", false, "Halve the rate on repeated 429/503 responses, honouring Retry-After, and ramp back up to -rl (or 10/s) on success")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print every request that would be sent, with its injection point, without sending it or starting a browser")
+	flag.BoolVar(&showProgress, "progress", false, "Print URLs scanned, payloads sent, callbacks received and an ETA to stderr every few seconds")
+	flag.StringVar(&defaultScheme, "default-scheme", "https", "Scheme for targets given without one (https, http, or auto to probe https then fall back to http)")
+	flag.BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification for HTTP requests (e.g. self-signed staging environments)")
+	flag.StringVar(&caCert, "ca-cert", "", "PEM file of extra certificate authorities to trust for HTTP requests")
+	flag.Var(&globalHeaders, "header", "Header sent with every request and browser navigation, repeatable (e.g. 'Authorization: Bearer abc')")
+	flag.BoolVar(&noDedupe, "no-dedupe", false, "Scan every input URL, even ones differing from an earlier URL only in parameter order, host case or trailing slashes")
+	flag.Var(&scopeAllow, "scope", "Only send requests to these hosts, repeatable or comma separated (e.g. example.com, *.example.com, /^api[0-9]+\\./)")
+	flag.Var(&scopeExclude, "exclude", "Never send requests to these hosts, same syntax as -scope and taking precedence over it")
+	flag.Int64Var(&maxRequests, "max-requests", 0, "Stop the scan after sending this many requests (0 for no limit)")
+	flag.DurationVar(&maxDuration, "max-duration", 0, "Stop the scan after this long (e.g. 30m, 0 for no limit)")
+	flag.DurationVar(&acquireTimeout, "acquire-timeout", browser.DefaultAcquireTimeout, "How long to wait for a free browser worker before skipping a request (0 waits indefinitely)")
+	flag.DurationVar(&releaseTimeout, "release-timeout", browser.DefaultReleaseTimeout, "How long to wait to return a browser worker to the pool before discarding it (0 waits indefinitely)")
+	flag.StringVar(&interactshServer, "interactsh-server", "", "Interactsh server whose per-injection subdomains fill in {{callback}}, polled for DNS/HTTP interactions (e.g. oast.pro)")
+	flag.StringVar(&interactshToken, "interactsh-token", "", "Authentication token for the -interactsh-server")
+	flag.StringVar(&payloadContexts, "context", "", "Also scan the built-in payloads for these reflection contexts, comma separated (html, attr, js, url or all)")
+	flag.BoolVar(&listPayloads, "list-payloads", false, "Print the built-in payloads (those of -context if given) and exit")
+	flag.BoolVar(&reflectCheck, "reflect-check", false, "Send each injection over HTTP first and only load it in the browser when the response reflects the payload")
+	flag.BoolVar(&reflectOnly, "reflect-only", false, "Report payloads reflected in HTTP responses without loading them in the browser")
+	flag.BoolVar(&rotateUA, "rotate-ua", false, "Send a different User-Agent with each request, from a built-in list of real browsers")
+	flag.StringVar(&uaFile, "ua-file", "", "Rotate through the User-Agents in this file instead of the built-in list (implies -rotate-ua)")
+	flag.StringVar(&graphQL.Query, "graphql", "", "POST this GraphQL query to each URL with the payload in each of its string variables, instead of the usual injections")
+	flag.StringVar(&graphQL.Variables, "graphql-vars", "", "JSON object with the values of the -graphql variables (e.g. '{\"msg\":\"hi\"}')")
+	flag.StringVar(&graphQL.Operation, "graphql-operation", "", "Operation of the -graphql document to run, when it holds several")
+	flag.StringVar(&multipartFields, "multipart", "", "Form fields to send as a multipart/form-data body with the payload in each in turn (e.g. 'name=bob&comment=hi')")
+	flag.StringVar(&multipartFile, "multipart-file", "", "Add a file part of this name to -multipart bodies and also inject the payload into its filename")
+	flag.BoolVar(&quiet, "quiet", false, "Only print errors, warnings and confirmed findings")
+	flag.BoolVar(&noColor, "no-color", false, "Print without colours, same as -color never")
+	flag.StringVar(&color, "color", logger.ColorAuto, "Colour the output: always, never, or auto to colour terminals unless NO_COLOR is set")
+	flag.StringVar(&matchStatus, "match-status", "", "Only load responses with these status codes in the browser, comma separated with ranges or all (e.g. 200,300-399)")
+	flag.StringVar(&filterStatus, "filter-status", "", "Don't load responses with these status codes in the browser, comma separated with ranges (e.g. 404,500-599)")
+	flag.StringVar(&matchSize, "match-size", "", "Only load responses with these body sizes in bytes in the browser, comma separated with ranges")
+	flag.StringVar(&filterSize, "filter-size", "", "Don't load responses with these body sizes in bytes in the browser, comma separated with ranges")
+	flag.DurationVar(&httpTimeout, "http-timeout", 0, "Timeout of each HTTP request, apart from -browser-timeout (e.g. 5s, 30s; 0 keeps the defaults: 3s for probes, 10s for request files and crawling)")
+	flag.BoolVar(&http1, "http1", false, "Force HTTP/1.1 for HTTP requests instead of negotiating HTTP/2, for servers or WAFs that misbehave on h2")
+	flag.BoolVar(&session, "session", false, "Keep the cookies responses set and send them with later HTTP requests to the same host")
+	flag.StringVar(&cookieFile, "cookie-file", "", "Seed the -session cookie jar from this Netscape cookies.txt file, e.g. from curl -c (implies -session)")
+	flag.StringVar(&authRequestFile, "auth-request-file", "", "Log in before the scan by sending the requests in this file in order, in the -request format (implies -session)")
+	flag.Var(&authExtract, "auth-extract", "Capture a value from -auth-request-file responses as name=regex, used as {{name}} in later requests, repeatable (e.g. 'csrf=name=\"csrf\" value=\"([^\"]+)\"')")
+	flag.BoolVar(&stopOnFirstHit, "stop-on-first-hit", false, "Stop the scan as soon as a dialog or callback confirms a payload")
+	flag.IntVar(&maxPayloads, "max-payloads-per-param", 0, "Test each parameter with at most this many payloads, sampled at random per URL (0 for no limit)")
+	flag.IntVar(&maxParams, "max-params", 0, "Test at most this many query parameters of each URL one at a time, sampled at random per URL (0 for no limit)")
+	flag.Var(&redactHeaders, "redact-header", "Header to redact from the requests and responses saved with confirmed findings, on top of Authorization and Proxy-Authorization, repeatable (e.g. Cookie)")
+	flag.BoolVar(&emitCurl, "emit-curl", false, "Print a curl command reproducing each finding")
+	flag.Var(&jsonPaths, "json-path", "Only inject into the value at this path of a JSON -data template instead of every value, repeatable (e.g. user.profile.bio, items[0].name)")
+	flag.StringVar(&mutators, "mutators", "", "Also scan the variants these payload mutators produce, comma separated ("+strings.Join(mutate.Names(), ", ")+")")
+	flag.BoolVar(&webSocket.Enabled, "websocket", false, "Send the payload in WebSocket messages to each URL (ws://, wss://, or http(s):// switched over) instead of HTTP requests")
+	flag.StringVar(&webSocket.Message, "ws-message", "", "JSON message template for -websocket with the payload in each of its values in turn (e.g. '{\"type\":\"chat\",\"text\":\"hi\"}')")
+	flag.StringVar(&wsSubprotocols, "ws-subprotocol", "", "WebSocket subprotocols to offer in the handshake, comma separated")
+	flag.StringVar(&replayFile, "replay-file", "", "Replay the confirmed findings of a previous JSON lines results file and report which still fire")
+	flag.DurationVar(&replayWait, "replay-wait", scan.DefaultCallbackWait, "How long -replay-file waits for the callback of each finding confirmed by one")
+	flag.StringVar(&injectMode, "mode", "", "How the payload is put into existing values: replace, append (as -a), prefix or typed (keeps numbers and booleans ahead of the payload)")
+	flag.StringVar(&openAPI, "openapi", "", "Scan the endpoints of this OpenAPI 3 or Swagger 2 JSON document instead of URLs from stdin, against its server or -base-url")
+	flag.BoolVar(&crawlTargets, "crawl", false, "Crawl each URL from stdin for same-origin links and forms taking parameters, and scan those")
+	flag.IntVar(&crawlDepth, "crawl-depth", 2, "How many links away from each start URL -crawl follows")
+	flag.BoolVar(&crawlRobots, "crawl-robots", false, "Skip the paths robots.txt disallows when crawling")
+	flag.IntVar(&redirectLimit, "follow-redirects-limit", 10, "Maximum redirects followed with -f, a redirect loop stops the chain early")
+	flag.StringVar(&tlsFingerprint, "tls-fingerprint", transport.FingerprintDefault, "TLS ClientHello the HTTP requests present: chrome, firefox or default (Go's own), for WAFs blocking on JA3")
+	flag.Var(&viewURLs, "view-url", "Page where stored payloads render, loaded in the browser after every injection to confirm them, repeatable (e.g. https://example.com/comments)")
+	flag.StringVar(&jitter, "jitter", "", "Random delay before each request on top of the rate limits, as min-max (e.g. 100ms-500ms)")
+	flag.BoolVar(&fragment, "fragment", false, "Also load each URL in the browser with the payload as its #fragment, for DOM XSS (combine with -a to append to an existing fragment)")
 	flag.BoolVar(&followRedirects, "f", false, "Follow redirects when testing (optional)")
+	flag.IntVar(&retries, "retries", 0, "Retry requests failing with network errors or 5xx responses this many times")
+	flag.DurationVar(&retryBackoff, "retry-backoff", retry.DefaultBackoff, "Delay before the first retry, doubled for each one after (with jitter)")
 	flag.BoolVar(&trace, "l", false, "Enable trace mode to track which host is vulnerable to XSS, if your canary server support custom parameters, insert url={LINK}")
-	flag.StringVar(&browserType, "browser", "chrome", "Browser to use for testing (chrome, firefox, chromium)")
+	flag.StringVar(&browserType, "browser", "chrome", "Browser to use for testing (chrome, firefox, chromium, edge)")
 	flag.StringVar(&browserPath, "browser-path", "", "Custom path to browser executable")
 	flag.IntVar(&workerPool, "workers", 2, "Number of browser worker instances to use")
+	flag.StringVar(&resumeFile, "resume-file", "", "Record finished scans in this file and skip them when the scan is run again")
 	flag.StringVar(&requestFile, "request", "", "Path to file containing custom HTTP requests to import")
+	flag.DurationVar(&browserTimeout, "browser-timeout", 10*time.Second, "Maximum lifetime of a browser context (e.g. 5s, 30s)")
+	flag.StringVar(&proxy, "proxy", "", "HTTP or SOCKS5 proxy for the browser and HTTP requests (e.g. http://127.0.0.1:8080, socks5://127.0.0.1:1080)")
+	flag.BoolVar(&headed, "headed", false, "Show the browser window instead of running headless (useful for debugging)")
+	flag.StringVar(&screenshotDir, "screenshot-dir", "", "Directory to save a screenshot to whenever a payload is confirmed")
+	flag.Var(&cookies, "cookie", "Cookie to set in the browser, repeatable (e.g. 'session=abc;domain=example.com;path=/')")
+	flag.StringVar(&remoteBrowser, "remote-browser", "", "Attach to a running Chrome over CDP instead of launching one (e.g. ws://127.0.0.1:9222)")
+	flag.Var(&chromeFlags, "chrome-flag", "Extra Chrome command line flag as key=value, repeatable (e.g. lang=en-US, no-sandbox=false)")
+	flag.BoolVar(&disableWebSec, "disable-web-security", false, "Disable the browser's same-origin policy (CORS/CSP) as older versions did by default")
+	flag.StringVar(&userAgent, "user-agent", "", "User-Agent to send from the browser and HTTP requests")
+	flag.StringVar(&windowSize, "window-size", "1920x1080", "Browser window size as WIDTHxHEIGHT (e.g. 375x812 for mobile layouts)")
+	flag.DurationVar(&workerLifetime, "worker-lifetime", 0, "Recycle browser workers older than this to limit memory growth (0 disables)")
+	flag.BoolVar(&lazyWorkers, "lazy-workers", false, "Start browser workers on demand so scanning begins as soon as the first one is ready")
+	flag.BoolVar(&noSandbox, "no-sandbox", false, "Always disable the Chrome sandbox (by default it is only disabled when running as root)")
+	flag.BoolVar(&hookEval, "hook-eval", false, "Also report code passed to eval as a DOM sink (makes direct eval calls run in the global scope)")
+	flag.IntVar(&waitIdle, "wait-idle", 0, "Wait for the network to be idle for this many milliseconds after each page load (0 disables)")
+	flag.StringVar(&baseURL, "base-url", "", "Scheme and host for raw requests in the request file, overriding their Host header (e.g. http://127.0.0.1:8080)")
+	flag.StringVar(&callbackListen, "callback-listen", "", "Listen for blind XSS callbacks on this address (e.g. :8000) and correlate them to {{token}} payloads")
+	flag.StringVar(&callbackURL, "callback-url", "", "Callback URL substituted for {{callback}} in payloads (e.g. https://xss.report/c/username)")
+	flag.BoolVar(&keepDuplicates, "keep-duplicates", false, "Keep repeated lines in payload and header files instead of removing them")
+	flag.StringVar(&encode, "encode", "", "Also scan encoded variants of each payload, comma separated and applied in order (url, double-url, html, base64)")
+	flag.BoolVar(&pathInject, "path-inject", false, "Also inject the payload into each URL path segment and as a new final segment (combine with -a to append to segments)")
+	flag.StringVar(&data, "data", "", "Form or JSON body template whose fields are injected one at a time for POST, PUT and PATCH (e.g. 'name=bob&comment=hi')")
+	flag.Var(&cookieParams, "cookie-param", "Name of a cookie to inject the payload into, repeatable (e.g. session_theme)")
 
 	// Parse the arguments
 	flag.Parse()
 
+	// WebDriver can't attach headers to a page load, so with Firefox a header
+	// under test would only reach the HTTP probe and never the page
+	if !browser.BrowserType(browserType).SendsHeaders() {
+		if header != "" || headerFile != "" {
+			return nil, fmt.Errorf("-browser %s can't send headers with page loads, header injection (-H, -hf) needs chrome, chromium or edge", browserType)
+		}
+		if len(globalHeaders) > 0 {
+			logger.Warn("-browser " + browserType + " can't send headers with page loads, -header is only sent with the HTTP requests")
+		}
+	}
+
+	parsedCookies, err := parseCookies(cookies)
+	if err != nil {
+		return nil, err
+	}
+
+	windowWidth, windowHeight, err := parseWindowSize(windowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	encodings, err := parseEncodings(encode)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts, err := parseContexts(payloadContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedHeaders, err := parseHeaders(globalHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	targetScope, err := scope.New(scopeAllow, scopeExclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var requestJitter ratelimit.Jitter
+	if jitter != "" {
+		requestJitter, err = ratelimit.ParseJitter(jitter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if graphQL.Query != "" {
+		if _, err := scan.GraphQLInjections(graphQL, scan.Fill(""), scan.ModeReplace); err != nil {
+			return nil, err
+		}
+	}
+
+	var allowedMethods []string
+	for _, name := range strings.Split(extraMethods, ",") {
+		if name = strings.ToUpper(strings.TrimSpace(name)); name != "" {
+			allowedMethods = append(allowedMethods, name)
+		}
+	}
+	methods, err := parseMethods(method, allowedMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseFilter scan.ResponseFilter
+	for _, filter := range []struct {
+		name   string
+		value  string
+		ranges *scan.Ranges
+	}{
+		{"match-status", matchStatus, &responseFilter.MatchStatus},
+		{"filter-status", filterStatus, &responseFilter.FilterStatus},
+		{"match-size", matchSize, &responseFilter.MatchSize},
+		{"filter-size", filterSize, &responseFilter.FilterSize},
+	} {
+		ranges, err := scan.ParseRanges(filter.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", filter.name, err)
+		}
+		*filter.ranges = ranges
+	}
+
+	if len(jsonPaths) > 0 {
+		if _, err := scan.JSONInjections(data, scan.Fill(""), scan.ModeReplace, jsonPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	if webSocket.Message != "" {
+		if _, err := scan.JSONInjections(webSocket.Message, scan.Fill(""), scan.ModeReplace, nil); err != nil {
+			return nil, fmt.Errorf("invalid -ws-message: %w", err)
+		}
+	}
+	for _, protocol := range strings.Split(wsSubprotocols, ",") {
+		if protocol = strings.TrimSpace(protocol); protocol != "" {
+			webSocket.Subprotocols = append(webSocket.Subprotocols, protocol)
+		}
+	}
+
+	mutatorNames, err := mutate.Parse(mutators)
+	if err != nil {
+		return nil, fmt.Errorf("%w, expected one of %s", err, strings.Join(mutate.Names(), ", "))
+	}
+
+	for _, view := range viewURLs {
+		u, err := url.Parse(view)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid -view-url '%s', expected an http(s) URL", view)
+		}
+		if !targetScope.AllowsURL(view) {
+			return nil, fmt.Errorf("-view-url %s is out of scope", view)
+		}
+	}
+
+	if !transport.ValidFingerprint(tlsFingerprint) {
+		return nil, fmt.Errorf("invalid -tls-fingerprint '%s', expected one of %s", tlsFingerprint, strings.Join(transport.Fingerprints, ", "))
+	}
+
+	if redirectLimit < 1 {
+		return nil, errors.New("-follow-redirects-limit must be at least 1")
+	}
+
+	if crawlDepth < 0 {
+		return nil, errors.New("-crawl-depth must not be negative")
+	}
+
+	// -a is short for -mode append, an explicit -mode wins
+	mode, err := scan.ParseMode(injectMode)
+	if err != nil {
+		return nil, err
+	}
+	if injectMode == "" && appendMode {
+		mode = scan.ModeAppend
+	}
+
+	var extractions []browser.Extraction
+	for _, value := range authExtract {
+		extraction, err := browser.ParseExtraction(value)
+		if err != nil {
+			return nil, err
+		}
+		extractions = append(extractions, extraction)
+	}
+
+	switch color {
+	case logger.ColorAuto, logger.ColorAlways, logger.ColorNever:
+	default:
+		return nil, fmt.Errorf("invalid colour mode %s, expected always, auto or never", color)
+	}
+
+	switch defaultScheme {
+	case "https", "http", "auto":
+	default:
+		return nil, fmt.Errorf("invalid default scheme %s, expected https, http or auto", defaultScheme)
+	}
+
 	return &Arguments{
-		Concurrency:     concurrency,
-		Header:          header,
-		HeaderFile:      headerFile,
-		Payload:         payload,
-		PayloadFile:     payloadFile,
-		Method:          method,
-		AppendMode:      appendMode,
-		Parameters:      parameters,
-		Debug:           debug,
-		RateLimit:       rateLimit,
-		FollowRedirects: followRedirects,
-		Trace:           trace,
-		BrowserType:     browserType,
-		BrowserPath:     browserPath,
-		WorkerPool:      workerPool,
-		RequestFile:     requestFile,
+		Concurrency:      concurrency,
+		Header:           header,
+		HeaderFile:       headerFile,
+		Payload:          payload,
+		PayloadFiles:     payloadFiles,
+		Method:           methods,
+		AppendMode:       appendMode,
+		Parameters:       parameters,
+		Debug:            debug,
+		RateLimit:        rateLimit,
+		FollowRedirects:  followRedirects,
+		Trace:            trace,
+		BrowserType:      browserType,
+		BrowserPath:      browserPath,
+		WorkerPool:       workerPool,
+		RequestFile:      requestFile,
+		BrowserTimeout:   browserTimeout,
+		Proxy:            proxy,
+		Headed:           headed,
+		ScreenshotDir:    screenshotDir,
+		Cookies:          parsedCookies,
+		RemoteBrowser:    remoteBrowser,
+		ChromeFlags:      parseChromeFlags(chromeFlags),
+		DisableWebSec:    disableWebSec,
+		UserAgent:        userAgent,
+		WindowWidth:      windowWidth,
+		WindowHeight:     windowHeight,
+		WorkerLifetime:   workerLifetime,
+		LazyWorkers:      lazyWorkers,
+		NoSandbox:        noSandbox,
+		HookEval:         hookEval,
+		WaitIdle:         waitIdle,
+		BaseURL:          baseURL,
+		CallbackListen:   callbackListen,
+		CallbackURL:      callbackURL,
+		KeepDuplicates:   keepDuplicates,
+		Encodings:        encodings,
+		PathInject:       pathInject,
+		Data:             data,
+		CookieParams:     cookieParams,
+		InjectAll:        injectAll,
+		Retries:          retries,
+		RetryBackoff:     retryBackoff,
+		Output:           output,
+		JSON:             jsonOutput,
+		Format:           format,
+		NotifyWebhook:    notifyWebhook,
+		NotifyTemplate:   notifyTemplate,
+		ResumeFile:       resumeFile,
+		RateLimitPerHost: rateLimitPerHost,
+		AdaptiveRate:     adaptiveRate,
+		DryRun:           dryRun,
+		Progress:         showProgress,
+		DefaultScheme:    defaultScheme,
+		Insecure:         insecure,
+		CACert:           caCert,
+		GlobalHeaders:    parsedHeaders,
+		NoDedupe:         noDedupe,
+		Scope:            targetScope,
+		MaxRequests:      maxRequests,
+		MaxDuration:      maxDuration,
+		AcquireTimeout:   acquireTimeout,
+		ReleaseTimeout:   releaseTimeout,
+		InteractshServer: interactshServer,
+		InteractshToken:  interactshToken,
+		Contexts:         contexts,
+		ListPayloads:     listPayloads,
+		ReflectCheck:     reflectCheck,
+		ReflectOnly:      reflectOnly,
+		Fragment:         fragment,
+		Jitter:           requestJitter,
+		RotateUA:         rotateUA || uaFile != "",
+		UAFile:           uaFile,
+		GraphQL:          graphQL,
+		Multipart:        multipartFields,
+		MultipartFile:    multipartFile,
+		Quiet:            quiet,
+		NoColor:          noColor || color == logger.ColorNever,
+		Color:            color,
+		ExtraMethods:     allowedMethods,
+		Filter:           responseFilter,
+		HTTPTimeout:      httpTimeout,
+		HTTP1:            http1,
+		Session:          session || cookieFile != "" || authRequestFile != "",
+		CookieFile:       cookieFile,
+		AuthRequestFile:  authRequestFile,
+		AuthExtract:      extractions,
+		StopOnFirstHit:   stopOnFirstHit,
+		MaxPayloads:      maxPayloads,
+		MaxParams:        maxParams,
+		RedactHeaders:    redactHeaders,
+		EmitCurl:         emitCurl,
+		JSONPaths:        jsonPaths,
+		Mutators:         mutatorNames,
+		WebSocket:        webSocket,
+		ReplayFile:       replayFile,
+		ReplayWait:       replayWait,
+		InjectMode:       mode,
+		OpenAPI:          openAPI,
+		Crawl:            crawlTargets,
+		CrawlDepth:       crawlDepth,
+		CrawlRobots:      crawlRobots,
+		RedirectLimit:    redirectLimit,
+		TLSFingerprint:   tlsFingerprint,
+		ViewURLs:         viewURLs,
+	}, nil
+}
+
+// parseChromeFlags parses --chrome-flag values of the form key=value. A bare key
+// enables the flag and the values true/false are converted to booleans.
+func parseChromeFlags(values []string) map[string]interface{} {
+	flags := make(map[string]interface{}, len(values))
+	for _, value := range values {
+		name, raw, found := strings.Cut(value, "=")
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+		if name == "" {
+			continue
+		}
+
+		if !found {
+			flags[name] = true
+			continue
+		}
+		if b, err := strconv.ParseBool(raw); err == nil {
+			flags[name] = b
+		} else {
+			flags[name] = raw
+		}
+	}
+	return flags
+}
+
+// parseWindowSize parses a --window-size value of the form WIDTHxHEIGHT
+func parseWindowSize(value string) (int, int, error) {
+	w, h, found := strings.Cut(strings.ToLower(value), "x")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid window size '%s', expected WIDTHxHEIGHT", value)
+	}
+
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid window size '%s', width and height must be positive integers", value)
+	}
+	return width, height, nil
+}
+
+// parseEncodings parses the comma separated --encode list
+func parseEncodings(value string) ([]string, error) {
+	var encodings []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case "url", "double-url", "html", "base64":
+			encodings = append(encodings, name)
+		default:
+			return nil, fmt.Errorf("unknown encoding '%s', expected url, double-url, html or base64", name)
+		}
+	}
+	return encodings, nil
+}
+
+// parseContexts parses the comma separated --context list, all standing for every context
+func parseContexts(value string) ([]string, error) {
+	var contexts []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case "all":
+			return []string{"html", "attr", "js", "url"}, nil
+		case "html", "attr", "js", "url":
+			contexts = append(contexts, name)
+		default:
+			return nil, fmt.Errorf("unknown payload context '%s', expected html, attr, js, url or all", name)
+		}
+	}
+	return contexts, nil
+}
+
+// parseMethods parses the comma separated -X list into upper case methods
+// joined by commas, each one a standard method or one of extra
+func parseMethods(value string, extra []string) (string, error) {
+	var methods []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" || slices.Contains(methods, name) {
+			continue
+		}
+		if !browser.ValidMethod(name, extra) {
+			return "", fmt.Errorf("unknown HTTP method '%s', allow custom methods with -extra-methods", name)
+		}
+		methods = append(methods, name)
+	}
+	return strings.Join(methods, ","), nil
+}
+
+// parseHeaders parses --header values of the form "Name: Value"
+func parseHeaders(values []string) (http.Header, error) {
+	headers := make(http.Header, len(values))
+	for _, value := range values {
+		name, val, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: Value'", value)
+		}
+		headers.Add(name, strings.TrimSpace(val))
+	}
+	return headers, nil
+}
+
+// parseCookies parses --cookie values of the form name=value;domain=...;path=...
+// Cookies without a domain are scoped to each target as it is scanned.
+func parseCookies(values []string) ([]*http.Cookie, error) {
+	var parsed []*http.Cookie
+	for _, value := range values {
+		cookie, err := http.ParseSetCookie(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie '%s': %w", value, err)
+		}
+		parsed = append(parsed, cookie)
 	}
+	return parsed, nil
 }
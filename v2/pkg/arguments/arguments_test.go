@@ -0,0 +1,71 @@
+package arguments
+
+import "testing"
+
+func TestParseWindowSize(t *testing.T) {
+	width, height, err := parseWindowSize("375X812")
+	if err != nil {
+		t.Fatalf("parseWindowSize: %v", err)
+	}
+	if width != 375 || height != 812 {
+		t.Errorf("size = %dx%d, want 375x812", width, height)
+	}
+
+	for _, value := range []string{"1920", "0x1080", "1920x-1", "widexhigh"} {
+		if _, _, err := parseWindowSize(value); err == nil {
+			t.Errorf("parseWindowSize(%q) accepted an invalid size", value)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"Authorization: Bearer a:b", "x-marker:bxss", "X-Marker: again"})
+	if err != nil {
+		t.Fatalf("parseHeaders: %v", err)
+	}
+	if got := headers.Get("Authorization"); got != "Bearer a:b" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := headers.Values("X-Marker"); len(got) != 2 || got[0] != "bxss" || got[1] != "again" {
+		t.Errorf("X-Marker = %q, want both values", got)
+	}
+
+	for _, value := range []string{"no colon", ": empty name"} {
+		if _, err := parseHeaders([]string{value}); err == nil {
+			t.Errorf("parseHeaders(%q) accepted an invalid header", value)
+		}
+	}
+}
+
+func TestParseContexts(t *testing.T) {
+	contexts, err := parseContexts(" Attr,js,")
+	if err != nil {
+		t.Fatalf("parseContexts: %v", err)
+	}
+	if len(contexts) != 2 || contexts[0] != "attr" || contexts[1] != "js" {
+		t.Errorf("contexts = %q, want attr and js", contexts)
+	}
+	if all, _ := parseContexts("html,all"); len(all) != 4 {
+		t.Errorf("all = %q, want every context", all)
+	}
+	if _, err := parseContexts("attr,css"); err == nil {
+		t.Error("parseContexts accepted an unknown context")
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	methods, err := parseMethods("get, Post,PUT,get", nil)
+	if err != nil {
+		t.Fatalf("parseMethods: %v", err)
+	}
+	if methods != "GET,POST,PUT" {
+		t.Errorf("methods = %q, want GET,POST,PUT", methods)
+	}
+
+	if _, err := parseMethods("GET,PURGE", nil); err == nil {
+		t.Error("parseMethods accepted PURGE without -extra-methods")
+	}
+	if methods, err := parseMethods("GET,purge", []string{"PURGE"}); err != nil || methods != "GET,PURGE" {
+		t.Errorf("parseMethods with PURGE allowed = %q, %v", methods, err)
+	}
+}
@@ -2,17 +2,30 @@ package browser
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/retry"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"golang.org/x/time/rate"
 )
 
 // BrowserType represents the type of browser to use
@@ -27,26 +40,339 @@ const (
 
 	// Firefox browser
 	Firefox BrowserType = "firefox"
+
+	// Edge browser (Chromium-based Microsoft Edge)
+	Edge BrowserType = "edge"
 )
 
+// supported reports whether t is a browser type bxss can drive
+func (t BrowserType) supported() bool {
+	return t == Chrome || t == Chromium || t == Firefox || t == Edge
+}
+
+// SendsHeaders reports whether the browser can send extra headers with a
+// page load. Firefox is driven over WebDriver, which can't.
+func (t BrowserType) SendsHeaders() bool {
+	return t != Firefox
+}
+
+// geteuid returns the effective user id, replaceable for testing
+var geteuid = os.Geteuid
+
+// DefaultTimeout is the lifetime applied to browser contexts when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+// Default browser window dimensions
+const (
+	DefaultWindowWidth  = 1920
+	DefaultWindowHeight = 1080
+)
+
+// Driver performs page operations on a browser context independently of the
+// automation protocol (CDP for Chrome/Chromium, WebDriver for Firefox) behind it
+type Driver interface {
+	// Navigate loads url, sending headers with the request where the protocol allows it
+	Navigate(ctx context.Context, url string, headers map[string]interface{}) error
+
+	// Evaluate runs a JavaScript expression in the page and stores its result in res
+	Evaluate(ctx context.Context, expression string, res interface{}) error
+
+	// DialogEvents returns the JavaScript dialogs opened since the last call and clears them
+	DialogEvents() []Dialog
+
+	// Screenshot captures the current viewport as a PNG image
+	Screenshot(ctx context.Context) ([]byte, error)
+
+	// WaitIdle blocks until the page has made no network requests for the quiet period
+	WaitIdle(ctx context.Context, quiet time.Duration) error
+
+	// SetCookies sets cookies scoped to url for the next navigation to it
+	SetCookies(ctx context.Context, url string, cookies []*http.Cookie) error
+}
+
+// Engine creates browser contexts, each carrying the Driver that operates it.
+// Browser is the real engine, FakeEngine an in-memory one for tests.
+type Engine interface {
+	CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error)
+}
+
+// maxIdleWait bounds how long NavigateAndWait waits for pages that never go
+// quiet, such as those holding long-polling connections open
+const maxIdleWait = 10 * time.Second
+
+// NavigateAndWait navigates to url and then waits for the network to be idle
+// for waitMs milliseconds, so DOM injected by XHR-driven pages is rendered
+// before sinks are checked. Pages that never settle are given up to
+// maxIdleWait before returning without an error.
+func NavigateAndWait(ctx context.Context, url string, headers map[string]interface{}, waitMs int) error {
+	driver := DriverFromContext(ctx)
+	if err := driver.Navigate(ctx, url, headers); err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, maxIdleWait)
+	defer cancel()
+
+	err := driver.WaitIdle(waitCtx, time.Duration(waitMs)*time.Millisecond)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+// idleTracker tracks in-flight network requests to detect when a page goes quiet
+type idleTracker struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]struct{}
+	lastActivity time.Time
+}
+
+// started records a request being sent
+func (t *idleTracker) started(id network.RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inflight == nil {
+		t.inflight = make(map[network.RequestID]struct{})
+	}
+	t.inflight[id] = struct{}{}
+	t.lastActivity = time.Now()
+}
+
+// finished records a request completing or failing
+func (t *idleTracker) finished(id network.RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.inflight, id)
+	t.lastActivity = time.Now()
+}
+
+// idleFor reports whether no request has been in flight for the quiet period
+func (t *idleTracker) idleFor(quiet time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.inflight) == 0 && time.Since(t.lastActivity) >= quiet
+}
+
+// Dialog is a JavaScript alert, confirm or prompt opened by a page
+type Dialog struct {
+	Type    string
+	Message string
+	URL     string
+}
+
+// dialogRecorder collects dialogs captured by a driver until they are drained
+type dialogRecorder struct {
+	mu      sync.Mutex
+	dialogs []Dialog
+}
+
+// record stores a captured dialog
+func (r *dialogRecorder) record(d Dialog) {
+	r.mu.Lock()
+	r.dialogs = append(r.dialogs, d)
+	r.mu.Unlock()
+}
+
+// DialogEvents returns the recorded dialogs and clears the recorder
+func (r *dialogRecorder) DialogEvents() []Dialog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dialogs := r.dialogs
+	r.dialogs = nil
+	return dialogs
+}
+
+// driverKey is the context key under which a context's Driver is stored
+type driverKey struct{}
+
+// withDriver returns a copy of ctx carrying the given Driver
+func withDriver(ctx context.Context, d Driver) context.Context {
+	return context.WithValue(ctx, driverKey{}, d)
+}
+
+// DriverFromContext returns the Driver backing a browser context. Contexts that
+// don't carry one are treated as chromedp contexts.
+func DriverFromContext(ctx context.Context) Driver {
+	if d, ok := ctx.Value(driverKey{}).(Driver); ok {
+		return d
+	}
+	return &chromeDriver{}
+}
+
+// chromeDriver drives Chrome/Chromium contexts over the DevTools protocol
+type chromeDriver struct {
+	dialogRecorder
+	idle    idleTracker
+	cookies []*http.Cookie
+}
+
+// listenNetwork tracks request activity in the target for WaitIdle
+func (d *chromeDriver) listenNetwork(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			d.idle.started(ev.RequestID)
+		case *network.EventLoadingFinished:
+			d.idle.finished(ev.RequestID)
+		case *network.EventLoadingFailed:
+			d.idle.finished(ev.RequestID)
+		}
+	})
+}
+
+// listenDialogs records every JavaScript dialog opened in the target and
+// dismisses it so the page doesn't stall waiting for user input
+func (d *chromeDriver) listenDialogs(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*page.EventJavascriptDialogOpening); ok {
+			d.record(Dialog{
+				Type:    string(ev.Type),
+				Message: ev.Message,
+				URL:     ev.URL,
+			})
+			go chromedp.Run(ctx, page.HandleJavaScriptDialog(false))
+		}
+	})
+}
+
+// Navigate sets any extra headers on the page and then navigates to url
+func (d *chromeDriver) Navigate(ctx context.Context, url string, headers map[string]interface{}) error {
+	var tasks chromedp.Tasks
+
+	// Cookies without a domain are scoped to the target being navigated to
+	if params := cookieParams(d.cookies, url, false); len(params) > 0 {
+		tasks = append(tasks, network.SetCookies(params))
+	}
+
+	if len(headers) > 0 {
+		tasks = append(tasks,
+			network.Enable(),
+			network.SetExtraHTTPHeaders(network.Headers(headers)),
+		)
+	}
+
+	tasks = append(tasks, chromedp.Navigate(url))
+	return chromedp.Run(ctx, tasks)
+}
+
+// SetCookies sets the cookies for url through the browser's cookie store
+func (*chromeDriver) SetCookies(ctx context.Context, url string, cookies []*http.Cookie) error {
+	params := cookieParams(cookies, url, false)
+	if len(params) == 0 {
+		return nil
+	}
+	return chromedp.Run(ctx, network.SetCookies(params))
+}
+
+// Evaluate runs the expression in the page via Runtime.evaluate
+func (*chromeDriver) Evaluate(ctx context.Context, expression string, res interface{}) error {
+	return chromedp.Run(ctx, chromedp.Evaluate(expression, res))
+}
+
+// WaitIdle polls the network tracker until no requests have been in flight for
+// the quiet period. Navigate has already waited for the load event by then.
+func (d *chromeDriver) WaitIdle(ctx context.Context, quiet time.Duration) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !d.idle.idleFor(quiet) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Screenshot captures the viewport via Page.captureScreenshot
+func (*chromeDriver) Screenshot(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// cookieParams converts cookies into CDP cookie parameters. When domainScoped
+// is true only cookies with an explicit domain are returned; otherwise only
+// host-only cookies are returned, bound to targetURL.
+func cookieParams(cookies []*http.Cookie, targetURL string, domainScoped bool) []*network.CookieParam {
+	var params []*network.CookieParam
+	for _, c := range cookies {
+		if (c.Domain != "") != domainScoped {
+			continue
+		}
+
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if !domainScoped {
+			param.URL = targetURL
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// Screenshot captures the page shown in a browser context and writes it to outPath as a PNG
+func Screenshot(ctx context.Context, outPath string) error {
+	buf, err := DriverFromContext(ctx).Screenshot(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to capture screenshot: %w", err)
+	}
+
+	if err := os.WriteFile(outPath, buf, 0644); err != nil {
+		return fmt.Errorf("failed to write screenshot: %w", err)
+	}
+	return nil
+}
+
 // Browser represents a browser instance
 type Browser struct {
-	Type     BrowserType
-	Path     string
-	browsers []string
+	Type               BrowserType
+	Path               string
+	Timeout            time.Duration
+	Proxy              string
+	Headless           bool
+	Cookies            []*http.Cookie
+	RemoteURL          string
+	ExtraFlags         map[string]interface{}
+	DisableWebSecurity bool
+	UserAgent          string
+	WindowWidth        int
+	WindowHeight       int
+	Debug              bool
+	NoSandbox          bool
+	HookEval           bool
+	browsers           []string
+	names              []string
 }
 
 // NewBrowser creates a new browser instance
 func NewBrowser(browserType string, customPath string) *Browser {
 	bt := BrowserType(browserType)
-	if bt != Chrome && bt != Chromium && bt != Firefox {
-		fmt.Printf(colours.ErrorColor, "Unsupported browser type: "+browserType+". Using Chrome as default.")
+	if !bt.supported() {
+		logger.Error("Unsupported browser type: " + browserType + ". Using Chrome as default.")
 		bt = Chrome
 	}
 
 	b := &Browser{
-		Type: bt,
-		Path: customPath,
+		Type:         bt,
+		Path:         customPath,
+		Timeout:      DefaultTimeout,
+		Headless:     true,
+		WindowWidth:  DefaultWindowWidth,
+		WindowHeight: DefaultWindowHeight,
 	}
 
 	// Initialize possible browser paths
@@ -67,6 +393,7 @@ func (b *Browser) initBrowserPaths() {
 			"C:\\Program Files\\Google\\Chrome\\Application\\chrome.exe",
 			"C:\\Program Files (x86)\\Google\\Chrome\\Application\\chrome.exe",
 		}
+		b.names = []string{"google-chrome", "google-chrome-stable", "chrome"}
 	case Chromium:
 		b.browsers = []string{
 			"/usr/bin/chromium",
@@ -75,6 +402,7 @@ func (b *Browser) initBrowserPaths() {
 			"/Applications/Chromium.app/Contents/MacOS/Chromium",
 			"C:\\Program Files\\Chromium\\Application\\chrome.exe",
 		}
+		b.names = []string{"chromium", "chromium-browser"}
 	case Firefox:
 		b.browsers = []string{
 			"/usr/bin/firefox",
@@ -83,6 +411,17 @@ func (b *Browser) initBrowserPaths() {
 			"C:\\Program Files\\Mozilla Firefox\\firefox.exe",
 			"C:\\Program Files (x86)\\Mozilla Firefox\\firefox.exe",
 		}
+		b.names = []string{"firefox"}
+	case Edge:
+		b.browsers = []string{
+			"/usr/bin/microsoft-edge",
+			"/usr/bin/microsoft-edge-stable",
+			"/opt/microsoft/msedge/msedge",
+			"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+			"C:\\Program Files (x86)\\Microsoft\\Edge\\Application\\msedge.exe",
+			"C:\\Program Files\\Microsoft\\Edge\\Application\\msedge.exe",
+		}
+		b.names = []string{"microsoft-edge", "microsoft-edge-stable", "msedge"}
 	}
 
 	// If custom path is provided, add it to the beginning of the list
@@ -98,7 +437,7 @@ func (b *Browser) findBrowserPath() (string, error) {
 		if _, err := os.Stat(b.Path); err == nil {
 			return b.Path, nil
 		}
-		fmt.Printf(colours.WarningColor, "Custom browser path not found: "+b.Path+". Trying default locations.")
+		logger.Warn("Custom browser path not found: " + b.Path + ". Trying default locations.")
 	}
 
 	// Check each possible path
@@ -108,111 +447,246 @@ func (b *Browser) findBrowserPath() (string, error) {
 		}
 	}
 
+	// Fall back to $PATH for installs in non-standard prefixes (snap, Nix, etc.)
+	for _, name := range b.names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
 	// If we're here, we couldn't find the browser
 	return "", errors.New("browser executable not found")
 }
 
 // CreateContext creates a new browser context
 func (b *Browser) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	// Attach to an already running Chrome instead of launching one. Launch
+	// options such as the proxy or headless mode are up to whoever started it.
+	if b.RemoteURL != "" {
+		allocCtx, allocCancel := chromedp.NewRemoteAllocator(ctx, b.RemoteURL)
+		return b.createChromeContext(allocCtx, allocCancel)
+	}
+
+	// Without paths to look in, an unknown type would pass for a missing browser
+	if !b.Type.supported() {
+		return nil, nil, startError(b, ErrUnsupportedBrowser, nil)
+	}
+
 	path, err := b.findBrowserPath()
 	if err != nil {
 		// Provide helpful error message with installation instructions
 		b.printBrowserInstallationHelp()
-		return nil, nil, fmt.Errorf("browser not found: %w", err)
+		return nil, nil, startError(b, ErrBrowserNotFound, err)
 	}
 
 	// Different browser types require different approaches
 	switch b.Type {
-	case Chrome, Chromium:
-		// Create Chrome/Chromium context
-		opts := append(chromedp.DefaultExecAllocatorOptions[:],
-			chromedp.ExecPath(path),
-			chromedp.Flag("headless", true),
-			chromedp.Flag("disable-gpu", true),
-			chromedp.Flag("no-sandbox", true),
-			chromedp.Flag("disable-dev-shm-usage", true),
-			chromedp.Flag("disable-extensions", true),
-			chromedp.Flag("disable-setuid-sandbox", true),
-			chromedp.Flag("disable-web-security", true),
-			chromedp.WindowSize(1920, 1080),
-		)
+	case Chrome, Chromium, Edge:
+		// Create Chrome/Chromium context, Edge is Chromium-based and speaks CDP too
+		opts := b.allocatorOptions(path)
 
 		allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
 		// Don't defer cancel here - the allocator context must live as long as the browser context
-		
-		browserCtx, _ := chromedp.NewContext(allocCtx, chromedp.WithLogf(func(format string, args ...interface{}) {
-			// Suppress chromedp logs unless in debug mode
-		}))
+		return b.createChromeContext(allocCtx, allocCancel)
+
+	case Firefox:
+		// chromedp only speaks CDP, so Firefox is driven over WebDriver via geckodriver
+		return b.createFirefoxContext(ctx, path)
+	}
+
+	return nil, nil, startError(b, ErrUnsupportedBrowser, nil)
+}
 
-		// Add a timeout for browser operations
-		browserCtx, timeoutCancel := context.WithTimeout(browserCtx, 10*time.Second)
-		// Don't defer here either - we're returning this context
+// createChromeContext starts a browser context on the given chromedp allocator
+// and takes ownership of allocCancel
+func (b *Browser) createChromeContext(allocCtx context.Context, allocCancel context.CancelFunc) (context.Context, context.CancelFunc, error) {
+	// Suppress chromedp logs unless in debug mode
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(b.logf))
+
+	// Capture JavaScript dialogs so executed payloads can be confirmed
+	driver := &chromeDriver{cookies: b.Cookies}
+	driver.listenDialogs(browserCtx)
+	driver.listenNetwork(browserCtx)
+
+	// Add a timeout for browser operations
+	timeoutCtx, timeoutCancel := context.WithTimeout(withDriver(browserCtx, driver), b.timeout())
+	// Don't defer here either - we're returning this context
+
+	// Return a cancel function that cleans up every context in the chain,
+	// innermost first, so the browser is closed before its allocator
+	combinedCancel := func() {
+		timeoutCancel()
+		browserCancel()
+		allocCancel()
+	}
+
+	// Ensure browser is started
+	if err := chromedp.Run(timeoutCtx, chromedp.Navigate("about:blank")); err != nil {
+		combinedCancel()
+		return nil, nil, startError(b, ErrBrowserStart, err)
+	}
 
-		// Ensure browser is started
-		if err := chromedp.Run(browserCtx, chromedp.Navigate("about:blank")); err != nil {
-			allocCancel()
-			timeoutCancel()
-			return nil, nil, fmt.Errorf("failed to start browser: %w", err)
+	// Override the User-Agent for every navigation made from this target
+	if b.UserAgent != "" {
+		if err := chromedp.Run(timeoutCtx, emulation.SetUserAgentOverride(b.UserAgent)); err != nil {
+			combinedCancel()
+			return nil, nil, startError(b, ErrBrowserStart, fmt.Errorf("failed to set user agent: %w", err))
 		}
+	}
+
+	// Record what pages write to DOM sinks, for payloads that reach one without executing
+	if err := instrumentSinks(timeoutCtx, b.HookEval); err != nil {
+		combinedCancel()
+		return nil, nil, startError(b, ErrBrowserStart, fmt.Errorf("failed to instrument DOM sinks: %w", err))
+	}
 
-		// Return a cancel function that cleans up both contexts
-		combinedCancel := func() {
-			timeoutCancel()
-			allocCancel()
+	// Cookies with an explicit domain can be installed up front
+	if params := cookieParams(b.Cookies, "", true); len(params) > 0 {
+		if err := chromedp.Run(timeoutCtx, network.SetCookies(params)); err != nil {
+			combinedCancel()
+			return nil, nil, startError(b, ErrBrowserStart, fmt.Errorf("failed to set cookies: %w", err))
 		}
-		return browserCtx, combinedCancel, nil
+	}
 
-	case Firefox:
-		// Currently, chromedp doesn't support Firefox directly
-		// For Firefox, we need to use a different approach or library
-		// This is a placeholder - in a real implementation, you might use another library for Firefox
-		return nil, nil, errors.New("firefox support is currently experimental and not fully implemented")
+	return timeoutCtx, combinedCancel, nil
+}
+
+// logf writes browser log output to stderr when debug mode is enabled
+func (b *Browser) logf(format string, args ...interface{}) {
+	if !b.Debug {
+		return
+	}
+	fmt.Fprintf(os.Stderr, colours.DebugColor, fmt.Sprintf(format, args...))
+}
+
+// windowSize returns the configured window dimensions, falling back to the defaults if unset
+func (b *Browser) windowSize() (int, int) {
+	width, height := b.WindowWidth, b.WindowHeight
+	if width <= 0 || height <= 0 {
+		return DefaultWindowWidth, DefaultWindowHeight
+	}
+	return width, height
+}
+
+// timeout returns the configured context lifetime, falling back to the default if unset
+func (b *Browser) timeout() time.Duration {
+	if b.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return b.Timeout
+}
+
+// allocatorOptions builds the exec allocator options used to launch Chrome/Chromium
+func (b *Browser) allocatorOptions(path string) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(path))
+	for name, value := range b.flags() {
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+
+	// Surface the browser's own stdout/stderr to help diagnose startup failures
+	if b.Debug {
+		opts = append(opts, chromedp.CombinedOutput(os.Stderr))
+	}
+
+	return opts
+}
+
+// flags returns the command line flags Chrome/Chromium is launched with on
+// top of chromedp's defaults. A false value removes a flag entirely.
+func (b *Browser) flags() map[string]interface{} {
+	width, height := b.windowSize()
+	flags := map[string]interface{}{
+		"headless":              b.Headless,
+		"disable-dev-shm-usage": true,
+		"disable-extensions":    true,
+		"window-size":           fmt.Sprintf("%d,%d", width, height),
+	}
+
+	// Chrome refuses to start sandboxed as root, so only drop the sandbox
+	// there unless the user explicitly asks for it to be disabled
+	if b.NoSandbox || geteuid() == 0 {
+		flags["no-sandbox"] = true
+		flags["disable-setuid-sandbox"] = true
+	}
+
+	// Turning off the same-origin policy changes how CORS/CSP behave, so it is
+	// opt-in to keep results faithful to what a real victim's browser would do
+	if b.DisableWebSecurity {
+		flags["disable-web-security"] = true
+	}
+
+	// GPU acceleration is only disabled headless so a headed window renders normally
+	if b.Headless {
+		flags["disable-gpu"] = true
 	}
 
-	return nil, nil, errors.New("unsupported browser type")
+	// Route browser traffic through the proxy, trusting its certificate so
+	// intercepting proxies such as Burp work without installing their CA
+	if b.Proxy != "" {
+		flags["proxy-server"] = b.Proxy
+		flags["ignore-certificate-errors"] = true
+	}
+
+	// User supplied flags go last so they override the defaults above
+	for name, value := range b.ExtraFlags {
+		flags[name] = value
+	}
+
+	return flags
 }
 
 // printBrowserInstallationHelp prints helpful instructions for installing the required browser
 func (b *Browser) printBrowserInstallationHelp() {
-	fmt.Printf(colours.ErrorColor, "Browser not found: "+string(b.Type))
-	fmt.Println()
+	logger.Error("Browser not found: " + string(b.Type))
+	logger.Println()
 
 	switch b.Type {
 	case Chrome:
-		fmt.Printf(colours.InfoColor, "To install Google Chrome, you can use:")
-		fmt.Println("\nDebian/Ubuntu:")
-		fmt.Println("  wget https://dl.google.com/linux/direct/google-chrome-stable_current_amd64.deb")
-		fmt.Println("  sudo apt install ./google-chrome-stable_current_amd64.deb")
-		fmt.Println("  sudo cp /usr/bin/google-chrome-stable /usr/bin/google-chrome")
-		fmt.Println("\nFedora:")
-		fmt.Println("  sudo dnf install https://dl.google.com/linux/direct/google-chrome-stable_current_x86_64.rpm")
-		fmt.Println("\nOr specify a custom path with --browser-path flag")
+		logger.Info("To install Google Chrome, you can use:")
+		logger.Println("\nDebian/Ubuntu:")
+		logger.Println("  wget https://dl.google.com/linux/direct/google-chrome-stable_current_amd64.deb")
+		logger.Println("  sudo apt install ./google-chrome-stable_current_amd64.deb")
+		logger.Println("  sudo cp /usr/bin/google-chrome-stable /usr/bin/google-chrome")
+		logger.Println("\nFedora:")
+		logger.Println("  sudo dnf install https://dl.google.com/linux/direct/google-chrome-stable_current_x86_64.rpm")
+		logger.Println("\nOr specify a custom path with --browser-path flag")
 
 	case Chromium:
-		fmt.Printf(colours.InfoColor, "To install Chromium, you can use:")
-		fmt.Println("\nDebian/Ubuntu:")
-		fmt.Println("  sudo apt install chromium-browser")
-		fmt.Println("\nFedora:")
-		fmt.Println("  sudo dnf install chromium")
-		fmt.Println("\nOr specify a custom path with --browser-path flag")
+		logger.Info("To install Chromium, you can use:")
+		logger.Println("\nDebian/Ubuntu:")
+		logger.Println("  sudo apt install chromium-browser")
+		logger.Println("\nFedora:")
+		logger.Println("  sudo dnf install chromium")
+		logger.Println("\nOr specify a custom path with --browser-path flag")
 
 	case Firefox:
-		fmt.Printf(colours.InfoColor, "To install Firefox, you can use:")
-		fmt.Println("\nDebian/Ubuntu:")
-		fmt.Println("  sudo apt install firefox")
-		fmt.Println("\nFedora:")
-		fmt.Println("  sudo dnf install firefox")
-		fmt.Println("\nOr specify a custom path with --browser-path flag")
+		logger.Info("To install Firefox, you can use:")
+		logger.Println("\nDebian/Ubuntu:")
+		logger.Println("  sudo apt install firefox")
+		logger.Println("\nFedora:")
+		logger.Println("  sudo dnf install firefox")
+		logger.Println("\ngeckodriver must also be on your PATH:")
+		logger.Println("  https://github.com/mozilla/geckodriver/releases")
+		logger.Println("\nOr specify a custom path with --browser-path flag")
+
+	case Edge:
+		logger.Info("To install Microsoft Edge, you can use:")
+		logger.Println("\nDebian/Ubuntu:")
+		logger.Println("  Add the https://packages.microsoft.com/repos/edge apt repository, then")
+		logger.Println("  sudo apt install microsoft-edge-stable")
+		logger.Println("\nWindows/macOS:")
+		logger.Println("  https://www.microsoft.com/edge/download")
+		logger.Println("\nOr specify a custom path with --browser-path flag")
 	}
 
-	fmt.Println()
+	logger.Println()
 }
 
 // BrowserPool represents a pool of browser contexts
 type BrowserPool struct {
-	browser        *Browser
+	browser        Engine
 	pool           chan context.Context
-	cancelFuncs    []context.CancelFunc
+	cancelFuncs    map[context.Context]context.CancelFunc
 	maxWorkers     int
 	mu             sync.Mutex
 	ctx            context.Context
@@ -220,21 +694,70 @@ type BrowserPool struct {
 	initializing   bool
 	initialized    bool
 	initErrCount   int
+	startErr       error
 	initialization sync.WaitGroup
+	closing        bool
+	checkedOut     map[context.Context]struct{}
+	active         sync.WaitGroup
+	oneTime        map[context.Context]context.CancelFunc
+	createdAt      map[context.Context]time.Time
+	spawned        int
+	warmOnce       sync.Once
+	created        int64
+	recycled       int64
+	waitTime       int64
+
+	// CloseTimeout caps how long Close waits for checked out contexts to be released
+	CloseTimeout time.Duration
+
+	// MaxLifetime recycles workers older than this when they are checked out; zero disables it
+	MaxLifetime time.Duration
+
+	// Lazy starts workers on demand from GetContext instead of all upfront in Initialize
+	Lazy bool
+
+	// AcquireTimeout caps how long GetContext waits for a context to be
+	// released; zero waits indefinitely
+	AcquireTimeout time.Duration
+
+	// ReleaseTimeout caps how long ReleaseContext waits for room in the pool
+	// before discarding the context; zero waits indefinitely
+	ReleaseTimeout time.Duration
+
+	// Logger receives the pool's messages, logger.Default() when nil
+	Logger *logger.Logger
 }
 
+// Default pool timeouts
+const (
+	// DefaultCloseTimeout is how long Close waits for in-flight contexts by default
+	DefaultCloseTimeout = 5 * time.Second
+
+	// DefaultAcquireTimeout is how long GetContext waits for a free context by default
+	DefaultAcquireTimeout = 5 * time.Second
+
+	// DefaultReleaseTimeout is how long ReleaseContext waits to return a context by default
+	DefaultReleaseTimeout = 1 * time.Second
+)
+
 // NewBrowserPool creates a new browser pool
-func NewBrowserPool(browser *Browser, maxWorkers int) *BrowserPool {
+func NewBrowserPool(browser Engine, maxWorkers int) *BrowserPool {
 	ctx, cancel := context.WithCancel(context.Background())
 	pool := &BrowserPool{
-		browser:      browser,
-		pool:         make(chan context.Context, maxWorkers),
-		cancelFuncs:  make([]context.CancelFunc, 0, maxWorkers),
-		maxWorkers:   maxWorkers,
-		ctx:          ctx,
-		cancel:       cancel,
-		initializing: false,
-		initialized:  false,
+		browser:        browser,
+		pool:           make(chan context.Context, maxWorkers),
+		cancelFuncs:    make(map[context.Context]context.CancelFunc, maxWorkers),
+		checkedOut:     make(map[context.Context]struct{}),
+		oneTime:        make(map[context.Context]context.CancelFunc),
+		createdAt:      make(map[context.Context]time.Time),
+		maxWorkers:     maxWorkers,
+		ctx:            ctx,
+		cancel:         cancel,
+		initializing:   false,
+		initialized:    false,
+		CloseTimeout:   DefaultCloseTimeout,
+		AcquireTimeout: DefaultAcquireTimeout,
+		ReleaseTimeout: DefaultReleaseTimeout,
 	}
 
 	return pool
@@ -253,155 +776,572 @@ func (p *BrowserPool) Initialize() error {
 
 	defer p.initialization.Done()
 
-	fmt.Printf(colours.InfoColor, fmt.Sprintf("Initializing browser pool with %d workers...\n", p.maxWorkers))
+	p.log().Info(fmt.Sprintf("Initializing browser pool with %d workers...\n", p.maxWorkers))
 
-	for i := 0; i < p.maxWorkers; i++ {
-		browserCtx, cancel, err := p.browser.CreateContext(p.ctx)
+	var lastErr error
+	for i := 0; i < p.maxWorkers && p.reserveWorker(); i++ {
+		browserCtx, err := p.startWorker()
 		if err != nil {
-			p.mu.Lock()
-			p.initErrCount++
-			count := p.initErrCount
-			p.mu.Unlock()
-
-			// Only log the first error to avoid spam
-			if count == 1 {
-				fmt.Printf(colours.WarningColor, fmt.Sprintf("Error initializing browser worker: %v\n", err))
+			lastErr = err
+			// The other workers would fail the same way
+			if !retryable(err) {
+				break
 			}
 			continue
 		}
-
-		p.mu.Lock()
-		p.cancelFuncs = append(p.cancelFuncs, cancel)
 		p.pool <- browserCtx
-		p.mu.Unlock()
 
-		fmt.Printf(colours.SuccessColor, fmt.Sprintf("Browser worker %d initialized\n", i+1))
+		p.log().Info(fmt.Sprintf("Browser worker %d initialized\n", i+1))
 	}
 
 	p.mu.Lock()
 	p.initializing = false
 
 	// Check if we actually initialized any browsers
-	if len(p.cancelFuncs) > 0 {
+	if workers := len(p.cancelFuncs); workers > 0 {
 		p.initialized = true
 		p.mu.Unlock()
-		fmt.Printf(colours.SuccessColor, fmt.Sprintf("Browser pool initialized with %d workers\n", len(p.cancelFuncs)))
+		p.log().Info(fmt.Sprintf("Browser pool initialized with %d workers\n", workers))
 		return nil
 	}
 
 	p.mu.Unlock()
+	if lastErr != nil {
+		return fmt.Errorf("failed to initialize any browser workers: %w", lastErr)
+	}
 	return fmt.Errorf("failed to initialize any browser workers")
 }
 
-// GetContext gets a browser context from the pool
-func (p *BrowserPool) GetContext() (context.Context, error) {
-	if !p.initialized && !p.initializing {
+// reserveWorker claims a worker slot if the pool is below maxWorkers
+func (p *BrowserPool) reserveWorker() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closing || p.spawned >= p.maxWorkers {
+		return false
+	}
+	p.spawned++
+	return true
+}
+
+// startWorker launches a browser context for a slot claimed with reserveWorker
+// and registers it with the pool. The slot is released again on failure.
+func (p *BrowserPool) startWorker() (context.Context, error) {
+	browserCtx, cancel, err := p.createContext()
+	if err != nil {
+		p.mu.Lock()
+		p.spawned--
+		p.initErrCount++
+		count := p.initErrCount
+		p.mu.Unlock()
+
+		// Only log the first error to avoid spam
+		if count == 1 {
+			p.log().Warn(fmt.Sprintf("Error initializing browser worker: %v\n", err))
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closing {
+		// The pool was closed while this worker was starting
+		p.spawned--
+		cancel()
+		return nil, errors.New("browser pool is closed")
+	}
+	p.cancelFuncs[browserCtx] = cancel
+	p.createdAt[browserCtx] = time.Now()
+	p.created++
+	return browserCtx, nil
+}
+
+// createContext creates a browser context with the engine, unless it already
+// failed in a way retrying can't fix, such as the browser not being installed,
+// in which case that error is returned again without another attempt
+func (p *BrowserPool) createContext() (context.Context, context.CancelFunc, error) {
+	p.mu.Lock()
+	startErr := p.startErr
+	p.mu.Unlock()
+	if startErr != nil {
+		return nil, nil, startErr
+	}
+
+	ctx, cancel, err := p.browser.CreateContext(p.ctx)
+	if err != nil && !retryable(err) {
+		p.mu.Lock()
+		p.startErr = err
+		p.mu.Unlock()
+	}
+	return ctx, cancel, err
+}
+
+// getLazy hands out an idle worker if there is one, otherwise starts a new
+// worker for the caller and warms the remaining slots in the background
+func (p *BrowserPool) getLazy(ctx context.Context) (context.Context, error) {
+	select {
+	case browserCtx := <-p.pool:
+		return p.prepare(browserCtx)
+	default:
+	}
+
+	if p.reserveWorker() {
+		browserCtx, err := p.startWorker()
+		if err == nil {
+			p.mu.Lock()
+			p.initialized = true
+			p.mu.Unlock()
+
+			p.warmOnce.Do(func() { go p.warm() })
+			return p.checkout(browserCtx)
+		}
+
+		// Nothing could be started at all, let the caller fall back
+		if p.HealthyWorkers() == 0 {
+			return nil, err
+		}
+	}
+
+	return p.wait(ctx)
+}
+
+// warm starts the remaining worker slots and makes them available in the pool
+func (p *BrowserPool) warm() {
+	for i := 0; i < p.maxWorkers && p.reserveWorker(); i++ {
+		ctx, err := p.startWorker()
+		if err != nil {
+			if !retryable(err) {
+				return
+			}
+			continue
+		}
+		p.pool <- ctx
+	}
+}
+
+// GetContext gets a browser context from the pool, giving up when ctx is done
+func (p *BrowserPool) GetContext(ctx context.Context) (context.Context, error) {
+	start := time.Now()
+	defer func() { atomic.AddInt64(&p.waitTime, int64(time.Since(start))) }()
+
+	p.mu.Lock()
+	closing := p.closing
+	p.mu.Unlock()
+	if closing {
+		return nil, errors.New("browser pool is closed")
+	}
+	// A free context mustn't win the race against a cancellation in wait
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.Lazy {
+		return p.getLazy(ctx)
+	}
+
+	p.mu.Lock()
+	initialized, initializing := p.initialized, p.initializing
+	p.mu.Unlock()
+	if !initialized && !initializing {
 		err := p.Initialize()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// If we're still initializing, wait for it to complete
-	if p.initializing {
+	// If we're still initializing, wait for it to complete. Initialize adds
+	// to the WaitGroup under the same lock it marks itself as running under,
+	// so seeing it running there means the wait can't miss it
+	p.mu.Lock()
+	initializing = p.initializing
+	p.mu.Unlock()
+	if initializing {
 		p.initialization.Wait()
 	}
 
 	// If we failed to initialize, create a one-time context
-	if !p.initialized {
-		fmt.Printf(colours.WarningColor, "Using one-time browser context as pool initialization failed\n")
-		ctx, cancel, err := p.browser.CreateContext(p.ctx)
+	p.mu.Lock()
+	initialized = p.initialized
+	p.mu.Unlock()
+	if !initialized {
+		p.log().Warn("Using one-time browser context as pool initialization failed\n")
+		return p.NewOneTimeContext()
+	}
+
+	// Normal pool operation
+	return p.wait(ctx)
+}
+
+// wait blocks until a pooled context is released, ctx is done or the wait
+// exceeds AcquireTimeout
+func (p *BrowserPool) wait(ctx context.Context) (context.Context, error) {
+	timeout, stop := after(p.AcquireTimeout)
+	defer stop()
+
+	select {
+	case browserCtx := <-p.pool:
+		return p.prepare(browserCtx)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.ctx.Done():
+		return nil, errors.New("browser pool is closed")
+	case <-timeout:
+		return nil, fmt.Errorf("timeout waiting %s for browser context", p.AcquireTimeout)
+	}
+}
+
+// log returns the logger for the pool's messages
+func (p *BrowserPool) log() *logger.Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return logger.Default()
+}
+
+// after returns a channel receiving once d has elapsed, which never receives
+// when d is zero, and a function releasing its timer
+func after(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
+
+// prepare checks out a context taken from the pool, replacing it first if it
+// is too old or its browser has crashed or timed out
+func (p *BrowserPool) prepare(ctx context.Context) (context.Context, error) {
+	if p.expired(ctx) || p.healthCheck(ctx) != nil {
+		var err error
+		ctx, err = p.recreateWorker(ctx)
 		if err != nil {
 			return nil, err
 		}
+	}
+	return p.checkout(ctx)
+}
 
-		// Since this is one-time use, we'll clean it up when done
-		go func() {
-			<-ctx.Done()
-			cancel()
-		}()
+// NewOneTimeContext creates a browser context outside the pool. It is
+// cancelled when passed to ReleaseContext, or at the latest by Close.
+func (p *BrowserPool) NewOneTimeContext() (context.Context, error) {
+	ctx, cancel, err := p.createContext()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closing {
+		cancel()
+		return nil, errors.New("browser pool is closed")
+	}
+	p.oneTime[ctx] = cancel
+	return ctx, nil
+}
+
+// checkout records ctx as in use so Close can wait for it to be released
+func (p *BrowserPool) checkout(ctx context.Context) (context.Context, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-		return ctx, nil
+	if p.closing {
+		return nil, errors.New("browser pool is closed")
 	}
+	p.checkedOut[ctx] = struct{}{}
+	p.active.Add(1)
+	return ctx, nil
+}
 
-	// Normal pool operation
-	select {
-	case ctx := <-p.pool:
-		return ctx, nil
-	case <-p.ctx.Done():
+// expired reports whether a worker has outlived MaxLifetime
+func (p *BrowserPool) expired(ctx context.Context) bool {
+	if p.MaxLifetime <= 0 {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	created, ok := p.createdAt[ctx]
+	return ok && time.Since(created) > p.MaxLifetime
+}
+
+// healthCheck verifies that a pooled context is still able to run JavaScript
+func (p *BrowserPool) healthCheck(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var x int
+	return DriverFromContext(checkCtx).Evaluate(checkCtx, "1", &x)
+}
+
+// recreateWorker tears down a dead worker and replaces it with a fresh context
+func (p *BrowserPool) recreateWorker(ctx context.Context) (context.Context, error) {
+	p.mu.Lock()
+	if cancel, ok := p.cancelFuncs[ctx]; ok {
+		cancel()
+		delete(p.cancelFuncs, ctx)
+		p.spawned--
+	}
+	delete(p.createdAt, ctx)
+	p.recycled++
+	p.mu.Unlock()
+
+	if !p.reserveWorker() {
 		return nil, errors.New("browser pool is closed")
-	case <-time.After(5 * time.Second):
-		return nil, errors.New("timeout waiting for browser context")
 	}
+	browserCtx, err := p.startWorker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to recreate browser worker: %w", err)
+	}
+	return browserCtx, nil
+}
+
+// PoolStats is a snapshot of a browser pool's usage
+type PoolStats struct {
+	// Idle is the number of pooled contexts waiting to be checked out
+	Idle int
+
+	// InUse is the number of contexts checked out, including one-time contexts
+	InUse int
+
+	// Created is the number of pooled workers started, including replacements
+	Created int64
+
+	// Recycled is the number of workers replaced for being too old or unhealthy
+	Recycled int64
+
+	// WaitTime is the total time callers have spent in GetContext
+	WaitTime time.Duration
+}
+
+// Stats returns the pool's current usage counts
+func (p *BrowserPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PoolStats{
+		Idle:     len(p.pool),
+		InUse:    len(p.checkedOut) + len(p.oneTime),
+		Created:  p.created,
+		Recycled: p.recycled,
+		WaitTime: time.Duration(atomic.LoadInt64(&p.waitTime)),
+	}
+}
+
+// String formats the stats for logging
+func (s PoolStats) String() string {
+	return fmt.Sprintf("idle %d, in use %d, created %d, recycled %d, waited %s",
+		s.Idle, s.InUse, s.Created, s.Recycled, s.WaitTime.Round(time.Millisecond))
+}
+
+// HealthyWorkers returns the number of pooled browser contexts that are still alive
+func (p *BrowserPool) HealthyWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	for ctx := range p.cancelFuncs {
+		if ctx.Err() == nil {
+			count++
+		}
+	}
+	return count
 }
 
 // ReleaseContext returns a browser context to the pool
 func (p *BrowserPool) ReleaseContext(ctx context.Context) {
-	if !p.initialized {
-		// For one-time contexts, we don't return to the pool
+	p.mu.Lock()
+	_, pooled := p.checkedOut[ctx]
+	delete(p.checkedOut, ctx)
+	oneTimeCancel, oneTime := p.oneTime[ctx]
+	delete(p.oneTime, ctx)
+	p.mu.Unlock()
+
+	if oneTime {
+		// One-time contexts are never reused, so tear them down straight away
+		oneTimeCancel()
+		return
+	}
+	if !pooled {
 		return
 	}
+	defer p.active.Done()
+
+	timeout, stop := after(p.ReleaseTimeout)
+	defer stop()
 
 	select {
 	case p.pool <- ctx:
 		// Successfully returned to pool
 	case <-p.ctx.Done():
 		// Pool is closed, don't return
-	case <-time.After(1 * time.Second):
+	case <-timeout:
 		// If we can't return it to the pool in a reasonable time, discard it
-		fmt.Printf(colours.WarningColor, "Timeout returning browser context to pool, discarding\n")
+		p.log().Warn("Timeout returning browser context to pool, discarding\n")
 	}
 }
 
-// Close closes the browser pool and all browser instances
+// Close closes the browser pool and all browser instances. Checked out
+// contexts are given up to CloseTimeout to be released before teardown.
 func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	if p.closing {
+		p.mu.Unlock()
+		return
+	}
+	p.closing = true
+	p.mu.Unlock()
+
+	// Wait for in-flight navigations to finish
+	drained := make(chan struct{})
+	go func() {
+		p.active.Wait()
+		close(drained)
+	}()
+
+	timeout := p.CloseTimeout
+	if timeout <= 0 {
+		timeout = DefaultCloseTimeout
+	}
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		p.log().Warn("Timeout waiting for browser contexts to be released, closing anyway\n")
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.cancel()
 
-	// Wait a moment for contexts to clean up
-	time.Sleep(100 * time.Millisecond)
-
-	// Cancel all browser contexts
+	// Cancel all browser contexts, including one-time contexts never released
 	for _, cancel := range p.cancelFuncs {
 		cancel()
 	}
-	p.cancelFuncs = nil
+	p.cancelFuncs = make(map[context.Context]context.CancelFunc)
+	p.createdAt = make(map[context.Context]time.Time)
+	p.spawned = 0
+	for _, cancel := range p.oneTime {
+		cancel()
+	}
+	p.oneTime = make(map[context.Context]context.CancelFunc)
 	p.initialized = false
 }
 
 // RequestParser represents a parser for custom HTTP requests
 type RequestParser struct {
-	FilePath string
+	FilePath  string
+	UserAgent string
+
+	// BaseURL supplies the scheme and host for raw requests, overriding their Host header
+	BaseURL string
+
+	// Connection tuning for the client used by ExecuteRequests. Transport,
+	// when set, is used as is and the other fields are ignored.
+	Transport           http.RoundTripper
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+
+	// Timeout bounds each request, including reading its response
+	Timeout time.Duration
+
+	// Jar, when set, keeps the cookies responses set and sends them with later requests
+	Jar http.CookieJar
+
+	// Concurrency is the number of requests ExecuteRequests sends at once
+	Concurrency int
+
+	// Retry settings for requests failing with network errors or 5xx responses
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Limiter, when set, paces every attempt, first ones and retries alike
+	Limiter *rate.Limiter
+
+	// Scope, when set, skips requests to hosts outside it
+	Scope *scope.Scope
+
+	// ExtraMethods are accepted in request lines on top of Methods
+	ExtraMethods []string
 }
 
+// Default connection settings for RequestParser
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultRequestTimeout      = 10 * time.Second
+)
+
 // NewRequestParser creates a new request parser
 func NewRequestParser(filePath string) *RequestParser {
 	return &RequestParser{
-		FilePath: filePath,
+		FilePath:            filePath,
+		MaxIdleConns:        DefaultMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+		Timeout:             DefaultRequestTimeout,
+	}
+}
+
+// client returns the HTTP client used to execute requests, reusing connections
+// across requests to the same host unless keep-alives are disabled
+func (p *RequestParser) client() *http.Client {
+	transport := p.Transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.MaxIdleConns = p.MaxIdleConns
+		t.MaxIdleConnsPerHost = p.MaxIdleConnsPerHost
+		t.IdleConnTimeout = p.IdleConnTimeout
+		t.DisableKeepAlives = p.DisableKeepAlives
+		transport = t
+	}
+
+	return &http.Client{
+		Timeout:   p.Timeout,
+		Transport: transport,
+		Jar:       p.Jar,
 	}
 }
 
 // ParseRequests parses the custom HTTP requests from the file
 // The file format should be a simple text file with one request per line
-// Each line should be in the format: METHOD URL [HEADER:VALUE]...
+// Each line should be in the format: METHOD URL [HEADER:VALUE]... [BODY=...]
 // Example: GET https://example.com User-Agent:CustomAgent X-Custom:Value
+// Everything after BODY= up to the end of the line is sent verbatim as the body
+// Example: POST https://example.com/api Content-Type:application/json BODY={"name": "x"}
 func (p *RequestParser) ParseRequests() ([]*http.Request, error) {
 	if p.FilePath == "" {
 		return nil, errors.New("no request file path provided")
 	}
 
-	// Open the file
-	file, err := os.Open(p.FilePath)
+	// Read the file
+	data, err := os.ReadFile(p.FilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open request file: %w", err)
 	}
-	defer file.Close()
+
+	// A file holding a raw HTTP request (e.g. copied from Burp Suite) starts
+	// with a request line ending in the protocol version
+	if isRawRequest(data) {
+		req, err := p.ParseRawRequest(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return []*http.Request{req}, nil
+	}
 
 	// Read the file line by line
 	var requests []*http.Request
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -433,78 +1373,391 @@ func (p *RequestParser) ParseRequests() ([]*http.Request, error) {
 	return requests, nil
 }
 
+// isRawRequest reports whether the first non-empty line of data is an HTTP request line
+func isRawRequest(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		return len(fields) == 3 && strings.HasPrefix(fields[2], "HTTP/")
+	}
+	return false
+}
+
+// ParseRawRequest parses a full raw HTTP request: request line, headers, a
+// blank line and an optional body. A request line with an absolute URL, as
+// sent to a proxy, is used as is. For an origin-form target such as /path the
+// scheme and host come from BaseURL when set, otherwise from the Host header
+// over https.
+func (p *RequestParser) ParseRawRequest(r io.Reader) (*http.Request, error) {
+	br := bufio.NewReader(r)
+
+	// Skip leading blank lines and normalise HTTP/2 request lines, which
+	// http.ReadRequest can't parse, to HTTP/1.1
+	var requestLine string
+	for {
+		line, err := br.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			requestLine = strings.TrimRight(line, "\r\n")
+			break
+		}
+		if err != nil {
+			return nil, errors.New("raw request is empty")
+		}
+	}
+	if strings.HasSuffix(requestLine, " HTTP/2") {
+		requestLine = strings.TrimSuffix(requestLine, "HTTP/2") + "HTTP/1.1"
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(io.MultiReader(strings.NewReader(requestLine+"\r\n"), br)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse raw request: %w", err)
+	}
+
+	// Read the body so the request no longer depends on the reader
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw request body: %w", err)
+	}
+	req.Body.Close()
+
+	target, err := p.rawTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build a fresh client request so the body length and Host are consistent
+	out, err := http.NewRequest(req.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if len(body) == 0 {
+		out.Body = http.NoBody
+		out.GetBody = nil
+		out.ContentLength = 0
+	}
+	out.Header = req.Header
+	out.Header.Del("Content-Length")
+
+	return out, nil
+}
+
+// rawTarget resolves the absolute URL a raw request is sent to
+func (p *RequestParser) rawTarget(req *http.Request) (*url.URL, error) {
+	if req.URL.IsAbs() {
+		return req.URL, nil
+	}
+
+	scheme, host := "https", req.Host
+	if p.BaseURL != "" {
+		base, err := url.Parse(p.BaseURL)
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("invalid base URL '%s'", p.BaseURL)
+		}
+		scheme, host = base.Scheme, base.Host
+	}
+	if host == "" {
+		return nil, errors.New("raw request has no Host header, specify a base URL")
+	}
+
+	target, err := url.Parse(scheme + "://" + host + req.RequestURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw request target: %w", err)
+	}
+	return target, nil
+}
+
 // parseRequestLine parses a single line into an http.Request
 func (p *RequestParser) parseRequestLine(line string, lineNum int) (*http.Request, error) {
+	// The body runs to the end of the line, so split it off before tokenizing
+	var body string
+	hasBody := false
+	if idx := strings.Index(line, " BODY="); idx != -1 {
+		body = line[idx+len(" BODY="):]
+		line = line[:idx]
+		hasBody = true
+	}
+
 	// Split the line into parts
-	parts := strings.Fields(line)
+	parts, err := splitRequestFields(line)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", lineNum, err)
+	}
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("line %d: invalid request format, expected at least METHOD and URL", lineNum)
 	}
 
 	// Extract method and URL
 	method := strings.ToUpper(parts[0])
-	url := parts[1]
+	// Spaces in a quoted URL must be escaped to be valid on the request line
+	url := strings.ReplaceAll(parts[1], " ", "%20")
 
 	// Validate method
-	validMethods := map[string]bool{
-		"GET": true, "POST": true, "PUT": true, "DELETE": true,
-		"HEAD": true, "OPTIONS": true, "PATCH": true,
-	}
-	if !validMethods[method] {
+	if !ValidMethod(method, p.ExtraMethods) {
 		return nil, fmt.Errorf("line %d: invalid HTTP method '%s'", lineNum, method)
 	}
 
-	// Create the request
-	req, err := http.NewRequest(method, url, nil)
+	// Create the request, NewRequest sets Content-Length from the body reader
+	var bodyReader io.Reader
+	if hasBody {
+		bodyReader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("line %d: failed to create request: %w", lineNum, err)
 	}
 
-	// Add headers if provided
+	// Add headers if provided. Headers are in the format Header:Value. A field
+	// that doesn't start with a valid header name and a colon, or that follows
+	// an unclosed parenthesis, continues the value of the header before it, so
+	// unquoted values such as "User-Agent:Mozilla/5.0 (X11; rv:109.0) Gecko"
+	// survive.
+	var headerName, headerValue string
 	for i := 2; i < len(parts); i++ {
 		headerPart := parts[i]
-		// Headers should be in the format Header:Value
-		if strings.Contains(headerPart, ":") {
-			headerParts := strings.SplitN(headerPart, ":", 2)
-			if len(headerParts) == 2 {
-				headerName := strings.TrimSpace(headerParts[0])
-				headerValue := strings.TrimSpace(headerParts[1])
+		name, value, found := strings.Cut(headerPart, ":")
+		switch {
+		case found && validHeaderName(name) && !unclosedParen(headerValue):
+			if headerName != "" {
 				req.Header.Add(headerName, headerValue)
-			} else {
-				return nil, fmt.Errorf("line %d: invalid header format '%s'", lineNum, headerPart)
 			}
-		} else {
+			headerName = strings.TrimSpace(name)
+			headerValue = strings.TrimSpace(value)
+		case headerName != "":
+			headerValue += " " + headerPart
+		default:
 			return nil, fmt.Errorf("line %d: invalid header format '%s'", lineNum, headerPart)
 		}
 	}
+	if headerName != "" {
+		req.Header.Add(headerName, headerValue)
+	}
+
+	// Guess the content type from the body unless one was given explicitly
+	if hasBody && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", detectContentType(body))
+	}
 
 	return req, nil
 }
 
+// validHeaderName reports whether name is a header field name, a non-empty
+// RFC 9110 token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// unclosedParen reports whether value opens more parentheses than it closes,
+// as a User-Agent does partway through its comment
+func unclosedParen(value string) bool {
+	return strings.Count(value, "(") > strings.Count(value, ")")
+}
+
+// splitRequestFields splits a request line on whitespace like strings.Fields,
+// except that single or double quoted sections are kept together with the
+// quotes removed, e.g. "User-Agent:Mozilla/5.0 (X11; rv:109.0)" or a URL
+// containing spaces
+func splitRequestFields(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inField := false
+	var quote rune
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// detectContentType returns the JSON content type for bodies that look like
+// JSON and the form content type for everything else
+func detectContentType(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "application/json"
+	}
+	return "application/x-www-form-urlencoded"
+}
+
 // ExecuteRequests executes all parsed requests and returns the responses
 func (p *RequestParser) ExecuteRequests(ctx context.Context) ([]*http.Response, error) {
 	requests, err := p.ParseRequests()
 	if err != nil {
 		return nil, err
 	}
+	requests = p.inScope(requests)
+
+	// Responses and errors are stored by index so the order matches the file
+	responses := make([]*http.Response, len(requests))
+	errs := make([]error, len(requests))
+
+	// Stop handing out requests once one fails
+	p.dispatch(ctx, requests, func(i int, resp *http.Response, err error) bool {
+		responses[i], errs[i] = resp, err
+		return err == nil
+	})
+
+	err = ctx.Err()
+	for _, e := range errs {
+		if e != nil {
+			err = e
+			break
+		}
+	}
+	if err != nil {
+		for _, resp := range responses {
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}
+		return nil, err
+	}
+
+	return responses, nil
+}
 
-	var responses []*http.Response
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// ExecuteRequestsFunc executes all parsed requests and passes each response to
+// fn, closing the body once fn returns, so memory stays flat however large the
+// request file is. A failed request is passed to fn with its error rather than
+// stopping the run. fn is called from several goroutines when Concurrency is
+// above one. The returned error is a parse failure or the context's error.
+func (p *RequestParser) ExecuteRequestsFunc(ctx context.Context, fn func(*http.Request, *http.Response, error)) error {
+	requests, err := p.ParseRequests()
+	if err != nil {
+		return err
 	}
+	requests = p.inScope(requests)
 
+	p.dispatch(ctx, requests, func(i int, resp *http.Response, err error) bool {
+		fn(requests[i], resp, err)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return true
+	})
+
+	return ctx.Err()
+}
+
+// inScope returns the requests whose host is within Scope, logging the others
+func (p *RequestParser) inScope(requests []*http.Request) []*http.Request {
+	if p.Scope.Empty() {
+		return requests
+	}
+
+	kept := requests[:0]
 	for _, req := range requests {
-		// Use the provided context
-		reqWithCtx := req.WithContext(ctx)
+		if !p.Scope.Allows(req.URL.Host) {
+			logger.Notice("Skipping out-of-scope request: " + req.Method + " " + req.URL.String())
+			continue
+		}
+		kept = append(kept, req)
+	}
+	return kept
+}
 
-		// Execute the request
-		resp, err := client.Do(reqWithCtx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute request to %s: %w", req.URL.String(), err)
+// dispatch sends the requests across at most Concurrency goroutines and calls
+// handle with each result and the index of its request. Dispatching stops when
+// handle returns false or the context is cancelled.
+func (p *RequestParser) dispatch(ctx context.Context, requests []*http.Request, handle func(int, *http.Response, error) bool) {
+	client := p.client()
+	workers := p.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	jobs := make(chan int)
+	var stopOnce sync.Once
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				resp, err := p.execute(ctx, client, requests[i])
+				if !handle(i, resp, err) {
+					stopOnce.Do(func() { close(stop) })
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := range requests {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break loop
+		case <-ctx.Done():
+			break loop
 		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// execute sends a single parsed request with the provided context
+func (p *RequestParser) execute(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	reqWithCtx := req.WithContext(ctx)
 
-		responses = append(responses, resp)
+	// Apply the default User-Agent unless the request file sets its own
+	if p.UserAgent != "" && reqWithCtx.Header.Get("User-Agent") == "" {
+		reqWithCtx.Header.Set("User-Agent", p.UserAgent)
 	}
 
-	return responses, nil
+	// The policy waits on the limiter before retries only, the first attempt
+	// is paced here
+	if p.Limiter != nil {
+		if err := p.Limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed to execute request to %s: %w", req.URL.String(), err)
+		}
+	}
+
+	policy := retry.Policy{Retries: p.Retries, Backoff: p.RetryBackoff, Limiter: p.Limiter}
+	resp, err := policy.Do(client, reqWithCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request to %s: %w", req.URL.String(), err)
+	}
+	return resp, nil
 }
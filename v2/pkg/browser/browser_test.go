@@ -0,0 +1,913 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"golang.org/x/time/rate"
+)
+
+// testBrowser returns a headless Chrome or Chromium, skipping the test when
+// neither is installed
+func testBrowser(t *testing.T) *Browser {
+	t.Helper()
+	for _, browserType := range []string{"chrome", "chromium"} {
+		b := NewBrowser(browserType, "")
+		if _, err := b.findBrowserPath(); err == nil {
+			b.NoSandbox = true
+			return b
+		}
+	}
+	t.Skip("Chrome or Chromium is not installed")
+	return nil
+}
+
+func TestPooledContextNavigatesTwice(t *testing.T) {
+	pool := NewBrowserPool(testBrowser(t), 1)
+	defer pool.Close()
+
+	ctx, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	defer pool.ReleaseContext(ctx)
+
+	// The allocator used to be cancelled as CreateContext returned, failing
+	// every navigation after the first
+	for i := 1; i <= 2; i++ {
+		if err := DriverFromContext(ctx).Navigate(ctx, "about:blank", nil); err != nil {
+			t.Fatalf("navigation %d: %v", i, err)
+		}
+	}
+}
+
+func TestTimeoutDefault(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	b.Timeout = 0
+	if got := b.timeout(); got != DefaultTimeout {
+		t.Errorf("timeout() = %s, want %s", got, DefaultTimeout)
+	}
+	b.Timeout = time.Second
+	if got := b.timeout(); got != time.Second {
+		t.Errorf("timeout() = %s, want 1s", got)
+	}
+}
+
+func TestNavigationTimesOut(t *testing.T) {
+	b := testBrowser(t)
+	b.Timeout = time.Second
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	err = DriverFromContext(ctx).Navigate(ctx, slow.URL, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Navigate = %v, want a deadline exceeded error", err)
+	}
+}
+
+func TestProxyFlags(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	if flags := b.flags(); flags["proxy-server"] != nil {
+		t.Errorf("proxy-server = %v without a proxy", flags["proxy-server"])
+	}
+
+	b.Proxy = "socks5://127.0.0.1:1080"
+	flags := b.flags()
+	if flags["proxy-server"] != b.Proxy {
+		t.Errorf("proxy-server = %v, want %s", flags["proxy-server"], b.Proxy)
+	}
+	if flags["ignore-certificate-errors"] != true {
+		t.Error("ignore-certificate-errors not set with a proxy")
+	}
+}
+
+func TestWindowSizeFlag(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	if size := b.flags()["window-size"]; size != "1920,1080" {
+		t.Errorf("window-size = %v, want the 1920,1080 default", size)
+	}
+
+	b.WindowWidth, b.WindowHeight = 375, 812
+	if size := b.flags()["window-size"]; size != "375,812" {
+		t.Errorf("window-size = %v, want 375,812", size)
+	}
+}
+
+// captureStderr redirects os.Stderr to a file for the rest of the test and
+// returns a function reading what was written to it
+func captureStderr(t *testing.T) func() string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = f
+	t.Cleanup(func() {
+		os.Stderr = stderr
+		f.Close()
+	})
+	return func() string {
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestBrowserLogOnlyInDebug(t *testing.T) {
+	output := captureStderr(t)
+	b := NewBrowser("chrome", "")
+
+	b.logf("quiet %d", 1)
+	if out := output(); out != "" {
+		t.Fatalf("browser log written without debug: %q", out)
+	}
+
+	b.Debug = true
+	b.logf("loud %d", 2)
+	if out := output(); !strings.Contains(out, "loud 2") {
+		t.Errorf("stderr = %q, want the browser log line in debug", out)
+	}
+}
+
+func TestNoSandboxOnlyForRoot(t *testing.T) {
+	euid := geteuid
+	t.Cleanup(func() { geteuid = euid })
+	b := NewBrowser("chrome", "")
+
+	geteuid = func() int { return 1000 }
+	if flags := b.flags(); flags["no-sandbox"] != nil || flags["disable-setuid-sandbox"] != nil {
+		t.Errorf("sandbox disabled for a normal user: %v", flags)
+	}
+
+	geteuid = func() int { return 0 }
+	if flags := b.flags(); flags["no-sandbox"] != true || flags["disable-setuid-sandbox"] != true {
+		t.Errorf("sandbox not disabled for root: %v", flags)
+	}
+
+	geteuid = func() int { return 1000 }
+	b.NoSandbox = true
+	if flags := b.flags(); flags["no-sandbox"] != true {
+		t.Error("NoSandbox doesn't override the sandbox for a normal user")
+	}
+}
+
+func TestHeadlessFlag(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	if flags := b.flags(); flags["headless"] != true {
+		t.Errorf("headless = %v, want true by default", flags["headless"])
+	}
+
+	b.Headless = false
+	flags := b.flags()
+	if flags["headless"] != false {
+		t.Errorf("headless = %v, want false when headed", flags["headless"])
+	}
+	if _, ok := flags["disable-gpu"]; ok {
+		t.Error("disable-gpu set on a headed browser")
+	}
+}
+
+func TestDialogCaptured(t *testing.T) {
+	b := testBrowser(t)
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	driver := DriverFromContext(ctx)
+	if err := driver.Navigate(ctx, "data:text/html,<script>alert('x')</script>", nil); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+
+	// The dialog event may arrive just after the load event
+	deadline := time.Now().Add(2 * time.Second)
+	var dialogs []Dialog
+	for len(dialogs) == 0 && time.Now().Before(deadline) {
+		dialogs = driver.DialogEvents()
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(dialogs) != 1 || dialogs[0].Type != "alert" || dialogs[0].Message != "x" {
+		t.Fatalf("dialogs = %+v, want one alert with message x", dialogs)
+	}
+}
+
+func TestDialogEventsDrain(t *testing.T) {
+	var r dialogRecorder
+	r.record(Dialog{Type: "alert", Message: "1"})
+	r.record(Dialog{Type: "confirm", Message: "2"})
+
+	if got := r.DialogEvents(); len(got) != 2 || got[1].Message != "2" {
+		t.Fatalf("DialogEvents() = %+v, want both dialogs", got)
+	}
+	if got := r.DialogEvents(); len(got) != 0 {
+		t.Fatalf("DialogEvents() after draining = %+v, want none", got)
+	}
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func TestScreenshotOfBlankPage(t *testing.T) {
+	b := testBrowser(t)
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	outPath := filepath.Join(t.TempDir(), "blank.png")
+	if err := Screenshot(ctx, outPath); err != nil {
+		t.Fatalf("Screenshot: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) <= len(pngSignature) || !bytes.HasPrefix(data, pngSignature) {
+		t.Fatalf("screenshot is %d bytes, want a non-empty PNG", len(data))
+	}
+}
+
+func TestScreenshotWritesDriverImage(t *testing.T) {
+	engine := &FakeEngine{Screenshot: append(pngSignature, "image"...)}
+	ctx, cancel, err := engine.CreateContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	outPath := filepath.Join(t.TempDir(), "fake.png")
+	if err := Screenshot(ctx, outPath); err != nil {
+		t.Fatalf("Screenshot: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, engine.Screenshot) {
+		t.Fatalf("wrote %q, want %q", data, engine.Screenshot)
+	}
+}
+
+func TestCookieReadBack(t *testing.T) {
+	b := testBrowser(t)
+	b.Cookies = []*http.Cookie{{Name: "session", Value: "abc", Domain: "example.com", Path: "/"}}
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	var cookies []*network.Cookie
+	err = chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().WithUrls([]string{"https://example.com/"}).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("GetCookies: %v", err)
+	}
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Fatalf("cookies = %+v, want session=abc", cookies)
+	}
+}
+
+func TestCookieParams(t *testing.T) {
+	cookies := []*http.Cookie{
+		{Name: "scoped", Value: "1", Domain: "example.com"},
+		{Name: "host", Value: "2"},
+	}
+
+	scoped := cookieParams(cookies, "", true)
+	if len(scoped) != 1 || scoped[0].Name != "scoped" || scoped[0].URL != "" {
+		t.Errorf("domain scoped params = %+v", scoped)
+	}
+	host := cookieParams(cookies, "https://target.test/", false)
+	if len(host) != 1 || host[0].Name != "host" || host[0].URL != "https://target.test/" {
+		t.Errorf("host-only params = %+v", host)
+	}
+}
+
+func TestRemoteAllocator(t *testing.T) {
+	var versionHits int32
+	var stub *httptest.Server
+	stub = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json/version" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&versionHits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"Browser":"Chrome/120.0","webSocketDebuggerUrl":"ws://%s/devtools/browser/stub"}`, stub.Listener.Addr())
+	}))
+	defer stub.Close()
+
+	b := NewBrowser("chrome", "")
+	b.RemoteURL = stub.URL
+	b.Timeout = 2 * time.Second
+	_, _, err := b.CreateContext(context.Background())
+
+	if atomic.LoadInt32(&versionHits) == 0 {
+		t.Fatal("/json/version was never requested")
+	}
+	// The stub can't speak CDP, so starting fails once connected rather than
+	// for want of a local browser
+	if !errors.Is(err, ErrBrowserStart) {
+		t.Fatalf("CreateContext = %v, want ErrBrowserStart", err)
+	}
+}
+
+func TestBrowserFoundOnPath(t *testing.T) {
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "chromium-browser")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	b := NewBrowser("chromium", "")
+	b.browsers = nil // skip the standard install locations
+	path, err := b.findBrowserPath()
+	if err != nil {
+		t.Fatalf("findBrowserPath: %v", err)
+	}
+	if path != fake {
+		t.Fatalf("findBrowserPath = %s, want %s", path, fake)
+	}
+}
+
+// captureLog sends the default logger's output to a buffer for the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := logger.Default()
+	logger.SetDefault(logger.New(&buf, logger.LevelDebug, true))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+	return &buf
+}
+
+func TestEdgeSupported(t *testing.T) {
+	out := captureLog(t)
+
+	b := NewBrowser("edge", "")
+	if b.Type != Edge {
+		t.Errorf("Type = %s, want edge", b.Type)
+	}
+	if len(b.browsers) == 0 || len(b.names) == 0 {
+		t.Error("no Edge install locations or executable names")
+	}
+	if strings.Contains(strings.ToLower(out.String()), "unsupported") {
+		t.Errorf("unexpected warning: %s", out)
+	}
+}
+
+func TestExtraFlagsOverrideDefaults(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	b.ExtraFlags = map[string]interface{}{
+		"lang":                  "en-US",
+		"disable-dev-shm-usage": false,
+	}
+
+	flags := b.flags()
+	if flags["lang"] != "en-US" {
+		t.Errorf("lang = %v, want en-US", flags["lang"])
+	}
+	if flags["disable-dev-shm-usage"] != false {
+		t.Errorf("disable-dev-shm-usage = %v, want the user's false", flags["disable-dev-shm-usage"])
+	}
+}
+
+func TestWebSecurityOptIn(t *testing.T) {
+	b := NewBrowser("chrome", "")
+	if _, ok := b.flags()["disable-web-security"]; ok {
+		t.Error("disable-web-security set by default")
+	}
+
+	b.DisableWebSecurity = true
+	if b.flags()["disable-web-security"] != true {
+		t.Error("disable-web-security not set when enabled")
+	}
+}
+
+func TestUserAgentOverride(t *testing.T) {
+	b := testBrowser(t)
+	b.UserAgent = "bxss-test-agent"
+
+	agents := make(chan string, 1)
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			agents <- r.UserAgent()
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, r.UserAgent())
+	}))
+	defer echo.Close()
+
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	if err := DriverFromContext(ctx).Navigate(ctx, echo.URL+"/", nil); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+	if got := <-agents; got != b.UserAgent {
+		t.Fatalf("server saw User-Agent %q, want %q", got, b.UserAgent)
+	}
+}
+
+func TestNavigateAndWaitRendersDelayedContent(t *testing.T) {
+	b := testBrowser(t)
+
+	spa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/late" {
+			time.Sleep(200 * time.Millisecond)
+			io.WriteString(w, "rendered late")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<body><div id="app"></div><script>
+setTimeout(function() {
+	fetch("/late").then(function(r) { return r.text() }).then(function(text) {
+		document.getElementById("app").textContent = text
+	})
+}, 100)
+</script></body>`)
+	}))
+	defer spa.Close()
+
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	if err := NavigateAndWait(ctx, spa.URL+"/", nil, 500); err != nil {
+		t.Fatalf("NavigateAndWait: %v", err)
+	}
+	var text string
+	if err := DriverFromContext(ctx).Evaluate(ctx, `document.getElementById("app").textContent`, &text); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if text != "rendered late" {
+		t.Errorf("app content = %q, want the content loaded after the delay", text)
+	}
+}
+
+// writeRequestFile writes content to a request file in a temp directory
+func writeRequestFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "requests.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// received is a request as seen by recordServer
+type received struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// recordServer returns a server recording every request it receives
+func recordServer(t *testing.T) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, received{r.Method, r.URL.RequestURI(), r.Header.Clone(), string(body)})
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), requests...)
+	}
+}
+
+func TestRequestFileJSONBody(t *testing.T) {
+	server, requests := recordServer(t)
+	body := `{"name": "x", "tags": ["a b", "c"]}`
+	p := NewRequestParser(writeRequestFile(t, "POST "+server.URL+"/api BODY="+body+"\n"))
+
+	responses, err := p.ExecuteRequests(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRequests: %v", err)
+	}
+	for _, resp := range responses {
+		resp.Body.Close()
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(got))
+	}
+	if got[0].Method != http.MethodPost || got[0].URL != "/api" {
+		t.Errorf("request = %s %s, want POST /api", got[0].Method, got[0].URL)
+	}
+	if got[0].Body != body {
+		t.Errorf("body = %q, want %q verbatim", got[0].Body, body)
+	}
+	if length := got[0].Header.Get("Content-Length"); length != fmt.Sprint(len(body)) {
+		t.Errorf("Content-Length = %s, want %d", length, len(body))
+	}
+	if ct := got[0].Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
+
+func TestRawRequestPost(t *testing.T) {
+	server, requests := recordServer(t)
+	body := "name=x&comment=%3Cb%3Ehi%3C%2Fb%3E"
+	raw := "POST /comment?id=1 HTTP/1.1\r\n" +
+		"Host: target.example\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\n" +
+		"X-Custom: one\r\n" +
+		"Cookie: session=abc\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"\r\n" + body
+	p := NewRequestParser(writeRequestFile(t, raw))
+	p.BaseURL = server.URL
+
+	responses, err := p.ExecuteRequests(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRequests: %v", err)
+	}
+	for _, resp := range responses {
+		resp.Body.Close()
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(got))
+	}
+	if got[0].Method != http.MethodPost || got[0].URL != "/comment?id=1" {
+		t.Errorf("request = %s %s, want POST /comment?id=1", got[0].Method, got[0].URL)
+	}
+	if got[0].Body != body {
+		t.Errorf("body = %q, want %q", got[0].Body, body)
+	}
+	for name, want := range map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+		"X-Custom":     "one",
+		"Cookie":       "session=abc",
+	} {
+		if value := got[0].Header.Get(name); value != want {
+			t.Errorf("%s = %q, want %q", name, value, want)
+		}
+	}
+}
+
+func TestRawRequestAbsoluteForm(t *testing.T) {
+	p := NewRequestParser("")
+	req, err := p.ParseRawRequest(strings.NewReader("GET http://target.example:8080/path?q=1 HTTP/1.1\r\nHost: other.example\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRawRequest: %v", err)
+	}
+	if got := req.URL.String(); got != "http://target.example:8080/path?q=1" {
+		t.Errorf("URL = %s, want the absolute request target", got)
+	}
+
+	// The base URL only completes origin-form targets
+	p.BaseURL = "https://base.example"
+	req, err = p.ParseRawRequest(strings.NewReader("GET http://target.example/path HTTP/1.1\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRawRequest with a base URL: %v", err)
+	}
+	if got := req.URL.String(); got != "http://target.example/path" {
+		t.Errorf("URL = %s, want the absolute request target", got)
+	}
+	req, err = p.ParseRawRequest(strings.NewReader("GET /path HTTP/1.1\r\nHost: other.example\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("ParseRawRequest of an origin-form target: %v", err)
+	}
+	if got := req.URL.String(); got != "https://base.example/path" {
+		t.Errorf("URL = %s, want it resolved against the base URL", got)
+	}
+}
+
+func TestRequestLineUnquotedUserAgent(t *testing.T) {
+	p := NewRequestParser("")
+	agent := "Mozilla/5.0 (X11; rv:109.0) Gecko/20100101 Firefox/115.0"
+	req, err := p.parseRequestLine("GET https://example.com/ User-Agent:"+agent+" X-Custom:a:b", 1)
+	if err != nil {
+		t.Fatalf("parseRequestLine: %v", err)
+	}
+	if got := req.Header.Get("User-Agent"); got != agent {
+		t.Errorf("User-Agent = %q, want %q", got, agent)
+	}
+	if got := req.Header.Get("X-Custom"); got != "a:b" {
+		t.Errorf("X-Custom = %q, want a:b", got)
+	}
+	if len(req.Header) != 2 {
+		t.Errorf("headers = %v, want only User-Agent and X-Custom", req.Header)
+	}
+
+	if _, err := p.parseRequestLine("GET https://example.com/ (X11:1", 1); err == nil {
+		t.Error("parseRequestLine accepted a header named (X11")
+	}
+}
+
+// BenchmarkExecuteRequests compares Go's default transport, which keeps only
+// two idle connections per host, and one opening a connection per request with
+// the tuned transport ExecuteRequests builds, sending 8 requests at once
+func BenchmarkExecuteRequests(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(b.TempDir(), "requests.txt")
+	lines := strings.Repeat("GET "+server.URL+"/\n", 20)
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name  string
+		setup func(p *RequestParser)
+	}{
+		{"default", func(p *RequestParser) { p.Transport = http.DefaultTransport.(*http.Transport).Clone() }},
+		{"no-keep-alive", func(p *RequestParser) { p.DisableKeepAlives = true }},
+		{"tuned", func(p *RequestParser) {}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			p := NewRequestParser(path)
+			p.Concurrency = 8
+			bench.setup(p)
+			// One transport across iterations, as in a scan
+			p.Transport = p.client().Transport
+			for i := 0; i < b.N; i++ {
+				responses, err := p.ExecuteRequests(context.Background())
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, resp := range responses {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteRequestsReusesConnections(t *testing.T) {
+	var opened atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	path := writeRequestFile(t, strings.Repeat("GET "+server.URL+"/\n", 20))
+	for _, tt := range []struct {
+		keepAlive bool
+		want      int32
+	}{
+		{true, 1},
+		{false, 20},
+	} {
+		opened.Store(0)
+		p := NewRequestParser(path)
+		p.DisableKeepAlives = !tt.keepAlive
+
+		// ExecuteRequests hands back every response with its body open, so
+		// only ExecuteRequestsFunc can reuse a connection within one run
+		err := p.ExecuteRequestsFunc(context.Background(), func(req *http.Request, resp *http.Response, err error) {
+			if err != nil {
+				t.Errorf("%s: %v", req.URL, err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+		})
+		if err != nil {
+			t.Fatalf("ExecuteRequestsFunc: %v", err)
+		}
+		if n := opened.Load(); n != tt.want {
+			t.Errorf("keep-alive %v: %d connections for 20 requests, want %d", tt.keepAlive, n, tt.want)
+		}
+	}
+}
+
+func TestExecuteRequestsConcurrentOrder(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		io.WriteString(w, r.URL.Query().Get("n"))
+	}))
+	defer server.Close()
+
+	var lines strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&lines, "GET %s/?n=%d\n", server.URL, i)
+	}
+	p := NewRequestParser(writeRequestFile(t, lines.String()))
+	p.Concurrency = 8
+
+	responses, err := p.ExecuteRequests(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRequests: %v", err)
+	}
+	if len(responses) != 50 {
+		t.Fatalf("%d responses, want 50", len(responses))
+	}
+	for i, resp := range responses {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != fmt.Sprint(i) {
+			t.Errorf("response %d answers request %s", i, body)
+		}
+	}
+	if got := peak.Load(); got < 2 || got > 8 {
+		t.Errorf("peak concurrency = %d, want between 2 and 8", got)
+	}
+}
+
+// closeTracker counts the response bodies closed
+type closeTracker struct {
+	io.ReadCloser
+	closed *atomic.Int32
+}
+
+func (c closeTracker) Close() error {
+	c.closed.Add(1)
+	return c.ReadCloser.Close()
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestExecuteRequestsFuncClosesBodies(t *testing.T) {
+	server, _ := recordServer(t)
+	var lines strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&lines, "GET %s/?n=%d\n", server.URL, i)
+	}
+	p := NewRequestParser(writeRequestFile(t, lines.String()))
+	p.Concurrency = 3
+
+	var closed atomic.Int32
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	p.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body = closeTracker{resp.Body, &closed}
+		}
+		return resp, err
+	})
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	err := p.ExecuteRequestsFunc(context.Background(), func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Errorf("request %s failed: %v", req.URL, err)
+		}
+		mu.Lock()
+		calls[req.URL.RawQuery]++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequestsFunc: %v", err)
+	}
+
+	if len(calls) != 10 {
+		t.Errorf("callback saw %d requests, want 10", len(calls))
+	}
+	for query, n := range calls {
+		if n != 1 {
+			t.Errorf("callback called %d times for %s, want once", n, query)
+		}
+	}
+	if got := closed.Load(); got != 10 {
+		t.Errorf("%d bodies closed, want 10", got)
+	}
+}
+
+func TestRequestLineExtraMethods(t *testing.T) {
+	p := NewRequestParser("")
+	if _, err := p.parseRequestLine("PURGE https://example.com/cache", 1); err == nil {
+		t.Error("parseRequestLine accepted PURGE without it being allowed")
+	}
+
+	p.ExtraMethods = []string{"PURGE"}
+	req, err := p.parseRequestLine("purge https://example.com/cache", 1)
+	if err != nil {
+		t.Fatalf("parseRequestLine with PURGE allowed: %v", err)
+	}
+	if req.Method != "PURGE" {
+		t.Errorf("method = %q, want PURGE", req.Method)
+	}
+	if !ValidMethod("patch", nil) || ValidMethod("FETCH", nil) {
+		t.Error("ValidMethod doesn't match the standard methods in any case")
+	}
+}
+
+// slowServer returns a server answering after delay, or once the client gives up
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestFileTimeout(t *testing.T) {
+	server := slowServer(t, 5*time.Second)
+	p := NewRequestParser(writeRequestFile(t, "GET "+server.URL+"/slow\n"))
+	if p.Timeout != DefaultRequestTimeout {
+		t.Errorf("default timeout = %s, want %s", p.Timeout, DefaultRequestTimeout)
+	}
+	p.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if _, err := p.ExecuteRequests(context.Background()); err == nil {
+		t.Fatal("ExecuteRequests against a slow endpoint succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request gave up after %s, want about %s", elapsed, p.Timeout)
+	}
+}
+
+func TestExecuteRequestsRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	p := NewRequestParser(writeRequestFile(t, strings.Repeat("GET "+server.URL+"/\n", 5)))
+	p.Concurrency = 5
+	p.Limiter = rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+
+	// The first request goes out at once, each of the other four waits its turn
+	start := time.Now()
+	responses, err := p.ExecuteRequests(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRequests: %v", err)
+	}
+	for _, resp := range responses {
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("5 requests at 20/s took %v, want at least 200ms", elapsed)
+	}
+}
@@ -0,0 +1,56 @@
+package browser
+
+import "errors"
+
+// Reasons a browser context couldn't be created, matched with errors.Is
+var (
+	// ErrBrowserNotFound means no executable of the browser type, or of
+	// geckodriver for Firefox, was found
+	ErrBrowserNotFound = errors.New("browser not found")
+
+	// ErrBrowserStart means the browser was found but failed to start or to be
+	// set up, which may succeed on another attempt
+	ErrBrowserStart = errors.New("failed to start browser")
+
+	// ErrUnsupportedBrowser means the browser type isn't one bxss can drive
+	ErrUnsupportedBrowser = errors.New("unsupported browser type")
+)
+
+// StartError is the error CreateContext returns when a browser context can't
+// be created. Kind is one of ErrBrowserNotFound, ErrBrowserStart or
+// ErrUnsupportedBrowser and Err the underlying error, if any; errors.Is
+// matches both.
+type StartError struct {
+	Browser BrowserType
+	Kind    error
+	Err     error
+}
+
+// startError returns a StartError of kind for browser b
+func startError(b *Browser, kind error, err error) error {
+	return &StartError{Browser: b.Type, Kind: kind, Err: err}
+}
+
+func (e *StartError) Error() string {
+	msg := e.Kind.Error()
+	if e.Browser != "" {
+		msg += " (" + string(e.Browser) + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *StartError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// retryable reports whether starting a browser again could get past err. A
+// missing or unsupported browser stays that way for the rest of the scan.
+func retryable(err error) bool {
+	return !errors.Is(err, ErrBrowserNotFound) && !errors.Is(err, ErrUnsupportedBrowser)
+}
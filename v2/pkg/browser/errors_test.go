@@ -0,0 +1,151 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeExecutable writes a shell script named name that exits with status 1
+// into dir
+func fakeExecutable(t *testing.T, dir string, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStartErrorKinds(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		browser func(t *testing.T) *Browser
+		want    error
+	}{
+		{
+			name: "unsupported type",
+			browser: func(t *testing.T) *Browser {
+				b := NewBrowser("chrome", "")
+				b.Type = "netscape"
+				return b
+			},
+			want: ErrUnsupportedBrowser,
+		},
+		{
+			name: "browser not installed",
+			browser: func(t *testing.T) *Browser {
+				t.Setenv("PATH", t.TempDir())
+				b := NewBrowser("chromium", "")
+				b.browsers = nil
+				return b
+			},
+			want: ErrBrowserNotFound,
+		},
+		{
+			name: "browser failing to start",
+			browser: func(t *testing.T) *Browser {
+				dir := t.TempDir()
+				fakeExecutable(t, dir, "chromium-browser")
+				t.Setenv("PATH", dir)
+				b := NewBrowser("chromium", "")
+				b.browsers = nil
+				return b
+			},
+			want: ErrBrowserStart,
+		},
+		{
+			name: "geckodriver not installed",
+			browser: func(t *testing.T) *Browser {
+				dir := t.TempDir()
+				fakeExecutable(t, dir, "firefox")
+				t.Setenv("PATH", dir)
+				b := NewBrowser("firefox", "")
+				b.browsers = nil
+				return b
+			},
+			want: ErrBrowserNotFound,
+		},
+		{
+			name: "geckodriver failing to start",
+			browser: func(t *testing.T) *Browser {
+				dir := t.TempDir()
+				fakeExecutable(t, dir, "firefox")
+				fakeExecutable(t, dir, "geckodriver")
+				t.Setenv("PATH", dir)
+				b := NewBrowser("firefox", "")
+				b.browsers = nil
+				return b
+			},
+			want: ErrBrowserStart,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.browser(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			_, _, err := b.CreateContext(ctx)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CreateContext = %v, want %v", err, tt.want)
+			}
+			var startErr *StartError
+			if !errors.As(err, &startErr) || startErr.Kind != tt.want || startErr.Browser != b.Type {
+				t.Errorf("CreateContext = %#v, want a StartError of %v for %s", err, tt.want, b.Type)
+			}
+			for _, other := range []error{ErrUnsupportedBrowser, ErrBrowserNotFound, ErrBrowserStart} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("CreateContext = %v, also matching %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestStartErrorUnwrapsCause(t *testing.T) {
+	cause := errors.New("exec: permission denied")
+	err := error(&StartError{Browser: Chrome, Kind: ErrBrowserStart, Err: cause})
+	if !errors.Is(err, cause) || !errors.Is(err, ErrBrowserStart) {
+		t.Errorf("%v doesn't match both its kind and its cause", err)
+	}
+	if want := "failed to start browser (chrome): exec: permission denied"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// failingEngine fails every CreateContext with err, counting the attempts
+type failingEngine struct {
+	FakeEngine
+	err      error
+	attempts atomic.Int32
+}
+
+func (e *failingEngine) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	e.attempts.Add(1)
+	return nil, nil, e.err
+}
+
+func TestPoolRetriesOnlyStartFailures(t *testing.T) {
+	for _, tt := range []struct {
+		kind     error
+		attempts int32
+	}{
+		{ErrBrowserStart, 3},
+		{ErrBrowserNotFound, 1},
+		{ErrUnsupportedBrowser, 1},
+	} {
+		engine := &failingEngine{err: &StartError{Browser: Chrome, Kind: tt.kind}}
+		pool := testPool(t, engine, 3)
+		err := pool.Initialize()
+		if !errors.Is(err, tt.kind) {
+			t.Errorf("Initialize = %v, want %v", err, tt.kind)
+		}
+		if n := engine.attempts.Load(); n != tt.attempts {
+			t.Errorf("%v: %d attempts to start a worker, want %d", tt.kind, n, tt.attempts)
+		}
+	}
+}
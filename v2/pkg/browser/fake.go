@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeEngine is an in-memory Engine for testing the scanner without a real
+// browser. It records every navigation and opens a dialog on the pages that
+// Fire selects.
+type FakeEngine struct {
+	// Fire, when set, is called for every navigation and returns the dialogs
+	// the page opens, none when it returns nil
+	Fire func(url string, headers map[string]interface{}) []Dialog
+
+	// Sinks, when set, is called for every navigation and returns the DOM sink
+	// writes the page makes, reported by SinkEvents
+	Sinks func(url string) []Sink
+
+	// Screenshot is returned as the PNG of every screenshot
+	Screenshot []byte
+
+	mu          sync.Mutex
+	navigations []Navigation
+	contexts    int
+}
+
+// Navigation is a page load recorded by FakeEngine
+type Navigation struct {
+	URL     string
+	Headers map[string]interface{}
+	Cookies []*http.Cookie
+}
+
+// FireOn returns a Fire function opening an alert with message on every page
+// whose URL contains substr
+func FireOn(substr string, message string) func(string, map[string]interface{}) []Dialog {
+	return func(url string, headers map[string]interface{}) []Dialog {
+		if !strings.Contains(url, substr) {
+			return nil
+		}
+		return []Dialog{{Type: "alert", Message: message, URL: url}}
+	}
+}
+
+// CreateContext returns a context backed by a fake driver
+func (e *FakeEngine) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	e.mu.Lock()
+	e.contexts++
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	return withDriver(ctx, &fakeDriver{engine: e}), cancel, nil
+}
+
+// Navigations returns every navigation made so far
+func (e *FakeEngine) Navigations() []Navigation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]Navigation(nil), e.navigations...)
+}
+
+// Contexts returns the number of contexts created
+func (e *FakeEngine) Contexts() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.contexts
+}
+
+// fakeDriver is the Driver of a FakeEngine context
+type fakeDriver struct {
+	dialogRecorder
+	engine  *FakeEngine
+	cookies []*http.Cookie
+	sinks   []Sink
+}
+
+func (d *fakeDriver) Navigate(ctx context.Context, url string, headers map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e := d.engine
+	e.mu.Lock()
+	e.navigations = append(e.navigations, Navigation{URL: url, Headers: headers, Cookies: d.cookies})
+	e.mu.Unlock()
+	d.cookies = nil
+
+	if e.Fire != nil {
+		for _, dialog := range e.Fire(url, headers) {
+			d.record(dialog)
+		}
+	}
+	if e.Sinks != nil {
+		d.sinks = e.Sinks(url)
+	}
+	return nil
+}
+
+func (d *fakeDriver) Evaluate(ctx context.Context, expression string, res interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Only draining the sinks is emulated
+	if expression != drainSinks {
+		return nil
+	}
+	sinks := d.sinks
+	d.sinks = nil
+	if sinks == nil {
+		sinks = []Sink{}
+	}
+	data, err := json.Marshal(sinks)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, res)
+}
+
+func (d *fakeDriver) Screenshot(ctx context.Context) ([]byte, error) {
+	return d.engine.Screenshot, ctx.Err()
+}
+
+func (d *fakeDriver) WaitIdle(ctx context.Context, quiet time.Duration) error {
+	return ctx.Err()
+}
+
+func (d *fakeDriver) SetCookies(ctx context.Context, url string, cookies []*http.Cookie) error {
+	d.cookies = append(d.cookies, cookies...)
+	return nil
+}
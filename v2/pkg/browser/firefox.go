@@ -0,0 +1,398 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// geckodriverStartTimeout is how long to wait for geckodriver to accept connections
+const geckodriverStartTimeout = 10 * time.Second
+
+// webDriverSession is a WebDriver session running against a geckodriver process
+type webDriverSession struct {
+	dialogRecorder
+	baseURL      string
+	sessionID    string
+	client       *http.Client
+	cookies      []*http.Cookie
+	cookiedHosts map[string]bool
+
+	// pending holds cookies from SetCookies to install on the next navigation
+	pending []*http.Cookie
+}
+
+// webDriverError is the error object returned by a WebDriver endpoint
+type webDriverError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// geckodriverArgs returns the command line used to launch geckodriver on the given port
+func geckodriverArgs(port int) []string {
+	return []string{"--host", "127.0.0.1", "--port", strconv.Itoa(port)}
+}
+
+// firefoxCapabilities builds the WebDriver capabilities used to start a Firefox session
+func (b *Browser) firefoxCapabilities(path string) map[string]interface{} {
+	width, height := b.windowSize()
+	args := []string{"--width=" + strconv.Itoa(width), "--height=" + strconv.Itoa(height)}
+	if b.Headless {
+		args = append(args, "-headless")
+	}
+
+	firefoxOptions := map[string]interface{}{
+		"binary": path,
+		"args":   args,
+	}
+	if b.UserAgent != "" {
+		firefoxOptions["prefs"] = map[string]interface{}{
+			"general.useragent.override": b.UserAgent,
+		}
+	}
+
+	alwaysMatch := map[string]interface{}{
+		"browserName":         "firefox",
+		"acceptInsecureCerts": true,
+		// Leave prompts open so Navigate can record and dismiss them itself
+		"unhandledPromptBehavior": "ignore",
+		"moz:firefoxOptions":      firefoxOptions,
+	}
+
+	if proxy := firefoxProxy(b.Proxy); proxy != nil {
+		alwaysMatch["proxy"] = proxy
+	}
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"alwaysMatch": alwaysMatch,
+		},
+	}
+}
+
+// firefoxProxy converts a proxy URL into a WebDriver manual proxy configuration
+func firefoxProxy(proxy string) map[string]interface{} {
+	if proxy == "" {
+		return nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		return map[string]interface{}{
+			"proxyType":    "manual",
+			"socksProxy":   u.Host,
+			"socksVersion": 5,
+		}
+	default:
+		return map[string]interface{}{
+			"proxyType": "manual",
+			"httpProxy": u.Host,
+			"sslProxy":  u.Host,
+		}
+	}
+}
+
+// createFirefoxContext launches geckodriver, starts a Firefox session and returns
+// a context carrying a WebDriver-backed Driver
+func (b *Browser) createFirefoxContext(ctx context.Context, path string) (context.Context, context.CancelFunc, error) {
+	driverPath, err := exec.LookPath("geckodriver")
+	if err != nil {
+		return nil, nil, startError(b, ErrBrowserNotFound, fmt.Errorf("geckodriver not found in PATH: %w", err))
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, nil, startError(b, ErrBrowserStart, fmt.Errorf("failed to reserve port for geckodriver: %w", err))
+	}
+
+	cmd := exec.Command(driverPath, geckodriverArgs(port)...)
+	if b.Debug {
+		// geckodriver also relays Firefox's own output
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, startError(b, ErrBrowserStart, fmt.Errorf("failed to start geckodriver: %w", err))
+	}
+
+	stopDriver := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+
+	session := &webDriverSession{
+		baseURL:      fmt.Sprintf("http://127.0.0.1:%d", port),
+		client:       &http.Client{},
+		cookies:      b.Cookies,
+		cookiedHosts: make(map[string]bool),
+	}
+
+	if err := session.waitReady(ctx); err != nil {
+		stopDriver()
+		return nil, nil, startError(b, ErrBrowserStart, err)
+	}
+
+	if err := session.start(ctx, b.firefoxCapabilities(path)); err != nil {
+		stopDriver()
+		return nil, nil, startError(b, ErrBrowserStart, err)
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(withDriver(ctx, session), b.timeout())
+
+	// Close the session before killing geckodriver so Firefox exits cleanly
+	combinedCancel := func() {
+		timeoutCancel()
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), 2*time.Second)
+		session.delete(closeCtx)
+		closeCancel()
+		stopDriver()
+	}
+
+	return timeoutCtx, combinedCancel, nil
+}
+
+// freePort asks the kernel for an unused local TCP port
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitReady polls the geckodriver status endpoint until it accepts sessions
+func (s *webDriverSession) waitReady(ctx context.Context) error {
+	deadline := time.Now().Add(geckodriverStartTimeout)
+	for time.Now().Before(deadline) {
+		var status struct {
+			Ready bool `json:"ready"`
+		}
+		if err := s.do(ctx, http.MethodGet, "/status", nil, &status); err == nil && status.Ready {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return errors.New("timeout waiting for geckodriver to start")
+}
+
+// start creates a new WebDriver session with the given capabilities
+func (s *webDriverSession) start(ctx context.Context, capabilities map[string]interface{}) error {
+	var result struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/session", capabilities, &result); err != nil {
+		return err
+	}
+	if result.SessionID == "" {
+		return errors.New("geckodriver returned an empty session id")
+	}
+	s.sessionID = result.SessionID
+	return nil
+}
+
+// delete ends the WebDriver session, which closes the browser
+func (s *webDriverSession) delete(ctx context.Context) error {
+	return s.do(ctx, http.MethodDelete, "/session/"+s.sessionID, nil, nil)
+}
+
+// Navigate loads the URL in the session's current window. WebDriver cannot
+// attach extra headers to a navigation, so headers are ignored; header
+// injection is rejected for Firefox up front, see BrowserType.SendsHeaders.
+func (s *webDriverSession) Navigate(ctx context.Context, url string, headers map[string]interface{}) error {
+	if err := s.do(ctx, http.MethodPost, "/session/"+s.sessionID+"/url", map[string]string{"url": url}, nil); err != nil {
+		return err
+	}
+
+	// WebDriver can only add cookies for the current document's domain, so
+	// the first visit to a host, or one following SetCookies, installs them and
+	// then reloads the page
+	if added, err := s.addCookies(ctx, url); err != nil {
+		return err
+	} else if added {
+		s.DialogEvents()
+		if err := s.do(ctx, http.MethodPost, "/session/"+s.sessionID+"/url", map[string]string{"url": url}, nil); err != nil {
+			return err
+		}
+	}
+
+	s.collectDialogs(ctx, url)
+	return nil
+}
+
+// SetCookies queues cookies to be installed on the next navigation, as
+// WebDriver can't set cookies for a domain that isn't loaded
+func (s *webDriverSession) SetCookies(ctx context.Context, url string, cookies []*http.Cookie) error {
+	s.pending = append(s.pending, cookies...)
+	return nil
+}
+
+// addCookies installs the configured cookies the first time a host is visited,
+// along with any pending ones, and reports whether any were added
+func (s *webDriverSession) addCookies(ctx context.Context, target string) (bool, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false, nil
+	}
+
+	var cookies []*http.Cookie
+	if !s.cookiedHosts[u.Host] {
+		s.cookiedHosts[u.Host] = true
+		cookies = append(cookies, s.cookies...)
+	}
+	cookies = append(cookies, s.pending...)
+	s.pending = nil
+	if len(cookies) == 0 {
+		return false, nil
+	}
+
+	// Dismiss anything the first load opened, otherwise cookie commands fail
+	s.collectDialogs(ctx, target)
+
+	for _, c := range cookies {
+		cookie := map[string]interface{}{
+			"name":     c.Name,
+			"value":    c.Value,
+			"secure":   c.Secure,
+			"httpOnly": c.HttpOnly,
+		}
+		if c.Domain != "" {
+			cookie["domain"] = c.Domain
+		}
+		if c.Path != "" {
+			cookie["path"] = c.Path
+		}
+		if err := s.do(ctx, http.MethodPost, "/session/"+s.sessionID+"/cookie", map[string]interface{}{"cookie": cookie}, nil); err != nil {
+			return false, fmt.Errorf("failed to set cookie %s: %w", c.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// collectDialogs records and dismisses any prompts left open by the page
+func (s *webDriverSession) collectDialogs(ctx context.Context, url string) {
+	// Bound the loop in case the page opens dialogs endlessly
+	for i := 0; i < 10; i++ {
+		var text string
+		if err := s.do(ctx, http.MethodGet, "/session/"+s.sessionID+"/alert/text", nil, &text); err != nil {
+			return
+		}
+		s.record(Dialog{Type: "alert", Message: text, URL: url})
+		if err := s.do(ctx, http.MethodPost, "/session/"+s.sessionID+"/alert/dismiss", map[string]string{}, nil); err != nil {
+			return
+		}
+	}
+}
+
+// Evaluate runs the JavaScript expression in the page and decodes its result into res
+func (s *webDriverSession) Evaluate(ctx context.Context, expression string, res interface{}) error {
+	body := map[string]interface{}{
+		"script": "return " + expression,
+		"args":   []interface{}{},
+	}
+	return s.do(ctx, http.MethodPost, "/session/"+s.sessionID+"/execute/sync", body, res)
+}
+
+// WaitIdle polls the page's resource timing entries until none have been added
+// for the quiet period. WebDriver has no network events, so requests still in
+// flight can't be seen, only ones that have completed.
+func (s *webDriverSession) WaitIdle(ctx context.Context, quiet time.Duration) error {
+	lastCount := -1
+	lastChange := time.Now()
+
+	for {
+		var count int
+		if err := s.Evaluate(ctx, "performance.getEntriesByType('resource').length", &count); err != nil {
+			return err
+		}
+
+		if count != lastCount {
+			lastCount = count
+			lastChange = time.Now()
+		} else if time.Since(lastChange) >= quiet {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Screenshot captures the current viewport, which WebDriver returns base64 encoded
+func (s *webDriverSession) Screenshot(ctx context.Context) ([]byte, error) {
+	var encoded string
+	if err := s.do(ctx, http.MethodGet, "/session/"+s.sessionID+"/screenshot", nil, &encoded); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// do sends a WebDriver command and decodes the "value" member of the response into out
+func (s *webDriverSession) do(ctx context.Context, method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid webdriver response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var wdErr webDriverError
+		if err := json.Unmarshal(envelope.Value, &wdErr); err == nil && wdErr.Error != "" {
+			return fmt.Errorf("webdriver %s: %s", wdErr.Error, wdErr.Message)
+		}
+		return fmt.Errorf("webdriver returned status %d", resp.StatusCode)
+	}
+
+	if out != nil && len(envelope.Value) > 0 {
+		return json.Unmarshal(envelope.Value, out)
+	}
+	return nil
+}
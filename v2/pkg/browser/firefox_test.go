@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestGeckodriverArgs(t *testing.T) {
+	want := []string{"--host", "127.0.0.1", "--port", "4444"}
+	if got := geckodriverArgs(4444); !reflect.DeepEqual(got, want) {
+		t.Errorf("geckodriverArgs(4444) = %q, want %q", got, want)
+	}
+}
+
+func TestFirefoxCapabilities(t *testing.T) {
+	b := NewBrowser("firefox", "")
+	b.UserAgent = "bxss-test"
+	b.Proxy = "socks5://127.0.0.1:1080"
+	b.WindowWidth, b.WindowHeight = 800, 600
+
+	caps := b.firefoxCapabilities("/usr/bin/firefox")
+	alwaysMatch := caps["capabilities"].(map[string]interface{})["alwaysMatch"].(map[string]interface{})
+	options := alwaysMatch["moz:firefoxOptions"].(map[string]interface{})
+
+	if options["binary"] != "/usr/bin/firefox" {
+		t.Errorf("binary = %v", options["binary"])
+	}
+	wantArgs := []string{"--width=800", "--height=600", "-headless"}
+	if !reflect.DeepEqual(options["args"], wantArgs) {
+		t.Errorf("args = %q, want %q", options["args"], wantArgs)
+	}
+	prefs := options["prefs"].(map[string]interface{})
+	if prefs["general.useragent.override"] != "bxss-test" {
+		t.Errorf("user agent pref = %v", prefs["general.useragent.override"])
+	}
+	proxy := alwaysMatch["proxy"].(map[string]interface{})
+	if proxy["socksProxy"] != "127.0.0.1:1080" || proxy["socksVersion"] != 5 {
+		t.Errorf("proxy = %v", proxy)
+	}
+}
+
+func TestFirefoxProxy(t *testing.T) {
+	if got := firefoxProxy(""); got != nil {
+		t.Errorf("firefoxProxy(\"\") = %v, want nil", got)
+	}
+	got := firefoxProxy("http://127.0.0.1:8080")
+	if got["httpProxy"] != "127.0.0.1:8080" || got["sslProxy"] != "127.0.0.1:8080" {
+		t.Errorf("firefoxProxy(http) = %v", got)
+	}
+}
+
+func TestFirefoxSendsNoHeaders(t *testing.T) {
+	if Firefox.SendsHeaders() {
+		t.Error("Firefox.SendsHeaders() = true")
+	}
+	for _, browserType := range []BrowserType{Chrome, Chromium, Edge} {
+		if !browserType.SendsHeaders() {
+			t.Errorf("%s.SendsHeaders() = false", browserType)
+		}
+	}
+}
+
+func TestFirefoxLaunch(t *testing.T) {
+	if _, err := exec.LookPath("geckodriver"); err != nil {
+		t.Skip("geckodriver is not installed")
+	}
+	b := NewBrowser("firefox", "")
+	if _, err := b.findBrowserPath(); err != nil {
+		t.Skip("Firefox is not installed")
+	}
+
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	if err := DriverFromContext(ctx).Navigate(ctx, "about:blank", nil); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package browser
+
+import "strings"
+
+// Methods are the HTTP methods accepted by default, in -X and request files
+var Methods = []string{
+	"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS", "PATCH",
+}
+
+// ValidMethod reports whether method, in any case, is one of Methods or of
+// the extra methods allowed on top of them
+func ValidMethod(method string, extra []string) bool {
+	method = strings.ToUpper(method)
+	for _, allowed := range Methods {
+		if method == allowed {
+			return true
+		}
+	}
+	for _, allowed := range extra {
+		if method == strings.ToUpper(allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,446 @@
+package browser
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+)
+
+// testPool returns a pool of fake browsers that logs nothing
+func testPool(t *testing.T, engine Engine, workers int) *BrowserPool {
+	t.Helper()
+	pool := NewBrowserPool(engine, workers)
+	pool.Logger = logger.Default().To(io.Discard)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestGetContextReplacesDeadWorker(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 1)
+
+	ctx, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	pool.ReleaseContext(ctx)
+
+	// Kill the worker as a browser crash would
+	pool.mu.Lock()
+	pool.cancelFuncs[ctx]()
+	pool.mu.Unlock()
+
+	fresh, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext after the worker died: %v", err)
+	}
+	defer pool.ReleaseContext(fresh)
+
+	if fresh == ctx {
+		t.Fatal("GetContext returned the dead context")
+	}
+	if err := DriverFromContext(fresh).Navigate(fresh, "https://example.com/", nil); err != nil {
+		t.Fatalf("Navigate on the fresh context: %v", err)
+	}
+	if recycled := pool.Stats().Recycled; recycled != 1 {
+		t.Errorf("Recycled = %d, want 1", recycled)
+	}
+}
+
+// countingEngine is a FakeEngine counting how often each context's cancel
+// function runs. While fail is set, creating a context fails as a browser
+// crashing on startup would.
+type countingEngine struct {
+	FakeEngine
+
+	fail    atomic.Bool
+	mu      sync.Mutex
+	cancels []*int32
+}
+
+func (e *countingEngine) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if e.fail.Load() {
+		return nil, nil, &StartError{Browser: Chrome, Kind: ErrBrowserStart}
+	}
+	ctx, cancel, err := e.FakeEngine.CreateContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	count := new(int32)
+	e.mu.Lock()
+	e.cancels = append(e.cancels, count)
+	e.mu.Unlock()
+	return ctx, func() {
+		atomic.AddInt32(count, 1)
+		cancel()
+	}, nil
+}
+
+// counts returns how often the cancel function of each context created ran
+func (e *countingEngine) counts() []int32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counts := make([]int32, len(e.cancels))
+	for i, count := range e.cancels {
+		counts[i] = atomic.LoadInt32(count)
+	}
+	return counts
+}
+
+func TestCloseDrainsCheckedOutContexts(t *testing.T) {
+	engine := &countingEngine{}
+	pool := testPool(t, engine, 2)
+	if err := pool.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a context was checked out")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	pool.ReleaseContext(ctx)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't return once the context was released")
+	}
+
+	counts := engine.counts()
+	if len(counts) != 2 {
+		t.Fatalf("%d contexts created, want 2", len(counts))
+	}
+	for i, count := range counts {
+		if count != 1 {
+			t.Errorf("context %d cancelled %d times, want once", i, count)
+		}
+	}
+}
+
+func TestCloseCancelsOneTimeContexts(t *testing.T) {
+	engine := &countingEngine{}
+	engine.fail.Store(true)
+	pool := testPool(t, engine, 2)
+	if err := pool.Initialize(); err == nil {
+		t.Fatal("Initialize succeeded with a failing browser")
+	}
+
+	// The browser recovers, but the pool stays uninitialized
+	engine.fail.Store(false)
+	var contexts []context.Context
+	for i := 0; i < 3; i++ {
+		ctx, err := pool.NewOneTimeContext()
+		if err != nil {
+			t.Fatalf("NewOneTimeContext: %v", err)
+		}
+		contexts = append(contexts, ctx)
+	}
+	if inUse := pool.Stats().InUse; inUse != 3 {
+		t.Errorf("InUse = %d, want 3", inUse)
+	}
+
+	pool.Close()
+
+	for i, ctx := range contexts {
+		if ctx.Err() == nil {
+			t.Errorf("one-time context %d still alive after Close", i)
+		}
+	}
+	for i, count := range engine.counts() {
+		if count != 1 {
+			t.Errorf("context %d cancelled %d times, want once", i, count)
+		}
+	}
+}
+
+func TestReleaseCancelsOneTimeContext(t *testing.T) {
+	engine := &countingEngine{}
+	pool := testPool(t, engine, 1)
+
+	ctx, err := pool.NewOneTimeContext()
+	if err != nil {
+		t.Fatalf("NewOneTimeContext: %v", err)
+	}
+	pool.ReleaseContext(ctx)
+	if ctx.Err() == nil {
+		t.Fatal("one-time context still alive after ReleaseContext")
+	}
+
+	pool.Close()
+	if counts := engine.counts(); len(counts) != 1 || counts[0] != 1 {
+		t.Errorf("cancel counts = %v, want [1]", counts)
+	}
+}
+
+func TestMaxLifetimeRecyclesWorker(t *testing.T) {
+	engine := &countingEngine{}
+	pool := testPool(t, engine, 1)
+	pool.MaxLifetime = 10 * time.Millisecond
+
+	ctx, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	pool.ReleaseContext(ctx)
+	time.Sleep(2 * pool.MaxLifetime)
+
+	fresh, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext after the lifetime: %v", err)
+	}
+	defer pool.ReleaseContext(fresh)
+
+	if fresh == ctx {
+		t.Fatal("GetContext returned the expired context")
+	}
+	if ctx.Err() == nil {
+		t.Error("expired context wasn't cancelled")
+	}
+	if counts := engine.counts(); len(counts) != 2 || counts[0] != 1 || counts[1] != 0 {
+		t.Errorf("cancel counts = %v, want [1 0]", counts)
+	}
+	if recycled := pool.Stats().Recycled; recycled != 1 {
+		t.Errorf("Recycled = %d, want 1", recycled)
+	}
+}
+
+// gatedEngine is a FakeEngine whose browsers after the first don't start
+// until release is closed
+type gatedEngine struct {
+	FakeEngine
+
+	release chan struct{}
+	started atomic.Int32
+}
+
+func (e *gatedEngine) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if e.started.Add(1) > 1 {
+		<-e.release
+	}
+	return e.FakeEngine.CreateContext(ctx)
+}
+
+func TestLazyGetContextReturnsFirstWorker(t *testing.T) {
+	engine := &gatedEngine{release: make(chan struct{})}
+	pool := testPool(t, engine, 4)
+	pool.Lazy = true
+
+	got := make(chan error, 1)
+	go func() {
+		ctx, err := pool.GetContext(context.Background())
+		if err == nil {
+			pool.ReleaseContext(ctx)
+		}
+		got <- err
+	}()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("GetContext: %v", err)
+		}
+	case <-time.After(time.Second):
+		close(engine.release)
+		t.Fatal("GetContext waited for the other workers to start")
+	}
+
+	if created := pool.Stats().Created; created != 1 {
+		t.Errorf("Created = %d, want only the first worker", created)
+	}
+
+	// The rest start in the background once their browsers come up
+	close(engine.release)
+	deadline := time.Now().Add(time.Second)
+	for pool.Stats().Created < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if created := pool.Stats().Created; created != 4 {
+		t.Errorf("Created = %d after warming, want 4", created)
+	}
+}
+
+func TestGetContextHonorsCancellation(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 1)
+	held, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	defer pool.ReleaseContext(held)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := pool.GetContext(ctx); err != context.Canceled {
+		t.Errorf("GetContext on a busy pool = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetContext returned %s after the cancellation", elapsed)
+	}
+}
+
+func TestGetContextCancelledWithFreeWorker(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 2)
+	if err := pool.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 20; i++ {
+		if browserCtx, err := pool.GetContext(ctx); err != context.Canceled {
+			if err == nil {
+				pool.ReleaseContext(browserCtx)
+			}
+			t.Fatalf("GetContext with a cancelled context = %v, want context.Canceled", err)
+		}
+	}
+}
+
+func TestAcquireTimeoutExpires(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 1)
+	pool.AcquireTimeout = 30 * time.Millisecond
+	held, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	defer pool.ReleaseContext(held)
+
+	start := time.Now()
+	if _, err := pool.GetContext(context.Background()); err == nil {
+		t.Fatal("GetContext on a busy pool succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed < pool.AcquireTimeout || elapsed > time.Second {
+		t.Errorf("GetContext gave up after %s, want about %s", elapsed, pool.AcquireTimeout)
+	}
+}
+
+func TestZeroAcquireTimeoutWaitsForRelease(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 1)
+	pool.AcquireTimeout = 0
+	held, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	// The second caller waits for as long as the context is held
+	const hold = 100 * time.Millisecond
+	time.AfterFunc(hold, func() { pool.ReleaseContext(held) })
+	start := time.Now()
+	got, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext with no acquire timeout: %v", err)
+	}
+	defer pool.ReleaseContext(got)
+	if elapsed := time.Since(start); elapsed < hold {
+		t.Errorf("GetContext returned after %s, before the release", elapsed)
+	}
+	if got != held {
+		t.Error("GetContext did not return the released context")
+	}
+}
+
+func TestReleaseTimeoutDiscardsContext(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 1)
+	pool.ReleaseTimeout = 30 * time.Millisecond
+	held, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	// Fill the pool so there is no room to return the context to
+	pool.pool <- context.Background()
+	start := time.Now()
+	pool.ReleaseContext(held)
+	if elapsed := time.Since(start); elapsed < pool.ReleaseTimeout || elapsed > time.Second {
+		t.Errorf("ReleaseContext gave up after %s, want about %s", elapsed, pool.ReleaseTimeout)
+	}
+	if got := <-pool.pool; got == held {
+		t.Error("the discarded context was returned to the pool")
+	}
+	if inUse := pool.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d after the release, want 0", inUse)
+	}
+}
+
+func TestStatsTrackCheckouts(t *testing.T) {
+	pool := testPool(t, &FakeEngine{}, 2)
+	if err := pool.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	check := func(when string, idle, inUse int) {
+		t.Helper()
+		stats := pool.Stats()
+		if stats.Idle != idle || stats.InUse != inUse {
+			t.Errorf("%s: idle %d, in use %d, want %d and %d", when, stats.Idle, stats.InUse, idle, inUse)
+		}
+	}
+	check("after initializing", 2, 0)
+
+	first, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	check("after one checkout", 1, 1)
+	second, err := pool.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	check("after two checkouts", 0, 2)
+
+	pool.ReleaseContext(first)
+	check("after one release", 1, 1)
+	pool.ReleaseContext(second)
+	check("after both releases", 2, 0)
+
+	if stats := pool.Stats(); stats.Created != 2 || stats.Recycled != 0 {
+		t.Errorf("Created = %d, Recycled = %d, want 2 and 0", stats.Created, stats.Recycled)
+	}
+}
+
+// slowEngine is a FakeEngine taking a while to start every context, as a
+// browser does
+type slowEngine struct {
+	FakeEngine
+}
+
+func (e *slowEngine) CreateContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	time.Sleep(20 * time.Millisecond)
+	return e.FakeEngine.CreateContext(ctx)
+}
+
+func TestGetContextDuringInitialize(t *testing.T) {
+	pool := testPool(t, &slowEngine{}, 2)
+	go pool.Initialize()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Errorf("GetContext: %v", err)
+				return
+			}
+			pool.ReleaseContext(ctx)
+		}()
+	}
+	wg.Wait()
+}
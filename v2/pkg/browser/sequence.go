@@ -0,0 +1,158 @@
+package browser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+)
+
+// maxSequenceBody bounds how much of each response Extractions are matched against
+const maxSequenceBody = 1 << 20
+
+// Extraction captures a value from a response body, such as a CSRF token, into
+// a variable that later requests of a sequence reference as {{Name}}. The
+// value is the pattern's first group, or the whole match when it has none.
+type Extraction struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseExtraction parses an extraction of the form name=regex
+func ParseExtraction(value string) (Extraction, error) {
+	name, pattern, found := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || pattern == "" {
+		return Extraction{}, fmt.Errorf("invalid extraction %q, expected name=regex", value)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Extraction{}, fmt.Errorf("invalid extraction %q: %w", value, err)
+	}
+	return Extraction{Name: name, Pattern: re}, nil
+}
+
+// ExecuteSequence sends the requests in the file one after the other, as a
+// login would, filling {{name}} in each with the values extracted from the
+// responses before it. The sequence stops at the first request that fails or
+// is answered with an error status. Cookies set along the way are sent on with
+// the requests after them, kept in Jar when one is set, and returned scoped to
+// the host that set them.
+func (p *RequestParser) ExecuteSequence(ctx context.Context, extractions []Extraction) ([]*http.Cookie, error) {
+	if p.FilePath == "" {
+		return nil, errors.New("no request file path provided")
+	}
+	data, err := os.ReadFile(p.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request file: %w", err)
+	}
+
+	// A raw request is a single step, otherwise each line is one
+	var steps []string
+	if isRawRequest(data) {
+		steps = []string{string(data)}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			steps = append(steps, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading request file: %w", err)
+		}
+	}
+
+	// Without a shared jar the sequence still needs one of its own, for the
+	// session cookie a login sets to reach the requests after it
+	client := p.client()
+	if client.Jar == nil {
+		client.Jar, err = cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	vars := make(map[string]string)
+	hosts := make(map[string]bool)
+
+	for i, step := range steps {
+		step = fillVariables(step, vars)
+
+		var req *http.Request
+		if len(steps) == 1 && isRawRequest([]byte(step)) {
+			req, err = p.ParseRawRequest(strings.NewReader(step))
+		} else {
+			line := strings.TrimSpace(step)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			req, err = p.parseRequestLine(line, i+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !p.Scope.Allows(req.URL.Host) {
+			return nil, fmt.Errorf("request to %s is out of scope", req.URL.Host)
+		}
+
+		logger.Info("Sequence request: " + req.Method + " " + req.URL.String())
+		resp, err := p.execute(ctx, client, req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxSequenceBody))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response from %s: %w", req.URL.String(), err)
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			return nil, fmt.Errorf("request to %s answered %s", req.URL.String(), resp.Status)
+		}
+
+		for _, extraction := range extractions {
+			match := extraction.Pattern.FindSubmatch(body)
+			if match == nil {
+				continue
+			}
+			value := match[0]
+			if len(match) > 1 {
+				value = match[1]
+			}
+			vars[extraction.Name] = string(value)
+		}
+
+		hosts[req.URL.Scheme+"://"+req.URL.Host] = true
+	}
+
+	var cookies []*http.Cookie
+	for host := range hosts {
+		req, err := http.NewRequest(http.MethodGet, host+"/", nil)
+		if err != nil {
+			continue
+		}
+		for _, cookie := range client.Jar.Cookies(req.URL) {
+			cookies = append(cookies, scopedCookie(cookie.Name, cookie.Value, req.URL.Hostname()))
+		}
+	}
+	return cookies, nil
+}
+
+// fillVariables replaces each {{name}} in s with its value in vars
+func fillVariables(s string, vars map[string]string) string {
+	for name, value := range vars {
+		s = strings.ReplaceAll(s, "{{"+name+"}}", value)
+	}
+	return s
+}
+
+// scopedCookie returns a cookie for the browser scoped to every path of host
+func scopedCookie(name, value, host string) *http.Cookie {
+	return &http.Cookie{Name: name, Value: value, Domain: host, Path: "/"}
+}
@@ -0,0 +1,118 @@
+package browser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// loginServer hands out a CSRF token, sets a session cookie for a login
+// carrying it, and only serves /account to requests with that session
+func loginServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var account []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<form><input name="csrf" value="tok123"></form>`)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "csrf=tok123" {
+				http.Error(w, "bad csrf", http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Path: "/"})
+		case "/account":
+			mu.Lock()
+			account = append(account, r.Header.Get("Cookie"))
+			mu.Unlock()
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "s3cr3t" {
+				http.Error(w, "not logged in", http.StatusUnauthorized)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), account...)
+	}
+}
+
+// loginSequence writes a sequence logging in to server, with the extraction
+// of its CSRF token
+func loginSequence(t *testing.T, server *httptest.Server) (*RequestParser, []Extraction) {
+	t.Helper()
+	parser := NewRequestParser(writeRequestFile(t, strings.Join([]string{
+		"GET " + server.URL + "/login",
+		"POST " + server.URL + "/login Content-Type:application/x-www-form-urlencoded BODY=csrf={{csrf}}",
+		"GET " + server.URL + "/account",
+	}, "\n")))
+	extraction, err := ParseExtraction(`csrf=name="csrf" value="([^"]+)"`)
+	if err != nil {
+		t.Fatalf("ParseExtraction: %v", err)
+	}
+	return parser, []Extraction{extraction}
+}
+
+func TestSequenceLogsIn(t *testing.T) {
+	server, account := loginServer(t)
+	parser, extractions := loginSequence(t, server)
+
+	// No shared jar: the sequence keeps the session itself
+	cookies, err := parser.ExecuteSequence(context.Background(), extractions)
+	if err != nil {
+		t.Fatalf("ExecuteSequence: %v", err)
+	}
+	if got := account(); len(got) != 1 || got[0] != "session=s3cr3t" {
+		t.Errorf("/account requested with cookies %q, want the session set by the login", got)
+	}
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "s3cr3t" || cookies[0].Domain != "127.0.0.1" {
+		t.Errorf("cookies = %+v, want the session scoped to the host", cookies)
+	}
+}
+
+func TestSequenceSessionSharedWithScan(t *testing.T) {
+	server, account := loginServer(t)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser, extractions := loginSequence(t, server)
+	parser.Jar = jar
+	if _, err := parser.ExecuteSequence(context.Background(), extractions); err != nil {
+		t.Fatalf("ExecuteSequence: %v", err)
+	}
+
+	scan := NewRequestParser(writeRequestFile(t, "GET "+server.URL+"/account"))
+	scan.Jar = jar
+	responses, err := scan.ExecuteRequests(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteRequests: %v", err)
+	}
+	for _, resp := range responses {
+		resp.Body.Close()
+	}
+	if got := account(); len(got) != 2 || got[1] != "session=s3cr3t" {
+		t.Errorf("scan requested /account with cookies %q, want the login's session", got)
+	}
+}
+
+func TestSequenceStopsOnErrorStatus(t *testing.T) {
+	server, account := loginServer(t)
+	parser := NewRequestParser(writeRequestFile(t, "GET "+server.URL+"/account\nGET "+server.URL+"/account"))
+	if _, err := parser.ExecuteSequence(context.Background(), nil); err == nil {
+		t.Error("sequence answered 401 didn't fail")
+	}
+	if got := account(); len(got) != 1 {
+		t.Errorf("%d requests after the failure, want the sequence to stop", len(got)-1)
+	}
+}
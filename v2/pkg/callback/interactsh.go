@@ -0,0 +1,358 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+)
+
+// Interactsh subdomains are a correlation id identifying the session followed
+// by a nonce identifying the injection
+const (
+	correlationIDLength = 20
+	nonceLength         = 13
+)
+
+// DefaultPollInterval is how often interactions are fetched from the collaborator
+const DefaultPollInterval = 5 * time.Second
+
+// Interactsh registers a session with an interactsh collaborator server, such
+// as oast.pro, and polls it for the DNS and HTTP interactions made by fired
+// payloads. Each injection gets its own subdomain, so interactions are
+// correlated back to it even when only a DNS lookup gets out.
+type Interactsh struct {
+	// Server is the collaborator's base URL (e.g. https://oast.pro)
+	Server string
+
+	// Token authenticates with servers that require it
+	Token string
+
+	Index  *Index
+	Client *http.Client
+
+	// OnHit, when set, is called with every interaction after it has been logged
+	OnHit func(Hit)
+
+	// PollInterval is how often interactions are fetched
+	PollInterval time.Duration
+
+	key           *rsa.PrivateKey
+	correlationID string
+	secret        string
+	host          string
+
+	mu     sync.Mutex
+	nonces map[string]string
+	hits   []Hit
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// interaction is an interaction as reported by the interactsh server
+type interaction struct {
+	Protocol      string    `json:"protocol"`
+	UniqueID      string    `json:"unique-id"`
+	FullID        string    `json:"full-id"`
+	QType         string    `json:"q-type"`
+	RawRequest    string    `json:"raw-request"`
+	RemoteAddress string    `json:"remote-address"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// NewInteractsh creates a collaborator client for server (e.g. oast.pro,
+// https is assumed without a scheme) correlating against the tokens in index
+func NewInteractsh(server string, index *Index) *Interactsh {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	return &Interactsh{
+		Server:       strings.TrimSuffix(server, "/"),
+		Index:        index,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		PollInterval: DefaultPollInterval,
+		nonces:       make(map[string]string),
+	}
+}
+
+// Register creates a session on the server and starts polling it in the background
+func (i *Interactsh) Register(ctx context.Context) error {
+	u, err := url.Parse(i.Server)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid interactsh server '%s'", i.Server)
+	}
+	i.host = u.Hostname()
+
+	i.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("failed to generate interactsh key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&i.key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode interactsh key: %w", err)
+	}
+
+	i.correlationID = randomID(correlationIDLength)
+	i.secret = NewToken()
+
+	err = i.post(ctx, "/register", map[string]string{
+		"public-key":     base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: public})),
+		"secret-key":     i.secret,
+		"correlation-id": i.correlationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register with interactsh server: %w", err)
+	}
+
+	pollCtx, cancel := context.WithCancel(context.Background())
+	i.cancel = cancel
+	i.done = make(chan struct{})
+	go i.pollLoop(pollCtx)
+
+	logger.Info("Registered with interactsh server " + i.host + ", payloads will call back to " + i.correlationID + "*." + i.host)
+	return nil
+}
+
+// URL returns a callback URL on a subdomain unique to the injection carrying token
+func (i *Interactsh) URL(token string) string {
+	nonce := randomID(nonceLength)
+
+	i.mu.Lock()
+	i.nonces[nonce] = token
+	i.mu.Unlock()
+
+	return "https://" + i.correlationID + nonce + "." + i.host + "/" + token
+}
+
+// Close fetches any last interactions and deregisters the session
+func (i *Interactsh) Close() error {
+	if i.cancel == nil {
+		return nil
+	}
+	i.cancel()
+	<-i.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	i.poll(ctx)
+	return i.post(ctx, "/deregister", map[string]string{
+		"correlation-id": i.correlationID,
+		"secret-key":     i.secret,
+	})
+}
+
+// pollLoop fetches interactions every PollInterval until ctx is cancelled
+func (i *Interactsh) pollLoop(ctx context.Context) {
+	defer close(i.done)
+
+	interval := i.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := i.poll(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Error polling interactsh server: " + err.Error())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll fetches and handles the interactions received since the last poll
+func (i *Interactsh) poll(ctx context.Context) error {
+	query := url.Values{"id": {i.correlationID}, "secret": {i.secret}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.Server+"/poll?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if i.Token != "" {
+		req.Header.Set("Authorization", i.Token)
+	}
+
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var polled struct {
+		Data   []string `json:"data"`
+		AESKey string   `json:"aes_key"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&polled); err != nil {
+		return fmt.Errorf("failed to decode interactions: %w", err)
+	}
+	if len(polled.Data) == 0 {
+		return nil
+	}
+
+	key, err := i.decryptKey(polled.AESKey)
+	if err != nil {
+		return err
+	}
+	for _, data := range polled.Data {
+		plain, err := decryptMessage(key, data)
+		if err != nil {
+			return err
+		}
+		var in interaction
+		if err := json.Unmarshal(bytes.TrimSpace(plain), &in); err != nil {
+			return fmt.Errorf("failed to decode interaction: %w", err)
+		}
+		i.handle(in)
+	}
+	return nil
+}
+
+// Hits returns every interaction received so far
+func (i *Interactsh) Hits() []Hit {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]Hit(nil), i.hits...)
+}
+
+// handle correlates an interaction to its injection, logs it and passes it to OnHit
+func (i *Interactsh) handle(in interaction) {
+	hit := Hit{
+		Time:       in.Timestamp,
+		Method:     strings.ToUpper(in.Protocol),
+		Path:       in.FullID,
+		RemoteAddr: in.RemoteAddress,
+	}
+	if hit.Time.IsZero() {
+		hit.Time = time.Now()
+	}
+	if in.QType != "" {
+		hit.Method += " " + in.QType
+	}
+
+	if inj, ok := i.lookup(in); ok {
+		hit.Injection = &inj
+		i.Index.Fired(inj.Token)
+	}
+
+	i.mu.Lock()
+	i.hits = append(i.hits, hit)
+	i.mu.Unlock()
+
+	logHit(hit)
+	if i.OnHit != nil {
+		i.OnHit(hit)
+	}
+}
+
+// lookup finds the injection of an interaction from the nonce in its
+// subdomain, or failing that a token anywhere in the raw request
+func (i *Interactsh) lookup(in interaction) (Injection, bool) {
+	if len(in.UniqueID) == correlationIDLength+nonceLength {
+		i.mu.Lock()
+		token, ok := i.nonces[strings.ToLower(in.UniqueID[correlationIDLength:])]
+		i.mu.Unlock()
+		if ok {
+			return i.Index.Lookup(token)
+		}
+	}
+	return i.Index.Match(in.RawRequest)
+}
+
+// post sends body as JSON to path on the server
+func (i *Interactsh) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.Server+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if i.Token != "" {
+		req.Header.Set("Authorization", i.Token)
+	}
+
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// decryptKey decrypts the AES key the server encrypted with our public key
+func (i *Interactsh) decryptKey(encoded string) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode interaction key: %w", err)
+	}
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, i.key, encrypted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt interaction key: %w", err)
+	}
+	return key, nil
+}
+
+// decryptMessage decrypts an AES-CFB encrypted interaction, prefixed by its IV
+func decryptMessage(key []byte, encoded string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode interaction: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errors.New("interaction is too short")
+	}
+
+	iv, ciphertext := ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:]
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(plain, ciphertext)
+	return plain, nil
+}
+
+// randomID returns n random lowercase letters and digits, valid in a DNS label
+func randomID(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("callback: failed to generate id: %v", err))
+	}
+	for j := range b {
+		b[j] = alphabet[int(b[j])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,165 @@
+package callback
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockInteractsh is an interactsh-style server handing out the interactions
+// queued with add, encrypted for the registered session as the real one does
+type mockInteractsh struct {
+	t *testing.T
+
+	mu            sync.Mutex
+	key           *rsa.PublicKey
+	correlationID string
+	secret        string
+	pending       []interaction
+	deregistered  bool
+}
+
+func newMockInteractsh(t *testing.T) (*mockInteractsh, *httptest.Server) {
+	m := &mockInteractsh{t: t}
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+	return m, server
+}
+
+func (m *mockInteractsh) add(in interaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, in)
+}
+
+func (m *mockInteractsh) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/register":
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		encoded, _ := base64.StdEncoding.DecodeString(body["public-key"])
+		block, _ := pem.Decode(encoded)
+		if block == nil {
+			http.Error(w, "invalid public key", http.StatusBadRequest)
+			return
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.key = key.(*rsa.PublicKey)
+		m.correlationID = body["correlation-id"]
+		m.secret = body["secret-key"]
+	case "/poll":
+		if r.URL.Query().Get("id") != m.correlationID || r.URL.Query().Get("secret") != m.secret {
+			http.Error(w, "unknown session", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(m.encrypt())
+	case "/deregister":
+		m.deregistered = true
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// encrypt returns the pending interactions as a poll response, each encrypted
+// with a fresh AES key which is itself encrypted with the session's public key
+func (m *mockInteractsh) encrypt() map[string]interface{} {
+	key := make([]byte, 32)
+	rand.Read(key)
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, m.key, key, nil)
+	if err != nil {
+		m.t.Errorf("encrypting the AES key: %v", err)
+	}
+	block, _ := aes.NewCipher(key)
+
+	data := []string{}
+	for _, in := range m.pending {
+		plain, _ := json.Marshal(in)
+		message := make([]byte, aes.BlockSize+len(plain))
+		iv := message[:aes.BlockSize]
+		rand.Read(iv)
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(message[aes.BlockSize:], plain)
+		data = append(data, base64.StdEncoding.EncodeToString(message))
+	}
+	m.pending = nil
+	return map[string]interface{}{"data": data, "aes_key": base64.StdEncoding.EncodeToString(encryptedKey)}
+}
+
+func TestInteractshCorrelatesInteraction(t *testing.T) {
+	mock, server := newMockInteractsh(t)
+	index := NewIndex()
+	dnsInj := Injection{Token: NewToken(), URL: "https://target.example/?q=1", Point: "query", Param: "q"}
+	httpInj := Injection{Token: NewToken(), URL: "https://target.example/", Point: "header", Header: "Referer"}
+	index.Add(dnsInj)
+	index.Add(httpInj)
+	index.Add(Injection{Token: NewToken(), URL: "https://target.example/other", Point: "query", Param: "id"})
+
+	collaborator := NewInteractsh(server.URL, index)
+	collaborator.Client = server.Client()
+	if err := collaborator.Register(context.Background()); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// The payload's subdomain is all that gets out of a DNS lookup
+	callbackURL, err := url.Parse(collaborator.URL(dnsInj.Token))
+	if err != nil {
+		t.Fatalf("invalid callback URL: %v", err)
+	}
+	uniqueID := strings.Split(callbackURL.Hostname(), ".")[0]
+	if !strings.HasPrefix(uniqueID, mock.correlationID) {
+		t.Fatalf("callback host %s isn't under the session %s", callbackURL.Hostname(), mock.correlationID)
+	}
+	mock.add(interaction{Protocol: "dns", QType: "A", UniqueID: strings.ToUpper(uniqueID), FullID: uniqueID})
+
+	// An HTTP interaction is correlated by the token in its request
+	collaborator.URL(httpInj.Token)
+	mock.add(interaction{Protocol: "http", RawRequest: "GET /" + httpInj.Token + " HTTP/1.1\r\nHost: x\r\n\r\n", RemoteAddress: "203.0.113.7"})
+
+	// Close fetches the interactions received since the last poll
+	if err := collaborator.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !mock.deregistered {
+		t.Error("the session wasn't deregistered")
+	}
+
+	hits := collaborator.Hits()
+	if len(hits) != 2 {
+		t.Fatalf("%d hits, want 2", len(hits))
+	}
+	for i, want := range []Injection{dnsInj, httpInj} {
+		if hits[i].Injection == nil || !reflect.DeepEqual(*hits[i].Injection, want) {
+			t.Errorf("%s interaction correlated to %+v, want %+v", hits[i].Method, hits[i].Injection, want)
+		}
+	}
+	if hits[0].Method != "DNS A" || hits[1].RemoteAddr != "203.0.113.7" {
+		t.Errorf("hits = %+v", hits)
+	}
+	if !index.FiredSince(dnsInj.Token, time.Time{}) || !index.FiredSince(httpInj.Token, time.Time{}) {
+		t.Error("correlated interactions didn't mark their tokens fired")
+	}
+}
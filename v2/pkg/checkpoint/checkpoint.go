@@ -0,0 +1,99 @@
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// keySize is the length of a recorded key, a hex encoded SHA-256 digest
+const keySize = sha256.Size * 2
+
+// Log is an append-only record of completed (url, payload, header) scans so an
+// interrupted scan can be resumed. Each completion is one fixed-size line, so a
+// line cut short by a crash is recognised and ignored when the log is reopened.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]struct{}
+}
+
+// Open loads the completions recorded in the log at path, creating it if needed
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resume file: %w", err)
+	}
+
+	l := &Log{file: file, done: make(map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); len(line) == keySize {
+			l.done[line] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read resume file: %w", err)
+	}
+
+	// Terminate a partial last line so the next record starts on its own line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte("\n"))
+		}
+	}
+
+	return l, nil
+}
+
+// key identifies a (url, payload, header) tuple
+func key(link, payload, header string) string {
+	sum := sha256.Sum256([]byte(link + "\x00" + payload + "\x00" + header))
+	return hex.EncodeToString(sum[:])
+}
+
+// Done reports whether the tuple was completed by an earlier run
+func (l *Log) Done(link, payload, header string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.done[key(link, payload, header)]
+	return ok
+}
+
+// Len returns the number of completed tuples recorded
+func (l *Log) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.done)
+}
+
+// Mark records the tuple as completed
+func (l *Log) Mark(link, payload, header string) error {
+	k := key(link, payload, header)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.done[k]; ok {
+		return nil
+	}
+
+	// A single write per record keeps concurrent appends from interleaving
+	if _, err := l.file.Write([]byte(k + "\n")); err != nil {
+		return fmt.Errorf("failed to write resume file: %w", err)
+	}
+	l.done[k] = struct{}{}
+	return nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,50 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogSurvivesPartialWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	l.Mark("https://a.example/?q=1", "<b>", "")
+	l.Mark("https://a.example/?q=1", "<i>", "X-Test: 1")
+	l.Mark("https://a.example/?q=1", "<b>", "")
+	l.Close()
+
+	// A crash mid-write leaves the start of a record without its newline
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(key("https://a.example/?q=1", "<u>", "")[:20])
+	file.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open after crash: %v", err)
+	}
+	if l.Len() != 2 {
+		t.Errorf("%d completions, want 2", l.Len())
+	}
+	if !l.Done("https://a.example/?q=1", "<b>", "") || !l.Done("https://a.example/?q=1", "<i>", "X-Test: 1") {
+		t.Error("completed tuples weren't recovered")
+	}
+	if l.Done("https://a.example/?q=1", "<u>", "") || l.Done("https://a.example/?q=1", "<i>", "") {
+		t.Error("tuples never completed are recorded as done")
+	}
+
+	// Records made after the partial line are read back whole
+	l.Mark("https://a.example/?q=1", "<u>", "")
+	l.Close()
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer l.Close()
+	if !l.Done("https://a.example/?q=1", "<u>", "") || l.Len() != 3 {
+		t.Errorf("record after the partial line lost, %d completions", l.Len())
+	}
+}
@@ -0,0 +1,338 @@
+package crawl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxPages caps the pages fetched from one start URL
+const DefaultMaxPages = 500
+
+// maxPageSize bounds how much of a page is read for links and forms
+const maxPageSize = 2 * 1024 * 1024
+
+// Target is an injection point found by the crawler: a link with a query
+// string, or a form with the fields it submits
+type Target struct {
+	Method string
+
+	// URL carries the query parameters, including a GET form's fields
+	URL string
+
+	// Body holds a POST form's fields form encoded, in Multipart when the form
+	// is multipart/form-data
+	Body      string
+	Multipart string
+}
+
+// Crawler follows the links of same-origin pages from a start URL, up to
+// MaxDepth links away, collecting the links and forms that take parameters
+type Crawler struct {
+	Client *http.Client
+
+	// MaxDepth is how many links away from the start URL pages are fetched
+	MaxDepth int
+
+	// MaxPages caps the pages fetched (0 uses DefaultMaxPages)
+	MaxPages int
+
+	// Robots skips the paths robots.txt disallows
+	Robots bool
+
+	// Scope, Limiter and UserAgent apply to every page fetched when set
+	Scope     *scope.Scope
+	Limiter   *rate.Limiter
+	UserAgent string
+}
+
+var (
+	hrefPattern   = regexp.MustCompile(`(?is)<(?:a|area|iframe|frame)\b[^>]*?\b(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	formPattern   = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form>`)
+	fieldPattern  = regexp.MustCompile(`(?is)<(input|textarea|select|button)\b([^>]*)>`)
+	attrPattern   = regexp.MustCompile(`(?is)\b([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	skippedInputs = map[string]bool{"submit": true, "button": true, "image": true, "reset": true, "file": true}
+)
+
+// Crawl fetches start and the same-origin pages it links to, breadth first,
+// and returns the distinct targets found, in the order they were found
+func (c *Crawler) Crawl(ctx context.Context, start string) ([]Target, error) {
+	origin, err := url.Parse(start)
+	if err != nil || origin.Host == "" {
+		return nil, fmt.Errorf("invalid start URL '%s'", start)
+	}
+	maxPages := c.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	var disallowed []string
+	if c.Robots {
+		disallowed = c.robots(ctx, origin)
+	}
+
+	var targets []Target
+	seenTargets := make(map[string]bool)
+	addTarget := func(t Target) {
+		key := targetKey(t)
+		if !seenTargets[key] {
+			seenTargets[key] = true
+			targets = append(targets, t)
+		}
+	}
+
+	type page struct {
+		link  string
+		depth int
+	}
+	queue := []page{{start, 0}}
+	visited := map[string]bool{pageKey(origin): true}
+	for fetched := 0; len(queue) > 0 && fetched < maxPages; fetched++ {
+		if ctx.Err() != nil {
+			return targets, ctx.Err()
+		}
+		current := queue[0]
+		queue = queue[1:]
+
+		base, body, ok := c.fetch(ctx, current.link)
+		if !ok {
+			continue
+		}
+		if base.RawQuery != "" {
+			addTarget(Target{Method: http.MethodGet, URL: base.String()})
+		}
+		for _, form := range forms(base, body) {
+			if c.allowed(origin, form.URL, disallowed) {
+				addTarget(form)
+			}
+		}
+
+		for _, link := range links(base, body) {
+			u, err := url.Parse(link)
+			if err != nil || !c.allowed(origin, link, disallowed) {
+				continue
+			}
+			if u.RawQuery != "" {
+				addTarget(Target{Method: http.MethodGet, URL: link})
+			}
+			if current.depth >= c.MaxDepth || visited[pageKey(u)] {
+				continue
+			}
+			visited[pageKey(u)] = true
+			queue = append(queue, page{link, current.depth + 1})
+		}
+	}
+	return targets, nil
+}
+
+// fetch gets an HTML page, returning its final URL and up to maxPageSize of it
+func (c *Crawler) fetch(ctx context.Context, link string) (*url.URL, string, bool) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, "", false
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, "", false
+	}
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, "", false
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 || !strings.Contains(response.Header.Get("Content-Type"), "html") {
+		return nil, "", false
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxPageSize))
+	if err != nil {
+		return nil, "", false
+	}
+	return response.Request.URL, string(body), true
+}
+
+// allowed reports whether link is on the start URL's origin, in scope and not
+// disallowed by robots.txt
+func (c *Crawler) allowed(origin *url.URL, link string, disallowed []string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != origin.Scheme || u.Host != origin.Host {
+		return false
+	}
+	if c.Scope != nil && !c.Scope.AllowsURL(link) {
+		return false
+	}
+	for _, prefix := range disallowed {
+		if strings.HasPrefix(u.EscapedPath(), prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// robots returns the path prefixes robots.txt disallows for every user agent
+func (c *Crawler) robots(ctx context.Context, origin *url.URL) []string {
+	robotsURL := url.URL{Scheme: origin.Scheme, Host: origin.Host, Path: "/robots.txt"}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil
+	}
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var disallowed []string
+	applies := false
+	scanner := bufio.NewScanner(io.LimitReader(response.Body, maxPageSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "user-agent":
+			applies = value == "*"
+		case "disallow":
+			if applies && value != "" {
+				disallowed = append(disallowed, value)
+			}
+		}
+	}
+	return disallowed
+}
+
+// links returns the absolute URLs the page links to, without fragments
+func links(base *url.URL, body string) []string {
+	var found []string
+	for _, match := range hrefPattern.FindAllStringSubmatch(body, -1) {
+		if u := resolve(base, firstNonEmpty(match[1:])); u != nil {
+			found = append(found, u.String())
+		}
+	}
+	return found
+}
+
+// forms returns a target for each form of the page with fields to fill in
+func forms(base *url.URL, body string) []Target {
+	var targets []Target
+	for _, match := range formPattern.FindAllStringSubmatch(body, -1) {
+		attrs := attributes(match[1])
+		action := resolve(base, attrs["action"])
+		if action == nil {
+			continue
+		}
+
+		fields := url.Values{}
+		for _, field := range fieldPattern.FindAllStringSubmatch(match[2], -1) {
+			fieldAttrs := attributes(field[2])
+			name := fieldAttrs["name"]
+			if name == "" || (strings.EqualFold(field[1], "input") && skippedInputs[strings.ToLower(fieldAttrs["type"])]) {
+				continue
+			}
+			value := fieldAttrs["value"]
+			if value == "" {
+				value = "bxss"
+			}
+			fields.Set(name, value)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		target := Target{Method: strings.ToUpper(attrs["method"])}
+		switch {
+		case target.Method != http.MethodPost:
+			target.Method = http.MethodGet
+			action.RawQuery = fields.Encode()
+		case strings.EqualFold(attrs["enctype"], "multipart/form-data"):
+			target.Multipart = fields.Encode()
+		default:
+			target.Body = fields.Encode()
+		}
+		target.URL = action.String()
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// attributes parses the attributes of a tag, names lower cased
+func attributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		name := strings.ToLower(match[1])
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(firstNonEmpty(match[2:]))
+		}
+	}
+	return attrs
+}
+
+// resolve returns ref resolved against base without its fragment, or nil when
+// it isn't an http(s) URL
+func resolve(base *url.URL, ref string) *url.URL {
+	u, err := base.Parse(html.UnescapeString(strings.TrimSpace(ref)))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u
+}
+
+// pageKey identifies a page regardless of its query values, so a listing
+// linked with many different ids is fetched once
+func pageKey(u *url.URL) string {
+	names := make([]string, 0, len(u.Query()))
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return u.Scheme + "://" + u.Host + u.EscapedPath() + "?" + strings.Join(names, "&")
+}
+
+// targetKey identifies targets injecting into the same parameters of a page
+func targetKey(t Target) string {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return t.Method + " " + t.URL
+	}
+	fields := t.Body + t.Multipart
+	if values, err := url.ParseQuery(fields); err == nil {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields = strings.Join(names, "&")
+	}
+	return t.Method + " " + pageKey(u) + " " + fields
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values []string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+)
+
+// site is a small in-memory site recording the paths fetched from it
+type site struct {
+	*httptest.Server
+	mu      sync.Mutex
+	fetched map[string]bool
+}
+
+var sitePages = map[string]string{
+	"/": `<html><body>
+<a href="/search?q=shoes">Search</a>
+<a href='/about#team'>About</a>
+<a href="https://elsewhere.example/?x=1">Elsewhere</a>
+<a href=/private/admin?id=1>Admin</a>
+<form action="/comment" method="post">
+  <input type="hidden" name="post" value="7">
+  <textarea name="body"></textarea>
+  <input type="submit" name="go" value="Send">
+</form>
+</body></html>`,
+	"/search":        `<html><body>No results</body></html>`,
+	"/private/admin": `<html><body>Admin</body></html>`,
+	"/about": `<html><body>
+<a href="/deep">Deeper</a>
+<form action="/subscribe"><input name="email" type="email"><button>Go</button></form>
+</body></html>`,
+	"/deep": `<html><body>
+<form method="POST" enctype="multipart/form-data" action="/upload">
+  <input name="title">
+  <input type="file" name="attachment">
+</form>
+</body></html>`,
+}
+
+func newSite(t *testing.T) *site {
+	t.Helper()
+	s := &site{fetched: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.fetched[r.URL.Path] = true
+		s.mu.Unlock()
+
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: googlebot\nDisallow: /about\n\nUser-agent: *\nDisallow: /private # staff only\n"))
+			return
+		}
+		page, ok := sitePages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *site) wasFetched(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetched[path]
+}
+
+func TestCrawlFindsFormsAndLinks(t *testing.T) {
+	s := newSite(t)
+	crawler := &Crawler{Client: s.Client(), MaxDepth: 2}
+	targets, err := crawler.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	want := []Target{
+		{Method: http.MethodPost, URL: s.URL + "/comment", Body: "body=bxss&post=7"},
+		{Method: http.MethodGet, URL: s.URL + "/search?q=shoes"},
+		{Method: http.MethodGet, URL: s.URL + "/private/admin?id=1"},
+		{Method: http.MethodGet, URL: s.URL + "/subscribe?email=bxss"},
+		{Method: http.MethodPost, URL: s.URL + "/upload", Multipart: "title=bxss"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets =\n%+v\nwant\n%+v", targets, want)
+	}
+}
+
+func TestCrawlDepthLimited(t *testing.T) {
+	s := newSite(t)
+	crawler := &Crawler{Client: s.Client(), MaxDepth: 1}
+	targets, err := crawler.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	if !s.wasFetched("/about") {
+		t.Error("a page one link away wasn't fetched")
+	}
+	if s.wasFetched("/deep") {
+		t.Error("a page two links away was fetched at depth 1")
+	}
+	for _, target := range targets {
+		if target.URL == s.URL+"/upload" {
+			t.Errorf("found the form of a page beyond the depth: %+v", target)
+		}
+	}
+}
+
+func TestCrawlRespectsRobots(t *testing.T) {
+	s := newSite(t)
+	crawler := &Crawler{Client: s.Client(), MaxDepth: 2, Robots: true}
+	targets, err := crawler.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	if s.wasFetched("/private/admin") {
+		t.Error("fetched a path robots.txt disallows")
+	}
+	if !s.wasFetched("/about") {
+		t.Error("a rule for another user agent was applied")
+	}
+	for _, target := range targets {
+		if target.URL == s.URL+"/private/admin?id=1" {
+			t.Errorf("a disallowed link became a target: %+v", target)
+		}
+	}
+}
+
+func TestCrawlStaysInScope(t *testing.T) {
+	s := newSite(t)
+	excluded, err := scope.New(nil, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawler := &Crawler{Client: s.Client(), MaxDepth: 2, Scope: excluded}
+	targets, err := crawler.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if s.wasFetched("/about") || len(targets) != 0 {
+		t.Errorf("followed links to an out-of-scope host, found %+v", targets)
+	}
+}
+
+func TestCrawlCapsPages(t *testing.T) {
+	s := newSite(t)
+	crawler := &Crawler{Client: s.Client(), MaxDepth: 2, MaxPages: 1}
+	targets, err := crawler.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if s.wasFetched("/about") || len(targets) != 3 {
+		t.Errorf("fetched past the page cap, found %+v", targets)
+	}
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+)
+
+// Level is how much a logger prints, each level including those before it
+type Level int
+
+const (
+	// LevelError prints errors only
+	LevelError Level = iota
+
+	// LevelWarn adds warnings and successes, such as confirmed payloads
+	LevelWarn
+
+	// LevelInfo adds informational and progress messages, the default
+	LevelInfo
+
+	// LevelDebug adds debugging output
+	LevelDebug
+)
+
+// ansiEscape matches the colour codes of the colours formats
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// Logger writes levelled messages in the colours formats. Configure it before
+// sharing it between goroutines, writing is then safe for concurrent use.
+type Logger struct {
+	out     io.Writer
+	level   Level
+	noColor bool
+	mu      *sync.Mutex
+}
+
+// New creates a logger writing to out at level, without colours if noColor is set
+func New(out io.Writer, level Level, noColor bool) *Logger {
+	return &Logger{out: out, level: level, noColor: noColor, mu: &sync.Mutex{}}
+}
+
+// Colour modes accepted by UseColor
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// UseColor reports whether output to f is coloured in mode. Auto colours
+// terminals only, and nothing when the NO_COLOR environment variable is set.
+func UseColor(mode string, f *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+var std = New(os.Stdout, LevelInfo, !UseColor(ColorAuto, os.Stdout))
+
+// Default returns the logger used by the package functions and, unless they
+// are given one of their own, by the scanner and browser pool
+func Default() *Logger {
+	return std
+}
+
+// SetDefault replaces the default logger, call it before scanning starts
+func SetDefault(l *Logger) {
+	std = l
+}
+
+// To returns a copy of the logger writing to out instead
+func (l *Logger) To(out io.Writer) *Logger {
+	return &Logger{out: out, level: l.level, noColor: l.noColor, mu: &sync.Mutex{}}
+}
+
+// WithLevel returns a copy of the logger printing at level instead
+func (l *Logger) WithLevel(level Level) *Logger {
+	return &Logger{out: l.out, level: level, noColor: l.noColor, mu: l.mu}
+}
+
+// Enabled reports whether messages of level are printed
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.level
+}
+
+// Error prints msg as an error
+func (l *Logger) Error(msg string) { l.log(LevelError, colours.ErrorColor, msg) }
+
+// Warn prints msg as a warning
+func (l *Logger) Warn(msg string) { l.log(LevelWarn, colours.WarningColor, msg) }
+
+// Success prints msg as a success, such as a confirmed payload
+func (l *Logger) Success(msg string) { l.log(LevelWarn, colours.SuccessColor, msg) }
+
+// Info prints msg as information
+func (l *Logger) Info(msg string) { l.log(LevelInfo, colours.InfoColor, msg) }
+
+// Notice prints msg as a progress notice
+func (l *Logger) Notice(msg string) { l.log(LevelInfo, colours.NoticeColor, msg) }
+
+// Debug prints msg as debugging output
+func (l *Logger) Debug(msg string) { l.log(LevelDebug, colours.DebugColor, msg) }
+
+// Printf prints unlabelled text formatted as by fmt.Printf at the info level
+func (l *Logger) Printf(format string, a ...interface{}) {
+	l.log(LevelInfo, "%s", fmt.Sprintf(format, a...))
+}
+
+// Println prints unlabelled text as by fmt.Println at the info level
+func (l *Logger) Println(a ...interface{}) {
+	l.log(LevelInfo, "%s", fmt.Sprintln(a...))
+}
+
+// log writes msg in format if level is enabled
+func (l *Logger) log(level Level, format string, msg string) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	line := fmt.Sprintf(format, msg)
+	if l.noColor {
+		line = ansiEscape.ReplaceAllString(line, "")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line)
+}
+
+// Error prints msg as an error with the default logger
+func Error(msg string) { std.Error(msg) }
+
+// Warn prints msg as a warning with the default logger
+func Warn(msg string) { std.Warn(msg) }
+
+// Success prints msg as a success with the default logger
+func Success(msg string) { std.Success(msg) }
+
+// Info prints msg as information with the default logger
+func Info(msg string) { std.Info(msg) }
+
+// Notice prints msg as a progress notice with the default logger
+func Notice(msg string) { std.Notice(msg) }
+
+// Debug prints msg as debugging output with the default logger
+func Debug(msg string) { std.Debug(msg) }
+
+// Printf prints unlabelled text with the default logger
+func Printf(format string, a ...interface{}) { std.Printf(format, a...) }
+
+// Println prints unlabelled text with the default logger
+func Println(a ...interface{}) { std.Println(a...) }
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQuietSuppressesInfo(t *testing.T) {
+	// -quiet logs at the warning level
+	var buf bytes.Buffer
+	l := New(&buf, LevelWarn, true)
+	l.Info("starting scan")
+	l.Notice("checking URL")
+	l.Printf("progress %d\n", 1)
+	l.Debug("request dump")
+	l.Error("request failed")
+	l.Success("XSS confirmed")
+
+	out := buf.String()
+	for _, hidden := range []string{"starting scan", "checking URL", "progress", "request dump"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("quiet logger printed %q:\n%s", hidden, out)
+		}
+	}
+	for _, shown := range []string{"request failed", "XSS confirmed"} {
+		if !strings.Contains(out, shown) {
+			t.Errorf("quiet logger dropped %q:\n%s", shown, out)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelInfo, true)
+	l.Info("info")
+	l.Debug("debug")
+	if out := buf.String(); !strings.Contains(out, "info") || strings.Contains(out, "debug") {
+		t.Errorf("info logger printed %q", out)
+	}
+
+	buf.Reset()
+	l.WithLevel(LevelDebug).Debug("debug")
+	if !strings.Contains(buf.String(), "debug") {
+		t.Error("WithLevel(LevelDebug) didn't print debug output to the same writer")
+	}
+
+	var other bytes.Buffer
+	l.To(&other).Error("moved")
+	if !strings.Contains(other.String(), "moved") || strings.Contains(buf.String(), "moved") {
+		t.Error("To didn't redirect the output")
+	}
+}
+
+func TestNoColorWhenNotATerminal(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if UseColor(ColorAuto, file) {
+		t.Error("auto colours output to a file")
+	}
+
+	var buf bytes.Buffer
+	l := New(&buf, LevelInfo, !UseColor(ColorAuto, file))
+	l.Error("failed")
+	l.Success("confirmed")
+	l.Info("info")
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("output to a file carries ANSI escapes: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "confirmed") {
+		t.Errorf("output = %q, want the messages without colour", buf.String())
+	}
+}
+
+func TestColorModes(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if !UseColor(ColorAlways, file) {
+		t.Error("-color=always didn't colour output to a file")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if UseColor(ColorAuto, os.Stdout) {
+		t.Error("auto coloured output with NO_COLOR set")
+	}
+	if UseColor(ColorNever, os.Stdout) {
+		t.Error("-color=never coloured output")
+	}
+
+	var buf bytes.Buffer
+	New(&buf, LevelInfo, false).Error("failed")
+	if !strings.Contains(buf.String(), "\033[") {
+		t.Errorf("coloured logger wrote %q without escapes", buf.String())
+	}
+}
@@ -0,0 +1,129 @@
+// Package mutate expands payloads into variants, such as ones dodging a WAF
+// rule, through named mutators that callers can add to.
+package mutate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Mutator returns variants of payload to scan on top of it. Variants must keep
+// {{...}} placeholders such as {{param}} and {{token}} intact, as they are
+// filled in after mutation.
+type Mutator func(payload string) []string
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Mutator{
+		"case-swap":  caseSwap,
+		"whitespace": whitespace,
+	}
+)
+
+// Register adds a mutator selectable by name with -mutators, replacing any
+// registered under the same name
+func Register(name string, m Mutator) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registry[strings.ToLower(name)] = m
+}
+
+// Names returns the names of the registered mutators, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse parses a comma separated list of mutator names, checking each is registered
+func Parse(value string) ([]string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := registry[name]; !ok {
+			return nil, fmt.Errorf("unknown mutator '%s'", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Apply returns payload followed by the distinct variants the named mutators
+// produce from it, in order
+func Apply(payload string, names []string) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	payloads := []string{payload}
+	seen := map[string]bool{payload: true}
+	for _, name := range names {
+		m, ok := registry[name]
+		if !ok {
+			continue
+		}
+		for _, variant := range m(payload) {
+			if !seen[variant] {
+				seen[variant] = true
+				payloads = append(payloads, variant)
+			}
+		}
+	}
+	return payloads
+}
+
+// tagName matches an opening or closing tag's name and event handler attributes
+var tagName = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9]*|\bon[a-z]+=`)
+
+// caseSwap alternates the case of tag and event handler names, as HTML
+// ignores it but case-sensitive filters don't (<script> becomes <sCrIpT>).
+// URLs and script bodies are left alone so callbacks still reach their host.
+func caseSwap(payload string) []string {
+	return []string{tagName.ReplaceAllStringFunc(payload, func(match string) string {
+		runes := []rune(match)
+		upper := false
+		for i, r := range runes {
+			if unicode.IsLetter(r) {
+				if upper {
+					runes[i] = unicode.ToUpper(r)
+				} else {
+					runes[i] = unicode.ToLower(r)
+				}
+				upper = !upper
+			}
+		}
+		return string(runes)
+	})}
+}
+
+// tagSpace matches the whitespace between a tag's name and its first attribute
+var tagSpace = regexp.MustCompile(`(<[a-zA-Z][a-zA-Z0-9]*)\s+`)
+
+// whitespace returns variants separating tag names from their attributes with
+// a slash, a tab and a newline instead of a space, which browsers all accept
+func whitespace(payload string) []string {
+	if !tagSpace.MatchString(payload) {
+		return nil
+	}
+	var variants []string
+	for _, sep := range []string{"/", "\t", "\n"} {
+		variants = append(variants, tagSpace.ReplaceAllString(payload, "${1}"+sep))
+	}
+	return variants
+}
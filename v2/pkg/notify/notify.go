@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/retry"
+)
+
+// DefaultTemplate formats the message sent for a confirmed finding
+const DefaultTemplate = `Blind XSS fired on {{.Target}} via {{.InjectionPoint}}{{if .Param}} ({{.Param}}){{end}}{{if .Header}} (header {{.Header}}){{end}}: {{.Payload}}`
+
+// Webhook flavours, which differ in the JSON body they accept
+const (
+	flavourGeneric = "generic"
+	flavourSlack   = "slack"
+	flavourDiscord = "discord"
+)
+
+// Notifier posts confirmed findings to a webhook. It implements report.Writer
+// so it can sit alongside the other outputs; unconfirmed findings are ignored.
+type Notifier struct {
+	URL      string
+	Client   *http.Client
+	Retries  int
+	template *template.Template
+	flavour  string
+}
+
+// New creates a notifier for webhookURL formatting messages with tmpl, or
+// DefaultTemplate when tmpl is empty. Slack and Discord webhooks are detected
+// from the URL and sent in their own message format.
+func New(webhookURL string, tmpl string) (*Notifier, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL '%s'", webhookURL)
+	}
+
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New("notify").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+
+	return &Notifier{
+		URL:      webhookURL,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		Retries:  2,
+		template: t,
+		flavour:  flavourFor(u),
+	}, nil
+}
+
+// flavourFor picks the message format from the webhook host
+func flavourFor(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return flavourSlack
+	case (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks"):
+		return flavourDiscord
+	default:
+		return flavourGeneric
+	}
+}
+
+// Write sends a confirmed finding to the webhook
+func (n *Notifier) Write(f report.Finding) error {
+	if !f.Confirmed {
+		return nil
+	}
+
+	body, err := n.body(f)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := retry.Policy{Retries: n.Retries}.Do(n.Client, req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// body builds the JSON request body for the webhook flavour
+func (n *Notifier) body(f report.Finding) ([]byte, error) {
+	var text strings.Builder
+	if err := n.template.Execute(&text, f); err != nil {
+		return nil, fmt.Errorf("failed to render notification: %w", err)
+	}
+
+	var message interface{}
+	switch n.flavour {
+	case flavourSlack:
+		message = map[string]string{"text": text.String()}
+	case flavourDiscord:
+		message = map[string]string{"content": text.String()}
+	default:
+		message = struct {
+			Text    string         `json:"text"`
+			Finding report.Finding `json:"finding"`
+		}{text.String(), f}
+	}
+	return json.Marshal(message)
+}
+
+// Close is a no-op, notifications are sent as findings arrive
+func (n *Notifier) Close() error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// webhook is a stub webhook answering with the statuses given in turn, then
+// 200, and recording the bodies it received
+type webhook struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func newWebhook(t *testing.T, statuses ...int) *webhook {
+	t.Helper()
+	hook := &webhook{}
+	hook.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		hook.mu.Lock()
+		hook.bodies = append(hook.bodies, body)
+		n := len(hook.bodies)
+		hook.mu.Unlock()
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(hook.Close)
+	return hook
+}
+
+func (w *webhook) received() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([][]byte(nil), w.bodies...)
+}
+
+var confirmed = report.Finding{
+	Target:         "https://target.example/search",
+	Param:          "q",
+	Payload:        "<script>alert(1)</script>",
+	InjectionPoint: report.PointQuery,
+	Token:          "abc123",
+	Confirmed:      true,
+}
+
+func TestNotifierGenericBody(t *testing.T) {
+	hook := newWebhook(t)
+	n, err := New(hook.URL, "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	unconfirmed := confirmed
+	unconfirmed.Confirmed = false
+	if err := n.Write(unconfirmed); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := n.Write(confirmed); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	bodies := hook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("%d notifications, want only the confirmed finding's", len(bodies))
+	}
+	var body struct {
+		Text    string         `json:"text"`
+		Finding report.Finding `json:"finding"`
+	}
+	if err := json.Unmarshal(bodies[0], &body); err != nil {
+		t.Fatalf("invalid JSON body: %v\n%s", err, bodies[0])
+	}
+	if want := "Blind XSS fired on https://target.example/search via query (q): <script>alert(1)</script>"; body.Text != want {
+		t.Errorf("text = %q, want %q", body.Text, want)
+	}
+	if body.Finding.Token != "abc123" || body.Finding.Payload != confirmed.Payload {
+		t.Errorf("finding = %+v", body.Finding)
+	}
+}
+
+func TestNotifierFlavours(t *testing.T) {
+	for webhookURL, key := range map[string]string{
+		"https://hooks.slack.com/services/T0/B0/x":    "text",
+		"https://discord.com/api/webhooks/1/x":        "content",
+		"https://discordapp.com/api/webhooks/1/x":     "content",
+		"https://hooks.example/slack/hooks.slack.com": "finding",
+	} {
+		n, err := New(webhookURL, "{{.Target}} {{.Token}}")
+		if err != nil {
+			t.Fatalf("New(%s): %v", webhookURL, err)
+		}
+		data, err := n.body(confirmed)
+		if err != nil {
+			t.Fatalf("body: %v", err)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("invalid JSON body: %v", err)
+		}
+		if _, ok := body[key]; !ok {
+			t.Errorf("%s body = %s, want a %q key", webhookURL, data, key)
+		}
+		if key != "finding" && body[key] != "https://target.example/search abc123" {
+			t.Errorf("%s message = %v, want the template rendered", webhookURL, body[key])
+		}
+	}
+}
+
+func TestNotifierRetriesServerErrors(t *testing.T) {
+	hook := newWebhook(t, http.StatusBadGateway)
+	n, err := New(hook.URL, "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := n.Write(confirmed); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := len(hook.received()); got != 2 {
+		t.Errorf("%d attempts, want the 502 retried once", got)
+	}
+}
+
+func TestNotifierFailureDoesNotStopOtherOutputs(t *testing.T) {
+	hook := newWebhook(t, http.StatusForbidden)
+	n, err := New(hook.URL, "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	collector := report.NewCollector()
+	w := report.Multi(n, collector)
+	if err := w.Write(confirmed); err == nil {
+		t.Error("Write succeeded despite the webhook's 403")
+	}
+	if len(hook.received()) != 1 {
+		t.Errorf("a 403 was retried")
+	}
+	if len(collector.Findings()) != 1 {
+		t.Error("the finding didn't reach the other outputs")
+	}
+	if _, err := New("not a url", ""); err == nil {
+		t.Error("New accepted an invalid webhook URL")
+	}
+	if _, err := New(hook.URL, "{{.Target"); err == nil {
+		t.Error("New accepted an invalid template")
+	}
+}
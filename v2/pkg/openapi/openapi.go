@@ -0,0 +1,503 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxDepth bounds how deep sample values follow nested and recursive schemas
+const maxDepth = 8
+
+// Endpoint is a concrete request derived from an operation of the document,
+// with benign values filled in for its parameters and body
+type Endpoint struct {
+	Method string
+
+	// Path is the templated path of the operation, e.g. /users/{id}
+	Path string
+
+	// URL carries the path and query parameters filled in
+	URL string
+
+	// Query and PathParams name the parameters URL carries
+	Query      []string
+	PathParams []string
+
+	// Headers are the header parameters as "Name: value" lines, Cookies the
+	// names of the cookie parameters
+	Headers []string
+	Cookies []string
+
+	// Body is a JSON or form encoded body template, Multipart a form encoded
+	// template of multipart/form-data fields
+	Body      string
+	Multipart string
+}
+
+// Spec is an OpenAPI 3 or Swagger 2 document
+type Spec struct {
+	OpenAPI string `json:"openapi"`
+	Swagger string `json:"swagger"`
+
+	// Servers (OpenAPI 3) or Schemes, Host and BasePath (Swagger 2) give the base URL
+	Servers  []server `json:"servers"`
+	Schemes  []string `json:"schemes"`
+	Host     string   `json:"host"`
+	BasePath string   `json:"basePath"`
+
+	Paths map[string]*pathItem `json:"paths"`
+
+	Components struct {
+		Schemas       map[string]*schema      `json:"schemas"`
+		Parameters    map[string]*parameter   `json:"parameters"`
+		RequestBodies map[string]*requestBody `json:"requestBodies"`
+	} `json:"components"`
+	Definitions map[string]*schema    `json:"definitions"`
+	Parameters  map[string]*parameter `json:"parameters"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+}
+
+// methodOperation is an operation and the method it is under
+type methodOperation struct {
+	method string
+	op     *operation
+}
+
+// operations returns the operations of the path, in a fixed order of methods
+func (p *pathItem) operations() []methodOperation {
+	all := []methodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch},
+		{"DELETE", p.Delete}, {"OPTIONS", p.Options}, {"HEAD", p.Head},
+	}
+	ops := all[:0]
+	for _, o := range all {
+		if o.op != nil {
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+	Consumes    []string     `json:"consumes"`
+}
+
+type parameter struct {
+	Ref     string        `json:"$ref"`
+	Name    string        `json:"name"`
+	In      string        `json:"in"`
+	Schema  *schema       `json:"schema"`
+	Example interface{}   `json:"example"`
+	Default interface{}   `json:"default"`
+	Enum    []interface{} `json:"enum"`
+
+	// Type, Format and Items describe Swagger 2 parameters outside the body
+	Type   string  `json:"type"`
+	Format string  `json:"format"`
+	Items  *schema `json:"items"`
+}
+
+type requestBody struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	AllOf      []*schema          `json:"allOf"`
+	OneOf      []*schema          `json:"oneOf"`
+	AnyOf      []*schema          `json:"anyOf"`
+	Example    interface{}        `json:"example"`
+	Default    interface{}        `json:"default"`
+	Enum       []interface{}      `json:"enum"`
+}
+
+// Load reads and parses the document at path
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a JSON OpenAPI 3 or Swagger 2 document. YAML documents need
+// converting to JSON first.
+func Parse(data []byte) (*Spec, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, errors.New("OpenAPI document is not JSON, convert YAML documents to JSON first")
+	}
+	var spec Spec
+	if err := json.Unmarshal(trimmed, &spec); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if spec.OpenAPI == "" && spec.Swagger == "" {
+		return nil, errors.New("not an OpenAPI document, it has no openapi or swagger version")
+	}
+	return &spec, nil
+}
+
+// BaseURL returns the base URL the document gives for its paths
+func (s *Spec) BaseURL() string {
+	if len(s.Servers) > 0 {
+		return s.Servers[0].URL
+	}
+	if s.Host == "" {
+		return s.BasePath
+	}
+	scheme := "https"
+	if len(s.Schemes) > 0 {
+		scheme = s.Schemes[0]
+	}
+	return scheme + "://" + s.Host + s.BasePath
+}
+
+// Endpoints returns a request for every operation of the document, sorted by
+// path, against base or, when base is empty, the document's own base URL
+func (s *Spec) Endpoints(base string) ([]Endpoint, error) {
+	if base == "" {
+		base = s.BaseURL()
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("no absolute base URL for the OpenAPI paths (got '%s'), set one with -base-url", base)
+	}
+	base = strings.TrimSuffix(u.String(), "/")
+
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var endpoints []Endpoint
+	for _, path := range paths {
+		item := s.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, o := range item.operations() {
+			endpoint, err := s.endpoint(base, path, o.method, item.Parameters, o.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, path, err)
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+// endpoint fills in the parameters and body of one operation
+func (s *Spec) endpoint(base string, path string, method string, shared []*parameter, op *operation) (Endpoint, error) {
+	endpoint := Endpoint{Method: method, Path: path}
+
+	// Operation parameters override those of the path with the same name and location
+	params := make(map[string]*parameter)
+	var order []string
+	for _, list := range [][]*parameter{shared, op.Parameters} {
+		for _, param := range list {
+			param, err := s.resolveParameter(param)
+			if err != nil {
+				return endpoint, err
+			}
+			if param == nil {
+				continue
+			}
+			key := param.In + ":" + param.Name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+
+	filled := path
+	query := url.Values{}
+	form := url.Values{}
+	multipart := len(op.Consumes) > 0 && op.Consumes[0] == "multipart/form-data"
+	var body interface{}
+	for _, key := range order {
+		param := params[key]
+		value := valueString(s.parameterValue(param))
+		switch param.In {
+		case "path":
+			filled = strings.ReplaceAll(filled, "{"+param.Name+"}", url.PathEscape(value))
+			endpoint.PathParams = append(endpoint.PathParams, param.Name)
+		case "query":
+			query.Set(param.Name, value)
+			endpoint.Query = append(endpoint.Query, param.Name)
+		case "header":
+			endpoint.Headers = append(endpoint.Headers, param.Name+": "+value)
+		case "cookie":
+			endpoint.Cookies = append(endpoint.Cookies, param.Name)
+		case "formData":
+			form.Set(param.Name, value)
+		case "body":
+			body = s.sample(param.Schema, 0)
+		}
+	}
+
+	endpoint.URL = base + filled
+	if len(query) > 0 {
+		endpoint.URL += "?" + query.Encode()
+	}
+
+	if op.RequestBody != nil {
+		contentType, bodySchema, err := s.requestBodySchema(op.RequestBody)
+		if err != nil {
+			return endpoint, err
+		}
+		switch {
+		case strings.Contains(contentType, "json"):
+			body = s.sample(bodySchema, 0)
+		case contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data":
+			form = formValues(s.sample(bodySchema, 0))
+			multipart = contentType == "multipart/form-data"
+		}
+	}
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return endpoint, err
+		}
+		endpoint.Body = string(data)
+	} else if len(form) > 0 {
+		if multipart {
+			endpoint.Multipart = form.Encode()
+		} else {
+			endpoint.Body = form.Encode()
+		}
+	}
+	return endpoint, nil
+}
+
+// requestBodySchema picks the JSON content of body if it has one, then a form
+func (s *Spec) requestBodySchema(body *requestBody) (string, *schema, error) {
+	if body.Ref != "" {
+		name, err := refName(body.Ref, "#/components/requestBodies/")
+		if err != nil {
+			return "", nil, err
+		}
+		resolved, ok := s.Components.RequestBodies[name]
+		if !ok || resolved == nil {
+			return "", nil, fmt.Errorf("unresolved reference '%s'", body.Ref)
+		}
+		body = resolved
+	}
+
+	types := make([]string, 0, len(body.Content))
+	for contentType := range body.Content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	for _, want := range []string{"json", "application/x-www-form-urlencoded", "multipart/form-data"} {
+		for _, contentType := range types {
+			if strings.Contains(contentType, want) {
+				return contentType, body.Content[contentType].Schema, nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// resolveParameter follows a parameter's $ref
+func (s *Spec) resolveParameter(param *parameter) (*parameter, error) {
+	if param == nil || param.Ref == "" {
+		return param, nil
+	}
+	prefix := "#/parameters/"
+	lookup := s.Parameters
+	if strings.HasPrefix(param.Ref, "#/components/") {
+		prefix, lookup = "#/components/parameters/", s.Components.Parameters
+	}
+	name, err := refName(param.Ref, prefix)
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := lookup[name]
+	if !ok || resolved == nil {
+		return nil, fmt.Errorf("unresolved reference '%s'", param.Ref)
+	}
+	return resolved, nil
+}
+
+// resolveSchema follows a schema's $ref
+func (s *Spec) resolveSchema(sch *schema) *schema {
+	if sch == nil || sch.Ref == "" {
+		return sch
+	}
+	for prefix, lookup := range map[string]map[string]*schema{
+		"#/components/schemas/": s.Components.Schemas,
+		"#/definitions/":        s.Definitions,
+	} {
+		if name, err := refName(sch.Ref, prefix); err == nil {
+			return lookup[name]
+		}
+	}
+	return nil
+}
+
+// refName returns the name a local reference points to under prefix
+func refName(ref string, prefix string) (string, error) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference '%s'", ref)
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(strings.TrimPrefix(ref, prefix), "~1", "/"), "~0", "~"), nil
+}
+
+// parameterValue returns a benign value for a parameter
+func (s *Spec) parameterValue(param *parameter) interface{} {
+	switch {
+	case param.Example != nil:
+		return param.Example
+	case param.Default != nil:
+		return param.Default
+	case len(param.Enum) > 0:
+		return param.Enum[0]
+	case param.Schema != nil:
+		return s.sample(param.Schema, 0)
+	default:
+		return s.sample(&schema{Type: param.Type, Format: param.Format, Items: param.Items}, 0)
+	}
+}
+
+// sample returns a benign value matching the schema, with every property of
+// objects filled in so each becomes an injection point
+func (s *Spec) sample(sch *schema, depth int) interface{} {
+	sch = s.resolveSchema(sch)
+	if sch == nil || depth > maxDepth {
+		return nil
+	}
+	switch {
+	case sch.Example != nil:
+		return sch.Example
+	case sch.Default != nil:
+		return sch.Default
+	case len(sch.Enum) > 0:
+		return sch.Enum[0]
+	}
+
+	if len(sch.AllOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, part := range sch.AllOf {
+			if object, ok := s.sample(part, depth+1).(map[string]interface{}); ok {
+				for k, v := range object {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, choices := range [][]*schema{sch.OneOf, sch.AnyOf} {
+		if len(choices) > 0 {
+			return s.sample(choices[0], depth+1)
+		}
+	}
+
+	switch sch.Type {
+	case "integer", "number":
+		return 1
+	case "boolean":
+		return true
+	case "array":
+		if item := s.sample(sch.Items, depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "object", "":
+		if len(sch.Properties) == 0 && sch.Type == "" {
+			return sampleString(sch.Format)
+		}
+		object := make(map[string]interface{}, len(sch.Properties))
+		for name, property := range sch.Properties {
+			if value := s.sample(property, depth+1); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	default:
+		return sampleString(sch.Format)
+	}
+}
+
+// sampleString returns a benign string in format
+func sampleString(format string) string {
+	switch format {
+	case "email":
+		return "bxss@example.com"
+	case "uri", "url":
+		return "https://example.com/"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "ipv4":
+		return "127.0.0.1"
+	default:
+		return "bxss"
+	}
+}
+
+// formValues flattens the top level of an object sample into form fields
+func formValues(v interface{}) url.Values {
+	form := url.Values{}
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return form
+	}
+	for name, value := range object {
+		form.Set(name, valueString(value))
+	}
+	return form
+}
+
+// valueString formats a sample value for a parameter or form field, arrays as
+// their first item and objects as JSON
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		if len(v) == 0 {
+			return ""
+		}
+		return valueString(v[0])
+	case map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "servers": [{"url": "https://api.example/v1"}],
+  "paths": {
+    "/users/{id}": {
+      "parameters": [{"name": "id", "in": "path", "schema": {"type": "integer"}}],
+      "get": {
+        "parameters": [
+          {"name": "q", "in": "query", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/Trace"},
+          {"name": "session", "in": "cookie", "schema": {"type": "string"}}
+        ]
+      }
+    },
+    "/comments": {
+      "post": {"requestBody": {"$ref": "#/components/requestBodies/Comment"}}
+    },
+    "/upload": {
+      "post": {
+        "requestBody": {
+          "content": {
+            "multipart/form-data": {
+              "schema": {"type": "object", "properties": {"file": {"type": "string"}, "note": {"type": "string"}}}
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "Trace": {"name": "X-Trace", "in": "header", "example": "abc"}
+    },
+    "requestBodies": {
+      "Comment": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Comment"}}}}
+    },
+    "schemas": {
+      "Comment": {
+        "type": "object",
+        "properties": {
+          "text": {"type": "string"},
+          "email": {"type": "string", "format": "email"},
+          "author": {"$ref": "#/components/schemas/Author"},
+          "tags": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Author": {"type": "object", "properties": {"name": {"type": "string"}}}
+    }
+  }
+}`
+
+const swaggerDocument = `{
+  "swagger": "2.0",
+  "schemes": ["http"],
+  "host": "api.example",
+  "basePath": "/v2",
+  "parameters": {
+    "id": {"name": "id", "in": "path", "type": "string", "format": "uuid"}
+  },
+  "paths": {
+    "/posts/{id}": {
+      "put": {
+        "consumes": ["application/x-www-form-urlencoded"],
+        "parameters": [
+          {"$ref": "#/parameters/id"},
+          {"name": "title", "in": "formData", "type": "string"}
+        ]
+      }
+    },
+    "/items": {
+      "post": {
+        "parameters": [{"name": "item", "in": "body", "schema": {"$ref": "#/definitions/Item"}}]
+      }
+    }
+  },
+  "definitions": {
+    "Item": {"type": "object", "properties": {"name": {"type": "string"}, "count": {"type": "integer"}}}
+  }
+}`
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	spec, err := Parse([]byte(openAPIDocument))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	endpoints, err := spec.Endpoints("")
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+
+	want := []Endpoint{
+		{
+			Method: "POST",
+			Path:   "/comments",
+			URL:    "https://api.example/v1/comments",
+			Body:   `{"author":{"name":"bxss"},"email":"bxss@example.com","tags":["bxss"],"text":"bxss"}`,
+		},
+		{
+			Method:    "POST",
+			Path:      "/upload",
+			URL:       "https://api.example/v1/upload",
+			Multipart: "file=bxss&note=bxss",
+		},
+		{
+			Method:     "GET",
+			Path:       "/users/{id}",
+			URL:        "https://api.example/v1/users/1?q=bxss",
+			Query:      []string{"q"},
+			PathParams: []string{"id"},
+			Headers:    []string{"X-Trace: abc"},
+			Cookies:    []string{"session"},
+		},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("endpoints =\n%+v\nwant\n%+v", endpoints, want)
+	}
+}
+
+func TestSwaggerEndpoints(t *testing.T) {
+	spec, err := Parse([]byte(swaggerDocument))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if base := spec.BaseURL(); base != "http://api.example/v2" {
+		t.Errorf("BaseURL() = %q, want http://api.example/v2", base)
+	}
+	endpoints, err := spec.Endpoints("")
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+
+	want := []Endpoint{
+		{
+			Method: "POST",
+			Path:   "/items",
+			URL:    "http://api.example/v2/items",
+			Body:   `{"count":1,"name":"bxss"}`,
+		},
+		{
+			Method:     "PUT",
+			Path:       "/posts/{id}",
+			URL:        "http://api.example/v2/posts/00000000-0000-0000-0000-000000000000",
+			PathParams: []string{"id"},
+			Body:       "title=bxss",
+		},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("endpoints =\n%+v\nwant\n%+v", endpoints, want)
+	}
+}
+
+func TestEndpointsAgainstBaseURL(t *testing.T) {
+	spec, err := Parse([]byte(openAPIDocument))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	endpoints, err := spec.Endpoints("https://staging.example/api/")
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+	for _, endpoint := range endpoints {
+		if !strings.HasPrefix(endpoint.URL, "https://staging.example/api/") || strings.Contains(endpoint.URL, "//api/") {
+			t.Errorf("%s %s is at %s, not under the base URL", endpoint.Method, endpoint.Path, endpoint.URL)
+		}
+	}
+}
+
+func TestRecursiveSchemaBounded(t *testing.T) {
+	spec, err := Parse([]byte(`{
+  "openapi": "3.0.0",
+  "paths": {"/nodes": {"post": {"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Node"}}}}}}},
+  "components": {"schemas": {"Node": {"type": "object", "properties": {"name": {"type": "string"}, "child": {"$ref": "#/components/schemas/Node"}}}}}
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	endpoints, err := spec.Endpoints("https://api.example")
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+	if n := strings.Count(endpoints[0].Body, `"child"`); n != maxDepth {
+		t.Errorf("body nests %d children, want %d: %s", n, maxDepth, endpoints[0].Body)
+	}
+}
+
+func TestInvalidDocuments(t *testing.T) {
+	for name, document := range map[string]string{
+		"yaml":       "openapi: 3.0.0\npaths: {}\n",
+		"no version": `{"paths": {}}`,
+		"malformed":  `{"openapi": "3.0.0", "paths": [}`,
+	} {
+		if _, err := Parse([]byte(document)); err == nil {
+			t.Errorf("%s: Parse accepted %q", name, document)
+		}
+	}
+
+	spec, err := Parse([]byte(`{"openapi": "3.0.0", "paths": {"/a": {"get": {"parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := spec.Endpoints("https://api.example"); err == nil || !strings.Contains(err.Error(), "unresolved reference") {
+		t.Errorf("Endpoints with an unresolved reference: %v", err)
+	}
+	if _, err := spec.Endpoints(""); err == nil {
+		t.Error("Endpoints without a base URL succeeded")
+	}
+}
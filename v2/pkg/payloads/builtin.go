@@ -0,0 +1,51 @@
+package payloads
+
+import (
+	"embed"
+	"strings"
+)
+
+// Contexts are the names of the built-in payload sets, each for a different
+// place the injected value may be reflected
+var Contexts = []string{"html", "attr", "js", "url"}
+
+//go:embed sets/*.txt
+var sets embed.FS
+
+// Builtin returns the built-in payloads for the given contexts, in the order
+// given, or for every context when none are given
+func Builtin(contexts []string) ([]string, error) {
+	if len(contexts) == 0 {
+		contexts = Contexts
+	}
+
+	var lines []string
+	for _, name := range contexts {
+		data, err := sets.ReadFile("sets/" + name + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, strings.Split(string(data), "\n")...)
+	}
+	return normalizeLines(lines, false), nil
+}
+
+// BuiltinPayloads returns Builtin(contexts), leaving out the payloads using
+// {{callback}} when there is neither a -callback-url nor a collaborator to fill it
+func (p *PayloadParser) BuiltinPayloads(contexts []string) ([]string, error) {
+	payloads, err := Builtin(contexts)
+	if err != nil {
+		return nil, err
+	}
+	if p.args.CallbackURL != "" || p.Collaborator != nil {
+		return payloads, nil
+	}
+
+	kept := payloads[:0]
+	for _, payload := range payloads {
+		if !strings.Contains(payload, "{{callback}}") {
+			kept = append(kept, payload)
+		}
+	}
+	return kept, nil
+}
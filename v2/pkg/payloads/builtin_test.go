@@ -0,0 +1,94 @@
+package payloads
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+)
+
+func TestBuiltinAttrOnlyBreaksOutOfAttributes(t *testing.T) {
+	attr, err := Builtin([]string{"attr"})
+	if err != nil {
+		t.Fatalf("Builtin: %v", err)
+	}
+	if len(attr) == 0 {
+		t.Fatal("no attr payloads")
+	}
+
+	others, err := Builtin([]string{"html", "js", "url"})
+	if err != nil {
+		t.Fatalf("Builtin: %v", err)
+	}
+	for _, payload := range attr {
+		if !strings.HasPrefix(payload, `"`) && !strings.HasPrefix(payload, "'") {
+			t.Errorf("attr payload %q doesn't close the attribute's quotes", payload)
+		}
+		if slices.Contains(others, payload) {
+			t.Errorf("attr payload %q also belongs to another context", payload)
+		}
+	}
+}
+
+func TestBuiltinDefaultsToEveryContext(t *testing.T) {
+	all, err := Builtin(nil)
+	if err != nil {
+		t.Fatalf("Builtin: %v", err)
+	}
+	var want []string
+	for _, name := range Contexts {
+		payloads, err := Builtin([]string{name})
+		if err != nil {
+			t.Fatalf("Builtin(%s): %v", name, err)
+		}
+		want = append(want, payloads...)
+	}
+	if !slices.Equal(all, want) {
+		t.Errorf("Builtin(nil) = %d payloads, want the %d of every context in order", len(all), len(want))
+	}
+
+	if _, err := Builtin([]string{"css"}); err == nil {
+		t.Error("Builtin accepted an unknown context")
+	}
+}
+
+func TestBuiltinPayloadsDropsUnfilledCallbacks(t *testing.T) {
+	p := NewPayload(&arguments.Arguments{})
+	payloads, err := p.BuiltinPayloads([]string{"attr"})
+	if err != nil {
+		t.Fatalf("BuiltinPayloads: %v", err)
+	}
+	for _, payload := range payloads {
+		if strings.Contains(payload, "{{callback}}") {
+			t.Errorf("kept %q with no callback URL to fill it", payload)
+		}
+	}
+
+	p = NewPayload(&arguments.Arguments{CallbackURL: "https://cb.example"})
+	withCallback, _ := p.BuiltinPayloads([]string{"attr"})
+	if len(withCallback) <= len(payloads) {
+		t.Errorf("%d payloads with a callback URL, want more than the %d without", len(withCallback), len(payloads))
+	}
+}
+
+func TestReadPayloadsFallsBackToBuiltin(t *testing.T) {
+	p := NewPayload(&arguments.Arguments{})
+	payloads, err := p.ReadPayloads()
+	if err != nil {
+		t.Fatalf("ReadPayloads: %v", err)
+	}
+	if len(payloads) == 0 {
+		t.Fatal("no payloads loaded without a payload file")
+	}
+	builtin, _ := p.BuiltinPayloads(nil)
+	if !slices.Equal(payloads, builtin) {
+		t.Errorf("loaded %d payloads, want the %d embedded defaults", len(payloads), len(builtin))
+	}
+
+	// A payload given on the command line replaces the defaults
+	p = NewPayload(&arguments.Arguments{Payload: "<b>"})
+	if payloads, _ := p.ReadPayloads(); !slices.Equal(payloads, []string{"<b>"}) {
+		t.Errorf("payloads = %q, want only -payload", payloads)
+	}
+}
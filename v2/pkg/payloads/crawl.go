@@ -0,0 +1,86 @@
+package payloads
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/crawl"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/openapi"
+	"golang.org/x/time/rate"
+)
+
+// Crawl crawls from each start URL read from r, --crawl-depth links deep, and
+// returns the links and forms found taking parameters as endpoints for
+// ProcessEndpoints
+func (p *PayloadParser) Crawl(ctx context.Context, limiter *rate.Limiter, r io.Reader) ([]openapi.Endpoint, error) {
+	timeout := p.args.HTTPTimeout
+	if timeout <= 0 {
+		timeout = browser.DefaultRequestTimeout
+	}
+	crawler := &crawl.Crawler{
+		Client:    &http.Client{Transport: p.Transport, Jar: p.Jar, Timeout: timeout},
+		MaxDepth:  p.args.CrawlDepth,
+		Robots:    p.args.CrawlRobots,
+		Scope:     p.args.Scope,
+		Limiter:   limiter,
+		UserAgent: p.args.UserAgent,
+	}
+
+	var endpoints []openapi.Endpoint
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		link := strings.TrimSpace(scanner.Text())
+		if link == "" || strings.HasPrefix(link, "#") {
+			continue
+		}
+		link = p.resolveScheme(ctx, link)
+		if !p.args.Scope.AllowsURL(link) {
+			logger.Notice("Skipping out-of-scope URL: " + link)
+			continue
+		}
+
+		logger.Info("Crawling " + link)
+		targets, err := crawler.Crawl(ctx, link)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Error crawling " + link + ": " + err.Error())
+		}
+		logger.Info(fmt.Sprintf("Found %d injection points on %s", len(targets), link))
+		for _, target := range targets {
+			endpoints = append(endpoints, crawlEndpoint(target))
+		}
+		if ctx.Err() != nil {
+			return endpoints, ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return endpoints, fmt.Errorf("failed to read targets: %w", err)
+	}
+	return endpoints, nil
+}
+
+// crawlEndpoint describes a crawled link or form as an endpoint to scan
+func crawlEndpoint(target crawl.Target) openapi.Endpoint {
+	endpoint := openapi.Endpoint{
+		Method:    target.Method,
+		URL:       target.URL,
+		Body:      target.Body,
+		Multipart: target.Multipart,
+	}
+	if u, err := url.Parse(target.URL); err == nil {
+		endpoint.Path = u.Path
+		for name := range u.Query() {
+			endpoint.Query = append(endpoint.Query, name)
+		}
+		sort.Strings(endpoint.Query)
+	}
+	return endpoint
+}
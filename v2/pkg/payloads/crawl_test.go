@@ -0,0 +1,62 @@
+package payloads
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"golang.org/x/time/rate"
+)
+
+func TestCrawledFormsScanned(t *testing.T) {
+	const payload = "<b>"
+
+	var mu sync.Mutex
+	injected := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/search?q=shoes">Search</a>
+<form action="/comment" method="post"><input name="author"><textarea name="body"></textarea></form>`))
+			return
+		case "/search":
+			w.Header().Set("Content-Type", "text/html")
+		}
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		for name, values := range r.Form {
+			if values[0] == payload {
+				injected[r.Method+" "+r.URL.Path+" "+name] = true
+			}
+		}
+	}))
+	defer server.Close()
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Concurrency: 2, CrawlDepth: 1, WorkerPool: 1})
+	p.Engine = &browser.FakeEngine{}
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	endpoints, err := p.Crawl(context.Background(), limiter, strings.NewReader(server.URL+"/\n"))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(endpoints) != 2 || endpoints[0].Path != "/comment" || !reflect.DeepEqual(endpoints[1].Query, []string{"q"}) {
+		t.Fatalf("endpoints = %+v, want the comment form and the search link", endpoints)
+	}
+
+	if err := p.ProcessEndpoints(context.Background(), limiter, endpoints, []string{payload}, nil); err != nil {
+		t.Fatalf("ProcessEndpoints: %v", err)
+	}
+	for _, target := range []string{"POST /comment author", "POST /comment body", "GET /search q"} {
+		if !injected[target] {
+			t.Errorf("nothing injected into %s, got %v", target, injected)
+		}
+	}
+}
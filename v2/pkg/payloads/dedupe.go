@@ -0,0 +1,78 @@
+package payloads
+
+import (
+	"container/list"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// dedupeSize bounds how many canonical URLs are remembered when deduplicating
+// targets, the least recently seen are forgotten first
+const dedupeSize = 100000
+
+// Canonicalize returns the form of link used to spot duplicate targets: the
+// scheme and host lowercased, default ports dropped, the path cleaned of dot
+// segments and trailing slashes, the query parameters sorted and the fragment
+// removed. Links that can't be parsed are returned unchanged.
+func Canonicalize(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	p = path.Clean(p)
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		u.Path, u.RawPath = unescaped, p
+	}
+
+	u.RawQuery = u.Query().Encode()
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+// seenURLs is a bounded, least recently used set of canonical URLs
+type seenURLs struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newSeenURLs(size int) *seenURLs {
+	return &seenURLs{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Add records key and reports whether it was already present
+func (s *seenURLs) Add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+
+	s.items[key] = s.order.PushFront(key)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(string))
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package payloads
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestCanonicalize(t *testing.T) {
+	for _, tt := range []struct{ a, b string }{
+		{"https://target.example/a?x=1&y=2", "https://target.example/a?y=2&x=1"},
+		{"https://Target.Example:443/a/", "https://target.example/a"},
+		{"https://target.example/b/../a#top", "https://target.example/a"},
+		{"http://target.example:80", "http://target.example/"},
+	} {
+		if Canonicalize(tt.a) != Canonicalize(tt.b) {
+			t.Errorf("%s and %s canonicalize to %s and %s", tt.a, tt.b, Canonicalize(tt.a), Canonicalize(tt.b))
+		}
+	}
+	for _, tt := range []struct{ a, b string }{
+		{"https://target.example/a?x=1", "https://target.example/a?x=2"},
+		{"http://target.example/a", "https://target.example/a"},
+		{"https://target.example/A", "https://target.example/a"},
+		{"https://target.example:8443/a", "https://target.example/a"},
+	} {
+		if Canonicalize(tt.a) == Canonicalize(tt.b) {
+			t.Errorf("%s and %s both canonicalize to %s", tt.a, tt.b, Canonicalize(tt.a))
+		}
+	}
+}
+
+func TestSeenURLsForgetsLeastRecent(t *testing.T) {
+	seen := newSeenURLs(2)
+	if seen.Add("a") || seen.Add("b") || !seen.Add("a") {
+		t.Fatal("first sightings reported as seen, or a repeat as new")
+	}
+	seen.Add("c") // evicts b, a was seen more recently
+	if !seen.Add("a") {
+		t.Error("recently seen a was forgotten")
+	}
+	if seen.Add("b") {
+		t.Error("b is still remembered past the bound")
+	}
+	if len(seen.items) != 2 || seen.order.Len() != 2 {
+		t.Errorf("%d items remembered, want the bound of 2", len(seen.items))
+	}
+}
+
+func TestProcessLinksDedupes(t *testing.T) {
+	for _, tt := range []struct {
+		noDedupe bool
+		want     int
+	}{
+		{false, 1},
+		{true, 2},
+	} {
+		server := newConcurrencyServer(t, 0)
+		input := server.URL + "/a?x=1&y=2\n" + server.URL + "/a?y=2&x=1\n"
+
+		p := streamParser(1)
+		p.args.NoDedupe = tt.noDedupe
+		if err := p.ProcessLinks(context.Background(), rate.NewLimiter(rate.Inf, 1), strings.NewReader(input), []string{"<b>"}, nil); err != nil {
+			t.Fatalf("ProcessLinks: %v", err)
+		}
+		if n := server.paths["/a"]; n != tt.want {
+			t.Errorf("-no-dedupe %v: %d scans, want %d", tt.noDedupe, n, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package payloads
+
+import (
+	"encoding/base64"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scan"
+)
+
+// Variant is a payload to scan together with the encodings applied to produce it
+type Variant struct {
+	Payload   string
+	Encodings []string
+}
+
+// encoders maps each --encode name to its transform
+var encoders = map[string]func(string) string{
+	"url": url.QueryEscape,
+	"double-url": func(s string) string {
+		return url.QueryEscape(url.QueryEscape(s))
+	},
+	"html":   html.EscapeString,
+	"base64": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+}
+
+// Encode applies the named encodings to payload in order. The {{param}},
+// {{token}} and {{callback}} placeholders are kept intact so the scanner can
+// still fill them in.
+func Encode(payload string, encodings []string) string {
+	placeholders := scan.InjectionPlaceholders.FindAllString(payload, -1)
+	parts := scan.InjectionPlaceholders.Split(payload, -1)
+
+	var encoded strings.Builder
+	for i, part := range parts {
+		for _, name := range encodings {
+			if encode, ok := encoders[name]; ok {
+				part = encode(part)
+			}
+		}
+		encoded.WriteString(part)
+		if i < len(placeholders) {
+			encoded.WriteString(placeholders[i])
+		}
+	}
+	return encoded.String()
+}
+
+// Variants returns payload followed by one variant per step of the encoding
+// chain, e.g. url,base64 yields the raw payload, its URL encoding and the
+// base64 of that URL encoding
+func Variants(payload string, encodings []string) []Variant {
+	variants := []Variant{{Payload: payload}}
+	for i := range encodings {
+		applied := encodings[:i+1]
+		variants = append(variants, Variant{
+			Payload:   Encode(payload, applied),
+			Encodings: applied,
+		})
+	}
+	return variants
+}
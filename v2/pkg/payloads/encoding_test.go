@@ -0,0 +1,69 @@
+package payloads
+
+import (
+	"encoding/base64"
+	"html"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	const payload = "<script>alert(1)</script>"
+	decoders := map[string]func(string) (string, error){
+		"url": url.QueryUnescape,
+		"double-url": func(s string) (string, error) {
+			once, err := url.QueryUnescape(s)
+			if err != nil {
+				return "", err
+			}
+			return url.QueryUnescape(once)
+		},
+		"html": func(s string) (string, error) { return html.UnescapeString(s), nil },
+		"base64": func(s string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(s)
+			return string(data), err
+		},
+	}
+
+	for name, decode := range decoders {
+		encoded := Encode(payload, []string{name})
+		if encoded == payload {
+			t.Errorf("%s left %q unchanged", name, payload)
+		}
+		decoded, err := decode(encoded)
+		if err != nil || decoded != payload {
+			t.Errorf("%s: %q decodes to %q, %v", name, encoded, decoded, err)
+		}
+	}
+	if got := Encode(payload, []string{"double-url"}); got != "%253Cscript%253Ealert%25281%2529%253C%252Fscript%253E" {
+		t.Errorf("double-url = %q", got)
+	}
+}
+
+func TestEncodeKeepsPlaceholders(t *testing.T) {
+	got := Encode("<img src=//cb/{{token}} id={{param}}>", []string{"url"})
+	if want := "%3Cimg+src%3D%2F%2Fcb%2F{{token}}+id%3D{{param}}%3E"; got != want {
+		t.Errorf("Encode = %q, want %q", got, want)
+	}
+}
+
+func TestVariantsFollowTheChain(t *testing.T) {
+	variants := Variants("<b>", []string{"url", "base64"})
+	if len(variants) != 3 {
+		t.Fatalf("%d variants, want the payload and one per encoding", len(variants))
+	}
+	want := []struct {
+		payload   string
+		encodings []string
+	}{
+		{"<b>", nil},
+		{"%3Cb%3E", []string{"url"}},
+		{base64.StdEncoding.EncodeToString([]byte("%3Cb%3E")), []string{"url", "base64"}},
+	}
+	for i, w := range want {
+		if variants[i].Payload != w.payload || !slices.Equal(variants[i].Encodings, w.encodings) {
+			t.Errorf("variant %d = %+v, want %s %v", i, variants[i], w.payload, w.encodings)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package payloads
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/openapi"
+	"golang.org/x/time/rate"
+)
+
+// ProcessEndpoints scans each endpoint of an OpenAPI document with its own
+// method, injecting into its query, path, header and cookie parameters and
+// its body fields, on a pool of --concurrency workers sharing limiter. The
+// headers given are tested on every endpoint as well.
+//
+// It returns once every endpoint has been scanned, or when ctx is cancelled.
+func (p *PayloadParser) ProcessEndpoints(ctx context.Context, limiter *rate.Limiter, endpoints []openapi.Endpoint, payloads []string, headers []string) error {
+	workers := p.args.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan openapi.Endpoint)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for endpoint := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				p.scanEndpoint(ctx, limiter, endpoint, payloads, headers)
+				if p.Progress != nil {
+					p.Progress.Done()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, endpoint := range endpoints {
+		if p.Progress != nil {
+			p.Progress.Queued()
+		}
+		select {
+		case queue <- endpoint:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// scanEndpoint scans one endpoint, configured from the document rather than
+// the -X, -t, -path-inject, -data and -multipart flags
+func (p *PayloadParser) scanEndpoint(ctx context.Context, limiter *rate.Limiter, endpoint openapi.Endpoint, payloads []string, headers []string) {
+	logger.Notice("Endpoint: " + endpoint.Method + " " + endpoint.Path)
+
+	config := p.scannerConfig(ctx)
+	config.Method = endpoint.Method
+	config.IsParameters = len(endpoint.Query) > 0
+	config.PathInject = len(endpoint.PathParams) > 0
+	config.Data = endpoint.Body
+	config.Multipart = endpoint.Multipart
+	config.CookieParams = append(config.CookieParams[:len(config.CookieParams):len(config.CookieParams)], endpoint.Cookies...)
+
+	// Scan without a header under test as well as with each header parameter
+	tested := append([]string{""}, headers...)
+	tested = append(tested, endpoint.Headers...)
+
+	p.scanLink(ctx, limiter, endpoint.URL, payloads, tested, config)
+}
@@ -0,0 +1,84 @@
+package payloads
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/openapi"
+	"golang.org/x/time/rate"
+)
+
+func TestProcessEndpointsInjectsEachPoint(t *testing.T) {
+	const payload = "<b>"
+
+	var mu sync.Mutex
+	injected := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(r.URL.EscapedPath(), "%3Cb%3E") {
+			injected[r.Method+" path"] = true
+		}
+		if r.URL.Query().Get("q") == payload {
+			injected[r.Method+" query q"] = true
+		}
+		if r.Header.Get("X-Trace") == payload {
+			injected[r.Method+" header X-Trace"] = true
+		}
+		if strings.Contains(r.Header.Get("Cookie"), "session="+payload) {
+			injected[r.Method+" cookie session"] = true
+		}
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) == nil && fields["text"] == payload {
+			injected[r.Method+" body text"] = true
+		}
+	}))
+	defer server.Close()
+
+	spec, err := openapi.Parse([]byte(`{
+  "openapi": "3.0.0",
+  "paths": {
+    "/users/{id}": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "path", "schema": {"type": "integer"}},
+          {"name": "q", "in": "query", "schema": {"type": "string"}},
+          {"name": "X-Trace", "in": "header", "schema": {"type": "string"}},
+          {"name": "session", "in": "cookie", "schema": {"type": "string"}}
+        ]
+      }
+    },
+    "/comments": {
+      "post": {"requestBody": {"content": {"application/json": {"schema": {"type": "object", "properties": {"text": {"type": "string"}}}}}}}
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	endpoints, err := spec.Endpoints(server.URL)
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Concurrency: 2, WorkerPool: 1})
+	p.Engine = &browser.FakeEngine{}
+	if err := p.ProcessEndpoints(context.Background(), rate.NewLimiter(rate.Inf, 1), endpoints, []string{payload}, nil); err != nil {
+		t.Fatalf("ProcessEndpoints: %v", err)
+	}
+
+	for _, point := range []string{"GET path", "GET query q", "GET header X-Trace", "GET cookie session", "POST body text"} {
+		if !injected[point] {
+			t.Errorf("nothing injected into the %s, got %v", point, injected)
+		}
+	}
+}
@@ -4,33 +4,146 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
-	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/checkpoint"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/mutate"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/progress"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/scan"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/useragent"
 	"golang.org/x/time/rate"
 )
 
 type PayloadParser struct {
 	args *arguments.Arguments
+
+	// Tokens records every injection whose {{token}} placeholder the scanner
+	// filled in, so a token seen in a callback resolves to the injection that fired
+	Tokens *callback.Index
+
+	// Callbacks is the optional callback listener correlating hits against Tokens
+	Callbacks *callback.Server
+
+	// Collaborator, when set, supplies a unique {{callback}} URL for each injection
+	Collaborator *callback.Interactsh
+
+	// Report, when set, receives a finding for every injection made
+	Report report.Writer
+
+	// Checkpoint, when set, records finished scans so an interrupted run can resume
+	Checkpoint *checkpoint.Log
+
+	// HostLimiter, when set, rate limits each host separately
+	HostLimiter *ratelimit.HostLimiter
+
+	// Adaptive, when set, slows the global limiter down on 429/503 responses
+	Adaptive *ratelimit.Adaptive
+
+	// Progress, when set, counts the URLs and payloads scanned
+	Progress *progress.Reporter
+
+	// Budget, when set, caps the number of requests sent
+	Budget *ratelimit.Budget
+
+	// Transport, when set, sends every HTTP probe so connections and TLS settings are shared
+	Transport http.RoundTripper
+
+	// Unreachable, when set, tracks the hosts that can't be reached so the
+	// rest of their scans are skipped
+	Unreachable *transport.Unreachable
+
+	// Jar, when set, carries the cookies responses set across every HTTP probe
+	Jar http.CookieJar
+
+	// BrowserCookies are set in the browser only, the probes get them from Jar
+	BrowserCookies []*http.Cookie
+
+	// UserAgents, when set, rotates the User-Agent of each request
+	UserAgents *useragent.Pool
+
+	// Engine, when set, creates the browser contexts in place of the
+	// configured browser, e.g. a browser.FakeEngine in tests
+	Engine browser.Engine
+
+	// Sources maps each payload read by ReadLinesFromFile to the file it came
+	// from, which findings are tagged with
+	Sources map[string]string
+
+	// warned holds the unknown placeholders already reported
+	warned sync.Map
+
+	// schemes holds the scheme probed for each schemeless host
+	schemes sync.Map
+}
+
+// placeholderPattern matches {{name}} template placeholders in payloads
+var placeholderPattern = regexp.MustCompile(`\{\{[A-Za-z0-9_]+\}\}`)
+
+// knownPlaceholders are expanded by the payload parser or the scanner
+var knownPlaceholders = map[string]bool{
+	"{{callback}}":            true,
+	"{{url}}":                 true,
+	scan.ParamPlaceholder:     true,
+	callback.TokenPlaceholder: true,
 }
 
 func NewPayload(args *arguments.Arguments) *PayloadParser {
 	return &PayloadParser{
-		args: args,
+		args:   args,
+		Tokens: callback.NewIndex(),
 	}
 }
 
-// readLinesFromFile reads a file line by line and returns the lines as a slice of strings.
-//
-// The lines are trimmed of whitespace. If there is an error reading the file,
-// that error is returned. Otherwise, the function returns a slice of strings
-// and a nil error.
+// ReadLinesFromFile reads every payload file with ReadLines and merges them in
+// order. Each payload is tagged in Sources with the name of the file it was
+// first read from, and (unless --keep-duplicates is set) payloads repeated
+// across files are only kept once.
 func (p *PayloadParser) ReadLinesFromFile() ([]string, error) {
-	file, err := os.Open(p.args.PayloadFile)
+	if p.Sources == nil {
+		p.Sources = make(map[string]string)
+	}
+
+	var merged []string
+	for _, path := range p.args.PayloadFiles {
+		lines, err := p.ReadLines(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if _, seen := p.Sources[line]; !seen {
+				p.Sources[line] = filepath.Base(path)
+			} else if !p.args.KeepDuplicates {
+				continue
+			}
+			merged = append(merged, line)
+		}
+	}
+	return merged, nil
+}
+
+// ReadLines reads a file line by line and returns the lines as a slice of strings.
+//
+// The lines are trimmed of whitespace, and blank lines, lines starting with #
+// and (unless --keep-duplicates is set) repeated lines are dropped while the
+// order is preserved. If there is an error reading the file, that error is
+// returned. Otherwise, the function returns a slice of strings and a nil error.
+func (p *PayloadParser) ReadLines(path string) ([]string, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -39,14 +152,90 @@ func (p *PayloadParser) ReadLinesFromFile() ([]string, error) {
 	var lines []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		lines = append(lines, strings.TrimSpace(scanner.Text()))
+		lines = append(lines, scanner.Text())
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return lines, nil
+	return normalizeLines(lines, p.args.KeepDuplicates), nil
+}
+
+// ReadPayloads returns the payloads to scan with: those of the payload files,
+// or the one given by -payload, followed by the built-in payloads of the
+// selected reflection contexts. With none of these, every built-in payload is
+// used, so a payload file is never required.
+func (p *PayloadParser) ReadPayloads() ([]string, error) {
+	var payloads []string
+	if len(p.args.PayloadFiles) > 0 {
+		var err error
+		payloads, err = p.ReadLinesFromFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload file: %w", err)
+		}
+	} else if p.args.Payload != "" {
+		payloads = []string{p.args.Payload}
+	}
+
+	// Add the built-in payloads for the selected reflection contexts
+	if len(p.args.Contexts) > 0 {
+		builtin, err := p.BuiltinPayloads(p.args.Contexts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load built-in payloads: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Using %d built-in payloads for contexts: %s", len(builtin), strings.Join(p.args.Contexts, ", ")))
+		payloads = append(payloads, builtin...)
+	}
+
+	// Fall back to every built-in payload when none were given
+	if len(payloads) == 0 {
+		builtin, err := p.BuiltinPayloads(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load built-in payloads: %w", err)
+		}
+		logger.Info(fmt.Sprintf("No payloads given, using the %d built-in payloads (-list-payloads shows them)", len(builtin)))
+		payloads = builtin
+	}
+	return payloads, nil
+}
+
+// ReadHeaders returns the headers to test: those of the -hf file followed by
+// the one given by -H, unless the file already lists it
+func (p *PayloadParser) ReadHeaders() ([]string, error) {
+	var headers []string
+	if p.args.HeaderFile != "" {
+		var err error
+		headers, err = p.ReadLines(p.args.HeaderFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.args.Header != "" && !slices.Contains(headers, p.args.Header) {
+		headers = append(headers, p.args.Header)
+	}
+	return headers, nil
+}
+
+// normalizeLines trims lines, drops blank and # comment lines and removes
+// repeats, keeping the first occurrence, unless keepDuplicates is set
+func normalizeLines(lines []string, keepDuplicates bool) []string {
+	seen := make(map[string]bool, len(lines))
+	normalized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !keepDuplicates {
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+		}
+		normalized = append(normalized, line)
+	}
+	return normalized
 }
 
 // processPayloadsAndHeaders reads lines from standard input, and for each line,
@@ -58,10 +247,132 @@ func (p *PayloadParser) ReadLinesFromFile() ([]string, error) {
 // with a timeout and a redirect policy.
 //
 // If there is an error reading the input, that error is printed to standard
-// error. Otherwise, the function prints nothing and returns no value.
-func (p *PayloadParser) ProcessPayloadsAndHeaders(limiter *rate.Limiter, link string, payloads []string, headers []string) {
-	config := &scan.ScannerConfig{
+// error. Otherwise, the function prints nothing and returns no value. Cancelling
+// ctx stops the scan promptly, aborting in-flight requests.
+func (p *PayloadParser) ProcessPayloadsAndHeaders(ctx context.Context, limiter *rate.Limiter, link string, payloads []string, headers []string) {
+	p.scanLink(ctx, limiter, link, payloads, headers, p.scannerConfig(ctx))
+}
+
+// scanLink implements ProcessPayloadsAndHeaders with the given configuration
+func (p *PayloadParser) scanLink(ctx context.Context, limiter *rate.Limiter, link string, payloads []string, headers []string, config *scan.ScannerConfig) {
+	// Check the scope before anything, including a scheme probe, is sent
+	if !p.args.Scope.AllowsURL(p.EnsureProtocol(link)) {
+		logger.Notice("Skipping out-of-scope URL: " + strings.TrimSpace(link))
+		return
+	}
+	link = p.resolveScheme(ctx, link)
+
+	// Cap the payloads each parameter is tested with, sampling the same ones for
+	// the URL on every run so the resume file still matches
+	if max := p.args.MaxPayloads; max > 0 && len(payloads) > max {
+		logger.Notice(fmt.Sprintf("Sampling %d of %d payloads for %s (-max-payloads-per-param)", max, len(payloads), link))
+		payloads = scan.Sample(payloads, max, link)
+	}
+
+	// An empty header stands for testing the payload without one
+	if len(headers) == 0 {
+		headers = []string{""}
+	}
+
+	// Skip links a previous run already finished without starting a browser
+	if p.resumed(link, payloads, headers) {
+		logger.Notice("Skipping already scanned URL: " + link)
+		return
+	}
+
+	newScanner := scan.NewScanner(limiter, config)
+	defer newScanner.Close()
+	logger.Notice("Checking URL Scheme: " + link)
+	logger.Println("")
+
+	// The payload and header pairs are scanned by as many workers as the pool
+	// has browsers, each pair by a scanner attributing findings to the payload's
+	// file; Scan waits on the shared limiter so the rate holds. A dry run keeps
+	// to one worker to print the requests in order.
+	workers := p.args.WorkerPool
+	if workers <= 0 {
+		workers = 2
+	}
+	if p.args.DryRun {
+		workers = 1
+	}
+
+	type pair struct{ raw, header string }
+
+	// resume skips, and records, the payload and header pairs in the resume file
+	scanAll := func(resume bool) {
+		queue := make(chan pair)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range queue {
+					if ctx.Err() != nil {
+						continue
+					}
+					scanner := newScanner.WithSource(p.Sources[job.raw])
+					payload := p.expandTemplate(link, job.raw)
+					for _, mutated := range mutate.Apply(payload, p.args.Mutators) {
+						p.scanVariants(scanner, link, mutated, job.header)
+					}
+
+					// An interrupted scan may not have finished the pair, and a dry
+					// run sent nothing, so leave it for the next run
+					if resume && p.Checkpoint != nil && ctx.Err() == nil && !p.args.DryRun {
+						if err := p.Checkpoint.Mark(link, job.raw, job.header); err != nil {
+							logger.Error(err.Error())
+						}
+					}
+				}
+			}()
+		}
+
+	feed:
+		for _, raw := range payloads {
+			for _, header := range headers {
+				if resume && p.Checkpoint != nil && p.Checkpoint.Done(link, raw, header) {
+					continue
+				}
+				select {
+				case queue <- pair{raw, header}:
+				case <-ctx.Done():
+					break feed
+				}
+			}
+		}
+		close(queue)
+		wg.Wait()
+	}
+	scanAll(true)
+
+	// With every parameter injected in one request a dialog can't tell which
+	// parameter fired, nor can a callback from a payload without a token, so
+	// re-test the parameters one at a time on URLs that fired
+	if config.IsParameters && config.InjectAll && ctx.Err() == nil && p.fired(newScanner, link) {
+		logger.Notice("Payload fired, re-testing parameters one at a time: " + link)
+		newScanner.Config.InjectAll = false
+		scanAll(false)
+	}
+
+}
+
+// poolTimeout converts a -acquire-timeout or -release-timeout, 0 waiting
+// indefinitely, to a ScannerConfig one, where 0 keeps the pool's default
+func poolTimeout(timeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return -1
+	}
+	return timeout
+}
+
+// scannerConfig returns the scanner configuration for the arguments and the
+// shared state of the parser, cancelled with ctx
+func (p *PayloadParser) scannerConfig(ctx context.Context) *scan.ScannerConfig {
+	return &scan.ScannerConfig{
 		AppendMode:      p.args.AppendMode,
+		InjectMode:      p.args.InjectMode,
+		MaxRedirects:    p.args.RedirectLimit,
 		IsParameters:    p.args.Parameters,
 		RateLimit:       p.args.RateLimit,
 		Method:          p.args.Method,
@@ -72,32 +383,152 @@ func (p *PayloadParser) ProcessPayloadsAndHeaders(limiter *rate.Limiter, link st
 		BrowserPath:     p.args.BrowserPath,
 		WorkerPool:      p.args.WorkerPool,
 		RequestFile:     p.args.RequestFile,
+		BrowserTimeout:  p.args.BrowserTimeout,
+		Proxy:           p.args.Proxy,
+		Headed:          p.args.Headed,
+		ScreenshotDir:   p.args.ScreenshotDir,
+		Cookies:         p.args.Cookies,
+		Headers:         p.args.GlobalHeaders,
+		Scope:           p.args.Scope,
+		RemoteBrowser:   p.args.RemoteBrowser,
+		ChromeFlags:     p.args.ChromeFlags,
+		DisableWebSec:   p.args.DisableWebSec,
+		UserAgent:       p.args.UserAgent,
+		WindowWidth:     p.args.WindowWidth,
+		WindowHeight:    p.args.WindowHeight,
+		WorkerLifetime:  p.args.WorkerLifetime,
+		LazyWorkers:     p.args.LazyWorkers,
+		NoSandbox:       p.args.NoSandbox,
+		HookEval:        p.args.HookEval,
+		WaitIdle:        p.args.WaitIdle,
+		PathInject:      p.args.PathInject,
+		Data:            p.args.Data,
+		CookieParams:    p.args.CookieParams,
+		InjectAll:       p.args.InjectAll,
+		Retries:         p.args.Retries,
+		RetryBackoff:    p.args.RetryBackoff,
+		DryRun:          p.args.DryRun,
+		AcquireTimeout:  poolTimeout(p.args.AcquireTimeout),
+		ReleaseTimeout:  poolTimeout(p.args.ReleaseTimeout),
+		ReflectCheck:    p.args.ReflectCheck,
+		ReflectOnly:     p.args.ReflectOnly,
+		Fragment:        p.args.Fragment,
+		GraphQL:         p.args.GraphQL,
+		Multipart:       p.args.Multipart,
+		MultipartFile:   p.args.MultipartFile,
+		WebSocket:       p.args.WebSocket,
+		Filter:          p.args.Filter,
+		HTTPTimeout:     p.args.HTTPTimeout,
+		MaxParams:       p.args.MaxParams,
+		RedactHeaders:   p.args.RedactHeaders,
+		JSONPaths:       p.args.JSONPaths,
+		Jitter:          p.args.Jitter,
+		UserAgents:      p.UserAgents,
+		Engine:          p.Engine,
+		HostLimiter:     p.HostLimiter,
+		Adaptive:        p.Adaptive,
+		Progress:        p.Progress,
+		Budget:          p.Budget,
+		Transport:       p.Transport,
+		Jar:             p.Jar,
+		Unreachable:     p.Unreachable,
+		BrowserCookies:  p.BrowserCookies,
+		Report:          p.Report,
+		Tokens:          p.Tokens,
+		Collaborator:    p.Collaborator,
+		ViewURLs:        p.args.ViewURLs,
+		Context:         ctx,
 	}
-	newScanner := scan.NewScanner(limiter, config)
-	link = p.EnsureProtocol(link)
-	fmt.Printf(colours.NoticeColor, "Checking URL Scheme: "+link)
-	fmt.Println("")
-	if len(headers) == 0 {
-		for _, payload := range payloads {
-			newScanner.Scan(link, payload, "")
+}
+
+// resumed reports whether every payload and header pair for link is recorded
+// as done in the resume file
+func (p *PayloadParser) resumed(link string, payloads []string, headers []string) bool {
+	if p.Checkpoint == nil {
+		return false
+	}
+	for _, payload := range payloads {
+		for _, header := range headers {
+			if !p.Checkpoint.Done(link, payload, header) {
+				return false
+			}
 		}
-	} else {
-		for _, payload := range payloads {
-			for _, header := range headers {
-				newScanner.Scan(link, payload, header)
+	}
+	return true
+}
+
+// fired reports whether scanning link confirmed a payload in the browser or
+// has already produced a callback, on the listener or the collaborator
+func (p *PayloadParser) fired(scanner *scan.Scanner, link string) bool {
+	if scanner.Confirmed() > 0 {
+		return true
+	}
+	var hits []callback.Hit
+	if p.Callbacks != nil {
+		hits = append(hits, p.Callbacks.Hits()...)
+	}
+	if p.Collaborator != nil {
+		hits = append(hits, p.Collaborator.Hits()...)
+	}
+	for _, hit := range hits {
+		if hit.Injection != nil && hit.Injection.URL == link {
+			return true
+		}
+	}
+	return false
+}
+
+// scanVariants scans the payload and each of its --encode variants
+func (p *PayloadParser) scanVariants(scanner *scan.Scanner, link string, payload string, header string) {
+	for _, variant := range Variants(payload, p.args.Encodings) {
+		if len(variant.Encodings) == 0 {
+			scanner.Scan(link, variant.Payload, header)
+			continue
+		}
+		logger.Notice("Encoding: " + strings.Join(variant.Encodings, " -> "))
+		scanner.WithEncodings(variant.Encodings).Scan(link, variant.Payload, header)
+	}
+}
+
+// expandTemplate fills in the {{callback}} and {{url}} placeholders of payload.
+// {{param}} is left for the scanner, which knows the parameter or header being
+// fuzzed, and {{token}}, which the scanner fills in per injection. Unknown
+// placeholders are left untouched with a warning.
+func (p *PayloadParser) expandTemplate(link string, payload string) string {
+	for _, placeholder := range placeholderPattern.FindAllString(payload, -1) {
+		if knownPlaceholders[placeholder] {
+			continue
+		}
+		if _, seen := p.warned.LoadOrStore(placeholder, true); !seen {
+			logger.Warn("Unknown payload placeholder " + placeholder + " left as is")
+		}
+	}
+
+	// The scanner fills in {{callback}} per injection from the collaborator
+	if strings.Contains(payload, "{{callback}}") && p.Collaborator == nil {
+		if p.args.CallbackURL == "" {
+			if _, seen := p.warned.LoadOrStore("{{callback}}", true); !seen {
+				logger.Warn("Payload uses {{callback}} but no -callback-url was given")
 			}
+		} else {
+			payload = strings.ReplaceAll(payload, "{{callback}}", p.args.CallbackURL)
 		}
 	}
 
+	return strings.ReplaceAll(payload, "{{url}}", link)
 }
 
 // EnsureProtocol verifies that the provided link has a protocol prefix.
-// If the link does not start with "http://" or "https://", it prepends "https://" to the link.
+// If the link does not start with "http://" or "https://", it prepends the
+// --default-scheme to the link, https unless http is configured.
 // The function trims any leading or trailing whitespace from the link before checking the protocol.
 // It returns the modified or unmodified link with the appropriate protocol.
 func (p *PayloadParser) EnsureProtocol(link string) string {
 	link = strings.TrimSpace(link)
-	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+	if !hasScheme(link) {
+		if p.args.DefaultScheme == SchemeHTTP {
+			return "http://" + link
+		}
 		return "https://" + link
 	}
 	return link
@@ -107,20 +538,28 @@ func (p *PayloadParser) EnsureProtocol(link string) string {
 type RequestParser struct {
 	args     *arguments.Arguments
 	filePath string
+
+	// Transport, when set, sends the custom requests
+	Transport http.RoundTripper
+
+	// Jar, when set, carries the cookies responses set across the custom requests
+	Jar http.CookieJar
 }
 
 // NewRequestParser creates a new request parser for custom requests
-func NewRequestParser(filePath string) *RequestParser {
+func NewRequestParser(args *arguments.Arguments, filePath string) *RequestParser {
 	return &RequestParser{
-		args:     nil, // Not needed for direct file processing
+		args:     args,
 		filePath: filePath,
 	}
 }
 
 // ProcessCustomRequests processes custom requests from a file
-func (p *RequestParser) ProcessCustomRequests(limiter *rate.Limiter, payloads []string) error {
+func (p *RequestParser) ProcessCustomRequests(ctx context.Context, limiter *rate.Limiter, payloads []string) error {
 	// Create the browser request parser
 	parser := browser.NewRequestParser(p.filePath)
+	parser.Transport = p.Transport
+	parser.Jar = p.Jar
 
 	// Create browser context for executing requests
 	browserType := "chrome" // Default fallback
@@ -128,28 +567,42 @@ func (p *RequestParser) ProcessCustomRequests(limiter *rate.Limiter, payloads []
 		browserType = p.args.BrowserType
 	}
 	b := browser.NewBrowser(browserType, "")
-	ctx, cancel, err := b.CreateContext(context.Background())
+	if p.args != nil {
+		b.UserAgent = p.args.UserAgent
+		parser.UserAgent = p.args.UserAgent
+		parser.BaseURL = p.args.BaseURL
+		parser.Concurrency = p.args.Concurrency
+		parser.Retries = p.args.Retries
+		parser.RetryBackoff = p.args.RetryBackoff
+		parser.Limiter = limiter
+		parser.Scope = p.args.Scope
+		parser.ExtraMethods = p.args.ExtraMethods
+		if p.args.HTTPTimeout > 0 {
+			parser.Timeout = p.args.HTTPTimeout
+		}
+	}
+	ctx, cancel, err := b.CreateContext(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create browser context: %w", err)
 	}
 	defer cancel()
 
 	// Execute the requests
-	fmt.Printf(colours.InfoColor, "Processing custom requests from file...")
-	responses, err := parser.ExecuteRequests(ctx)
+	logger.Info("Processing custom requests from file...")
+	var processed int64
+	err = parser.ExecuteRequestsFunc(ctx, func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		atomic.AddInt64(&processed, 1)
+	})
 	if err != nil {
 		return err
 	}
 
 	// Report on the responses
-	fmt.Printf(colours.InfoColor, fmt.Sprintf("Processed %d custom requests successfully", len(responses)))
-
-	// Clean up responses
-	for _, resp := range responses {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-	}
+	logger.Info(fmt.Sprintf("Processed %d custom requests successfully", processed))
 
 	return nil
 }
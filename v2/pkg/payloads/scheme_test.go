@@ -0,0 +1,103 @@
+package payloads
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+)
+
+// tlsOnlyListener hands out connections that completed a TLS handshake,
+// dropping plain HTTP ones without the 400 Go's HTTPS servers answer them with
+type tlsOnlyListener struct {
+	net.Listener
+	config *tls.Config
+}
+
+func (l tlsOnlyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Server(conn, l.config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			continue
+		}
+		return tlsConn, nil
+	}
+}
+
+// schemeHosts returns the addresses of an https-only, an http-only and an
+// https server that answers plain http too, and a transport trusting their
+// certificate
+func schemeHosts(t *testing.T) (httpsOnly, httpOnly, both string, transport http.RoundTripper) {
+	t.Helper()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsOnly := &http.Server{Handler: handler}
+	go tlsOnly.Serve(tlsOnlyListener{listener, tlsServer.TLS})
+	t.Cleanup(func() { tlsOnly.Close() })
+
+	plain := httptest.NewServer(handler)
+	t.Cleanup(plain.Close)
+
+	return listener.Addr().String(), strings.TrimPrefix(plain.URL, "http://"), strings.TrimPrefix(tlsServer.URL, "https://"), tlsServer.Client().Transport
+}
+
+func TestResolveSchemeAuto(t *testing.T) {
+	httpsOnly, httpOnly, both, transport := schemeHosts(t)
+	p := NewPayload(&arguments.Arguments{DefaultScheme: SchemeAuto})
+	p.Transport = transport
+
+	for host, want := range map[string]string{
+		httpsOnly: "https://" + httpsOnly + "/a?q=1",
+		httpOnly:  "http://" + httpOnly + "/a?q=1",
+		both:      "https://" + both + "/a?q=1",
+	} {
+		if got := p.resolveScheme(context.Background(), host+"/a?q=1"); got != want {
+			t.Errorf("resolveScheme(%s) = %s, want %s", host, got, want)
+		}
+	}
+
+	// The scheme found is remembered for the host's other links
+	p.Transport = nil
+	if got := p.resolveScheme(context.Background(), httpOnly+"/b"); got != "http://"+httpOnly+"/b" {
+		t.Errorf("second link of the http-only host resolved to %s", got)
+	}
+	if got := p.resolveScheme(context.Background(), "http://"+httpsOnly+"/c"); got != "http://"+httpsOnly+"/c" {
+		t.Errorf("link with a scheme rewritten to %s", got)
+	}
+}
+
+func TestResolveSchemeDefault(t *testing.T) {
+	for scheme, want := range map[string]string{
+		"":          "https://target.example/",
+		SchemeHTTPS: "https://target.example/",
+		SchemeHTTP:  "http://target.example/",
+	} {
+		p := NewPayload(&arguments.Arguments{DefaultScheme: scheme})
+		if got := p.resolveScheme(context.Background(), " target.example/ "); got != want {
+			t.Errorf("-default-scheme %q: %s, want %s", scheme, got, want)
+		}
+	}
+
+	// A dry run doesn't probe, it assumes https
+	p := NewPayload(&arguments.Arguments{DefaultScheme: SchemeAuto, DryRun: true})
+	if got := p.resolveScheme(context.Background(), "127.0.0.1:1/"); got != "https://127.0.0.1:1/" {
+		t.Errorf("dry run resolved to %s", got)
+	}
+}
@@ -0,0 +1,91 @@
+package payloads
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"golang.org/x/time/rate"
+)
+
+// maxLineSize bounds a single target line read from the input
+const maxLineSize = 1024 * 1024
+
+// ProcessLinks reads targets from r line by line and scans each one as soon as
+// it is read, on a pool of --concurrency workers sharing limiter. Blank lines,
+// lines starting with # and, unless --no-dedupe is set, targets that
+// canonicalize to one already read are skipped.
+//
+// It returns once every target read has been scanned, or when ctx is cancelled.
+func (p *PayloadParser) ProcessLinks(ctx context.Context, limiter *rate.Limiter, r io.Reader, payloads []string, headers []string) error {
+	workers := p.args.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Unbuffered, so reading never runs ahead of the workers
+	links := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range links {
+				if ctx.Err() != nil {
+					continue
+				}
+				p.ProcessPayloadsAndHeaders(ctx, limiter, link, payloads, headers)
+				if p.Progress != nil {
+					p.Progress.Done()
+				}
+			}
+		}()
+	}
+
+	err := p.feedLinks(ctx, r, links)
+	close(links)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// feedLinks sends every target line of r to links until r is exhausted or ctx is cancelled
+func (p *PayloadParser) feedLinks(ctx context.Context, r io.Reader, links chan<- string) error {
+	var seen *seenURLs
+	if !p.args.NoDedupe {
+		seen = newSeenURLs(dedupeSize)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		link := strings.TrimSpace(scanner.Text())
+		if link == "" || strings.HasPrefix(link, "#") {
+			continue
+		}
+		if seen != nil && seen.Add(Canonicalize(p.EnsureProtocol(link))) {
+			logger.Notice("Skipping duplicate URL: " + link)
+			continue
+		}
+		if p.Progress != nil {
+			p.Progress.Queued()
+		}
+		select {
+		case links <- link:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read targets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package payloads
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/arguments"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// concurrencyServer records the paths requested and the most requests it
+// served at once, each held for delay
+type concurrencyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	paths    map[string]int
+}
+
+func newConcurrencyServer(t *testing.T, delay time.Duration) *concurrencyServer {
+	t.Helper()
+	s := &concurrencyServer{paths: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.inFlight++
+		s.max = max(s.max, s.inFlight)
+		s.paths[r.URL.Path]++
+		s.mu.Unlock()
+
+		time.Sleep(delay)
+
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// streamParser returns a parser scanning with GET only on a fake browser
+func streamParser(concurrency int) *PayloadParser {
+	p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Concurrency: concurrency, WorkerPool: 1})
+	p.Engine = &browser.FakeEngine{}
+	return p
+}
+
+func TestProcessLinksBoundedAndComplete(t *testing.T) {
+	server := newConcurrencyServer(t, 2*time.Millisecond)
+
+	var input strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&input, "%s/%d\n", server.URL, i)
+	}
+	input.WriteString("\n# comment\n" + server.URL + "/0\n")
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if err := streamParser(8).ProcessLinks(context.Background(), limiter, strings.NewReader(input.String()), []string{"<b>"}, nil); err != nil {
+		t.Fatalf("ProcessLinks: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.max > 8 {
+		t.Errorf("%d requests in flight at once, want at most 8", server.max)
+	}
+	if server.max < 2 {
+		t.Errorf("at most %d request in flight, want the URLs scanned concurrently", server.max)
+	}
+	if len(server.paths) != 500 {
+		t.Errorf("%d URLs scanned, want 500", len(server.paths))
+	}
+	for path, n := range server.paths {
+		if n != 1 {
+			t.Errorf("%s scanned %d times", path, n)
+		}
+	}
+}
+
+func TestProcessLinksStartsBeforeInputEnds(t *testing.T) {
+	server := newConcurrencyServer(t, 0)
+	r, w := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- streamParser(2).ProcessLinks(context.Background(), rate.NewLimiter(rate.Inf, 1), r, []string{"<b>"}, nil)
+	}()
+
+	// The first URL is scanned while the input is still open
+	fmt.Fprintf(w, "%s/first\n", server.URL)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.mu.Lock()
+		scanned := server.paths["/first"]
+		server.mu.Unlock()
+		if scanned > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the first URL wasn't scanned before the input ended")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	fmt.Fprintf(w, "%s/second\n", server.URL)
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("ProcessLinks: %v", err)
+	}
+	if server.paths["/second"] != 1 {
+		t.Error("the URL written last wasn't scanned")
+	}
+}
+
+func TestProcessLinksSharesLimiter(t *testing.T) {
+	server := newConcurrencyServer(t, 0)
+	var input strings.Builder
+	for i := 0; i < 11; i++ {
+		fmt.Fprintf(&input, "%s/%d\n", server.URL, i)
+	}
+
+	// 11 URLs at 50/s take at least 10 intervals of 20ms, however many workers
+	start := time.Now()
+	if err := streamParser(4).ProcessLinks(context.Background(), rate.NewLimiter(50, 1), strings.NewReader(input.String()), []string{"<b>"}, nil); err != nil {
+		t.Fatalf("ProcessLinks: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("11 URLs scanned in %s, faster than the shared 50/s", elapsed)
+	}
+}
+
+func TestProcessLinksStopsAtBudget(t *testing.T) {
+	server := newConcurrencyServer(t, time.Millisecond)
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "%s/%d\n", server.URL, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := streamParser(4)
+	p.Budget = ratelimit.NewBudget(7, cancel)
+	err := p.ProcessLinks(ctx, rate.NewLimiter(rate.Inf, 1), strings.NewReader(input.String()), []string{"<b>"}, nil)
+	if err != context.Canceled {
+		t.Errorf("ProcessLinks = %v, want the scan cancelled", err)
+	}
+
+	count := func() int {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		n := 0
+		for _, hits := range server.paths {
+			n += hits
+		}
+		return n
+	}
+	if n := count(); n != 7 {
+		t.Errorf("%d requests sent, want exactly the budget of 7", n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := count(); n != 7 {
+		t.Errorf("%d requests sent after the scan stopped", n-7)
+	}
+}
+
+func TestProcessLinksBudgetCountsRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		budget int64
+		want   int32
+	}{
+		// The first URL's request is retried until the budget runs out
+		{1, 1},
+		// The first URL's request and its 3 retries, then the second URL's
+		// request and one retry
+		{6, 6},
+	} {
+		requests.Store(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewPayload(&arguments.Arguments{Method: http.MethodGet, Concurrency: 1, WorkerPool: 1, Retries: 3, RetryBackoff: time.Millisecond})
+		p.Engine = &browser.FakeEngine{}
+		p.Budget = ratelimit.NewBudget(tt.budget, cancel)
+		input := server.URL + "/a\n" + server.URL + "/b\n"
+		p.ProcessLinks(ctx, rate.NewLimiter(rate.Inf, 1), strings.NewReader(input), []string{"<b>"}, nil)
+		cancel()
+
+		if n := requests.Load(); n != tt.want {
+			t.Errorf("budget of %d: %d requests sent with 3 retries each, want %d", tt.budget, n, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval is how often the progress line is refreshed
+const DefaultInterval = 2 * time.Second
+
+// Reporter counts the work done by a scan and periodically prints a progress
+// line with an estimate of the time remaining. Its counters are safe to update
+// from any goroutine.
+type Reporter struct {
+	// Out receives the progress line, stderr so it stays out of piped findings
+	Out io.Writer
+
+	// Interval is how often the line is refreshed
+	Interval time.Duration
+
+	// Rate is the configured requests per second, 0 when unlimited, and is
+	// used for the estimate instead of the observed speed when set
+	Rate float64
+
+	queued    int64
+	done      int64
+	sent      int64
+	callbacks int64
+
+	start    time.Time
+	inPlace  bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// New creates a reporter printing to stderr, rewriting a single line when
+// stderr is a terminal
+func New(rate float64) *Reporter {
+	inPlace := false
+	if info, err := os.Stderr.Stat(); err == nil {
+		inPlace = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return &Reporter{
+		Out:      os.Stderr,
+		Interval: DefaultInterval,
+		Rate:     rate,
+		inPlace:  inPlace,
+	}
+}
+
+// Queued records a URL read from the input
+func (r *Reporter) Queued() { atomic.AddInt64(&r.queued, 1) }
+
+// Done records a URL whose scan finished
+func (r *Reporter) Done() { atomic.AddInt64(&r.done, 1) }
+
+// Sent records a request carrying a payload sent to a URL
+func (r *Reporter) Sent() { atomic.AddInt64(&r.sent, 1) }
+
+// Callback records a callback received by the listener
+func (r *Reporter) Callback() { atomic.AddInt64(&r.callbacks, 1) }
+
+// Start begins printing the progress line every Interval until Stop is called
+func (r *Reporter) Start() {
+	r.start = time.Now()
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.print()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop prints the final progress line and stops the reporter
+func (r *Reporter) Stop() {
+	if r.stop == nil {
+		return
+	}
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		<-r.stopped
+		r.print()
+		if r.inPlace {
+			fmt.Fprintln(r.Out)
+		}
+	})
+}
+
+// String formats the current counts and estimate as a single line
+func (r *Reporter) String() string {
+	queued := atomic.LoadInt64(&r.queued)
+	done := atomic.LoadInt64(&r.done)
+
+	line := fmt.Sprintf("[PROGRESS] URLs %d/%d, payloads sent %d, callbacks %d",
+		done, queued, atomic.LoadInt64(&r.sent), atomic.LoadInt64(&r.callbacks))
+	if eta, ok := r.ETA(time.Since(r.start)); ok {
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+	return line
+}
+
+// ETA estimates the time left to scan the URLs queued so far, given the time
+// elapsed since the scan started. Each remaining URL is assumed to need as many
+// requests as the finished ones did on average, sent at Rate when it is set or
+// otherwise at the speed observed so far. It reports false until a URL has
+// finished, as there is nothing to base the estimate on.
+func (r *Reporter) ETA(elapsed time.Duration) (time.Duration, bool) {
+	queued := atomic.LoadInt64(&r.queued)
+	done := atomic.LoadInt64(&r.done)
+	if done == 0 {
+		return 0, false
+	}
+
+	remaining := queued - done
+	if remaining <= 0 {
+		return 0, true
+	}
+
+	if r.Rate > 0 {
+		perURL := float64(atomic.LoadInt64(&r.sent)) / float64(done)
+		seconds := float64(remaining) * perURL / r.Rate
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+
+	return time.Duration(int64(elapsed) / done * remaining), true
+}
+
+// print writes the progress line, in place when writing to a terminal
+func (r *Reporter) print() {
+	if r.inPlace {
+		fmt.Fprint(r.Out, "\r\033[K"+r.String())
+		return
+	}
+	fmt.Fprintln(r.Out, r.String())
+}
@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reporter returns a reporter with the given counts
+func reporter(rate float64, queued, done, sent int) *Reporter {
+	r := &Reporter{Rate: rate}
+	for i := 0; i < queued; i++ {
+		r.Queued()
+	}
+	for i := 0; i < done; i++ {
+		r.Done()
+	}
+	for i := 0; i < sent; i++ {
+		r.Sent()
+	}
+	return r
+}
+
+func TestETA(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		rate         float64
+		queued, done int
+		sent         int
+		elapsed      time.Duration
+		want         time.Duration
+		ok           bool
+	}{
+		// 10 payloads per URL at 5/s is 2s per URL, for 6 URLs left
+		{"configured rate", 5, 10, 4, 40, time.Hour, 12 * time.Second, true},
+		// 4 URLs took 20s, so 5s each for the 6 left
+		{"observed speed", 0, 10, 4, 40, 20 * time.Second, 30 * time.Second, true},
+		{"nothing finished", 5, 10, 0, 3, time.Minute, 0, false},
+		{"all finished", 5, 10, 10, 100, time.Minute, 0, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reporter(tt.rate, tt.queued, tt.done, tt.sent)
+			got, ok := r.ETA(tt.elapsed)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ETA = %s, %v, want %s, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReporterLine(t *testing.T) {
+	r := reporter(5, 10, 4, 40)
+	r.Callback()
+	r.start = time.Now()
+
+	var out strings.Builder
+	r.Out = &out
+	r.print()
+	want := "[PROGRESS] URLs 4/10, payloads sent 40, callbacks 1, ETA 12s\n"
+	if out.String() != want {
+		t.Errorf("line = %q, want %q", out.String(), want)
+	}
+
+	// A terminal gets the line rewritten in place
+	out.Reset()
+	r.inPlace = true
+	r.print()
+	if !strings.HasPrefix(out.String(), "\r\033[K[PROGRESS]") || strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("in place line = %q", out.String())
+	}
+}
+
+func TestReporterStopPrintsFinalLine(t *testing.T) {
+	var out strings.Builder
+	r := reporter(0, 1, 1, 3)
+	r.Out = &out
+	r.Interval = time.Hour
+	r.Start()
+	r.Stop()
+	r.Stop()
+
+	if got := strings.Count(out.String(), "[PROGRESS]"); got != 1 {
+		t.Errorf("%d lines printed, want the final one once:\n%s", got, out.String())
+	}
+	if !strings.Contains(out.String(), "URLs 1/1, payloads sent 3") {
+		t.Errorf("final line = %q", out.String())
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Adaptive tuning parameters
+const (
+	// DefaultCeiling is the rate adaptive limiting ramps up to when no limit is set
+	DefaultCeiling = 10
+
+	// throttleThreshold consecutive 429/503 responses halve the rate
+	throttleThreshold = 2
+
+	// recoverThreshold consecutive successful responses raise the rate again
+	recoverThreshold = 20
+
+	// minRate is the lowest rate backing off goes down to
+	minRate = 0.1
+
+	// maxRetryAfter caps how long a Retry-After header can pause the scan
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Adaptive adjusts a limiter from the responses it sees: repeated 429 or 503
+// responses halve its rate and sustained successes ramp it back up towards
+// the ceiling. A Retry-After header pauses requests for the time it asks.
+type Adaptive struct {
+	limiter *rate.Limiter
+	ceiling rate.Limit
+
+	mu          sync.Mutex
+	throttled   int
+	succeeded   int
+	pausedUntil time.Time
+}
+
+// NewAdaptive adapts limiter, never exceeding its current rate
+func NewAdaptive(limiter *rate.Limiter) *Adaptive {
+	return &Adaptive{limiter: limiter, ceiling: limiter.Limit()}
+}
+
+// Limit returns the current rate
+func (a *Adaptive) Limit() rate.Limit {
+	return a.limiter.Limit()
+}
+
+// Wait blocks until any Retry-After pause has passed and the limiter allows a
+// request, or ctx is done
+func (a *Adaptive) Wait(ctx context.Context) error {
+	a.mu.Lock()
+	pause := time.Until(a.pausedUntil)
+	a.mu.Unlock()
+
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return a.limiter.Wait(ctx)
+}
+
+// Observe records a response and adjusts the rate
+func (a *Adaptive) Observe(resp *http.Response) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		a.succeeded = 0
+		a.throttled++
+		if d := retryAfter(resp.Header.Get("Retry-After")); d > 0 {
+			if until := time.Now().Add(d); until.After(a.pausedUntil) {
+				a.pausedUntil = until
+			}
+		}
+		if a.throttled >= throttleThreshold {
+			a.throttled = 0
+			a.setLimit(a.limiter.Limit() / 2)
+		}
+
+	case resp.StatusCode < 400:
+		a.throttled = 0
+		a.succeeded++
+		if a.succeeded >= recoverThreshold {
+			a.succeeded = 0
+			a.setLimit(a.limiter.Limit() * 1.25)
+		}
+	}
+}
+
+// setLimit applies limit, kept between minRate and the ceiling
+func (a *Adaptive) setLimit(limit rate.Limit) {
+	if limit > a.ceiling {
+		limit = a.ceiling
+	}
+	if limit < minRate {
+		limit = minRate
+	}
+	a.limiter.SetLimit(limit)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	}
+
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestAdaptiveBacksOffOnTooManyRequests(t *testing.T) {
+	// The server answers 429 to requests closer than 10ms apart, above 100/s
+	var mu sync.Mutex
+	var last time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(last) < 10*time.Millisecond {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+		last = now
+	}))
+	defer server.Close()
+
+	a := NewAdaptive(rate.NewLimiter(1000, 1))
+	var sent []time.Time
+	var statuses []int
+	for i := 0; i < 40; i++ {
+		if err := a.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, time.Now())
+		resp, err := server.Client().Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		a.Observe(resp)
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	if a.Limit() >= 100 {
+		t.Errorf("rate still %v after 429s, want it below the server's 100/s", a.Limit())
+	}
+	for i, status := range statuses[30:] {
+		if status != http.StatusOK {
+			t.Errorf("request %d got %d once the rate had adapted", 30+i, status)
+		}
+	}
+	if observed := float64(9) / sent[39].Sub(sent[30]).Seconds(); observed >= 100 {
+		t.Errorf("observed %.0f requests/s at the end, want under 100", observed)
+	}
+}
+
+func TestAdaptiveRecoversTowardsCeiling(t *testing.T) {
+	a := NewAdaptive(rate.NewLimiter(8, 1))
+	throttled := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	ok := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+	a.Observe(throttled)
+	if a.Limit() != 8 {
+		t.Errorf("a single 503 changed the rate to %v", a.Limit())
+	}
+	a.Observe(throttled)
+	if a.Limit() != 4 {
+		t.Errorf("rate = %v after two 503s, want 4", a.Limit())
+	}
+
+	for i := 0; i < 10*recoverThreshold; i++ {
+		a.Observe(ok)
+	}
+	if a.Limit() != 8 {
+		t.Errorf("rate = %v after sustained successes, want the ceiling of 8", a.Limit())
+	}
+}
+
+func TestAdaptiveHonorsRetryAfter(t *testing.T) {
+	a := NewAdaptive(rate.NewLimiter(rate.Inf, 1))
+	a.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.Wait(ctx); err == nil {
+		t.Error("Wait didn't pause for the Retry-After")
+	}
+
+	if d := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); d != maxRetryAfter {
+		t.Errorf("retryAfter of an hour = %s, want it capped at %s", d, maxRetryAfter)
+	}
+	if d := retryAfter("soon"); d != 0 {
+		t.Errorf("retryAfter(soon) = %s", d)
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+)
+
+// Budget caps the total number of requests a scan may send. Every request
+// sent, retries included, takes one unit, and the request that would exceed
+// the cap is refused. The scan is then cancelled, as soon as the requests
+// already granted have finished so their results aren't lost.
+type Budget struct {
+	mu       sync.Mutex
+	max      int64
+	used     int64
+	inFlight int64
+	refused  bool
+	cancel   context.CancelFunc
+}
+
+// NewBudget creates a budget of max requests calling cancel once it runs out
+func NewBudget(max int64, cancel context.CancelFunc) *Budget {
+	return &Budget{max: max, cancel: cancel}
+}
+
+// Take reserves one request, reporting false when the budget is used up. Each
+// request granted must be handed back with Finish once it is done.
+func (b *Budget) Take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.used < b.max {
+		b.used++
+		b.inFlight++
+		return true
+	}
+	b.refused = true
+	b.stop()
+	return false
+}
+
+// Finish records that a request granted by Take is done
+func (b *Budget) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	b.stop()
+}
+
+// stop cancels the scan once a request was refused and none is in flight
+func (b *Budget) stop() {
+	if b.refused && b.inFlight == 0 && b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// Used returns the number of requests taken, at most the budget
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+// Exhausted reports whether a request has been refused
+func (b *Budget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.refused
+}
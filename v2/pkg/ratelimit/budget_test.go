@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBudgetConcurrentTake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewBudget(10, cancel)
+
+	var granted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.Take() {
+				granted.Add(1)
+				b.Finish()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := granted.Load(); n != 10 {
+		t.Errorf("%d requests granted, want exactly 10", n)
+	}
+	if b.Used() != 10 || !b.Exhausted() {
+		t.Errorf("Used = %d, Exhausted = %v", b.Used(), b.Exhausted())
+	}
+	if ctx.Err() == nil {
+		t.Error("running out didn't cancel the scan")
+	}
+}
+
+func TestBudgetWaitsForGrantedRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewBudget(2, cancel)
+
+	b.Take()
+	b.Take()
+	if b.Exhausted() {
+		t.Error("budget exhausted before a request was refused")
+	}
+	if b.Take() || !b.Exhausted() {
+		t.Error("request past the budget granted")
+	}
+
+	// The scan goes on until the granted requests are done
+	b.Finish()
+	if ctx.Err() != nil {
+		t.Error("scan cancelled with a granted request still in flight")
+	}
+	b.Finish()
+	if ctx.Err() == nil {
+		t.Error("scan not cancelled once the granted requests were done")
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Jitter is a random delay between Min and Max added before each request, so
+// the request rate isn't perfectly steady. The zero value adds no delay.
+type Jitter struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// ParseJitter parses a "min-max" range of durations such as "100ms-500ms". A
+// single duration is the range from zero to it.
+func ParseJitter(s string) (Jitter, error) {
+	minText, maxText, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		minText, maxText = "0s", minText
+	}
+
+	lo, err := time.ParseDuration(strings.TrimSpace(minText))
+	if err != nil {
+		return Jitter{}, fmt.Errorf("invalid jitter '%s': %w", s, err)
+	}
+	hi, err := time.ParseDuration(strings.TrimSpace(maxText))
+	if err != nil {
+		return Jitter{}, fmt.Errorf("invalid jitter '%s': %w", s, err)
+	}
+	if lo < 0 || hi < lo {
+		return Jitter{}, fmt.Errorf("invalid jitter '%s', expected min-max with 0 <= min <= max", s)
+	}
+	return Jitter{Min: lo, Max: hi}, nil
+}
+
+// Enabled reports whether the jitter adds any delay
+func (j Jitter) Enabled() bool {
+	return j.Max > 0
+}
+
+// Delay returns a random delay within the range
+func (j Jitter) Delay() time.Duration {
+	if j.Max <= j.Min {
+		return j.Min
+	}
+	return j.Min + rand.N(j.Max-j.Min+1)
+}
+
+// Wait sleeps for a random delay within the range, or until ctx is done
+func (j Jitter) Wait(ctx context.Context) error {
+	if !j.Enabled() {
+		return nil
+	}
+
+	timer := time.NewTimer(j.Delay())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// String formats the range as accepted by ParseJitter
+func (j Jitter) String() string {
+	return j.Min.String() + "-" + j.Max.String()
+}
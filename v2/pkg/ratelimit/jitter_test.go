@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseJitter(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  Jitter
+	}{
+		{"100ms-500ms", Jitter{100 * time.Millisecond, 500 * time.Millisecond}},
+		{" 1s - 2s ", Jitter{time.Second, 2 * time.Second}},
+		{"250ms", Jitter{0, 250 * time.Millisecond}},
+		{"0s-0s", Jitter{}},
+	} {
+		got, err := ParseJitter(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseJitter(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"500ms-100ms", "-1s", "fast", "1s-slow"} {
+		if _, err := ParseJitter(value); err == nil {
+			t.Errorf("ParseJitter(%q) accepted an invalid range", value)
+		}
+	}
+}
+
+func TestJitterDelayWithinRange(t *testing.T) {
+	j := Jitter{Min: 100 * time.Millisecond, Max: 500 * time.Millisecond}
+	var lo, hi time.Duration = j.Max, j.Min
+	for i := 0; i < 1000; i++ {
+		d := j.Delay()
+		if d < j.Min || d > j.Max {
+			t.Fatalf("Delay() = %s, outside %s", d, j)
+		}
+		lo, hi = min(lo, d), max(hi, d)
+	}
+	// The delays should spread over the range rather than sit at one end
+	if hi-lo < (j.Max-j.Min)/2 {
+		t.Errorf("delays only spread over %s-%s", lo, hi)
+	}
+	if (Jitter{}).Enabled() {
+		t.Error("the zero Jitter is enabled")
+	}
+}
+
+func TestJitterWaitHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := (Jitter{Min: time.Minute, Max: time.Minute}).Wait(ctx); err == nil {
+		t.Error("Wait didn't give up when the context ended")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait returned after %s", elapsed)
+	}
+}
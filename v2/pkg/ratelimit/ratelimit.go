@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// HostLimiter rate limits requests separately for each host, so a slow or
+// fragile host doesn't throttle scanning of the others
+type HostLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*rate.Limiter
+}
+
+// NewHostLimiter creates a limiter allowing requestsPerSecond to each host
+func NewHostLimiter(requestsPerSecond float64) *HostLimiter {
+	return &HostLimiter{
+		limit:    rate.Limit(requestsPerSecond),
+		burst:    1,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// For returns the limiter for host, creating it on first use
+func (h *HostLimiter) For(host string) *rate.Limiter {
+	host = strings.ToLower(host)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(h.limit, h.burst)
+		h.limiters[host] = l
+	}
+	return l
+}
+
+// Wait blocks until a request to host is allowed or ctx is done
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+	return h.For(host).Wait(ctx)
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterThrottlesHostsIndependently(t *testing.T) {
+	h := NewHostLimiter(10)
+	if h.For("A.example") != h.For("a.example") {
+		t.Error("hosts differing in case got separate limiters")
+	}
+
+	// Three requests to a host take two intervals of 100ms
+	elapsed := make(map[string]time.Duration)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, host := range []string{"a.example", "b.example:8443"} {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				if err := h.Wait(context.Background(), host); err != nil {
+					t.Error(err)
+				}
+			}
+			mu.Lock()
+			elapsed[host] = time.Since(start)
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	for host, d := range elapsed {
+		if d < 180*time.Millisecond {
+			t.Errorf("%s sent 3 requests in %s, faster than its 10/s", host, d)
+		}
+		if d > 400*time.Millisecond {
+			t.Errorf("%s took %s, throttled by the other host", host, d)
+		}
+	}
+}
+
+func TestHostLimiterWaitHonorsContext(t *testing.T) {
+	h := NewHostLimiter(0.1)
+	h.Wait(context.Background(), "a.example")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx, "a.example"); err == nil {
+		t.Error("Wait didn't give up when the context ended")
+	}
+	if err := h.Wait(context.Background(), "b.example"); err != nil {
+		t.Errorf("Wait on another host: %v", err)
+	}
+}
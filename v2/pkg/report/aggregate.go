@@ -0,0 +1,110 @@
+package report
+
+import "sync"
+
+// findingKey identifies findings reporting the same injection, whatever their
+// evidence, token or time. Confirmation is part of it, so a payload confirmed
+// after being reported unconfirmed is still passed on.
+type findingKey struct {
+	target, method, point, param, header, payload, sink, view string
+	confirmed                                                 bool
+}
+
+func keyOf(f Finding) findingKey {
+	return findingKey{
+		target:    f.Target,
+		method:    f.Method,
+		point:     f.InjectionPoint,
+		param:     f.Param,
+		header:    f.Header,
+		payload:   f.Payload,
+		sink:      f.Sink,
+		view:      f.View,
+		confirmed: f.Confirmed,
+	}
+}
+
+// Entry is a distinct finding and the number of times it was reported
+type Entry struct {
+	Finding Finding
+	Count   int
+}
+
+// Summary counts the findings an Aggregator has seen
+type Summary struct {
+	Findings   int
+	Confirmed  int
+	Duplicates int
+}
+
+// Aggregator is a writer passing only the first of each distinct finding on
+// to the writer it wraps, such as the same injection reported again after a
+// retry or an -inject-all follow-up, and counting the rest. It is safe for
+// concurrent use.
+type Aggregator struct {
+	w Writer
+
+	mu      sync.Mutex
+	entries []Entry
+	index   map[findingKey]int
+}
+
+// NewAggregator creates an aggregator passing findings on to w, which may be
+// nil to only aggregate them
+func NewAggregator(w Writer) *Aggregator {
+	return &Aggregator{w: w, index: make(map[findingKey]int)}
+}
+
+// Write counts f and passes it on unless an identical finding was written before
+func (a *Aggregator) Write(f Finding) error {
+	key := keyOf(f)
+
+	a.mu.Lock()
+	if i, ok := a.index[key]; ok {
+		a.entries[i].Count++
+		a.mu.Unlock()
+		return nil
+	}
+	a.index[key] = len(a.entries)
+	a.entries = append(a.entries, Entry{Finding: f, Count: 1})
+	a.mu.Unlock()
+
+	if a.w == nil {
+		return nil
+	}
+	return a.w.Write(f)
+}
+
+// Entries returns the distinct findings with their counts, in the order they
+// were first written
+func (a *Aggregator) Entries() []Entry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]Entry(nil), a.entries...)
+}
+
+// Summary returns the number of distinct and confirmed findings, and of
+// duplicates dropped
+func (a *Aggregator) Summary() Summary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var s Summary
+	for _, e := range a.entries {
+		s.Findings++
+		if e.Finding.Confirmed {
+			s.Confirmed++
+		}
+		s.Duplicates += e.Count - 1
+	}
+	return s
+}
+
+// Close closes the wrapped writer
+func (a *Aggregator) Close() error {
+	if a.w == nil {
+		return nil
+	}
+	return a.w.Close()
+}
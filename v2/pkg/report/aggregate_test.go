@@ -0,0 +1,67 @@
+package report
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestAggregatorDedupesConcurrentFindings(t *testing.T) {
+	collector := NewCollector()
+	a := NewAggregator(collector)
+	finding := testFindings()[0]
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Retries report the same injection with a token and evidence of their own
+			retry := finding
+			retry.Token = fmt.Sprint("token", i)
+			retry.Evidence = fmt.Sprint("attempt ", i)
+			if err := a.Write(retry); err != nil {
+				t.Errorf("Write: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries := a.Entries()
+	if len(entries) != 1 || entries[0].Count != 20 {
+		t.Fatalf("entries = %+v, want one counted 20 times", entries)
+	}
+	if got := collector.Findings(); len(got) != 1 {
+		t.Errorf("%d findings passed on, want the first only", len(got))
+	}
+	if s := a.Summary(); s != (Summary{Findings: 1, Duplicates: 19}) {
+		t.Errorf("Summary = %+v", s)
+	}
+}
+
+func TestAggregatorKeepsDistinctFindings(t *testing.T) {
+	a := NewAggregator(nil)
+	findings := testFindings()
+	for _, f := range findings {
+		a.Write(f)
+	}
+
+	// A payload confirmed after being reported unconfirmed is a finding of its own
+	confirmed := findings[0]
+	confirmed.Confirmed = true
+	a.Write(confirmed)
+	a.Write(findings[1])
+
+	entries := a.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("%d entries, want 4", len(entries))
+	}
+	for i, want := range []int{1, 2, 1, 1} {
+		if entries[i].Count != want {
+			t.Errorf("entry %d counted %d times, want %d", i, entries[i].Count, want)
+		}
+	}
+	if s := a.Summary(); s != (Summary{Findings: 4, Confirmed: 3, Duplicates: 1}) {
+		t.Errorf("Summary = %+v", s)
+	}
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// csvHeader is the header row of CSV output, in column order
+var csvHeader = []string{
+	"timestamp", "target", "method", "injection_point", "param", "header",
+	"payload", "encodings", "token", "source", "sink", "user_agent", "protocol", "redirects", "view", "confirmed", "evidence",
+}
+
+// CSVWriter writes findings as CSV rows under a fixed header row
+type CSVWriter struct {
+	mu sync.Mutex
+	w  *csv.Writer
+}
+
+// NewCSVWriter creates a CSV writer on w and writes the header row
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	cw := &CSVWriter{w: csv.NewWriter(w)}
+	if err := cw.writeRow(csvHeader); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write appends a finding as a row, quoting fields containing commas, quotes or newlines
+func (w *CSVWriter) Write(f Finding) error {
+	return w.writeRow([]string{
+		f.Timestamp.Format(time.RFC3339),
+		f.Target,
+		f.Method,
+		f.InjectionPoint,
+		f.Param,
+		f.Header,
+		f.Payload,
+		strings.Join(f.Encodings, " -> "),
+		f.Token,
+		f.Source,
+		f.Sink,
+		f.UserAgent,
+		f.Protocol,
+		strings.Join(f.Redirects, " -> "),
+		f.View,
+		strconv.FormatBool(f.Confirmed),
+		f.Evidence,
+	})
+}
+
+// writeRow writes and flushes a row so findings survive an interrupted scan
+func (w *CSVWriter) writeRow(row []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.w.Write(row); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Close flushes any buffered output
+func (w *CSVWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.w.Flush()
+	return w.w.Error()
+}
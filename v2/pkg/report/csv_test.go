@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCSVWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewCSVWriter: %v", err)
+	}
+	findings := testFindings()
+	for _, f := range findings {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output isn't valid CSV: %v", err)
+	}
+	if len(rows) != len(findings)+1 || !slices.Equal(rows[0], csvHeader) {
+		t.Fatalf("got %d rows headed %q", len(rows), rows[0])
+	}
+
+	for i, f := range findings {
+		row := rows[i+1]
+		if got := row[csvColumn("payload")]; got != f.Payload {
+			t.Errorf("row %d payload = %q, want %q", i+1, got, f.Payload)
+		}
+		if got := row[csvColumn("timestamp")]; got != f.Timestamp.Format(time.RFC3339) {
+			t.Errorf("row %d timestamp = %q", i+1, got)
+		}
+		if got := row[csvColumn("confirmed")]; got != fmt.Sprint(f.Confirmed) {
+			t.Errorf("row %d confirmed = %q", i+1, got)
+		}
+		if got := row[csvColumn("evidence")]; got != f.Evidence {
+			t.Errorf("row %d evidence = %q, want %q", i+1, got, f.Evidence)
+		}
+	}
+	if got := rows[2][csvColumn("redirects")]; got != "https://target.example/ -> https://target.example/home" {
+		t.Errorf("redirects = %q", got)
+	}
+	if got := rows[3][csvColumn("encodings")]; got != "url -> html" {
+		t.Errorf("encodings = %q", got)
+	}
+}
+
+func TestCSVWriterConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewCSVWriter: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.Write(Finding{Target: fmt.Sprintf("https://target.example/%d", i), Payload: "a,\"b\"\nc"})
+		}(i)
+	}
+	wg.Wait()
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("interleaved rows: %v", err)
+	}
+	if len(rows) != 51 {
+		t.Errorf("%d rows, want the header and 50 findings", len(rows))
+	}
+	for _, row := range rows[1:] {
+		if row[csvColumn("payload")] != "a,\"b\"\nc" {
+			t.Errorf("payload = %q", row[csvColumn("payload")])
+		}
+	}
+}
+
+// csvColumn returns the index of the named CSV column
+func csvColumn(name string) int {
+	return slices.Index(csvHeader, name)
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AsCurl returns a curl command reproducing the injection, built from the
+// captured Request when there is one and otherwise from the target, method
+// and header of the finding. Every argument is single quoted, so payload
+// characters are passed to curl as is rather than interpreted by the shell.
+func (f Finding) AsCurl() string {
+	method, target, body := f.Method, f.Target, ""
+	header := http.Header{}
+	if f.Request != nil {
+		method, target, body = f.Request.Method, f.Request.URL, f.Request.Body
+		header = f.Request.Header
+	} else if f.Header != "" {
+		header.Set(f.Header, f.Payload)
+	}
+
+	// -g stops curl expanding the {} and [] payloads are full of
+	args := []string{"curl", "-g"}
+	if f.InjectionPoint == PointPath {
+		args = append(args, "--path-as-is")
+	}
+	if method != "" && method != http.MethodGet {
+		args = append(args, "-X", shellQuote(method))
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	if body != "" {
+		args = append(args, "--data-raw", shellQuote(body))
+	}
+	args = append(args, shellQuote(target))
+	return strings.Join(args, " ")
+}
+
+// shellQuote single quotes s for POSIX shells, closing the quotes around each
+// single quote in s and escaping it
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CurlWriter writes a curl command reproducing each finding, one per line
+type CurlWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewCurlWriter creates a curl command writer on w
+func NewCurlWriter(w io.Writer) *CurlWriter {
+	return &CurlWriter{w: w}
+}
+
+// Write writes the curl command for f
+func (w *CurlWriter) Write(f Finding) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := fmt.Fprintln(w.w, f.AsCurl())
+	return err
+}
+
+// Close is a no-op, every command is written as it arrives
+func (w *CurlWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// shellArgs runs command in sh with curl replaced by a function printing its
+// arguments, and returns the arguments curl would have been given
+func shellArgs(t *testing.T, command string) []string {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	cmd := exec.Command(sh, "-c", `curl() { printf '%s\0' "$@"; }; `+command)
+	cmd.Dir = t.TempDir()
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running %s: %v", command, err)
+	}
+	entries, _ := os.ReadDir(cmd.Dir)
+	if len(entries) > 0 {
+		t.Errorf("running %s created %s", command, entries[0].Name())
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+}
+
+func TestAsCurlRoundTripsPayload(t *testing.T) {
+	for _, payload := range []string{
+		`"><script>alert('x')</script>`,
+		`'; touch pwned; echo '`,
+		"$(touch pwned) `touch pwned` ${HOME} \\ \n !",
+		`{{callback}}[0]*?`,
+	} {
+		target := "https://target.example/?q=" + payload
+		f := Finding{
+			Target:         target,
+			Method:         http.MethodPost,
+			Header:         "X-Test",
+			Payload:        payload,
+			InjectionPoint: PointHeader,
+			Request: &Request{
+				Method: http.MethodPost,
+				URL:    target,
+				Header: http.Header{"X-Test": {payload}, "Content-Type": {"text/plain"}},
+				Body:   "a=" + payload,
+			},
+		}
+
+		want := []string{"-g", "-X", "POST", "-H", "Content-Type: text/plain", "-H", "X-Test: " + payload, "--data-raw", "a=" + payload, target}
+		if got := shellArgs(t, f.AsCurl()); !slices.Equal(got, want) {
+			t.Errorf("curl given %q, want %q", got, want)
+		}
+	}
+}
+
+func TestAsCurlWithoutRequest(t *testing.T) {
+	f := Finding{Target: "https://target.example/a/<b>", Method: http.MethodGet, InjectionPoint: PointPath}
+	if got, want := shellArgs(t, f.AsCurl()), []string{"-g", "--path-as-is", f.Target}; !slices.Equal(got, want) {
+		t.Errorf("curl given %q, want %q", got, want)
+	}
+
+	f = Finding{Target: "https://target.example/", Header: "Referer", Payload: "'<b>", InjectionPoint: PointHeader}
+	if got, want := shellArgs(t, f.AsCurl()), []string{"-g", "-H", "Referer: '<b>", f.Target}; !slices.Equal(got, want) {
+		t.Errorf("curl given %q, want %q", got, want)
+	}
+}
+
+func TestCurlWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCurlWriter(&buf)
+	for _, f := range testFindings() {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	var want strings.Builder
+	for _, f := range testFindings() {
+		want.WriteString(f.AsCurl() + "\n")
+	}
+	if buf.String() != want.String() {
+		t.Errorf("output = %q, want %q", buf.String(), want.String())
+	}
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive headers in captured requests and responses
+const Redacted = "[REDACTED]"
+
+// SensitiveHeaders are always redacted from captured requests and responses
+var SensitiveHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// Request is the HTTP request behind a confirmed finding, enough to replay it
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"headers,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is the status and headers of the response to a finding's request
+type Response struct {
+	Status int         `json:"status"`
+	Proto  string      `json:"proto,omitempty"`
+	Header http.Header `json:"headers,omitempty"`
+}
+
+// CaptureRequest records r and its body, with the SensitiveHeaders and the
+// headers named in redact replaced by Redacted
+func CaptureRequest(r *http.Request, body string, redact []string) *Request {
+	return &Request{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: redactHeaders(r.Header, redact),
+		Body:   body,
+	}
+}
+
+// CaptureResponse records a response's status and headers, redacted as by CaptureRequest
+func CaptureResponse(status int, proto string, header http.Header, redact []string) *Response {
+	return &Response{
+		Status: status,
+		Proto:  proto,
+		Header: redactHeaders(header, redact),
+	}
+}
+
+// redactHeaders returns a copy of header with the sensitive headers' values redacted
+func redactHeaders(header http.Header, redact []string) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := header.Clone()
+	for name := range redacted {
+		if sensitive(name, redact) {
+			values := make([]string, len(redacted[name]))
+			for i := range values {
+				values[i] = Redacted
+			}
+			redacted[name] = values
+		}
+	}
+	return redacted
+}
+
+// sensitive reports whether the header name is one of SensitiveHeaders or redact
+func sensitive(name string, redact []string) bool {
+	for _, list := range [][]string{SensitiveHeaders, redact} {
+		for _, s := range list {
+			if strings.EqualFold(name, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
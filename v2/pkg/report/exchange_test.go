@@ -0,0 +1,55 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCaptureRequestRedacts(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "https://target.example/api?q=1", strings.NewReader(`{"q":"<b>"}`))
+	r.Header.Set("Authorization", "Bearer abc")
+	r.Header.Set("Cookie", "session=s3cr3t")
+	r.Header.Set("Content-Type", "application/json")
+
+	captured := CaptureRequest(r, `{"q":"<b>"}`, []string{"cookie"})
+	if captured.Method != http.MethodPost || captured.URL != "https://target.example/api?q=1" || captured.Body != `{"q":"<b>"}` {
+		t.Errorf("captured %+v", captured)
+	}
+	for name, want := range map[string]string{"Authorization": Redacted, "Cookie": Redacted, "Content-Type": "application/json"} {
+		if got := captured.Header.Get(name); got != want {
+			t.Errorf("captured %s = %q, want %q", name, got, want)
+		}
+	}
+	if r.Header.Get("Authorization") != "Bearer abc" {
+		t.Error("redaction changed the request itself")
+	}
+}
+
+func TestExchangeInJSON(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "https://target.example/?q=1", nil)
+	r.Header.Set("Proxy-Authorization", "Basic x")
+	finding := testFindings()[1]
+	finding.Request = CaptureRequest(r, "", nil)
+	finding.Response = CaptureResponse(http.StatusOK, "HTTP/2.0", http.Header{"Server": {"test"}}, nil)
+
+	var buf bytes.Buffer
+	w := NewJSONLWriter(&buf)
+	if err := w.Write(finding); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	w.Close()
+
+	var got Finding
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if got.Request == nil || got.Request.Header.Get("Proxy-Authorization") != Redacted || got.Request.URL != "https://target.example/?q=1" {
+		t.Errorf("request = %+v", got.Request)
+	}
+	if got.Response == nil || got.Response.Status != http.StatusOK || got.Response.Proto != "HTTP/2.0" || got.Response.Header.Get("Server") != "test" {
+		t.Errorf("response = %+v", got.Response)
+	}
+}
@@ -0,0 +1,261 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Injection points recorded on findings
+const (
+	PointQuery     = "query"
+	PointHeader    = "header"
+	PointPath      = "path"
+	PointBody      = "body"
+	PointCookie    = "cookie"
+	PointURL       = "url"
+	PointFragment  = "fragment"
+	PointGraphQL   = "graphql"
+	PointMultipart = "multipart"
+	PointWebSocket = "websocket"
+	PointCallback  = "callback"
+)
+
+// Finding is a single injection made by the scanner, confirmed when the
+// payload was seen to execute through a dialog or a callback. Sink names the
+// DOM sink, such as innerHTML, that the page wrote the payload to, or is
+// "redirect" when a redirect pointed at it. Source is the payload file the
+// payload was loaded from, Encodings the --encode transforms applied to it, Protocol the HTTP version the probe negotiated
+// (e.g. HTTP/2.0) and Redirects the URLs the probe was redirected through,
+// starting with its own. View is the page a stored payload executed on, when
+// it isn't the one injected. Confirmed findings also carry the
+// Request sent and the Response to it, for replaying them.
+type Finding struct {
+	Target         string    `json:"target"`
+	Method         string    `json:"method,omitempty"`
+	Param          string    `json:"param,omitempty"`
+	Header         string    `json:"header,omitempty"`
+	Payload        string    `json:"payload"`
+	InjectionPoint string    `json:"injection_point"`
+	Token          string    `json:"token,omitempty"`
+	Source         string    `json:"source,omitempty"`
+	Encodings      []string  `json:"encodings,omitempty"`
+	Sink           string    `json:"sink,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	Protocol       string    `json:"protocol,omitempty"`
+	Redirects      []string  `json:"redirects,omitempty"`
+	View           string    `json:"view,omitempty"`
+	Confirmed      bool      `json:"confirmed"`
+	Evidence       string    `json:"evidence,omitempty"`
+	Request        *Request  `json:"request,omitempty"`
+	Response       *Response `json:"response,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Writer records findings. Implementations are safe for concurrent use.
+type Writer interface {
+	Write(f Finding) error
+	Close() error
+}
+
+// JSONLWriter writes each finding as a line of JSON
+type JSONLWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLWriter creates a JSON lines writer on w
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLWriter{enc: enc}
+}
+
+// Write appends a finding as a single line
+func (w *JSONLWriter) Write(f Finding) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.enc.Encode(f)
+}
+
+// Close is a no-op, every finding is written as it arrives
+func (w *JSONLWriter) Close() error {
+	return nil
+}
+
+// ReadJSONL reads the findings of a JSON lines report, skipping blank lines
+func ReadJSONL(r io.Reader) ([]Finding, error) {
+	dec := json.NewDecoder(r)
+	var findings []Finding
+	for {
+		var f Finding
+		if err := dec.Decode(&f); err == io.EOF {
+			return findings, nil
+		} else if err != nil {
+			return findings, fmt.Errorf("invalid finding %d: %w", len(findings)+1, err)
+		}
+		findings = append(findings, f)
+	}
+}
+
+// Collector keeps findings in memory, for callers using bxss as a library
+type Collector struct {
+	mu       sync.Mutex
+	findings []Finding
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Write keeps a finding
+func (c *Collector) Write(f Finding) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.findings = append(c.findings, f)
+	return nil
+}
+
+// Findings returns the findings kept so far, in the order they were written
+func (c *Collector) Findings() []Finding {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Finding(nil), c.findings...)
+}
+
+// Close is a no-op, the findings stay available
+func (c *Collector) Close() error {
+	return nil
+}
+
+// Output formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatSARIF = "sarif"
+)
+
+// New returns a writer producing format on w
+func New(w io.Writer, format string) (Writer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSONL, "json":
+		return NewJSONLWriter(w), nil
+	case FormatCSV:
+		return NewCSVWriter(w)
+	case FormatSARIF:
+		return NewSARIFWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%s', expected jsonl, csv or sarif", format)
+	}
+}
+
+// Open creates (or truncates) the file at path and returns a writer for it.
+// An empty format is picked from the file extension, defaulting to JSON lines.
+func Open(path string, format string) (Writer, error) {
+	if format == "" {
+		format = formatForPath(path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	w, err := New(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileWriter{Writer: w, file: file}, nil
+}
+
+// formatForPath guesses the output format from a file name
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".sarif":
+		return FormatSARIF
+	default:
+		return FormatJSONL
+	}
+}
+
+// fileWriter closes the output file once the wrapped writer is closed
+type fileWriter struct {
+	Writer
+	file *os.File
+}
+
+// Close closes the wrapped writer and then the file
+func (w *fileWriter) Close() error {
+	err := w.Writer.Close()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// multiWriter duplicates findings to several writers
+type multiWriter []Writer
+
+// Multi returns a writer that writes every finding to each of writers
+func Multi(writers ...Writer) Writer {
+	if len(writers) == 1 {
+		return writers[0]
+	}
+	return multiWriter(writers)
+}
+
+// Write writes f to every writer, returning the first error after trying them all
+func (m multiWriter) Write(f Finding) error {
+	var first error
+	for _, w := range m {
+		if err := w.Write(f); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every writer, returning the first error
+func (m multiWriter) Close() error {
+	var first error
+	for _, w := range m {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// firstConfirmed calls fn once with the first confirmed finding written
+type firstConfirmed struct {
+	Writer
+	once sync.Once
+	fn   func(Finding)
+}
+
+// OnFirstConfirmed returns a writer passing findings on to w that calls fn
+// with the first confirmed one, once it has been written
+func OnFirstConfirmed(w Writer, fn func(Finding)) Writer {
+	return &firstConfirmed{Writer: w, fn: fn}
+}
+
+// Write writes f and then calls fn if it is the first confirmed finding
+func (w *firstConfirmed) Write(f Finding) error {
+	err := w.Writer.Write(f)
+	if f.Confirmed {
+		w.once.Do(func() { w.fn(f) })
+	}
+	return err
+}
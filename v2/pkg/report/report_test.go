@@ -0,0 +1,145 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testFindings returns findings with payloads that need escaping in every format
+func testFindings() []Finding {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []Finding{
+		{
+			Target:         "https://target.example/search?q=1",
+			Method:         "GET",
+			Param:          "q",
+			Payload:        `"><script>alert(1)</script>`,
+			InjectionPoint: PointQuery,
+			Token:          "abc123",
+			Timestamp:      at,
+		},
+		{
+			Target:         "https://target.example/",
+			Header:         "User-Agent",
+			Payload:        "a,b \"quoted\"\nsecond line",
+			InjectionPoint: PointHeader,
+			Confirmed:      true,
+			Evidence:       `alert dialog with message "1"`,
+			Redirects:      []string{"https://target.example/", "https://target.example/home"},
+			Timestamp:      at.Add(time.Second),
+		},
+		{
+			Target:         "https://target.example/api",
+			Method:         "POST",
+			Param:          "name",
+			Payload:        "<svg onload=alert(1)>",
+			InjectionPoint: PointBody,
+			Encodings:      []string{"url", "html"},
+			Confirmed:      true,
+			Timestamp:      at.Add(2 * time.Second),
+		},
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONLWriter(&buf)
+	findings := testFindings()
+	for _, f := range findings {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(findings) {
+		t.Fatalf("%d lines, want one per finding:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("line %d isn't valid JSON: %v\n%s", i+1, err, line)
+		}
+		for _, key := range []string{"target", "payload", "injection_point", "confirmed", "timestamp"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("line %d has no %s: %s", i+1, key, line)
+			}
+		}
+	}
+	if !strings.Contains(lines[0], `<script>`) {
+		t.Errorf("payload HTML escaped: %s", lines[0])
+	}
+
+	got, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONL: %v", err)
+	}
+	if !reflect.DeepEqual(got, findings) {
+		t.Errorf("read back %+v, want %+v", got, findings)
+	}
+}
+
+func TestJSONLWriterConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONLWriter(&buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.Write(Finding{Target: fmt.Sprintf("https://target.example/%d", i), Payload: strings.Repeat("x", 1000)})
+		}(i)
+	}
+	wg.Wait()
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	lines := 0
+	for scanner.Scan() {
+		if !json.Valid(scanner.Bytes()) {
+			t.Fatalf("interleaved line: %s", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 50 {
+		t.Errorf("%d lines, want 50", lines)
+	}
+}
+
+func TestOpenPicksFormatFromExtension(t *testing.T) {
+	dir := t.TempDir()
+	for name, want := range map[string]string{
+		"results.jsonl": "{",
+		"results.csv":   "timestamp,target",
+		"results.sarif": "{\n",
+	} {
+		path := filepath.Join(dir, name)
+		w, err := Open(path, "")
+		if err != nil {
+			t.Fatalf("Open(%s): %v", name, err)
+		}
+		if err := w.Write(testFindings()[1]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(data), want) {
+			t.Errorf("%s starts %q, want %q", name, data[:min(len(data), 20)], want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SARIF identifiers for bxss results
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRuleID  = "bxss/blind-xss"
+)
+
+// SARIFWriter collects confirmed findings and writes them as a single SARIF
+// 2.1.0 log on Close, for code scanning tools such as GitHub's. Unconfirmed
+// injections are left out.
+type SARIFWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	findings []Finding
+}
+
+// NewSARIFWriter creates a SARIF writer on w
+func NewSARIFWriter(w io.Writer) *SARIFWriter {
+	return &SARIFWriter{w: w}
+}
+
+// Write records a finding if it was confirmed
+func (w *SARIFWriter) Write(f Finding) error {
+	if !f.Confirmed {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.findings = append(w.findings, f)
+	return nil
+}
+
+// Close writes the SARIF log
+func (w *SARIFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	results := make([]map[string]interface{}, 0, len(w.findings))
+	for _, f := range w.findings {
+		properties := map[string]interface{}{
+			"payload":        f.Payload,
+			"injectionPoint": f.InjectionPoint,
+			"timestamp":      f.Timestamp,
+		}
+		for key, value := range map[string]string{
+			"token":     f.Token,
+			"method":    f.Method,
+			"param":     f.Param,
+			"header":    f.Header,
+			"source":    f.Source,
+			"sink":      f.Sink,
+			"userAgent": f.UserAgent,
+			"protocol":  f.Protocol,
+			"evidence":  f.Evidence,
+			"view":      f.View,
+		} {
+			if value != "" {
+				properties[key] = value
+			}
+		}
+		if len(f.Encodings) > 0 {
+			properties["encodings"] = f.Encodings
+		}
+		if len(f.Redirects) > 0 {
+			properties["redirects"] = f.Redirects
+		}
+
+		results = append(results, map[string]interface{}{
+			"ruleId": sarifRuleID,
+			"level":  "error",
+			"message": map[string]interface{}{
+				"text": fmt.Sprintf("Blind XSS payload fired via %s on %s", f.InjectionPoint, f.Target),
+			},
+			"locations": []interface{}{
+				map[string]interface{}{
+					"physicalLocation": map[string]interface{}{
+						"artifactLocation": map[string]interface{}{"uri": f.Target},
+					},
+				},
+			},
+			"properties": properties,
+		})
+	}
+
+	log := map[string]interface{}{
+		"version": sarifVersion,
+		"$schema": sarifSchema,
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "bxss",
+						"informationUri": "https://github.com/ethicalhackingplayground/bxss",
+						"rules": []interface{}{
+							map[string]interface{}{
+								"id":               sarifRuleID,
+								"name":             "BlindXSS",
+								"shortDescription": map[string]interface{}{"text": "Blind cross-site scripting"},
+								"fullDescription":  map[string]interface{}{"text": "A payload injected by bxss executed, confirmed by a JavaScript dialog or an out-of-band callback."},
+							},
+						},
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w.w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
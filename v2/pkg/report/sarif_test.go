@@ -0,0 +1,100 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSARIFWriterRequiredFields(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSARIFWriter(&buf)
+	for _, f := range testFindings() {
+		if err := w.Write(f); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var log struct {
+		Version string  `json:"version"`
+		Schema  *string `json:"$schema"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message *struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output isn't valid JSON: %v", err)
+	}
+
+	// The schema requires version and runs, each run a tool driver with a
+	// name, and each result a message
+	if log.Version != "2.1.0" || log.Schema == nil || len(log.Runs) != 1 {
+		t.Fatalf("log = version %q, %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "bxss" || len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "bxss/blind-xss" {
+		t.Errorf("driver = %+v", run.Tool.Driver)
+	}
+
+	// Only the two confirmed findings are results
+	findings := testFindings()[1:]
+	if len(run.Results) != len(findings) {
+		t.Fatalf("%d results, want one per confirmed finding", len(run.Results))
+	}
+	for i, result := range run.Results {
+		f := findings[i]
+		if result.RuleID != "bxss/blind-xss" || result.Level != "error" || result.Message == nil || result.Message.Text == "" {
+			t.Errorf("result %d = %+v", i, result)
+		}
+		if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation.ArtifactLocation.URI != f.Target {
+			t.Errorf("result %d locations = %+v, want %s", i, result.Locations, f.Target)
+		}
+		if result.Properties["payload"] != f.Payload || result.Properties["injectionPoint"] != f.InjectionPoint {
+			t.Errorf("result %d properties = %v", i, result.Properties)
+		}
+	}
+}
+
+func TestSARIFWriterEmptyRun(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSARIFWriter(&buf)
+	w.Write(testFindings()[0])
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var log struct {
+		Runs []map[string]json.RawMessage `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output isn't valid JSON: %v", err)
+	}
+	if results := string(log.Runs[0]["results"]); results != "[]" {
+		t.Errorf("results = %s, want an empty array rather than null", results)
+	}
+}
@@ -0,0 +1,137 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
+	"golang.org/x/time/rate"
+)
+
+// DefaultBackoff is the delay before the first retry when none is configured
+const DefaultBackoff = 500 * time.Millisecond
+
+// Policy retries requests that fail with a network error or a 5xx response,
+// backing off exponentially with jitter between attempts. 4xx responses, DNS
+// failures and TLS errors are returned as is.
+type Policy struct {
+	// Retries is the number of attempts made after the first one
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled for each one after
+	Backoff time.Duration
+
+	// Limiter, when set, is waited on before each retry so retries stay within
+	// the rate limit the first attempt was sent under
+	Limiter *rate.Limiter
+
+	// Attempt, when set, is called before every attempt, numbered from 0 for
+	// the first. An error stops the request: before the first attempt it is
+	// returned, before a retry the previous attempt's result is.
+	Attempt func(attempt int) error
+}
+
+// Do sends req with client, retrying according to the policy. The body is
+// made Rewindable first, so retries and 307/308 redirects re-send it intact.
+func (p Policy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries := p.Retries
+	if err := Rewindable(req); err != nil {
+		return nil, err
+	}
+	if p.Attempt != nil {
+		if err := p.Attempt(0); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && p.Limiter != nil {
+			if err := p.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := client.Do(req)
+		if attempt >= retries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+		if p.Attempt != nil && p.Attempt(attempt+1) != nil {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if err := p.sleep(ctx, attempt); err != nil {
+			return nil, err
+		}
+
+		// Replay the body for the next attempt
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// Rewindable lets the body of req be read again, setting GetBody to replay a
+// copy of it buffered in memory. Bodies NewRequest made from a strings.Reader,
+// bytes.Reader or bytes.Buffer already have a GetBody and are left alone.
+func Rewindable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// retryable reports whether an attempt failed in a way worth retrying
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Once the caller has given up, or the host doesn't resolve or fails
+		// the TLS handshake, another attempt would fail the same way
+		return ctx.Err() == nil && !transport.Permanent(transport.Classify(err))
+	}
+	return resp.StatusCode >= 500
+}
+
+// sleep waits out the backoff for the given attempt, returning early if ctx is done
+func (p Policy) sleep(ctx context.Context, attempt int) error {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	delay := backoff << attempt
+
+	// Jitter within the upper half keeps concurrent retries from synchronising
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// flakyServer fails the first failures requests, by resetting the connection
+// and then with a 503, and answers the rest with the body they were sent
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := attempts.Add(1)
+		switch {
+		case n > failures:
+			w.Write(body)
+		case n == 1:
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &attempts
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	server, attempts := flakyServer(t, 2)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+
+	resp, err := Policy{Retries: 3, Backoff: time.Millisecond}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" {
+		t.Errorf("response = %d %q, want 200 with the body re-sent", resp.StatusCode, body)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("%d attempts, want 3", n)
+	}
+}
+
+func TestDoGivesUpAfterRetries(t *testing.T) {
+	server, attempts := flakyServer(t, 10)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	resp, err := Policy{Retries: 2, Backoff: time.Millisecond}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts.Load() != 3 {
+		t.Errorf("got %d after %d attempts, want the last 503 after 3", resp.StatusCode, attempts.Load())
+	}
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	resp, err := Policy{Retries: 3, Backoff: time.Millisecond}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("%d attempts for a 404, want 1", n)
+	}
+}
+
+func TestDoRetriesWaitOnLimiter(t *testing.T) {
+	server, _ := flakyServer(t, 2)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	// The limiter holds one token, so each retry waits 50ms for the next
+	limiter := rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+	limiter.Allow()
+	start := time.Now()
+	resp, err := Policy{Retries: 2, Backoff: time.Millisecond, Limiter: limiter}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("two retries took %s, want them paced by the limiter", elapsed)
+	}
+}
+
+func TestDoAttemptHook(t *testing.T) {
+	server, attempts := flakyServer(t, 10)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	// The hook refuses the third attempt, so the second one's 503 is returned
+	var called []int
+	refused := errors.New("refused")
+	resp, err := Policy{Retries: 5, Backoff: time.Millisecond, Attempt: func(attempt int) error {
+		called = append(called, attempt)
+		if attempt == 2 {
+			return refused
+		}
+		return nil
+	}}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts.Load() != 2 {
+		t.Errorf("response = %d after %d attempts, want the 503 of the second", resp.StatusCode, attempts.Load())
+	}
+	if !slices.Equal(called, []int{0, 1, 2}) {
+		t.Errorf("hook called for attempts %v, want 0, 1 and 2", called)
+	}
+
+	// Refusing the first attempt sends nothing
+	attempts.Store(0)
+	_, err = Policy{Attempt: func(int) error { return refused }}.Do(server.Client(), req)
+	if err != refused || attempts.Load() != 0 {
+		t.Errorf("Do = %v after %d attempts, want the hook's error and none", err, attempts.Load())
+	}
+}
+
+// redirectServer answers POST /old with a 307 to /new, which echoes the body
+// it was sent
+func redirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			io.Copy(io.Discard, r.Body)
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDoResendsBodyOnRedirect(t *testing.T) {
+	server := redirectServer(t)
+
+	// A body NewRequest can't rewind by itself
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/old", io.MultiReader(strings.NewReader("comment=<b>")))
+	if req.GetBody != nil {
+		t.Fatal("the request body is already rewindable")
+	}
+
+	resp, err := Policy{}.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Request.URL.Path != "/new" || string(body) != "comment=<b>" {
+		t.Errorf("%s received %q, want the body intact after the 307", resp.Request.URL.Path, body)
+	}
+}
+
+func TestRewindable(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://target.example/", io.MultiReader(strings.NewReader("a=1")))
+	if err := Rewindable(req); err != nil {
+		t.Fatalf("Rewindable: %v", err)
+	}
+	if req.ContentLength != 3 {
+		t.Errorf("ContentLength = %d, want 3", req.ContentLength)
+	}
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		if err != nil {
+			t.Fatalf("GetBody: %v", err)
+		}
+		if data, _ := io.ReadAll(body); string(data) != "a=1" {
+			t.Errorf("read %d of the body = %q, want a=1", i, data)
+		}
+	}
+	if data, _ := io.ReadAll(req.Body); string(data) != "a=1" {
+		t.Errorf("body = %q after buffering, want a=1", data)
+	}
+
+	get, _ := http.NewRequest(http.MethodGet, "http://target.example/", nil)
+	if err := Rewindable(get); err != nil || get.GetBody != nil {
+		t.Errorf("Rewindable on a request without a body: GetBody set %v, err %v", get.GetBody != nil, err)
+	}
+}
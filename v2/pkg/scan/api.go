@@ -0,0 +1,99 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// ScanURL scans link with each of the configured Payloads, against each of the
+// ScanHeaders if any, and returns a finding for every distinct injection made, with
+// Confirmed set on those seen to execute. The scan prints nothing, so bxss can
+// be embedded in other tools; set Output to io.Discard when creating the
+// scanner to silence its browser pool as well. The pool is shared, and ScanURL
+// may be called from several goroutines at once. When ctx is cancelled the
+// findings so far are returned with its error.
+func (s *Scanner) ScanURL(ctx context.Context, link string) ([]report.Finding, error) {
+	if len(s.Config.Payloads) == 0 {
+		return nil, errors.New("no payloads to scan with")
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL '%s'", link)
+	}
+
+	findings := report.NewCollector()
+	run := s.fork()
+	run.Config.Context = ctx
+	run.log = s.log.To(io.Discard)
+	run.Config.Report = report.NewAggregator(findings)
+
+	headers := s.Config.ScanHeaders
+	if len(headers) == 0 {
+		headers = []string{""}
+	}
+	for _, payload := range s.Config.Payloads {
+		for _, header := range headers {
+			if ctx.Err() != nil {
+				return findings.Findings(), ctx.Err()
+			}
+			run.Scan(link, payload, header)
+		}
+	}
+	return findings.Findings(), ctx.Err()
+}
+
+// clone returns a scanner with config sharing the browser pool, HTTP client,
+// confirmed count, payload indexes and view URL state of s. Only s is closed,
+// the copy shares its browser pool.
+func (s *Scanner) clone(config ScannerConfig) *Scanner {
+	s.mu.Lock()
+	pool := s.browserPool
+	s.mu.Unlock()
+
+	return &Scanner{
+		Config:         config,
+		Client:         s.Client,
+		browserPool:    pool,
+		payloadIndexes: s.payloadIndexes,
+		indexMu:        s.indexMu,
+		oneTime:        make(map[context.Context]context.CancelFunc),
+		confirmed:      s.confirmed,
+		views:          s.views,
+		log:            s.log,
+	}
+}
+
+// fork returns a scanner with a copy of the configuration sharing the browser
+// pool and HTTP client, but counting and indexing on its own, so it can be
+// reconfigured for a single scan
+func (s *Scanner) fork() *Scanner {
+	run := s.clone(s.Config)
+	run.payloadIndexes = make(map[string]int)
+	run.indexMu = new(sync.Mutex)
+	run.confirmed = new(int64)
+	run.views = newViewState()
+	return run
+}
+
+// WithSource returns a scanner sharing everything with s, see clone, whose
+// findings are attributed to source, so payloads from different files can be
+// scanned concurrently
+func (s *Scanner) WithSource(source string) *Scanner {
+	config := s.Config
+	config.Source = source
+	return s.clone(config)
+}
+
+// WithEncodings returns a scanner sharing everything with s, as WithSource
+// does, whose findings record the --encode transforms applied to the payload
+func (s *Scanner) WithEncodings(encodings []string) *Scanner {
+	config := s.Config
+	config.Encodings = encodings
+	return s.clone(config)
+}
@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// syncBuffer is a buffer safe to write to from the browser pool's goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestScanURLReturnsFindings(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	var output syncBuffer
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		Payloads:     []string{"<b>", "<i>"},
+		Output:       &output,
+		Engine:       &browser.FakeEngine{Fire: browser.FireOn("id=%3Ci%3E", "fired")},
+	})
+
+	findings, err := s.ScanURL(context.Background(), server.URL+"/item?id=1")
+	if err != nil {
+		t.Fatalf("ScanURL: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("%d findings, want one per payload: %+v", len(findings), findings)
+	}
+	for _, f := range findings {
+		if f.InjectionPoint != report.PointQuery || f.Param != "id" || f.Method != http.MethodGet {
+			t.Errorf("finding = %+v, want the id parameter", f)
+		}
+		if f.Confirmed != (f.Payload == "<i>") {
+			t.Errorf("%s confirmed = %v", f.Payload, f.Confirmed)
+		}
+	}
+	if printed := output.String(); strings.Contains(printed, "Using Payload") || strings.Contains(printed, "confirmed") {
+		t.Errorf("ScanURL printed the scan:\n%s", printed)
+	}
+}
+
+func TestScanURLConcurrentCalls(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Payloads: []string{"<b>"}})
+
+	var wg sync.WaitGroup
+	results := make([][]report.Finding, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = s.ScanURL(context.Background(), server.URL+"/?q=1")
+		}(i)
+	}
+	wg.Wait()
+
+	// Each call gets the findings of its own scan only
+	for i, findings := range results {
+		if len(findings) != 1 {
+			t.Errorf("call %d returned %d findings, want 1", i, len(findings))
+		}
+	}
+}
+
+func TestScanURLErrors(t *testing.T) {
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet})
+	if _, err := s.ScanURL(context.Background(), "https://target.example/"); err == nil {
+		t.Error("ScanURL without payloads succeeded")
+	}
+
+	s = testScanner(t, &ScannerConfig{Method: http.MethodGet, Payloads: []string{"<b>"}})
+	if _, err := s.ScanURL(context.Background(), "not a url"); err == nil {
+		t.Error("ScanURL accepted an invalid URL")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ScanURL(ctx, "https://target.example/?q=1"); err != context.Canceled {
+		t.Errorf("ScanURL with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestCopiesShareScanState(t *testing.T) {
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, Source: "a.txt"})
+
+	shared := map[string]*Scanner{
+		"WithSource":    s.WithSource("b.txt"),
+		"WithEncodings": s.WithEncodings([]string{"url"}),
+	}
+	for name, dup := range shared {
+		if dup.browserPool != s.browserPool || dup.Client != s.Client || dup.confirmed != s.confirmed || dup.views != s.views || dup.indexMu != s.indexMu {
+			t.Errorf("%s doesn't share the scanner's pool, client and scan state", name)
+		}
+	}
+	if c := shared["WithSource"].Config; c.Source != "b.txt" || c.Method != http.MethodGet {
+		t.Errorf("WithSource config = %+v, want source b.txt and the rest copied", c)
+	}
+	if c := shared["WithEncodings"].Config; c.Source != "a.txt" || len(c.Encodings) != 1 {
+		t.Errorf("WithEncodings config = %+v, want the encodings and the rest copied", c)
+	}
+	if s.Config.Source != "a.txt" || s.Config.Encodings != nil {
+		t.Errorf("copying changed the scanner's own config: %+v", s.Config)
+	}
+
+	run := s.fork()
+	if run.browserPool != s.browserPool || run.Client != s.Client {
+		t.Error("fork doesn't share the scanner's pool and client")
+	}
+	if run.confirmed == s.confirmed || run.views == s.views || run.indexMu == s.indexMu {
+		t.Error("fork shares the scanner's scan state")
+	}
+}
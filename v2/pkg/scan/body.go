@@ -0,0 +1,162 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// BodyInjection is a request body with the payload placed in one of its
+// fields, and the correlation token that payload carries, if any
+type BodyInjection struct {
+	Field       string
+	Body        string
+	ContentType string
+	Token       string
+}
+
+// hasBody reports whether requests with method carry a body worth injecting into
+func hasBody(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// BodyInjections returns one body per field of the template with the payload
+// fill returns for that field put into it according to mode. Templates that
+// are JSON objects are treated as JSON and injected at every value, however
+// deeply nested, anything else as a form encoded body.
+func BodyInjections(template string, fill Filler, mode Mode) ([]BodyInjection, error) {
+	trimmed := strings.TrimSpace(template)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return JSONInjections(trimmed, fill, mode, nil)
+	}
+	return formBodyInjections(trimmed, fill, mode)
+}
+
+// formBodyInjections injects into each field of a form encoded body
+func formBodyInjections(template string, fill Filler, mode Mode) ([]BodyInjection, error) {
+	values, err := url.ParseQuery(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid form body: %w", err)
+	}
+
+	fieldNames := make([]string, 0, len(values))
+	for field := range values {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+
+	var injections []BodyInjection
+	for _, field := range fieldNames {
+		injected := url.Values{}
+		for k, v := range values {
+			injected[k] = append([]string(nil), v...)
+		}
+
+		value, token := fill(field)
+		injected.Set(field, mode.Inject(values.Get(field), value))
+
+		injections = append(injections, BodyInjection{
+			Field:       field,
+			Body:        injected.Encode(),
+			ContentType: "application/x-www-form-urlencoded",
+			Token:       token,
+		})
+	}
+	return injections, nil
+}
+
+// injectBody sends the payload in each field of the --data body template when
+// method carries a body
+func (s *Scanner) injectBody(method string, payload string, link string) {
+	if s.Config.Data == "" || !hasBody(method) {
+		return
+	}
+
+	fill := s.filler(payload, link, report.PointBody)
+	injections, err := BodyInjections(s.Config.Data, fill, s.mode())
+	if len(s.Config.JSONPaths) > 0 {
+		injections, err = JSONInjections(s.Config.Data, fill, s.mode(), s.Config.JSONPaths)
+	}
+	if err != nil {
+		s.log.Error("Error parsing body template: " + err.Error())
+		return
+	}
+
+	for _, body := range injections {
+		if s.context().Err() != nil {
+			return
+		}
+		s.log.Notice("Body field: " + body.Field)
+		if !s.sendBody(method, payload, link, body, report.PointBody) {
+			return
+		}
+	}
+}
+
+// sendBody sends body to link over HTTP and reports it as an injection at
+// point. It reports false when the request budget is spent.
+func (s *Scanner) sendBody(method string, payload string, link string, body BodyInjection, point string) bool {
+	request, err := http.NewRequestWithContext(s.context(), strings.ToUpper(method), link, strings.NewReader(body.Body))
+	if err != nil {
+		s.log.Error("Error creating request: " + err.Error())
+		return true
+	}
+	for _, cookie := range s.Config.Cookies {
+		request.AddCookie(cookie)
+	}
+	if userAgent := s.userAgent(); userAgent != "" {
+		request.Header.Set("User-Agent", userAgent)
+	}
+	setHeaders(request, s.Config.Headers)
+	request.Header.Set("Content-Type", body.ContentType)
+
+	if s.Config.DryRun {
+		s.printDryRun(request, injection{point: point, name: body.Field})
+		return true
+	}
+	if s.Config.Budget != nil {
+		if !s.Config.Budget.Take() {
+			return false
+		}
+		defer s.Config.Budget.Finish()
+	}
+	s.pace(request.URL.Host)
+
+	response, err := s.do(request)
+	if err != nil {
+		s.log.Error("Error making request: " + err.Error())
+		return true
+	}
+
+	if s.Config.Debug {
+		s.DebugRequest(request)
+		s.DebugResponse(response)
+	}
+	response.Body.Close()
+
+	finding := report.Finding{
+		Target:         link,
+		Method:         request.Method,
+		Param:          body.Field,
+		Payload:        payload,
+		InjectionPoint: point,
+		Token:          body.Token,
+		UserAgent:      request.Header.Get("User-Agent"),
+		Protocol:       response.Proto,
+		Redirects:      redirectChain(response),
+		Request:        report.CaptureRequest(request, body.Body, s.Config.RedactHeaders),
+		Response:       report.CaptureResponse(response.StatusCode, response.Proto, response.Header, s.Config.RedactHeaders),
+	}
+	s.writeFinding(finding)
+	s.checkViews(finding)
+	return true
+}
@@ -0,0 +1,124 @@
+package scan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+func TestFormBodyInjection(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodPost, Data: "name=alice&comment=hi"})
+	s.Scan(server.URL+"/submit", "<b>", "")
+
+	injected := make(map[string]bool)
+	for _, req := range requests() {
+		if req.Body == "" {
+			continue
+		}
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("body sent as %s %s", req.Method, req.Header.Get("Content-Type"))
+		}
+		values, err := url.ParseQuery(req.Body)
+		if err != nil {
+			t.Fatalf("invalid form body %q: %v", req.Body, err)
+		}
+		switch {
+		case values.Get("name") == "<b>" && values.Get("comment") == "hi":
+			injected["name"] = true
+		case values.Get("comment") == "<b>" && values.Get("name") == "alice":
+			injected["comment"] = true
+		default:
+			t.Errorf("body %q doesn't carry the payload in exactly one field", req.Body)
+		}
+	}
+	if len(injected) != 2 {
+		t.Errorf("payload sent in %v, want name and comment", injected)
+	}
+}
+
+func TestJSONBodyInjection(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodPost, Data: `{"title":"t","author":{"name":"a"},"count":1}`})
+	s.Scan(server.URL+"/api", "<b>", "")
+
+	injected := make(map[string]bool)
+	for _, req := range requests() {
+		if req.Body == "" {
+			continue
+		}
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("body sent as %s", req.Header.Get("Content-Type"))
+		}
+		var body struct {
+			Title  string `json:"title"`
+			Author struct {
+				Name string `json:"name"`
+			} `json:"author"`
+			Count interface{} `json:"count"`
+		}
+		if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+			t.Fatalf("invalid JSON body %q: %v", req.Body, err)
+		}
+		switch {
+		case body.Title == "<b>" && body.Author.Name == "a" && body.Count == 1.0:
+			injected["title"] = true
+		case body.Author.Name == "<b>" && body.Title == "t" && body.Count == 1.0:
+			injected["name"] = true
+		case body.Count == "<b>" && body.Title == "t" && body.Author.Name == "a":
+			injected["count"] = true
+		default:
+			t.Errorf("body %q doesn't carry the payload in exactly one field", req.Body)
+		}
+	}
+	if len(injected) != 3 {
+		t.Errorf("payload sent in %v, want title, name and count", injected)
+	}
+}
+
+func TestBodyFindingCapturesRequest(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+
+	// The comments page only runs the payload once it was posted in a body
+	fire := func(string, map[string]interface{}) []browser.Dialog {
+		got := requests()
+		if last := got[len(got)-1]; strings.Contains(last.Body, "%3Cb%3E") {
+			return []browser.Dialog{{Type: "alert", Message: "1"}}
+		}
+		return nil
+	}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:   http.MethodPost,
+		Data:     "comment=hi",
+		ViewURLs: []string{server.URL + "/comments"},
+		Engine:   &browser.FakeEngine{Fire: fire},
+		Report:   findings,
+	})
+	s.Scan(server.URL+"/comment", "<b>", "")
+
+	for _, f := range findings.Findings() {
+		if !f.Confirmed || f.InjectionPoint != report.PointBody {
+			continue
+		}
+		if f.Request == nil {
+			t.Fatal("stored body finding doesn't carry the request sent")
+		}
+		if f.Request.Method != http.MethodPost || f.Request.Body != "comment=%3Cb%3E" {
+			t.Errorf("captured request = %s %q", f.Request.Method, f.Request.Body)
+		}
+		if !strings.HasPrefix(f.Request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			t.Errorf("captured Content-Type = %q", f.Request.Header.Get("Content-Type"))
+		}
+		if f.Response == nil || f.Response.Status != http.StatusOK {
+			t.Errorf("captured response = %+v", f.Response)
+		}
+		return
+	}
+	t.Errorf("no confirmed body finding in %+v", findings.Findings())
+}
@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of status codes or response sizes
+type Range struct {
+	Min, Max int
+}
+
+// Ranges is a list of ranges, matching a value that falls in any of them
+type Ranges []Range
+
+// ParseRanges parses a comma separated list of values and ranges such as
+// "200,301-399", or "all" to match every value
+func ParseRanges(value string) (Ranges, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(value, "all") {
+		return Ranges{{Min: 0, Max: int(^uint(0) >> 1)}}, nil
+	}
+
+	var ranges Ranges
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || lo < 0 {
+			return nil, fmt.Errorf("invalid value %q", part)
+		}
+		hi := lo
+		if isRange {
+			hi, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || hi < lo {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		ranges = append(ranges, Range{Min: lo, Max: hi})
+	}
+	return ranges, nil
+}
+
+// Contains reports whether n falls in any of the ranges
+func (r Ranges) Contains(n int) bool {
+	for _, rng := range r {
+		if n >= rng.Min && n <= rng.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseFilter decides which HTTP probe responses are worth loading in the
+// browser, with ffuf's semantics: a response must hit one of the matchers when
+// any are set, and is dropped when it hits any of the filters.
+type ResponseFilter struct {
+	MatchStatus  Ranges
+	FilterStatus Ranges
+	MatchSize    Ranges
+	FilterSize   Ranges
+}
+
+// Enabled reports whether any matcher or filter is set
+func (f ResponseFilter) Enabled() bool {
+	return len(f.MatchStatus) > 0 || len(f.FilterStatus) > 0 || len(f.MatchSize) > 0 || len(f.FilterSize) > 0
+}
+
+// Allows reports whether a response with the given status code and body size
+// in bytes gets past the filter
+func (f ResponseFilter) Allows(status int, size int) bool {
+	if len(f.MatchStatus) > 0 || len(f.MatchSize) > 0 {
+		if !f.MatchStatus.Contains(status) && !f.MatchSize.Contains(size) {
+			return false
+		}
+	}
+	return !f.FilterStatus.Contains(status) && !f.FilterSize.Contains(size)
+}
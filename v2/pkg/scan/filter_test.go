@@ -0,0 +1,95 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+)
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := ParseRanges(" 200, 301-399 ,")
+	if err != nil {
+		t.Fatalf("ParseRanges: %v", err)
+	}
+	for n, want := range map[int]bool{200: true, 301: true, 350: true, 399: true, 201: false, 400: false} {
+		if ranges.Contains(n) != want {
+			t.Errorf("Contains(%d) = %v, want %v", n, !want, want)
+		}
+	}
+	if all, _ := ParseRanges("all"); !all.Contains(0) || !all.Contains(1<<40) {
+		t.Error("all doesn't match every value")
+	}
+	for _, value := range []string{"abc", "-1", "400-300", "200-x"} {
+		if _, err := ParseRanges(value); err == nil {
+			t.Errorf("ParseRanges(%q) accepted an invalid list", value)
+		}
+	}
+}
+
+func TestResponseFilterAllows(t *testing.T) {
+	must := func(value string) Ranges {
+		ranges, err := ParseRanges(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ranges
+	}
+	for _, tt := range []struct {
+		name         string
+		filter       ResponseFilter
+		status, size int
+		want         bool
+	}{
+		{"no filters", ResponseFilter{}, 404, 10, true},
+		{"matched status", ResponseFilter{MatchStatus: must("200")}, 200, 10, true},
+		{"unmatched status", ResponseFilter{MatchStatus: must("200")}, 302, 10, false},
+		{"filtered status", ResponseFilter{FilterStatus: must("301-399")}, 302, 10, false},
+		{"unfiltered status", ResponseFilter{FilterStatus: must("301-399")}, 200, 10, true},
+		{"matched size", ResponseFilter{MatchSize: must("100-200")}, 200, 150, true},
+		{"unmatched size", ResponseFilter{MatchSize: must("100-200")}, 200, 10, false},
+		{"filtered size", ResponseFilter{FilterSize: must("1234")}, 200, 1234, false},
+		{"either matcher", ResponseFilter{MatchStatus: must("500"), MatchSize: must("10")}, 200, 10, true},
+		{"filter beats matcher", ResponseFilter{MatchStatus: must("200"), FilterSize: must("10")}, 200, 10, false},
+	} {
+		if got := tt.filter.Allows(tt.status, tt.size); got != tt.want {
+			t.Errorf("%s: Allows(%d, %d) = %v, want %v", tt.name, tt.status, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestFilteredResponsesSkipBrowser(t *testing.T) {
+	// Missing pages get a 302 without a Location, and every page is 1234 bytes
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusFound)
+		}
+		fmt.Fprint(w, strings.Repeat("x", 1234))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name   string
+		filter ResponseFilter
+		path   string
+		want   int
+	}{
+		{"match-status hit", ResponseFilter{MatchStatus: Ranges{{200, 200}}}, "/ok", 1},
+		{"match-status miss", ResponseFilter{MatchStatus: Ranges{{200, 200}}}, "/missing", 0},
+		{"filter-status hit", ResponseFilter{FilterStatus: Ranges{{300, 399}}}, "/missing", 0},
+		{"filter-status miss", ResponseFilter{FilterStatus: Ranges{{300, 399}}}, "/ok", 1},
+		{"filter-size hit", ResponseFilter{FilterSize: Ranges{{1234, 1234}}}, "/ok", 0},
+		{"match-size miss", ResponseFilter{MatchSize: Ranges{{0, 100}}}, "/ok", 0},
+	} {
+		engine := &browser.FakeEngine{}
+		s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Filter: tt.filter, Engine: engine})
+		s.Scan(server.URL+tt.path+"?q=1", "<b>", "")
+
+		if got := len(engine.Navigations()); got != tt.want {
+			t.Errorf("%s: %d navigations, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package scan
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// FragmentInjection returns a copy of u with the payload put into its fragment
+// according to mode
+func FragmentInjection(u *url.URL, payload string, mode Mode) *url.URL {
+	target := *u
+	target.Fragment = mode.Inject(target.Fragment, payload)
+	target.RawFragment = ""
+	return &target
+}
+
+// injectFragment loads link in the browser with the payload in its fragment
+// when --fragment is set. Browsers never send the fragment to the server, so
+// there is no HTTP probe, only the page's own scripts can pick it up from
+// location.hash.
+func (s *Scanner) injectFragment(payload string, link string) {
+	if !s.Config.Fragment || s.context().Err() != nil {
+		return
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		s.log.Error("Error parsing URL: " + err.Error())
+		return
+	}
+	filled, token := s.withToken(payload, link, injection{point: report.PointFragment})
+	target := FragmentInjection(u, filled, s.mode())
+	s.log.Notice("Fragment: " + target.String())
+
+	if s.Config.DryRun {
+		s.log.Printf("%s", "\n--- Dry run ("+report.PointFragment+") ---\nBrowser only: "+target.String()+"\n\n")
+		return
+	}
+	if s.Config.Budget != nil {
+		if !s.Config.Budget.Take() {
+			return
+		}
+		defer s.Config.Budget.Finish()
+	}
+	s.pace(target.Host)
+
+	var headers map[string]interface{}
+	if len(s.Config.Headers) > 0 {
+		headers = make(map[string]interface{})
+		for key := range s.Config.Headers {
+			headers[key] = s.Config.Headers.Get(key)
+		}
+	}
+
+	finding := s.finding(http.MethodGet, payload, target.String(), "", injection{point: report.PointFragment, token: token})
+	if s.Config.UserAgents != nil && s.Config.Headers.Get("User-Agent") == "" {
+		if headers == nil {
+			headers = make(map[string]interface{})
+		}
+		finding.UserAgent = s.Config.UserAgents.Next()
+		headers["User-Agent"] = finding.UserAgent
+	}
+	s.browse(finding, target, headers, nil)
+}
@@ -0,0 +1,97 @@
+package scan
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+func TestFragmentInjection(t *testing.T) {
+	u, _ := url.Parse("https://target.example/app?x=1#/home")
+	if got := FragmentInjection(u, "<b>", ModeReplace).String(); got != "https://target.example/app?x=1#%3Cb%3E" {
+		t.Errorf("replaced fragment = %s", got)
+	}
+	if got := FragmentInjection(u, "<b>", ModeAppend).Fragment; got != "/home<b>" {
+		t.Errorf("appended fragment = %q, want /home<b>", got)
+	}
+	if u.Fragment != "/home" {
+		t.Errorf("FragmentInjection changed the original URL to %s", u)
+	}
+}
+
+// hashSink is a FakeEngine Sinks function for a page writing location.hash
+// into innerHTML
+func hashSink(link string) []browser.Sink {
+	u, err := url.Parse(link)
+	if err != nil || u.Fragment == "" {
+		return nil
+	}
+	return []browser.Sink{{Sink: "innerHTML", Value: u.Fragment, URL: link}}
+}
+
+func TestFragmentModeReachesSink(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	engine := &browser.FakeEngine{Sinks: hashSink}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, Fragment: true, Engine: engine, Report: findings})
+	const payload = "<img src=x onerror=alert(1)>"
+	s.Scan(server.URL+"/app", payload, "")
+
+	// The fragment never reaches the server
+	for _, req := range requests() {
+		if strings.Contains(req.Path, "img") || len(req.Query) > 0 {
+			t.Errorf("the payload was sent to the server: %+v", req)
+		}
+	}
+
+	var navigated bool
+	for _, nav := range engine.Navigations() {
+		u, _ := url.Parse(nav.URL)
+		navigated = navigated || u.Fragment == payload
+	}
+	if !navigated {
+		t.Errorf("navigations %+v, want one with the payload in the fragment", engine.Navigations())
+	}
+
+	var sinks []report.Finding
+	for _, f := range findings.Findings() {
+		if f.Sink != "" {
+			sinks = append(sinks, f)
+		}
+	}
+	if len(sinks) != 1 || sinks[0].InjectionPoint != report.PointFragment || sinks[0].Sink != "innerHTML" {
+		t.Errorf("sink findings = %+v, want the fragment written to innerHTML", sinks)
+	}
+}
+
+func TestFragmentModeConfirmsDialog(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	engine := &browser.FakeEngine{Fire: browser.FireOn("#%3Cimg", "1")}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, Fragment: true, Engine: engine, Report: findings})
+	s.Scan(server.URL+"/app", "<img src=x onerror=alert(1)>", "")
+
+	for _, f := range findings.Findings() {
+		if f.Confirmed && f.InjectionPoint == report.PointFragment {
+			return
+		}
+	}
+	t.Errorf("findings = %+v, want a confirmed fragment finding", findings.Findings())
+}
+
+func TestFragmentModeOff(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, Engine: engine})
+	s.Scan(server.URL+"/app", "<b>", "")
+
+	for _, nav := range engine.Navigations() {
+		if strings.Contains(nav.URL, "#") {
+			t.Errorf("navigated to %s without -fragment", nav.URL)
+		}
+	}
+}
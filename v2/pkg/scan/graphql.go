@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// GraphQL is the operation sent in GraphQL mode, with the payload placed in
+// one of its string variables at a time
+type GraphQL struct {
+	// Query is the GraphQL document, e.g. 'mutation($msg: String!) { comment(text: $msg) { id } }'
+	Query string
+
+	// Operation names the operation to run when Query holds several
+	Operation string
+
+	// Variables is a JSON object with the variables' values, string ones are injected
+	Variables string
+}
+
+// graphQLRequest is the JSON body of a GraphQL request over HTTP
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLInjections returns one request body per string variable of the
+// operation with the payload fill returns for that variable put into it
+// according to mode. The bodies are JSON, so the payload is escaped as a JSON
+// string wherever it is placed.
+func GraphQLInjections(op GraphQL, fill Filler, mode Mode) ([]BodyInjection, error) {
+	variables := map[string]interface{}{}
+	if strings.TrimSpace(op.Variables) != "" {
+		if err := json.Unmarshal([]byte(op.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("invalid GraphQL variables: %w", err)
+		}
+	}
+
+	var names []string
+	for name, value := range variables {
+		if _, ok := value.(string); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no string GraphQL variables to inject into")
+	}
+	sort.Strings(names)
+
+	var injections []BodyInjection
+	for _, name := range names {
+		injected := make(map[string]interface{}, len(variables))
+		for k, v := range variables {
+			injected[k] = v
+		}
+
+		value, token := fill(name)
+		injected[name] = mode.Inject(variables[name].(string), value)
+
+		// Keep the payload's <, > and & as is rather than \u escapes
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(graphQLRequest{Query: op.Query, OperationName: op.Operation, Variables: injected})
+		if err != nil {
+			return nil, err
+		}
+
+		injections = append(injections, BodyInjection{
+			Field:       name,
+			Body:        strings.TrimSuffix(buf.String(), "\n"),
+			ContentType: "application/json",
+			Token:       token,
+		})
+	}
+	return injections, nil
+}
+
+// injectGraphQL posts the GraphQL operation to link with the payload in each
+// of its string variables in turn when --graphql is set
+func (s *Scanner) injectGraphQL(payload string, link string) {
+	if s.Config.GraphQL.Query == "" {
+		return
+	}
+
+	injections, err := GraphQLInjections(s.Config.GraphQL, s.filler(payload, link, report.PointGraphQL), s.mode())
+	if err != nil {
+		s.log.Error("Error building GraphQL request: " + err.Error())
+		return
+	}
+
+	for _, body := range injections {
+		if s.context().Err() != nil {
+			return
+		}
+		s.log.Notice("GraphQL variable: " + body.Field)
+		if !s.sendBody(http.MethodPost, payload, link, body, report.PointGraphQL) {
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package scan
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+const testGraphQLQuery = `mutation Comment($msg: String!, $post: ID!) { comment(text: $msg, post: $post) { id } }`
+
+func TestGraphQLInjectionsEscapePayload(t *testing.T) {
+	op := GraphQL{Query: testGraphQLQuery, Operation: "Comment", Variables: `{"msg":"hi","post":"42","count":3}`}
+	const payload = `"><img src=x onerror="alert('\\x')">`
+	injections, err := GraphQLInjections(op, Fill(payload), ModeReplace)
+	if err != nil {
+		t.Fatalf("GraphQLInjections: %v", err)
+	}
+	if len(injections) != 2 {
+		t.Fatalf("%d injections, want one per string variable", len(injections))
+	}
+
+	for i, name := range []string{"msg", "post"} {
+		body := injections[i]
+		if body.Field != name || body.ContentType != "application/json" {
+			t.Errorf("injection %d = %s as %s, want %s as application/json", i, body.Field, body.ContentType, name)
+		}
+		var got graphQLRequest
+		if err := json.Unmarshal([]byte(body.Body), &got); err != nil {
+			t.Fatalf("invalid JSON body %s: %v", body.Body, err)
+		}
+		if got.Query != testGraphQLQuery || got.OperationName != "Comment" {
+			t.Errorf("operation = %q %q", got.OperationName, got.Query)
+		}
+		if got.Variables[name] != payload {
+			t.Errorf("variables.%s = %q, want the payload intact", name, got.Variables[name])
+		}
+		if got.Variables["count"] != 3.0 {
+			t.Errorf("variables.count = %v, want 3 left alone", got.Variables["count"])
+		}
+	}
+
+	if _, err := GraphQLInjections(GraphQL{Query: testGraphQLQuery, Variables: `{"count":3}`}, Fill(payload), ModeReplace); err == nil {
+		t.Error("GraphQLInjections accepted variables without a string to inject into")
+	}
+}
+
+func TestGraphQLModePostsJSON(t *testing.T) {
+	server, requests := recordServer(t, `{"data":{}}`)
+	s := testScanner(t, &ScannerConfig{
+		Method:  http.MethodGet,
+		GraphQL: GraphQL{Query: testGraphQLQuery, Variables: `{"msg":"hi","post":"42"}`},
+	})
+	s.Scan(server.URL+"/graphql?q=1", "<b>", "")
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("%d requests, want one per string variable", len(got))
+	}
+	injected := make(map[string]bool)
+	for _, req := range got {
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("sent as %s %s, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+		}
+		if q := req.Query["q"]; len(q) != 1 || q[0] != "1" {
+			t.Errorf("query string changed to %v", req.Query)
+		}
+		var body graphQLRequest
+		if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+			t.Fatalf("invalid JSON body %q: %v", req.Body, err)
+		}
+		for name, value := range body.Variables {
+			if value == "<b>" {
+				injected[name] = true
+			}
+		}
+	}
+	if !injected["msg"] || !injected["post"] {
+		t.Errorf("payload sent in %v, want msg and post", injected)
+	}
+}
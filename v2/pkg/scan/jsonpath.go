@@ -0,0 +1,163 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonLeaf is a scalar value of a JSON document and the path leading to it
+type jsonLeaf struct {
+	segments []string
+	path     string // e.g. user.tags[0]
+	key      string // the closest object key, filling in {{param}}
+}
+
+// JSONInjections returns one body per scalar value of the JSON template, at
+// any depth of nested objects and arrays, with the payload put into that
+// value according to mode, filled in for the value's closest object key.
+// Paths such as user.profile.bio or items[0].name restrict the injections to
+// those values.
+func JSONInjections(template string, fill Filler, mode Mode, paths []string) ([]BodyInjection, error) {
+	doc, err := decodeJSON(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	var leaves []jsonLeaf
+	collectLeaves(doc, nil, "", "", &leaves)
+
+	if len(paths) > 0 {
+		byPath := make(map[string]jsonLeaf, len(leaves))
+		for _, leaf := range leaves {
+			byPath[strings.Join(leaf.segments, ".")] = leaf
+		}
+		var selected []jsonLeaf
+		for _, path := range paths {
+			leaf, ok := byPath[strings.Join(jsonPathSegments(path), ".")]
+			if !ok {
+				return nil, fmt.Errorf("JSON path '%s' is not a value of the body template", path)
+			}
+			selected = append(selected, leaf)
+		}
+		leaves = selected
+	}
+
+	var injections []BodyInjection
+	for _, leaf := range leaves {
+		// Decode the template afresh so every injection starts from it
+		injected, err := decodeJSON(template)
+		if err != nil {
+			return nil, err
+		}
+
+		value, token := fill(leaf.key)
+		injected = setJSONValue(injected, leaf.segments, value, mode)
+
+		// Keep the payload's <, > and & as is rather than \u escapes
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(injected); err != nil {
+			return nil, err
+		}
+
+		injections = append(injections, BodyInjection{
+			Field:       leaf.path,
+			Body:        strings.TrimSuffix(buf.String(), "\n"),
+			ContentType: "application/json",
+			Token:       token,
+		})
+	}
+	return injections, nil
+}
+
+// decodeJSON decodes a document keeping numbers as written
+func decodeJSON(template string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(template))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// collectLeaves appends the scalar values under v to leaves, object keys in
+// sorted order
+func collectLeaves(v interface{}, segments []string, path string, key string, leaves *[]jsonLeaf) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			collectLeaves(v[k], appendSegment(segments, k), childPath, k, leaves)
+		}
+	case []interface{}:
+		for i, item := range v {
+			index := strconv.Itoa(i)
+			collectLeaves(item, appendSegment(segments, index), path+"["+index+"]", key, leaves)
+		}
+	default:
+		// The document itself is only a leaf when it's inside an object or array
+		if len(segments) > 0 {
+			*leaves = append(*leaves, jsonLeaf{segments: segments, path: path, key: key})
+		}
+	}
+}
+
+// appendSegment returns segments with segment added, without sharing its array
+func appendSegment(segments []string, segment string) []string {
+	return append(segments[:len(segments):len(segments)], segment)
+}
+
+// setJSONValue puts value into the scalar at segments in doc according to
+// mode, as a string, and returns doc. Numbers and booleans are put into as
+// written, so typed mode keeps them.
+func setJSONValue(doc interface{}, segments []string, value string, mode Mode) interface{} {
+	if len(segments) == 0 {
+		switch v := doc.(type) {
+		case string:
+			return mode.Inject(v, value)
+		case json.Number:
+			return mode.Inject(v.String(), value)
+		case bool:
+			return mode.Inject(strconv.FormatBool(v), value)
+		default:
+			return mode.Inject("", value)
+		}
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[segments[0]] = setJSONValue(node[segments[0]], segments[1:], value, mode)
+	case []interface{}:
+		if i, err := strconv.Atoi(segments[0]); err == nil && i >= 0 && i < len(node) {
+			node[i] = setJSONValue(node[i], segments[1:], value, mode)
+		}
+	}
+	return doc
+}
+
+// jsonPathSegments splits a path such as user.tags[0] or user.tags.0 into its
+// object keys and array indexes
+func jsonPathSegments(path string) []string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
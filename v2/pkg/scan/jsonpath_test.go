@@ -0,0 +1,125 @@
+package scan
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const jsonTemplate = `{"user":{"id":7,"profile":{"bio":"hi","tags":["a","b"]}},"items":[{"name":"x"},{"name":"y"}]}`
+
+func TestJSONInjectionAtPath(t *testing.T) {
+	var template map[string]interface{}
+	json.Unmarshal([]byte(jsonTemplate), &template)
+
+	for _, tt := range []struct {
+		path  string
+		field string
+		value func(doc map[string]interface{}) interface{}
+		reset func(doc map[string]interface{})
+	}{
+		{
+			path:  "user.profile.bio",
+			field: "user.profile.bio",
+			value: func(doc map[string]interface{}) interface{} {
+				return doc["user"].(map[string]interface{})["profile"].(map[string]interface{})["bio"]
+			},
+			reset: func(doc map[string]interface{}) {
+				doc["user"].(map[string]interface{})["profile"].(map[string]interface{})["bio"] = "hi"
+			},
+		},
+		{
+			path:  "items[1].name",
+			field: "items[1].name",
+			value: func(doc map[string]interface{}) interface{} {
+				return doc["items"].([]interface{})[1].(map[string]interface{})["name"]
+			},
+			reset: func(doc map[string]interface{}) {
+				doc["items"].([]interface{})[1].(map[string]interface{})["name"] = "y"
+			},
+		},
+		{
+			path:  "user.profile.tags.0",
+			field: "user.profile.tags[0]",
+			value: func(doc map[string]interface{}) interface{} {
+				return doc["user"].(map[string]interface{})["profile"].(map[string]interface{})["tags"].([]interface{})[0]
+			},
+			reset: func(doc map[string]interface{}) {
+				doc["user"].(map[string]interface{})["profile"].(map[string]interface{})["tags"].([]interface{})[0] = "a"
+			},
+		},
+	} {
+		injections, err := JSONInjections(jsonTemplate, Fill(`"><b>`), ModeReplace, []string{tt.path})
+		if err != nil {
+			t.Fatalf("JSONInjections(%s): %v", tt.path, err)
+		}
+		if len(injections) != 1 || injections[0].Field != tt.field || injections[0].ContentType != "application/json" {
+			t.Fatalf("JSONInjections(%s) = %+v", tt.path, injections)
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(injections[0].Body), &doc); err != nil {
+			t.Fatalf("invalid JSON %q: %v", injections[0].Body, err)
+		}
+		if got := tt.value(doc); got != `"><b>` {
+			t.Errorf("%s = %v, want the payload", tt.path, got)
+		}
+		// Everything else is left as in the template
+		tt.reset(doc)
+		if !reflect.DeepEqual(doc, template) {
+			t.Errorf("injecting at %s changed other values: %s", tt.path, injections[0].Body)
+		}
+	}
+}
+
+func TestJSONInjectionsEveryLeaf(t *testing.T) {
+	injections, err := JSONInjections(jsonTemplate, Fill("<b>"), ModeAppend, nil)
+	if err != nil {
+		t.Fatalf("JSONInjections: %v", err)
+	}
+	var fields []string
+	for _, injection := range injections {
+		fields = append(fields, injection.Field)
+		if !json.Valid([]byte(injection.Body)) {
+			t.Errorf("invalid JSON for %s: %s", injection.Field, injection.Body)
+		}
+	}
+	want := []string{"items[0].name", "items[1].name", "user.id", "user.profile.bio", "user.profile.tags[0]", "user.profile.tags[1]"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %q, want %q", fields, want)
+	}
+
+	// Append mode keeps the values, numbers becoming strings
+	if injections[3].Body != `{"items":[{"name":"x"},{"name":"y"}],"user":{"id":7,"profile":{"bio":"hi<b>","tags":["a","b"]}}}` {
+		t.Errorf("appended bio = %s", injections[3].Body)
+	}
+	if injections[2].Body != `{"items":[{"name":"x"},{"name":"y"}],"user":{"id":"7<b>","profile":{"bio":"hi","tags":["a","b"]}}}` {
+		t.Errorf("injected id = %s", injections[2].Body)
+	}
+}
+
+func TestJSONInjectionUnknownPath(t *testing.T) {
+	for _, path := range []string{"user.name", "items[2].name", "user.profile"} {
+		if _, err := JSONInjections(jsonTemplate, Fill("<b>"), ModeReplace, []string{path}); err == nil {
+			t.Errorf("path %s accepted", path)
+		}
+	}
+}
+
+func TestJSONPathScan(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodPost, Data: jsonTemplate, JSONPaths: []string{"items[0].name"}})
+	s.Scan(server.URL+"/api", "<b>", "")
+
+	var bodies []string
+	for _, req := range requests() {
+		if req.Body != "" {
+			bodies = append(bodies, req.Body)
+		}
+	}
+	want := `{"items":[{"name":"<b>"},{"name":"y"}],"user":{"id":7,"profile":{"bio":"hi","tags":["a","b"]}}}`
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("bodies = %q, want only %s", bodies, want)
+	}
+}
@@ -0,0 +1,70 @@
+package scan
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mode is how a payload is put into the value of the parameter, header, field
+// or path segment under test
+type Mode string
+
+// Injection modes
+const (
+	// ModeReplace discards the value for the payload
+	ModeReplace Mode = "replace"
+
+	// ModeAppend keeps the value, followed by the payload
+	ModeAppend Mode = "append"
+
+	// ModePrefix keeps the value, preceded by the payload
+	ModePrefix Mode = "prefix"
+
+	// ModeTyped replaces the value unless it is a number or boolean, which is
+	// kept ahead of the payload so strictly typed backends still parse it
+	ModeTyped Mode = "typed"
+)
+
+// ParseMode parses an injection mode name, empty meaning ModeReplace
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return ModeReplace, nil
+	case ModeReplace, ModeAppend, ModePrefix, ModeTyped:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown injection mode '%s', expected replace, append, prefix or typed", value)
+	}
+}
+
+// typedValue matches values a typed backend parses as a number or boolean
+var typedValue = regexp.MustCompile(`^(?i:-?[0-9]+(\.[0-9]+)?|true|false)$`)
+
+// Inject returns value with the payload put into it according to m
+func (m Mode) Inject(value string, payload string) string {
+	switch m {
+	case ModeAppend:
+		return value + payload
+	case ModePrefix:
+		return payload + value
+	case ModeTyped:
+		if typedValue.MatchString(value) {
+			return value + payload
+		}
+		return payload
+	default:
+		return payload
+	}
+}
+
+// mode returns the configured injection mode, falling back on AppendMode
+func (s *Scanner) mode() Mode {
+	if s.Config.InjectMode != "" {
+		return s.Config.InjectMode
+	}
+	if s.Config.AppendMode {
+		return ModeAppend
+	}
+	return ModeReplace
+}
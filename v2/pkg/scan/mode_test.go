@@ -0,0 +1,111 @@
+package scan
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestModeInject(t *testing.T) {
+	for _, tt := range []struct {
+		mode  Mode
+		value string
+		want  string
+	}{
+		{ModeReplace, "alice", "<b>"},
+		{ModeAppend, "alice", "alice<b>"},
+		{ModePrefix, "alice", "<b>alice"},
+		{ModeTyped, "alice", "<b>"},
+		{ModeTyped, "42", "42<b>"},
+		{ModeTyped, "-1.5", "-1.5<b>"},
+		{ModeTyped, "TRUE", "TRUE<b>"},
+		{ModeTyped, "4e2", "<b>"},
+		{ModeAppend, "", "<b>"},
+	} {
+		if got := tt.mode.Inject(tt.value, "<b>"); got != tt.want {
+			t.Errorf("%s.Inject(%q) = %q, want %q", tt.mode, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for value, want := range map[string]Mode{"": ModeReplace, "Append": ModeAppend, " prefix ": ModePrefix, "typed": ModeTyped} {
+		if got, err := ParseMode(value); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseMode("suffix"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+// injectedValues scans with mode and returns the values the name and id
+// parameters were sent with when injected, for query, form and JSON parameters
+func injectedValues(t *testing.T, mode Mode) map[string]string {
+	t.Helper()
+	server, requests := recordServer(t, "ok")
+	styles := []struct {
+		style, method, link, data string
+	}{
+		{"query", http.MethodGet, server.URL + "/?name=alice&id=7", ""},
+		{"form", http.MethodPost, server.URL + "/", "name=alice&id=7"},
+		{"json", http.MethodPost, server.URL + "/", `{"name":"alice","id":7}`},
+	}
+
+	values := make(map[string]string)
+	for _, style := range styles {
+		before := len(requests())
+		s := testScanner(t, &ScannerConfig{Method: style.method, IsParameters: true, Data: style.data, InjectMode: mode})
+		s.Scan(style.link, "<b>", "")
+
+		for _, req := range requests()[before:] {
+			sent := make(map[string]string)
+			switch style.style {
+			case "query":
+				for name, v := range req.Query {
+					sent[name] = v[0]
+				}
+			case "form":
+				form, _ := url.ParseQuery(req.Body)
+				for name := range form {
+					sent[name] = form.Get(name)
+				}
+			case "json":
+				var body map[string]interface{}
+				json.Unmarshal([]byte(req.Body), &body)
+				for name, v := range body {
+					if s, ok := v.(string); ok {
+						sent[name] = s
+					}
+				}
+			}
+			for name, value := range sent {
+				if value != "alice" && value != "7" {
+					values[style.style+" "+name] = value
+				}
+			}
+		}
+	}
+	return values
+}
+
+func TestModesAcrossParameterStyles(t *testing.T) {
+	for _, tt := range []struct {
+		mode     Mode
+		name, id string
+	}{
+		{ModeReplace, "<b>", "<b>"},
+		{ModeAppend, "alice<b>", "7<b>"},
+		{ModePrefix, "<b>alice", "<b>7"},
+		{ModeTyped, "<b>", "7<b>"},
+	} {
+		got := injectedValues(t, tt.mode)
+		for _, style := range []string{"query", "form", "json"} {
+			if got[style+" name"] != tt.name || got[style+" id"] != tt.id {
+				t.Errorf("%s mode, %s parameters: name=%q id=%q, want name=%q id=%q",
+					tt.mode, style, got[style+" name"], got[style+" id"], tt.name, tt.id)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// multipartFileContent is the content of the file part of multipart bodies
+const multipartFileContent = "bxss\n"
+
+// MultipartInjections returns one multipart/form-data body per field of the
+// form encoded template with the payload fill returns for that field put into
+// it according to mode. When fileField is set every body also carries a file
+// part of that name, and one more body has the payload as its filename.
+func MultipartInjections(template string, fileField string, fill Filler, mode Mode) ([]BodyInjection, error) {
+	values, err := url.ParseQuery(strings.TrimSpace(template))
+	if err != nil {
+		return nil, fmt.Errorf("invalid multipart fields: %w", err)
+	}
+
+	fieldNames := make([]string, 0, len(values))
+	for field := range values {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+
+	var injections []BodyInjection
+	for _, field := range fieldNames {
+		injected := url.Values{}
+		for k, v := range values {
+			injected[k] = append([]string(nil), v...)
+		}
+
+		value, token := fill(field)
+		injected.Set(field, mode.Inject(values.Get(field), value))
+
+		injection, err := multipartBody(field, fieldNames, injected, fileField, "bxss.txt")
+		if err != nil {
+			return nil, err
+		}
+		injection.Token = token
+		injections = append(injections, injection)
+	}
+
+	if fileField != "" {
+		value, token := fill(fileField)
+		injection, err := multipartBody(fileField+".filename", fieldNames, values, fileField, mode.Inject("bxss.txt", value))
+		if err != nil {
+			return nil, err
+		}
+		injection.Token = token
+		injections = append(injections, injection)
+	}
+	return injections, nil
+}
+
+// multipartBody encodes the fields, in order, and the file part when fileField
+// is set, as a multipart/form-data body under a fresh boundary
+func multipartBody(name string, fieldNames []string, values url.Values, fileField string, filename string) (BodyInjection, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, field := range fieldNames {
+		for _, value := range values[field] {
+			if err := w.WriteField(field, value); err != nil {
+				return BodyInjection{}, err
+			}
+		}
+	}
+	if fileField != "" {
+		part, err := w.CreateFormFile(fileField, filename)
+		if err != nil {
+			return BodyInjection{}, err
+		}
+		if _, err := part.Write([]byte(multipartFileContent)); err != nil {
+			return BodyInjection{}, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return BodyInjection{}, err
+	}
+
+	return BodyInjection{
+		Field:       name,
+		Body:        buf.String(),
+		ContentType: w.FormDataContentType(),
+	}, nil
+}
+
+// injectMultipart sends the payload in each field of the --multipart template,
+// and the filename of the --multipart-file part, when method carries a body
+func (s *Scanner) injectMultipart(method string, payload string, link string) {
+	if (s.Config.Multipart == "" && s.Config.MultipartFile == "") || !hasBody(method) {
+		return
+	}
+
+	injections, err := MultipartInjections(s.Config.Multipart, s.Config.MultipartFile, s.filler(payload, link, report.PointMultipart), s.mode())
+	if err != nil {
+		s.log.Error("Error building multipart body: " + err.Error())
+		return
+	}
+
+	for _, body := range injections {
+		if s.context().Err() != nil {
+			return
+		}
+		s.log.Notice("Multipart field: " + body.Field)
+		if !s.sendBody(method, payload, link, body, report.PointMultipart) {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// multipartPart is a part of a multipart body read back by parseMultipart
+type multipartPart struct {
+	Name, Filename, Value string
+}
+
+// parseMultipart parses body, sent with contentType, into its parts
+func parseMultipart(t *testing.T, contentType string, body string) []multipartPart {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		t.Fatalf("Content-Type %q isn't multipart/form-data with a boundary", contentType)
+	}
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	var parts []multipartPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("invalid multipart body: %v", err)
+		}
+		value, _ := io.ReadAll(part)
+		parts = append(parts, multipartPart{part.FormName(), part.FileName(), string(value)})
+	}
+}
+
+func TestMultipartInjections(t *testing.T) {
+	const payload = `"><svg onload=alert(1)>`
+	injections, err := MultipartInjections("title=t&comment=hi", "avatar", Fill(payload), ModeReplace)
+	if err != nil {
+		t.Fatalf("MultipartInjections: %v", err)
+	}
+	if len(injections) != 3 {
+		t.Fatalf("%d bodies, want one per field and one for the filename", len(injections))
+	}
+
+	// Each body carries the payload in exactly one place
+	want := map[string]string{"comment": "comment", "title": "title", "avatar.filename": "avatar filename"}
+	for _, body := range injections {
+		parts := parseMultipart(t, body.ContentType, body.Body)
+		if len(parts) != 3 {
+			t.Fatalf("%s body has %d parts, want both fields and the file", body.Field, len(parts))
+		}
+		var injected []string
+		for _, part := range parts {
+			if part.Value == payload {
+				injected = append(injected, part.Name)
+			}
+			if part.Filename == payload {
+				injected = append(injected, part.Name+" filename")
+			}
+		}
+		if len(injected) != 1 || injected[0] != want[body.Field] {
+			t.Errorf("%s body carries the payload in %q, want %s only", body.Field, injected, want[body.Field])
+		}
+	}
+}
+
+func TestMultipartModeSendsField(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodPost, Multipart: "note=hi"})
+	s.Scan(server.URL+"/upload", "<b>", "")
+
+	var found bool
+	for _, req := range requests() {
+		if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+			continue
+		}
+		for _, part := range parseMultipart(t, req.Header.Get("Content-Type"), req.Body) {
+			found = found || (part.Name == "note" && part.Value == "<b>")
+		}
+	}
+	if !found {
+		t.Error("no multipart request carried the payload in the note field")
+	}
+}
@@ -0,0 +1,111 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// DefaultMaxRedirects caps the redirects a probe follows when
+// ScannerConfig.MaxRedirects is unset
+const DefaultMaxRedirects = 10
+
+// SinkRedirect is the Sink of findings whose payload ended up in the Location
+// of a redirect, where a javascript: or attacker URL is an open redirect
+const SinkRedirect = "redirect"
+
+// checkRedirect returns the redirect policy of the probes. With redirects
+// followed, the chain stops at the last response before a hop over the limit,
+// a hop back to a URL already visited or a hop to a scheme other than
+// http(s), so that response and its Location are still inspected.
+func checkRedirect(config *ScannerConfig) func(req *http.Request, via []*http.Request) error {
+	limit := config.MaxRedirects
+	if limit <= 0 {
+		limit = DefaultMaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if !config.FollowRedirects || len(via) > limit {
+			return http.ErrUseLastResponse
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return http.ErrUseLastResponse
+		}
+		for _, previous := range via {
+			if previous.URL.String() == req.URL.String() {
+				return http.ErrUseLastResponse
+			}
+		}
+		if !config.Scope.Allows(req.URL.Host) {
+			return fmt.Errorf("not following redirect to out-of-scope host %s", req.URL.Host)
+		}
+		return nil
+	}
+}
+
+// reportRedirects logs the redirects a probe followed, and reports finding as
+// an open redirect when the payload ended up in where one of them points
+func (s *Scanner) reportRedirects(result probeResult, finding report.Finding, request *http.Request) {
+	if len(result.chain) > 0 {
+		s.log.Notice("Redirected: " + strings.Join(result.chain, " -> "))
+	}
+	target := result.redirectTarget(finding.Payload)
+	if target == "" {
+		return
+	}
+
+	s.log.Success("Payload redirected to: " + target)
+	finding.UserAgent = request.Header.Get("User-Agent")
+	finding.Protocol = result.proto
+	finding.Redirects = result.chain
+	finding.Sink = SinkRedirect
+	finding.Evidence = "redirects to " + target
+	s.writeFinding(finding)
+}
+
+// redirectChain returns the URLs requested on the way to response, starting
+// with the first, or nil when no redirect was followed
+func redirectChain(response *http.Response) []string {
+	var chain []string
+	for request := response.Request; request != nil; {
+		chain = append([]string{request.URL.String()}, chain...)
+		if request.Response == nil {
+			break
+		}
+		request = request.Response.Request
+	}
+	if len(chain) < 2 {
+		return nil
+	}
+	return chain
+}
+
+// redirectTarget returns the URL redirected to, a hop of the chain or the
+// unfollowed Location of the response, that the payload takes over, or "".
+// A URL merely carrying the payload along in its query doesn't count, it has
+// to start with it, as a javascript: URL or a URL of the payload's host would.
+func (r probeResult) redirectTarget(payload string) string {
+	marker := payloadMarker(payload)
+	if marker == "" {
+		return ""
+	}
+
+	var targets []string
+	if len(r.chain) > 0 {
+		targets = append(targets, r.chain[1:]...)
+	}
+	if location := r.header.Get("Location"); location != "" && r.status >= 300 && r.status < 400 {
+		targets = append(targets, location)
+	}
+	for _, target := range targets {
+		if strings.HasPrefix(target, marker) {
+			return target
+		}
+		if unescaped, err := url.QueryUnescape(target); err == nil && strings.HasPrefix(unescaped, marker) {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// redirectServer serves a chain of three redirects from /start to /end, a
+// loop between /loop1 and /loop2, /go redirecting to its next parameter and
+// /wrap carrying it along in the query, recording the paths requested
+func redirectServer(t *testing.T) (*httptest.Server, func(path string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	hops := map[string]string{"/start": "/a", "/a": "/b", "/b": "/end", "/loop1": "/loop2", "/loop2": "/loop1"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if next, ok := hops[r.URL.Path]; ok {
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/go":
+			w.Header().Set("Location", r.URL.Query().Get("next"))
+			w.WriteHeader(http.StatusFound)
+			return
+		case "/wrap":
+			w.Header().Set("Location", "/home?from="+url.QueryEscape(r.URL.Query().Get("next")))
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	t.Cleanup(server.Close)
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+}
+
+func TestRedirectChainRecorded(t *testing.T) {
+	server, _ := redirectServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, FollowRedirects: true, Report: findings})
+	s.Scan(server.URL+"/start?q=1", "<b>", "")
+
+	got := findings.Findings()
+	if len(got) != 1 {
+		t.Fatalf("%d findings, want 1", len(got))
+	}
+	want := []string{server.URL + "/start?q=%3Cb%3E", server.URL + "/a", server.URL + "/b", server.URL + "/end"}
+	if !reflect.DeepEqual(got[0].Redirects, want) {
+		t.Errorf("redirect chain = %q, want %q", got[0].Redirects, want)
+	}
+}
+
+func TestRedirectLimitEnforced(t *testing.T) {
+	server, hits := redirectServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, FollowRedirects: true, MaxRedirects: 2, Report: findings})
+	s.Scan(server.URL+"/start?q=1", "<b>", "")
+
+	if n := hits("/end"); n != 0 {
+		t.Errorf("followed a third redirect with a limit of 2")
+	}
+	got := findings.Findings()
+	want := []string{server.URL + "/start?q=%3Cb%3E", server.URL + "/a", server.URL + "/b"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Redirects, want) {
+		t.Errorf("findings = %+v, want one with the chain cut at %q", got, want)
+	}
+}
+
+func TestRedirectLoopStopped(t *testing.T) {
+	server, hits := redirectServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, FollowRedirects: true, Report: findings})
+	s.Scan(server.URL+"/loop1", "<b>", "")
+
+	if n := hits("/loop1") + hits("/loop2"); n > 3 {
+		t.Errorf("%d requests around the redirect loop", n)
+	}
+	got := findings.Findings()
+	want := []string{server.URL + "/loop1", server.URL + "/loop2"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Redirects, want) {
+		t.Errorf("findings = %+v, want one with the chain stopped at %q", got, want)
+	}
+}
+
+func TestRedirectsNotFollowedByDefault(t *testing.T) {
+	server, hits := redirectServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Report: findings})
+	s.Scan(server.URL+"/start?q=1", "<b>", "")
+
+	if hits("/a") != 0 {
+		t.Error("followed a redirect without FollowRedirects")
+	}
+	if got := findings.Findings(); len(got) != 1 || got[0].Redirects != nil {
+		t.Errorf("findings = %+v, want one without a redirect chain", got)
+	}
+}
+
+func TestOpenRedirectToPayloadReported(t *testing.T) {
+	server, _ := redirectServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Report: findings})
+	s.Scan(server.URL+"/go?next=/home", "javascript:alert(1)", "")
+
+	var redirects []report.Finding
+	for _, f := range findings.Findings() {
+		if f.Sink == SinkRedirect {
+			redirects = append(redirects, f)
+		}
+	}
+	if len(redirects) != 1 || redirects[0].Param != "next" || redirects[0].Evidence != "redirects to javascript:alert(1)" {
+		t.Errorf("redirect findings = %+v, want one for next", redirects)
+	}
+
+	// A payload carried along in the redirect's query isn't an open redirect
+	findings = report.NewCollector()
+	s = testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Report: findings})
+	s.Scan(server.URL+"/wrap?next=/home", "javascript:alert(1)", "")
+	for _, f := range findings.Findings() {
+		if f.Sink == SinkRedirect {
+			t.Errorf("reported an open redirect for a payload in the query: %+v", f)
+		}
+	}
+}
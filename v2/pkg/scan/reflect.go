@@ -0,0 +1,106 @@
+package scan
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxReflectBody bounds how much of a response is searched for a reflection
+const maxReflectBody = 1 << 20
+
+// Reflection kinds reported by reflected
+const (
+	reflectedFull    = "full"
+	reflectedPartial = "partial"
+)
+
+// probeResult is the response to an HTTP probe sent ahead of the browser
+type probeResult struct {
+	proto  string
+	status int
+	size   int
+	header http.Header
+	body   []byte
+
+	// chain holds the URLs redirected through, nil when there were none
+	chain []string
+}
+
+// probe sends request over HTTP ahead of the browser. Only the first
+// maxReflectBody bytes of the body are kept, but size counts all of it. It
+// reports false when the request failed.
+func (s *Scanner) probe(request *http.Request) (probeResult, bool) {
+	probe := request.Clone(s.context())
+
+	response, err := s.do(probe)
+	if err != nil {
+		s.log.Error("Error making request: " + err.Error())
+		return probeResult{}, false
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxReflectBody))
+	if err != nil {
+		s.log.Error("Error reading response body: " + err.Error())
+		return probeResult{}, false
+	}
+	rest, _ := io.Copy(io.Discard, response.Body)
+	if s.Config.Debug {
+		s.DebugRequest(probe)
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		s.DebugResponse(response)
+	}
+
+	return probeResult{
+		proto:  response.Proto,
+		status: response.StatusCode,
+		size:   len(body) + int(rest),
+		header: response.Header,
+		body:   body,
+		chain:  redirectChain(response),
+	}, true
+}
+
+// reflection reports how the payload is reflected in the response headers or
+// body, empty when it isn't
+func (r probeResult) reflection(payload string) string {
+	var headers strings.Builder
+	r.header.Write(&headers)
+	return reflected(payload, headers.String()+"\n"+string(r.body))
+}
+
+// reflected reports whether payload appears in response unchanged ("full"),
+// or only some of its fragments holding HTML or JavaScript syntax do
+// ("partial"), as when part of it is filtered. The {{param}}, {{token}} and
+// {{callback}} placeholders are skipped, as they are filled in differently for
+// each injection.
+func reflected(payload string, response string) string {
+	marker := payloadMarker(payload)
+	if marker == "" {
+		return ""
+	}
+	if strings.Contains(response, marker) {
+		return reflectedFull
+	}
+
+	for _, fragment := range strings.Fields(marker) {
+		if len(fragment) >= 4 && strings.ContainsAny(fragment, `<>"'`) && strings.Contains(response, fragment) {
+			return reflectedPartial
+		}
+	}
+	return ""
+}
+
+// payloadMarker returns the longest part of payload around the placeholders
+// filled in per injection, which is sent the same wherever the payload is injected
+func payloadMarker(payload string) string {
+	marker := ""
+	for _, part := range InjectionPlaceholders.Split(payload, -1) {
+		if len(part) > len(marker) {
+			marker = part
+		}
+	}
+	return marker
+}
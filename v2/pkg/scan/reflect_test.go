@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+func TestReflected(t *testing.T) {
+	for _, tt := range []struct {
+		payload, response, want string
+	}{
+		{`"><img src=x onerror=alert(1)>`, `<input value=""><img src=x onerror=alert(1)>">`, reflectedFull},
+		{`"><img src=x onerror=alert(1)>`, `<input value="&quot;&gt;<img src=x onerror=alert(1)>">`, reflectedPartial},
+		{`"><img src=x onerror=alert(1)>`, `<input value="&quot;&gt;&lt;img">`, ""},
+		{`<script src=//cb/{{token}}></script>`, `<script src=//cb/abc123></script>`, reflectedFull},
+	} {
+		if got := reflected(tt.payload, tt.response); got != tt.want {
+			t.Errorf("reflected(%q, %q) = %q, want %q", tt.payload, tt.response, got, tt.want)
+		}
+	}
+}
+
+// echoServer returns a server that reflects the q parameter in its page when
+// reflect is set, and ignores it otherwise
+func echoServer(t *testing.T, reflect bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if reflect {
+			io.WriteString(w, "<p>Results for "+r.URL.Query().Get("q")+"</p>")
+			return
+		}
+		io.WriteString(w, "<p>No results</p>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReflectCheckEngagesBrowserOnReflection(t *testing.T) {
+	for _, reflect := range []bool{true, false} {
+		server := echoServer(t, reflect)
+		engine := &browser.FakeEngine{}
+		s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, ReflectCheck: true, Engine: engine})
+		s.Scan(server.URL+"/search?q=1", "<svg onload=alert(1)>", "")
+
+		want := 0
+		if reflect {
+			want = 1
+		}
+		if got := len(engine.Navigations()); got != want {
+			t.Errorf("reflecting %v: %d navigations, want %d", reflect, got, want)
+		}
+	}
+}
+
+func TestReflectOnlyReportsWithoutBrowser(t *testing.T) {
+	server := echoServer(t, true)
+	engine := &browser.FakeEngine{}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, ReflectOnly: true, Engine: engine, Report: findings})
+	s.Scan(server.URL+"/search?q=1", "<svg onload=alert(1)>", "")
+
+	if n := len(engine.Navigations()); n != 0 {
+		t.Errorf("%d navigations with -reflect-only, want none", n)
+	}
+	got := findings.Findings()
+	if len(got) != 1 || got[0].Confirmed || !strings.Contains(got[0].Evidence, "reflected (full)") {
+		t.Errorf("findings = %+v, want one unconfirmed full reflection", got)
+	}
+}
+
+func TestSinkWriteReported(t *testing.T) {
+	server := echoServer(t, false)
+	const payload = "<img src=x onerror=alert(1)>"
+
+	// The page writes the q parameter into innerHTML without executing it
+	engine := &browser.FakeEngine{Sinks: func(link string) []browser.Sink {
+		u, _ := url.Parse(link)
+		return []browser.Sink{{Sink: "innerHTML", Value: u.Query().Get("q"), URL: link}}
+	}}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Engine: engine, Report: findings})
+	s.Scan(server.URL+"/?q=1", payload, "")
+
+	var sinks []report.Finding
+	for _, f := range findings.Findings() {
+		if f.Sink != "" {
+			sinks = append(sinks, f)
+		}
+	}
+	if len(sinks) != 1 || sinks[0].Sink != "innerHTML" || sinks[0].Confirmed || sinks[0].Param != "q" {
+		t.Errorf("sink findings = %+v, want one unconfirmed innerHTML write from q", sinks)
+	}
+}
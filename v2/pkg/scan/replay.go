@@ -0,0 +1,227 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// DefaultCallbackWait is how long Replay waits for a callback when
+// ScannerConfig.CallbackWait is unset
+const DefaultCallbackWait = 10 * time.Second
+
+// callbackPoll is how often Replay checks the token index for a callback
+const callbackPoll = 100 * time.Millisecond
+
+// ErrNotReplayable is returned by Replay for findings it can't confirm again:
+// those sent in a request that wasn't recorded, other than a GET the browser
+// can load on its own
+var ErrNotReplayable = errors.New("finding can't be replayed")
+
+// Replayable reports whether Replay can confirm f again: a confirmed finding
+// whose request was recorded, or one confirmed by a dialog on a page the
+// browser loaded with a GET
+func Replayable(f report.Finding) bool {
+	if !f.Confirmed {
+		return false
+	}
+	if f.InjectionPoint == report.PointCallback || f.View != "" {
+		return f.Request != nil
+	}
+	method := f.Method
+	if f.Request != nil {
+		method = f.Request.Method
+	}
+	return method == "" || strings.EqualFold(method, http.MethodGet)
+}
+
+// Replay issues the injection of a previously confirmed finding again and
+// reports whether the payload still fires, the same way it was confirmed: a
+// page with a dialog is loaded again in the browser, while a stored payload's
+// recorded request is sent again before loading its view, and a callback's
+// before waiting up to CallbackWait for its token to call back. Headers
+// redacted when the finding was recorded are left to the scanner's own
+// configuration. New findings are written to the report like any other.
+func (s *Scanner) Replay(f report.Finding) (bool, error) {
+	if !Replayable(f) {
+		return false, ErrNotReplayable
+	}
+
+	switch {
+	case f.InjectionPoint == report.PointCallback:
+		return s.replayCallback(f)
+	case f.View != "":
+		return s.replayStored(f)
+	default:
+		return s.replayPage(f)
+	}
+}
+
+// replayPage loads the page of a finding confirmed by a dialog in the browser
+func (s *Scanner) replayPage(f report.Finding) (bool, error) {
+	link := f.Target
+	if f.Request != nil && f.Request.URL != "" && f.InjectionPoint != report.PointFragment {
+		link = f.Request.URL
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false, err
+	}
+
+	headers := make(map[string]interface{})
+	for key := range s.Config.Headers {
+		headers[key] = s.Config.Headers.Get(key)
+	}
+	if f.Request != nil {
+		for key, values := range f.Request.Header {
+			if len(values) == 0 || values[0] == report.Redacted {
+				continue
+			}
+			headers[key] = values[0]
+		}
+	} else if f.Header != "" {
+		headers[f.Header] = f.Payload
+	}
+	if f.UserAgent != "" {
+		headers["User-Agent"] = f.UserAgent
+	}
+	if s.Config.DryRun {
+		s.log.Printf("%s", "\n--- Dry run (replay "+f.InjectionPoint+") ---\nBrowser only: "+u.String()+"\n\n")
+		return false, nil
+	}
+
+	// A fresh, unconfirmed copy is what the browser confirms again
+	finding := f
+	finding.Confirmed = false
+	finding.Evidence = ""
+	finding.Sink = ""
+	finding.Response = nil
+
+	before := s.Confirmed()
+	if !s.browse(finding, u, headers, nil) {
+		return false, fmt.Errorf("couldn't load %s", u.String())
+	}
+	return s.Confirmed() > before, nil
+}
+
+// replayStored sends the recorded request of a stored finding again and
+// loads its view, checking for a dialog credited to it
+func (s *Scanner) replayStored(f report.Finding) (bool, error) {
+	if err := s.replayRequest(f); err != nil || s.Config.DryRun {
+		return false, err
+	}
+
+	dialogs, err := s.viewDialogs(f.View)
+	if err != nil {
+		return false, err
+	}
+	for _, dialog := range dialogs {
+		if s.credits(f.View, dialog, f.Token) {
+			atomic.AddInt64(s.confirmed, 1)
+			stored := f
+			stored.Evidence = fmt.Sprintf("%s dialog with message %q on %s", dialog.Type, dialog.Message, f.View)
+			stored.Response = nil
+			s.writeFinding(stored)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// replayCallback sends the recorded request of a callback finding again and
+// waits for its token to call back. The callback is left for the listener
+// to report.
+func (s *Scanner) replayCallback(f report.Finding) (bool, error) {
+	if s.Config.Tokens == nil || f.Token == "" {
+		return false, ErrNotReplayable
+	}
+	s.Config.Tokens.Add(callback.Injection{
+		Token:     f.Token,
+		URL:       f.Target,
+		Point:     f.InjectionPoint,
+		Param:     f.Param,
+		Header:    f.Header,
+		Payload:   f.Payload,
+		Source:    f.Source,
+		Encodings: f.Encodings,
+	})
+
+	sent := time.Now()
+	if err := s.replayRequest(f); err != nil || s.Config.DryRun {
+		return false, err
+	}
+
+	wait := s.Config.CallbackWait
+	if wait <= 0 {
+		wait = DefaultCallbackWait
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	poll := time.NewTicker(callbackPoll)
+	defer poll.Stop()
+	for {
+		if s.Config.Tokens.FiredSince(f.Token, sent) {
+			return true, nil
+		}
+		select {
+		case <-poll.C:
+		case <-deadline.C:
+			return s.Config.Tokens.FiredSince(f.Token, sent), nil
+		case <-s.context().Done():
+			return false, s.context().Err()
+		}
+	}
+}
+
+// replayRequest sends the recorded request of f again, as it was sent apart
+// from redacted headers, which the scanner's configuration fills in
+func (s *Scanner) replayRequest(f report.Finding) error {
+	recorded := f.Request
+	request, err := http.NewRequestWithContext(s.context(), recorded.Method, recorded.URL, strings.NewReader(recorded.Body))
+	if err != nil {
+		return err
+	}
+	setHeaders(request, s.Config.Headers)
+	for name, values := range recorded.Header {
+		if len(values) == 0 || values[0] == report.Redacted {
+			continue
+		}
+		request.Header[name] = values
+	}
+	if request.Header.Get("Cookie") == "" {
+		for _, cookie := range s.Config.Cookies {
+			request.AddCookie(cookie)
+		}
+	}
+	if request.Header.Get("User-Agent") == "" {
+		if userAgent := s.userAgent(); userAgent != "" {
+			request.Header.Set("User-Agent", userAgent)
+		}
+	}
+
+	if s.Config.DryRun {
+		s.printDryRun(request, injection{point: "replay " + f.InjectionPoint})
+		return nil
+	}
+	if s.Config.Budget != nil {
+		if !s.Config.Budget.Take() {
+			return errors.New("request budget spent")
+		}
+		defer s.Config.Budget.Finish()
+	}
+	s.pace(request.URL.Host)
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
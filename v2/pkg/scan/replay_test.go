@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+func TestReplayCallbackSendsRecordedRequest(t *testing.T) {
+	tokens := callback.NewIndex()
+	fire := callback.NewToken()
+	var got []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone(), Body: string(body)})
+		if r.Header.Get("X-Token") == fire {
+			tokens.Fired(fire)
+		}
+	}))
+	defer server.Close()
+
+	s := testScanner(t, &ScannerConfig{
+		Headers:      http.Header{"Authorization": {"Bearer live"}},
+		Tokens:       tokens,
+		CallbackWait: 100 * time.Millisecond,
+	})
+	for _, tt := range []struct {
+		token string
+		want  bool
+	}{
+		{fire, true},
+		{callback.NewToken(), false},
+	} {
+		got = nil
+		f := report.Finding{
+			Target: server.URL + "/api", InjectionPoint: report.PointCallback, Token: tt.token, Confirmed: true,
+			Request: &report.Request{
+				Method: http.MethodPatch,
+				URL:    server.URL + "/api?v=1",
+				Header: http.Header{"Authorization": {report.Redacted}, "X-Token": {tt.token}},
+				Body:   "msg=" + tt.token,
+			},
+		}
+		fired, err := s.Replay(f)
+		if err != nil || fired != tt.want {
+			t.Errorf("Replay = %v, %v, want %v", fired, err, tt.want)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%d requests, want the recorded one", len(got))
+		}
+		req := got[0]
+		if req.Method != http.MethodPatch || req.Path != "/api?v=1" || req.Body != "msg="+tt.token {
+			t.Errorf("sent %s %s %q", req.Method, req.Path, req.Body)
+		}
+		if req.Header.Get("Authorization") != "Bearer live" || req.Header.Get("X-Token") != tt.token {
+			t.Errorf("sent headers %v, want the recorded ones with the redacted filled in", req.Header)
+		}
+	}
+}
+
+func TestReplayable(t *testing.T) {
+	request := &report.Request{Method: http.MethodPost, URL: "https://target.example/"}
+	for _, tt := range []struct {
+		name string
+		f    report.Finding
+		want bool
+	}{
+		{"unconfirmed", report.Finding{Method: http.MethodGet}, false},
+		{"page", report.Finding{Method: http.MethodGet, Confirmed: true}, true},
+		{"post page", report.Finding{Method: http.MethodPost, Confirmed: true}, false},
+		{"callback", report.Finding{InjectionPoint: report.PointCallback, Confirmed: true, Request: request}, true},
+		{"callback unrecorded", report.Finding{InjectionPoint: report.PointCallback, Confirmed: true}, false},
+		{"stored", report.Finding{Method: http.MethodPost, View: "https://target.example/v", Confirmed: true, Request: request}, true},
+		{"stored unrecorded", report.Finding{Method: http.MethodGet, View: "https://target.example/v", Confirmed: true}, false},
+	} {
+		if got := Replayable(tt.f); got != tt.want {
+			t.Errorf("%s: Replayable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
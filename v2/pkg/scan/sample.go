@@ -0,0 +1,30 @@
+package scan
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+)
+
+// Sample returns n of items picked at random, in their original order, or
+// items itself when there are no more than n. The pick is seeded by key, so
+// the same key always samples the same items: a URL gets the same parameters
+// for every payload, and a rerun with -resume-file the same payloads.
+func Sample(items []string, n int, key string) []string {
+	if n <= 0 || len(items) <= n {
+		return items
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	r := rand.New(rand.NewPCG(h.Sum64(), 0))
+
+	picked := r.Perm(len(items))[:n]
+	sort.Ints(picked)
+
+	sampled := make([]string, n)
+	for i, idx := range picked {
+		sampled[i] = items[idx]
+	}
+	return sampled
+}
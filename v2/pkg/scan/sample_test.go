@@ -0,0 +1,27 @@
+package scan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e", "f"}
+
+	got := Sample(items, 3, "https://target.example/")
+	if len(got) != 3 {
+		t.Fatalf("Sample = %q, want 3 items", got)
+	}
+	if !slices.IsSortedFunc(got, func(a, b string) int { return slices.Index(items, a) - slices.Index(items, b) }) {
+		t.Errorf("Sample = %q, want the items in their original order", got)
+	}
+	if again := Sample(items, 3, "https://target.example/"); !slices.Equal(got, again) {
+		t.Errorf("Sample = %q then %q for the same key", got, again)
+	}
+
+	for _, n := range []int{0, 6, 10} {
+		if got := Sample(items, n, "k"); !slices.Equal(got, items) {
+			t.Errorf("Sample(%d) = %q, want every item", n, got)
+		}
+	}
+}
@@ -2,22 +2,39 @@ package scan
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
-	"github.com/ethicalhackingplayground/bxss/v2/pkg/colours"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/logger"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/progress"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/retry"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/useragent"
 	"golang.org/x/time/rate"
 )
 
+// ParamPlaceholder is replaced in payloads with the name of the parameter or
+// header the payload is injected into
+const ParamPlaceholder = "{{param}}"
+
 type ScannerInterface interface {
 	Scan(url string)
 }
@@ -35,34 +52,188 @@ type ScannerConfig struct {
 	BrowserPath     string
 	WorkerPool      int
 	RequestFile     string
+	BrowserTimeout  time.Duration
+	Proxy           string
+	Headed          bool
+	ScreenshotDir   string
+	Cookies         []*http.Cookie
+	Headers         http.Header
+	Scope           *scope.Scope
+	RemoteBrowser   string
+	ChromeFlags     map[string]interface{}
+	DisableWebSec   bool
+	UserAgent       string
+	WindowWidth     int
+	WindowHeight    int
+	WorkerLifetime  time.Duration
+	LazyWorkers     bool
+	NoSandbox       bool
+	HookEval        bool
+	WaitIdle        int
+	PathInject      bool
+	Data            string
+	CookieParams    []string
+	InjectAll       bool
+	Retries         int
+	RetryBackoff    time.Duration
+	DryRun          bool
+	AcquireTimeout  time.Duration
+	ReleaseTimeout  time.Duration
+	ReflectCheck    bool
+	ReflectOnly     bool
+	Fragment        bool
+	GraphQL         GraphQL
+	Multipart       string
+	MultipartFile   string
+	WebSocket       WebSocket
+
+	// Source tags findings with the payload file of the payload being scanned
+	Source string
+
+	// Encodings tags findings with the --encode transforms applied to the payload
+	Encodings []string
+
+	// UserAgents, when set, rotates the User-Agent of each request in place of UserAgent
+	UserAgents *useragent.Pool
+
+	// Jitter delays each request randomly before the limiters are waited on
+	Jitter ratelimit.Jitter
+
+	// HostLimiter paces each host on its own, within the overall Limiter
+	HostLimiter *ratelimit.HostLimiter
+
+	// Adaptive, when set, adjusts Limiter from the probe responses and is waited on in its place
+	Adaptive *ratelimit.Adaptive
+
+	// Progress, when set, counts every request sent
+	Progress *progress.Reporter
+
+	// Budget, when set, caps the requests sent across every scanner
+	Budget *ratelimit.Budget
+
+	// Transport sends the HTTP probes, shared between scanners (nil uses the default)
+	Transport http.RoundTripper
+
+	// Unreachable, when set, tracks the hosts requests fail to reach, so
+	// scanners skip them once they are given up on
+	Unreachable *transport.Unreachable
+
+	// Jar, when set, keeps the cookies responses set and sends them with later probes
+	Jar http.CookieJar
+
+	// BrowserCookies are set in the browser only, e.g. those a login sequence
+	// left in Jar, which the probes already send
+	BrowserCookies []*http.Cookie
+
+	// Engine, when set, creates the browser contexts instead of the browser
+	// configured above, e.g. a browser.FakeEngine in tests
+	Engine browser.Engine
+
+	// Report receives a finding for every injection. Tokens, when set, records
+	// the token each injection of a {{token}} payload is given.
+	Report report.Writer
+	Tokens *callback.Index
+
+	// Collaborator, when set, fills in {{callback}} with a URL unique to each injection
+	Collaborator *callback.Interactsh
+
+	// CallbackWait is how long Replay waits for the callback of a finding
+	// confirmed by one (0 uses DefaultCallbackWait)
+	CallbackWait time.Duration
+
+	// Context cancels the scan, aborting in-flight requests and browser operations
+	Context context.Context
+
+	// Output receives the scanner's messages in place of the default logger's output
+	Output io.Writer
+
+	// Payloads, and ScanHeaders if any, are what ScanURL tests each URL with
+	Payloads    []string
+	ScanHeaders []string
+
+	// HTTPTimeout bounds each HTTP probe, apart from BrowserTimeout (0 uses DefaultHTTPTimeout)
+	HTTPTimeout time.Duration
+
+	// RedactHeaders are redacted from the requests and responses of confirmed
+	// findings, on top of report.SensitiveHeaders
+	RedactHeaders []string
+
+	// JSONPaths, when set, restricts the injections into a JSON Data template
+	// to the values at these paths (e.g. user.profile.bio, items[0].name)
+	JSONPaths []string
+
+	// MaxParams, when set, caps the query parameters tested one at a time,
+	// sampling the same ones for every payload of a URL
+	MaxParams int
+
+	// Filter, when enabled, keeps responses to the HTTP probe it doesn't allow out of the browser
+	Filter ResponseFilter
+
+	// InjectMode is how the payload is put into existing values, taking over
+	// from AppendMode when set
+	InjectMode Mode
+
+	// MaxRedirects caps the redirects followed with FollowRedirects (0 uses DefaultMaxRedirects)
+	MaxRedirects int
+
+	// ViewURLs are loaded in the browser after every injection, for stored
+	// payloads that render on another page than the one they were sent to
+	ViewURLs []string
 }
 
+// DefaultHTTPTimeout bounds the HTTP probes when ScannerConfig.HTTPTimeout is unset
+const DefaultHTTPTimeout = 3 * time.Second
+
 type Scanner struct {
-	Config      ScannerConfig
-	Client      *http.Client
-	browserPool *browser.BrowserPool
-	mu          sync.Mutex
+	Config         ScannerConfig
+	Client         *http.Client
+	browserPool    *browser.BrowserPool
+	mu             sync.Mutex
+	payloadIndexes map[string]int
+	indexMu        *sync.Mutex
+	oneTime        map[context.Context]context.CancelFunc
+	confirmed      *int64
+	views          *viewState
+	log            *logger.Logger
 }
 
 func NewScanner(limiter *rate.Limiter, config *ScannerConfig) *Scanner {
+	httpTimeout := config.HTTPTimeout
+	if httpTimeout <= 0 {
+		httpTimeout = DefaultHTTPTimeout
+	}
 	client := &http.Client{
-		Timeout: 3 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if !config.FollowRedirects {
-				return http.ErrUseLastResponse
-			}
-			return nil
-		},
+		Timeout:       httpTimeout,
+		Transport:     config.Transport,
+		Jar:           config.Jar,
+		CheckRedirect: checkRedirect(config),
 	}
 
-	// Default to Chrome if no browser type specified
-	browserType := config.BrowserType
-	if browserType == "" {
-		browserType = "chrome"
-	}
+	engine := config.Engine
+	if engine == nil {
+		// Default to Chrome if no browser type specified
+		browserType := config.BrowserType
+		if browserType == "" {
+			browserType = "chrome"
+		}
 
-	// Create browser instance
-	b := browser.NewBrowser(browserType, config.BrowserPath)
+		// Create browser instance
+		b := browser.NewBrowser(browserType, config.BrowserPath)
+		b.Timeout = config.BrowserTimeout
+		b.Proxy = config.Proxy
+		b.Headless = !config.Headed
+		b.Cookies = append(config.Cookies[:len(config.Cookies):len(config.Cookies)], config.BrowserCookies...)
+		b.RemoteURL = config.RemoteBrowser
+		b.ExtraFlags = config.ChromeFlags
+		b.DisableWebSecurity = config.DisableWebSec
+		b.UserAgent = config.UserAgent
+		b.WindowWidth = config.WindowWidth
+		b.WindowHeight = config.WindowHeight
+		b.Debug = config.Debug
+		b.NoSandbox = config.NoSandbox
+		b.HookEval = config.HookEval
+		engine = b
+	}
 
 	// Create a browser pool with the specified number of workers
 	workerCount := config.WorkerPool
@@ -70,21 +241,52 @@ func NewScanner(limiter *rate.Limiter, config *ScannerConfig) *Scanner {
 		workerCount = 2 // Default to 2 workers if not specified
 	}
 
-	browserPool := browser.NewBrowserPool(b, workerCount)
+	browserPool := browser.NewBrowserPool(engine, workerCount)
+	browserPool.MaxLifetime = config.WorkerLifetime
+	browserPool.Lazy = config.LazyWorkers
+	// Zero timeouts keep the pool's defaults, negative ones wait indefinitely
+	if config.AcquireTimeout != 0 {
+		browserPool.AcquireTimeout = max(config.AcquireTimeout, 0)
+	}
+	if config.ReleaseTimeout != 0 {
+		browserPool.ReleaseTimeout = max(config.ReleaseTimeout, 0)
+	}
+
+	// Scan waits on the limiter between payloads
+	if config.Limiter == nil {
+		config.Limiter = limiter
+	}
 
-	// Initialize the browser pool in the background
-	go func() {
-		err := browserPool.Initialize()
-		if err != nil {
-			fmt.Printf(colours.WarningColor, fmt.Sprintf("Warning: Browser pool initialization failed, will use one-time contexts: %v\n", err))
-		}
-	}()
+	s := &Scanner{
+		Config:         *config,
+		Client:         client,
+		browserPool:    browserPool,
+		payloadIndexes: make(map[string]int),
+		indexMu:        new(sync.Mutex),
+		oneTime:        make(map[context.Context]context.CancelFunc),
+		confirmed:      new(int64),
+		views:          newViewState(),
+		log:            logger.Default(),
+	}
+	if config.Output != nil {
+		s.log = s.log.To(config.Output)
+	}
+	if config.Debug && !s.log.Enabled(logger.LevelDebug) {
+		s.log = s.log.WithLevel(logger.LevelDebug)
+	}
+	browserPool.Logger = s.log
 
-	return &Scanner{
-		Config:      *config,
-		Client:      client,
-		browserPool: browserPool,
+	// Initialize the browser pool in the background, lazy pools start workers on demand
+	if !config.LazyWorkers && !config.DryRun {
+		go func() {
+			err := browserPool.Initialize()
+			if err != nil {
+				s.log.Warn(fmt.Sprintf("Warning: Browser pool initialization failed, will use one-time contexts: %v\n", err))
+			}
+		}()
 	}
+
+	return s
 }
 
 // Scan sends HTTP requests with different methods to a specified URL using a given payload and header.
@@ -93,45 +295,155 @@ func NewScanner(limiter *rate.Limiter, config *ScannerConfig) *Scanner {
 // for each, using the provided payload and header. The function outputs the header and payload details
 // to the console in a colored format.
 func (s *Scanner) Scan(url string, payload string, header string) {
-	fmt.Println("================================================================================")
-	if s.Config.Limiter != nil {
-		s.Config.Limiter.Wait(context.Background())
+	if s.context().Err() != nil {
+		return
+	}
+	if reason, down := s.Config.Unreachable.Down(hostOf(url)); down {
+		s.log.Notice("Skipping unreachable host " + hostOf(url) + " (" + reason + ")")
+		return
 	}
+
+	s.log.Println("================================================================================")
 	time.Sleep(500 * time.Microsecond)
-	fmt.Println("")
+	s.log.Println("")
 
 	if header != "" {
-		fmt.Printf(colours.InfoColor, "Using Header: "+header)
+		s.log.Info("Using Header: " + header)
 	}
 	if s.Config.Trace {
 		payload = strings.Replace(payload, "{LINK}", url, 1)
-		fmt.Printf(colours.InfoColor, "**Using Trace Mode**"+"")
-		fmt.Printf(colours.InfoColor, "New Payload:"+payload)
-		fmt.Printf("\n")
+		s.log.Info("**Using Trace Mode**" + "")
+		s.log.Info("New Payload:" + payload)
+		s.log.Printf("\n")
 	} else {
-		fmt.Printf(colours.InfoColor, "Using Payload: "+payload)
-		fmt.Printf("\n")
+		s.log.Info("Using Payload: " + payload)
+		s.log.Printf("\n")
 	}
 
-	if s.Config.Method != "" {
+	// WebSocket mode sends the payload in messages only, and GraphQL mode in
+	// the operation's variables only
+	if s.Config.WebSocket.Enabled {
+		s.injectWebSocket(payload, url)
+		s.log.Println("================================================================================")
+		return
+	}
+	if s.Config.GraphQL.Query != "" {
+		s.injectGraphQL(payload, url)
+	} else if s.Config.Method != "" {
 		// Split the list of methods seperated with a comma if the comma exists
 		// Otherwise just use the method passed
 		if strings.Contains(s.Config.Method, ",") {
 			methods := strings.Split(s.Config.Method, ",")
 			for _, method := range methods {
-				s.MakeRequest(method, payload, url, header, s.Config.AppendMode, s.Config.IsParameters)
+				s.MakeRequest(method, payload, url, header, s.mode(), s.Config.IsParameters)
+				s.injectPath(method, payload, url)
+				s.injectBody(method, payload, url)
+				s.injectMultipart(method, payload, url)
 			}
 		} else {
-			s.MakeRequest(s.Config.Method, payload, url, header, s.Config.AppendMode, s.Config.IsParameters)
+			s.MakeRequest(s.Config.Method, payload, url, header, s.mode(), s.Config.IsParameters)
+			s.injectPath(s.Config.Method, payload, url)
+			s.injectBody(s.Config.Method, payload, url)
+			s.injectMultipart(s.Config.Method, payload, url)
 		}
 	} else {
 		methods := []string{"GET", "POST", "OPTIONS", "PUT"}
 		for _, method := range methods {
-			s.MakeRequest(method, payload, url, header, s.Config.AppendMode, s.Config.IsParameters)
+			s.MakeRequest(method, payload, url, header, s.mode(), s.Config.IsParameters)
+			s.injectPath(method, payload, url)
+			s.injectBody(method, payload, url)
+			s.injectMultipart(method, payload, url)
 		}
 	}
+	s.injectFragment(payload, url)
+
+	s.log.Println("================================================================================")
+}
+
+// pace waits before each request to host is sent, for the jitter and then
+// the rate limiters, so the limiters still space requests out after the
+// random delay, and counts the request towards the progress estimate. A dry
+// run sends nothing, so it is never paced.
+func (s *Scanner) pace(host string) {
+	if s.Config.Progress != nil {
+		s.Config.Progress.Sent()
+	}
+	ctx := s.context()
+	s.Config.Jitter.Wait(ctx)
+	if s.Config.Adaptive != nil {
+		s.Config.Adaptive.Wait(ctx)
+	} else if s.Config.Limiter != nil {
+		s.Config.Limiter.Wait(ctx)
+	}
+	if s.Config.HostLimiter != nil {
+		s.Config.HostLimiter.Wait(ctx, host)
+	}
+}
 
-	fmt.Println("================================================================================")
+// hostOf returns the host of link, or link itself if it can't be parsed
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return u.Host
+}
+
+// injectPath requests link once per path injection point when --path-inject is set
+func (s *Scanner) injectPath(method string, payload string, link string) {
+	if !s.Config.PathInject {
+		return
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		s.log.Error("Error parsing URL: " + err.Error())
+		return
+	}
+
+	// The paths are built again for each injection so each gets a token of its own
+	for i := range PathInjections(u, payload, s.mode()) {
+		filled, token := s.tokenFor(payload)
+		target := PathInjections(u, filled, s.mode())[i]
+		at := injection{point: report.PointPath, name: target.EscapedPath(), token: token}
+		s.recordToken(filled, link, at)
+
+		s.log.Notice("Path: " + target.EscapedPath())
+		s.send(method, payload, link, target, "", s.mode(), at)
+	}
+}
+
+// PathInjections returns a copy of u for each path segment with the payload
+// put into that segment according to mode, followed by
+// one with the payload added as a new final segment. The payload is escaped so
+// it always stays within a single segment.
+func PathInjections(u *url.URL, payload string, mode Mode) []*url.URL {
+	escaped := url.PathEscape(payload)
+	segments := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+
+	var rawPaths []string
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		injected := append([]string(nil), segments...)
+		injected[i] = mode.Inject(segment, escaped)
+		rawPaths = append(rawPaths, "/"+strings.Join(injected, "/"))
+	}
+	rawPaths = append(rawPaths, strings.TrimSuffix(u.EscapedPath(), "/")+"/"+escaped)
+
+	targets := make([]*url.URL, 0, len(rawPaths))
+	for _, rawPath := range rawPaths {
+		path, err := url.PathUnescape(rawPath)
+		if err != nil {
+			continue
+		}
+		target := *u
+		target.Path = path
+		target.RawPath = rawPath
+		targets = append(targets, &target)
+	}
+	return targets
 }
 
 // setheaders returns a task list that sets the passed headers.
@@ -145,128 +457,508 @@ func (s *Scanner) Setheaders(host string, headers map[string]interface{}, res *s
 
 // MakeRequest constructs and sends an HTTP request with the specified method,
 // payload, and headers to the given link. If isParameters is true, the payload
-// is appended to each query parameter. The function also allows setting custom
+// is put into each query parameter according to mode. The function also allows setting custom
 // headers and handles special cases for the User-Agent header. It uses chromedp
 // to modify the request and navigate to the link. If ShowTimestamp is true, a
 // timestamp is printed. If Debug is true, the request and response are dumped
 // to the console. The function returns no value.
-func (s *Scanner) MakeRequest(method string, payload string, link string, header string, appendMode, isParameters bool) {
-	fmt.Printf(colours.NoticeColor, "Method: "+method)
+func (s *Scanner) MakeRequest(method string, payload string, link string, header string, mode Mode, isParameters bool) {
+	s.makeRequest(method, payload, link, header, mode, isParameters, injection{})
+}
+
+// injection names where a request carries the payload, for reporting, and the
+// token the payload was given there, if any
+type injection struct {
+	point string
+	name  string
+	token string
+}
+
+// makeRequest implements MakeRequest, reporting the request as an injection at
+// the given point, which is worked out from the arguments when empty
+func (s *Scanner) makeRequest(method string, payload string, link string, header string, mode Mode, isParameters bool, at injection) {
+	if s.context().Err() != nil {
+		return
+	}
 
 	u, err := url.Parse(link)
 	if err != nil {
-		fmt.Printf(colours.InfoColor, "Error parsing URL: "+err.Error())
+		s.log.Info("Error parsing URL: " + err.Error())
+		return
+	}
+	if !isParameters {
+		s.send(method, payload, link, u, header, mode, at)
 		return
 	}
 
-	if isParameters {
-		qs := u.Query()
-		for param, vv := range qs {
-			if appendMode {
-				fmt.Printf(colours.NoticeColor, "Parameter: "+param)
-				qs.Set(param, vv[0]+payload)
-			} else {
-				fmt.Printf(colours.NoticeColor, "Parameter: "+param)
-				qs.Set(param, payload)
-			}
+	// Every parameter gets a token of its own, so a callback identifies the
+	// parameter that fired even when they are all injected at once
+	qs := u.Query()
+	params := make([]string, 0, len(qs))
+	for param := range qs {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	fill := s.filler(payload, link, report.PointQuery)
 
+	// Unless --inject-all is set, test each parameter in its own request so a
+	// dialog identifies the parameter that fired too
+	if !s.Config.InjectAll && len(params) > 1 {
+		if max := s.Config.MaxParams; max > 0 && len(params) > max {
+			s.log.Notice(fmt.Sprintf("Sampling %d of %d parameters (-max-params)", max, len(params)))
+			params = Sample(params, max, link)
 		}
-		u.RawQuery = qs.Encode()
+
+		for _, param := range params {
+			s.log.Notice("Parameter: " + param)
+			target := *u
+			var token string
+			target.RawQuery = injectParams(qs, func(name string) (string, string) {
+				value, t := fill(name)
+				token = t
+				return value, t
+			}, mode, param).Encode()
+			s.send(method, payload, link, &target, header, mode, injection{point: report.PointQuery, name: param, token: token})
+		}
+		return
 	}
 
-	fmt.Printf(colours.NoticeColor, ""+u.String()+"\n")
-	request, err := http.NewRequest(method, u.String(), nil)
-	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error creating request: "+err.Error())
+	tokens := make(map[string]string, len(params))
+	for _, param := range params {
+		s.log.Notice("Parameter: " + param)
+	}
+	u.RawQuery = injectParams(qs, func(name string) (string, string) {
+		value, token := fill(name)
+		tokens[name] = token
+		return value, token
+	}, mode, "").Encode()
+
+	if at.point == "" {
+		at = injection{point: report.PointQuery, name: strings.Join(params, ",")}
+		if len(params) == 1 {
+			at.token = tokens[params[0]]
+		}
+	}
+	s.send(method, payload, link, u, header, mode, at)
+}
+
+// send requests u, link with the payload put into the point under test, and
+// into the header and cookies under test on top. The request is reported as an
+// injection at the given point, which is worked out from the arguments when empty.
+func (s *Scanner) send(method string, payload string, link string, u *url.URL, header string, mode Mode, at injection) {
+	if s.context().Err() != nil {
 		return
 	}
+	s.log.Notice("Method: " + method)
 
-	// Get a browser context from the pool instead of creating a new one each time
-	ctx, err := s.getBrowserContext()
+	// Register the payload so its index reflects scan order, not confirmation order
+	s.payloadIndex(payload)
+
+	s.log.Notice("" + u.String() + "\n")
+	request, err := http.NewRequestWithContext(s.context(), method, u.String(), nil)
 	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error getting browser context: "+err.Error())
+		s.log.Error("Error creating request: " + err.Error())
 		return
 	}
-	defer s.releaseBrowserContext(ctx)
 
-	// Check if the header is empty
-	if header != "" {
-		// Remove existing headers that we're testing
-		request.Header.Del("User-Agent")
-		request.Header.Del("X-Forwarded-Host")
-		request.Header.Del("X-Forwarded-For")
+	// Send the configured session cookies with the HTTP probe as well
+	for _, cookie := range s.Config.Cookies {
+		request.AddCookie(cookie)
+	}
+
+	// Global headers go on every request, the header under test overrides them below
+	setHeaders(request, s.Config.Headers)
+
+	// Inject the payload into the cookies under test
+	injectedCookies, cookieTokens := s.cookieInjections(payload, link)
+	addRawCookies(request, injectedCookies)
 
-		// Set the header with the payload
+	var headerToken string
+	if header != "" {
+		// Set the header with the payload, replacing any global value
 		headerParts := strings.SplitN(header, ":", 2)
 		if len(headerParts) == 2 {
 			headerName := strings.TrimSpace(headerParts[0])
 			headerValue := strings.TrimSpace(headerParts[1])
-			// Special handling for User-Agent header
-			if strings.ToLower(headerName) == "user-agent" {
-				// If appendMode is true, append the payload to the existing value
-				if appendMode {
-					request.Header.Set("User-Agent", headerValue+payload)
-				} else {
-					request.Header.Set("User-Agent", payload)
-				}
-			} else {
-				// If appendMode is true, append the payload to the existing value
-				if appendMode {
-					request.Header.Set(headerName, headerValue+payload)
-				} else {
-					request.Header.Set(headerName, payload)
-				}
-			}
+			var value string
+			value, headerToken = s.fill(payload, link, injection{point: report.PointHeader, name: headerName})
+			// Put the payload into the header's value according to the injection mode
+			request.Header.Set(headerName, mode.Inject(headerValue, value))
 		} else {
 			// If no value is provided, use the payload as the value
-			request.Header.Set(header, payload)
+			var value string
+			value, headerToken = s.fill(payload, link, injection{point: report.PointHeader, name: header})
+			request.Header.Set(header, value)
+		}
+	}
+
+	// Without a point of its own the request is reported at the header or
+	// cookies under test, with their token when there is a single one
+	if at.point == "" {
+		at = s.resolve(header, at)
+		switch {
+		case at.point == report.PointHeader:
+			at.token = headerToken
+		case at.point == report.PointCookie && len(cookieTokens) == 1:
+			at.token = cookieTokens[0]
+		}
+	}
+
+	// Send the User-Agent picked for this request unless it is the header under test
+	if userAgent := s.userAgent(); userAgent != "" && request.Header.Get("User-Agent") == "" {
+		request.Header.Set("User-Agent", userAgent)
+	}
+
+	if s.Config.DryRun {
+		s.printDryRun(request, at)
+		return
+	}
+	if s.Config.Budget != nil {
+		if !s.Config.Budget.Take() {
+			return
+		}
+		defer s.Config.Budget.Finish()
+	}
+	s.pace(request.URL.Host)
+
+	// Probe over HTTP ahead of the browser, which is only brought in for
+	// responses that get past the filters and reflect the payload. A failed
+	// probe only stops the scan when they depend on it, as the browser may
+	// still reach the target through its proxy.
+	result, ok := s.probe(request)
+	if !ok && (s.Config.ReflectCheck || s.Config.ReflectOnly || s.Config.Filter.Enabled()) {
+		return
+	}
+	if ok && s.Config.Filter.Enabled() {
+		if !s.Config.Filter.Allows(result.status, result.size) {
+			s.log.Notice(fmt.Sprintf("Response filtered (status %d, size %d), skipping the browser", result.status, result.size))
+			return
 		}
+	}
+	if ok {
+		s.reportRedirects(result, s.finding(method, payload, u.String(), header, at), request)
+		s.checkViews(s.finding(method, payload, u.String(), header, at))
+	}
+	if s.Config.ReflectCheck || s.Config.ReflectOnly {
+		reflection := result.reflection(payload)
+		if reflection == "" {
+			s.log.Notice("Payload not reflected, skipping the browser")
+			return
+		}
+		s.log.Info("Payload reflected (" + reflection + ") in the response")
+		if s.Config.ReflectOnly {
+			finding := s.finding(method, payload, u.String(), header, at)
+			finding.UserAgent = request.Header.Get("User-Agent")
+			finding.Protocol = result.proto
+			finding.Redirects = result.chain
+			finding.Evidence = "payload reflected (" + reflection + ") in the response"
+			s.writeFinding(finding)
+			return
+		}
+	}
 
+	// Check if there are headers to send, a rotated User-Agent is one of them
+	var headers map[string]interface{}
+	if header != "" || len(s.Config.Headers) > 0 || s.Config.UserAgents != nil {
 		// Get the headers from the request
-		headers := make(map[string]interface{})
+		headers = make(map[string]interface{})
 		for key := range request.Header {
 			header := request.Header.Get(key)
 			if s.Config.Debug {
-				fmt.Printf(colours.DebugColor, "Header: "+key)
-				fmt.Printf(colours.DebugColor, "Value: "+header)
+				s.log.Debug("Header: " + key)
+				s.log.Debug("Value: " + header)
 			}
 
 			headers[key] = header
 		}
+	}
 
-		// Set the headers for the request using chromedp
-		var res string
-		err = chromedp.Run(ctx, s.Setheaders(
-			u.String(),
-			headers,
-			&res,
-		))
-		if err != nil {
-			fmt.Printf(colours.ErrorColor, "Error making request: "+err.Error())
-			return
+	finding := s.finding(method, payload, u.String(), header, at)
+	finding.UserAgent = request.Header.Get("User-Agent")
+	finding.Protocol = result.proto
+	finding.Redirects = result.chain
+	finding.Request = report.CaptureRequest(request, "", s.Config.RedactHeaders)
+	if ok {
+		finding.Response = report.CaptureResponse(result.status, result.proto, result.header, s.Config.RedactHeaders)
+	}
+	s.browse(finding, u, headers, injectedCookies)
+}
+
+// browse loads u in a browser context with the given headers and cookies, then
+// reports the dialogs and DOM sinks showing the payload fired as variations of
+// finding. It reports false when the page couldn't be loaded.
+func (s *Scanner) browse(finding report.Finding, u *url.URL, headers map[string]interface{}, cookies []*http.Cookie) bool {
+	// Get a browser context from the pool instead of creating a new one each time
+	browserCtx, err := s.getBrowserContext()
+	if err != nil {
+		s.log.Error("Error getting browser context: " + err.Error())
+		return false
+	}
+	defer s.releaseBrowserContext(browserCtx)
+
+	// Abort browser operations if the scan is cancelled, without closing the worker
+	ctx, cancel := context.WithCancel(browserCtx)
+	defer cancel()
+	stop := context.AfterFunc(s.context(), cancel)
+	defer stop()
+
+	// Discard dialogs left over from the context's previous user
+	browser.DriverFromContext(ctx).DialogEvents()
+
+	if len(cookies) > 0 {
+		// The browser may refuse some payload characters in cookies, the HTTP probe still carries them
+		if err := browser.DriverFromContext(ctx).SetCookies(ctx, u.String(), cookies); err != nil {
+			s.log.Error("Error setting cookies in browser: " + err.Error())
 		}
+	}
 
-	} else {
-		err = chromedp.Run(ctx, chromedp.Navigate(u.String()))
-		if err != nil {
-			fmt.Printf(colours.ErrorColor, "Error making request: "+err.Error())
-			return
+	// Set the headers for the request using the browser driver
+	if err := s.navigate(ctx, u.String(), headers); err != nil {
+		s.log.Error("Error making request: " + err.Error())
+		return false
+	}
+
+	// A dialog opened by the page means the payload executed
+	dialogs := browser.DriverFromContext(ctx).DialogEvents()
+	for _, dialog := range dialogs {
+		s.log.Success(fmt.Sprintf("XSS confirmed: %s dialog with message %q on %s", dialog.Type, dialog.Message, u.String()))
+	}
+	atomic.AddInt64(s.confirmed, int64(len(dialogs)))
+	s.reportInjection(finding, dialogs)
+	s.checkSinks(ctx, finding)
+	if len(dialogs) > 0 && s.Config.ScreenshotDir != "" {
+		s.saveScreenshot(ctx, u, finding.Method, finding.Payload)
+	}
+	return true
+}
+
+// reportInjection writes the finding for a request made through the browser,
+// confirmed when the page opened a dialog
+func (s *Scanner) reportInjection(finding report.Finding, dialogs []browser.Dialog) {
+	if s.Config.Report == nil {
+		return
+	}
+
+	if len(dialogs) > 0 {
+		finding.Confirmed = true
+		finding.Evidence = fmt.Sprintf("%s dialog with message %q", dialogs[0].Type, dialogs[0].Message)
+	}
+	s.writeFinding(finding)
+}
+
+// checkSinks reports the DOM sinks the page wrote the payload to, whether or
+// not it executed, as DOM-based XSS candidates
+func (s *Scanner) checkSinks(ctx context.Context, finding report.Finding) {
+	sinks, err := browser.SinkEvents(ctx)
+	if err != nil {
+		if s.Config.Debug {
+			s.log.Debug("Error reading DOM sinks: " + err.Error())
+		}
+		return
+	}
+
+	marker := payloadMarker(finding.Payload)
+	if marker == "" {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, sink := range sinks {
+		if seen[sink.Sink] || !strings.Contains(sink.Value, marker) {
+			continue
+		}
+		seen[sink.Sink] = true
+
+		s.log.Success(fmt.Sprintf("DOM sink: payload written to %s on %s", sink.Sink, sink.URL))
+		if s.Config.Report == nil {
+			continue
 		}
+		sinkFinding := finding
+		sinkFinding.Sink = sink.Sink
+		sinkFinding.Evidence = fmt.Sprintf("payload written to %s on %s", sink.Sink, sink.URL)
+		s.writeFinding(sinkFinding)
+	}
+}
+
+// finding describes an injection of payload into target, unconfirmed
+func (s *Scanner) finding(method string, payload string, target string, header string, at injection) report.Finding {
+	at = s.resolve(header, at)
+	headerName, _, _ := strings.Cut(header, ":")
+	finding := report.Finding{
+		Target:         target,
+		Method:         strings.ToUpper(method),
+		Header:         strings.TrimSpace(headerName),
+		Payload:        payload,
+		InjectionPoint: at.point,
+		Token:          at.token,
+	}
+	if at.point != report.PointHeader {
+		finding.Param = at.name
 	}
+	return finding
+}
+
+// userAgent returns the User-Agent for a request, the next of the rotation
+// when one is configured
+func (s *Scanner) userAgent() string {
+	if s.Config.UserAgents != nil {
+		return s.Config.UserAgents.Next()
+	}
+	return s.Config.UserAgent
+}
+
+// resolve works out the injection point of a request from the header under
+// test and the configuration when at is empty
+func (s *Scanner) resolve(header string, at injection) injection {
+	if at.point != "" {
+		return at
+	}
+
+	headerName, _, _ := strings.Cut(header, ":")
+	switch {
+	case header != "":
+		return injection{point: report.PointHeader, name: strings.TrimSpace(headerName)}
+	case len(s.Config.CookieParams) > 0:
+		return injection{point: report.PointCookie, name: strings.Join(s.Config.CookieParams, ",")}
+	default:
+		return injection{point: report.PointURL}
+	}
+}
 
-	// Get the response from the request
-	response, err := s.Client.Do(request)
+// printDryRun prints the request that would be sent and where it carries the payload
+func (s *Scanner) printDryRun(request *http.Request, at injection) {
+	dump, err := httputil.DumpRequestOut(request, true)
 	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error making request: "+err.Error())
+		s.log.Error("Error dumping request: " + err.Error())
+		return
+	}
+
+	point := at.point
+	if at.name != "" {
+		point += " " + at.name
+	}
+	s.log.Printf("%s", "\n--- Dry run ("+point+") ---\n"+string(dump)+"\n")
+}
+
+// writeFinding fills in the source, encodings and timestamp of a finding and
+// writes it to the report
+func (s *Scanner) writeFinding(finding report.Finding) {
+	if s.Config.Report == nil {
 		return
 	}
-	defer response.Body.Close()
 
-	if s.Config.Debug {
-		s.DebugRequest(request)
-		s.DebugResponse(response)
+	finding.Source = s.Config.Source
+	finding.Encodings = s.Config.Encodings
+	finding.Timestamp = time.Now()
+
+	// Only confirmed findings need replaying, and those with a token as a
+	// callback may confirm them later, the rest stay small
+	if !finding.Confirmed && finding.Token == "" {
+		finding.Request, finding.Response = nil, nil
 	}
 
+	if err := s.Config.Report.Write(finding); err != nil {
+		s.log.Error("Error writing finding: " + err.Error())
+	}
+}
+
+// injectParams returns a copy of qs with the payload fill returns for param
+// put into it, or into every parameter when param is empty, according to mode
+func injectParams(qs url.Values, fill Filler, mode Mode, param string) url.Values {
+	injected := url.Values{}
+	for name, vv := range qs {
+		injected[name] = append([]string(nil), vv...)
+		if param != "" && name != param {
+			continue
+		}
+
+		value, _ := fill(name)
+		injected.Set(name, mode.Inject(vv[0], value))
+	}
+	return injected
+}
+
+// Confirmed returns the number of payloads confirmed by a dialog so far
+func (s *Scanner) Confirmed() int {
+	return int(atomic.LoadInt64(s.confirmed))
+}
+
+// cookieInjections returns a cookie carrying the payload for each
+// --cookie-param name, injected into link, and the tokens they were given
+func (s *Scanner) cookieInjections(payload string, link string) ([]*http.Cookie, []string) {
+	var cookies []*http.Cookie
+	var tokens []string
+	for _, name := range s.Config.CookieParams {
+		s.log.Notice("Cookie: " + name)
+		value, token := s.fill(payload, link, injection{point: report.PointCookie, name: name})
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return cookies, tokens
+}
+
+// setHeaders sets each of headers on request, replacing any existing values
+func setHeaders(request *http.Request, headers http.Header) {
+	for name, values := range headers {
+		request.Header.Del(name)
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+}
+
+// addRawCookies appends cookies to the request's Cookie header without the
+// sanitising AddCookie applies, which would strip quotes and other payload characters
+func addRawCookies(request *http.Request, cookies []*http.Cookie) {
+	for _, cookie := range cookies {
+		pair := cookie.Name + "=" + cookie.Value
+		if existing := request.Header.Get("Cookie"); existing != "" {
+			pair = existing + "; " + pair
+		}
+		request.Header.Set("Cookie", pair)
+	}
+}
+
+// navigate loads link in the browser, waiting for the network to go idle
+// first when --wait-idle is set so XHR-rendered sinks are reached
+func (s *Scanner) navigate(ctx context.Context, link string, headers map[string]interface{}) error {
+	if s.Config.WaitIdle > 0 {
+		return browser.NavigateAndWait(ctx, link, headers, s.Config.WaitIdle)
+	}
+	return browser.DriverFromContext(ctx).Navigate(ctx, link, headers)
+}
+
+// saveScreenshot captures the browser as evidence of a confirmed payload. The
+// file name encodes the target host, payload index and method for traceability.
+func (s *Scanner) saveScreenshot(ctx context.Context, u *url.URL, method string, payload string) {
+	if err := os.MkdirAll(s.Config.ScreenshotDir, 0755); err != nil {
+		s.log.Error("Error creating screenshot directory: " + err.Error())
+		return
+	}
+
+	host := strings.ReplaceAll(u.Host, ":", "_")
+	name := fmt.Sprintf("%s_payload-%d_%s.png", host, s.payloadIndex(payload), strings.ToUpper(method))
+	outPath := filepath.Join(s.Config.ScreenshotDir, name)
+
+	if err := browser.Screenshot(ctx, outPath); err != nil {
+		s.log.Error("Error saving screenshot: " + err.Error())
+		return
+	}
+	s.log.Info("Screenshot saved: " + outPath)
+}
+
+// payloadIndex returns a stable index for payload, assigned in the order payloads are first scanned
+func (s *Scanner) payloadIndex(payload string) int {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	if idx, ok := s.payloadIndexes[payload]; ok {
+		return idx
+	}
+	idx := len(s.payloadIndexes)
+	s.payloadIndexes[payload] = idx
+	return idx
 }
 
 // DebugRequest dumps the request to the console in a human-readable format
@@ -274,9 +966,9 @@ func (s *Scanner) MakeRequest(method string, payload string, link string, header
 func (s *Scanner) DebugRequest(req *http.Request) {
 	dump, err := httputil.DumpRequestOut(req, true)
 	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error dumping request: "+err.Error())
+		s.log.Error("Error dumping request: " + err.Error())
 	} else {
-		fmt.Printf("%s", "\n--- Request ---\n"+string(dump))
+		s.log.Printf("%s", "\n--- Request ---\n"+string(dump))
 	}
 }
 
@@ -285,48 +977,118 @@ func (s *Scanner) DebugRequest(req *http.Request) {
 func (s *Scanner) DebugResponse(resp *http.Response) {
 	dump, err := httputil.DumpResponse(resp, true)
 	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error dumping response: "+err.Error())
+		s.log.Error("Error dumping response: " + err.Error())
 	} else {
-		fmt.Println(string(dump))
+		s.log.Println(string(dump))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf(colours.ErrorColor, "Error reading response body: "+err.Error())
+		s.log.Error("Error reading response body: " + err.Error())
 	} else {
-		fmt.Println(string(body))
+		s.log.Println(string(body))
 	}
 }
 
 // getBrowserContext gets a browser context from the pool
 func (s *Scanner) getBrowserContext() (context.Context, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	pool := s.browserPool
+	if pool == nil {
+		defer s.mu.Unlock()
 
-	if s.browserPool == nil {
-		// Create a one-time context if no pool is available
-		fmt.Printf(colours.WarningColor, "Browser pool not available, creating one-time context\n")
-		ctx, _ := chromedp.NewContext(context.Background())
+		// Create a one-time context if no pool is available, tracking its
+		// cancel so releaseBrowserContext can shut the browser down
+		s.log.Warn("Browser pool not available, creating one-time context\n")
+		ctx, cancel := chromedp.NewContext(context.Background())
+		s.oneTime[ctx] = cancel
 		return ctx, nil
 	}
+	// Don't hold the lock while waiting, releases need it to hand contexts back
+	s.mu.Unlock()
 
 	// Get context from the pool
-	ctx, err := s.browserPool.GetContext()
+	ctx, err := pool.GetContext(s.context())
 	if err != nil {
+		// A cancelled scan shouldn't start a new browser
+		if s.context().Err() != nil {
+			return nil, err
+		}
+
 		// Fall back to creating a new context if the pool fails
-		fmt.Printf(colours.WarningColor, fmt.Sprintf("Failed to get context from pool: %v, creating one-time context\n", err))
-		oneTimeCtx, _ := chromedp.NewContext(context.Background())
-		return oneTimeCtx, nil
+		s.log.Warn(fmt.Sprintf("Failed to get context from pool: %v, creating one-time context\n", err))
+		return pool.NewOneTimeContext()
 	}
 
 	return ctx, nil
 }
 
+// do sends an HTTP probe with retries, letting adaptive rate limiting see the response
+func (s *Scanner) do(request *http.Request) (*http.Response, error) {
+	host := request.URL.Host
+	if reason, down := s.Config.Unreachable.Down(host); down {
+		return nil, fmt.Errorf("%s is unreachable (%s)", host, reason)
+	}
+
+	response, err := s.retryPolicy().Do(s.Client, request)
+	if err != nil {
+		s.Config.Unreachable.Failed(host, err)
+		return nil, err
+	}
+	s.Config.Unreachable.Succeeded(host)
+	if s.Config.Adaptive != nil {
+		s.Config.Adaptive.Observe(response)
+	}
+	return response, nil
+}
+
+// retryPolicy returns the retry settings for HTTP probes
+func (s *Scanner) retryPolicy() retry.Policy {
+	return retry.Policy{
+		Retries: s.Config.Retries,
+		Backoff: s.Config.RetryBackoff,
+		Limiter: s.Config.Limiter,
+		Attempt: s.takeRetry,
+	}
+}
+
+// errBudgetSpent stops a request the budget has no unit left for
+var errBudgetSpent = errors.New("request budget spent")
+
+// takeRetry takes a unit of the budget for each retry, so every attempt sent
+// counts against it. The first attempt took its unit before the request was
+// paced, and holds the budget in flight until the request is done, so a
+// retry's unit is handed back at once.
+func (s *Scanner) takeRetry(attempt int) error {
+	if attempt == 0 || s.Config.Budget == nil {
+		return nil
+	}
+	if !s.Config.Budget.Take() {
+		return errBudgetSpent
+	}
+	s.Config.Budget.Finish()
+	return nil
+}
+
+// context returns the context the scan runs under
+func (s *Scanner) context() context.Context {
+	if s.Config.Context != nil {
+		return s.Config.Context
+	}
+	return context.Background()
+}
+
 // releaseBrowserContext returns a browser context to the pool
 func (s *Scanner) releaseBrowserContext(ctx context.Context) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if cancel, ok := s.oneTime[ctx]; ok {
+		cancel()
+		delete(s.oneTime, ctx)
+		return
+	}
+
 	if s.browserPool != nil {
 		s.browserPool.ReleaseContext(ctx)
 	}
@@ -335,10 +1097,19 @@ func (s *Scanner) releaseBrowserContext(ctx context.Context) {
 // Close cleans up resources used by the scanner
 func (s *Scanner) Close() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	pool := s.browserPool
+	s.browserPool = nil
+	for _, cancel := range s.oneTime {
+		cancel()
+	}
+	s.oneTime = make(map[context.Context]context.CancelFunc)
+	s.mu.Unlock()
 
-	if s.browserPool != nil {
-		s.browserPool.Close()
-		s.browserPool = nil
+	// Close outside the lock since it waits for contexts to be released
+	if pool != nil {
+		if s.Config.Debug {
+			s.log.Debug("Browser pool: " + pool.Stats().String())
+		}
+		pool.Close()
 	}
 }
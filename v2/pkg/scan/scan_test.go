@@ -0,0 +1,827 @@
+package scan
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/progress"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/ratelimit"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/scope"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/transport"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/useragent"
+)
+
+// testScanner returns a scanner for config that, unless the config has an
+// output or engine of its own, logs nothing and drives a browser.FakeEngine
+func testScanner(t *testing.T, config *ScannerConfig) *Scanner {
+	t.Helper()
+	if config.Engine == nil {
+		config.Engine = &browser.FakeEngine{}
+	}
+	if config.Context == nil {
+		config.Context = context.Background()
+	}
+	if config.Output == nil {
+		config.Output = io.Discard
+	}
+	s := NewScanner(nil, config)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// received is a request as seen by recordServer
+type received struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Header http.Header
+	Body   string
+}
+
+// recordServer returns a server answering every request with body and a
+// function returning the requests it received so far
+func recordServer(t *testing.T, body string) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, received{r.Method, r.URL.Path, r.URL.Query(), r.Header.Clone(), string(data)})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), requests...)
+	}
+}
+
+func TestParamPlaceholderFilled(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true})
+	s.Scan(server.URL+"/?q=1&id=2", "<b id={{param}}>", "X-Api: v")
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("%d requests, want one per parameter", len(got))
+	}
+	for _, req := range got {
+		for name, values := range req.Query {
+			if values[0] != "<b id="+name+">" && values[0] != "1" && values[0] != "2" {
+				t.Errorf("parameter %s = %q", name, values[0])
+			}
+		}
+		if header := req.Header.Get("X-Api"); header != "<b id=X-Api>" {
+			t.Errorf("X-Api = %q, want the payload filled in with the header name", header)
+		}
+	}
+}
+
+func TestPathInjections(t *testing.T) {
+	u, _ := url.Parse("https://target.example/user/42/profile?tab=1")
+	var got []string
+	for _, target := range PathInjections(u, "<x y>", ModeReplace) {
+		if target.RawQuery != "tab=1" {
+			t.Errorf("%s lost the query", target)
+		}
+		got = append(got, target.EscapedPath())
+	}
+	want := []string{
+		"/%3Cx%20y%3E/42/profile",
+		"/user/%3Cx%20y%3E/profile",
+		"/user/42/%3Cx%20y%3E",
+		"/user/42/profile/%3Cx%20y%3E",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paths = %q, want %q", got, want)
+	}
+
+	got = got[:0]
+	for _, target := range PathInjections(u, "/x", ModeAppend) {
+		got = append(got, target.EscapedPath())
+	}
+	if want := "/user/42%2Fx/profile"; got[1] != want {
+		t.Errorf("appended segment = %q, want %q with the slash kept in the segment", got[1], want)
+	}
+}
+
+func TestPathInjectSendsEverySegment(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, PathInject: true})
+	s.Scan(server.URL+"/user/42/profile", "<b>", "")
+
+	// The link itself is requested too, for the headers under test
+	var paths []string
+	for _, req := range requests() {
+		if strings.Contains(req.Path, "<b>") {
+			paths = append(paths, req.Path)
+		}
+	}
+	slices.Sort(paths)
+	want := []string{"/<b>/42/profile", "/user/42/<b>", "/user/42/profile/<b>", "/user/<b>/profile"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("requested paths %q, want %q", paths, want)
+	}
+}
+
+func TestCookieParamInjection(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		CookieParams: []string{"session_note"},
+		Cookies:      []*http.Cookie{{Name: "auth", Value: "1"}},
+		Engine:       engine,
+	})
+	const payload = `"><img src=x onerror=alert(1)>`
+	s.Scan(server.URL+"/admin", payload, "")
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("%d requests, want 1", len(got))
+	}
+	if cookie := got[0].Header.Get("Cookie"); cookie != "auth=1; session_note="+payload {
+		t.Errorf("Cookie = %q, want the payload unsanitised under session_note", cookie)
+	}
+
+	navigations := engine.Navigations()
+	if len(navigations) != 1 {
+		t.Fatalf("%d navigations, want 1", len(navigations))
+	}
+	var found bool
+	for _, cookie := range navigations[0].Cookies {
+		if cookie.Name == "session_note" {
+			found = cookie.Value == payload
+		}
+	}
+	if !found {
+		t.Errorf("browser cookies = %v, want session_note set to the payload", navigations[0].Cookies)
+	}
+}
+
+func TestInjectAllRequestCount(t *testing.T) {
+	for _, tt := range []struct {
+		injectAll bool
+		want      int
+	}{
+		{false, 3},
+		{true, 1},
+	} {
+		server, requests := recordServer(t, "ok")
+		tokens := callback.NewIndex()
+		s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, InjectAll: tt.injectAll, Tokens: tokens})
+		s.Scan(server.URL+"/?a=1&b=2&c=3", "<b>{{token}}", "")
+
+		got := requests()
+		if len(got) != tt.want {
+			t.Errorf("inject-all %v: %d requests, want %d", tt.injectAll, len(got), tt.want)
+		}
+		injected := 0
+		for _, req := range got {
+			for _, values := range req.Query {
+				if strings.HasPrefix(values[0], "<b>") {
+					injected++
+				}
+			}
+		}
+		if injected != 3 || tokens.Len() != 3 {
+			t.Errorf("inject-all %v: %d parameters injected with %d tokens, want 3 of each", tt.injectAll, injected, tokens.Len())
+		}
+	}
+}
+
+func TestCancelMidScan(t *testing.T) {
+	var requests atomic.Int32
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		arrived <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{Method: "GET,POST", IsParameters: true, Engine: engine, Context: ctx})
+
+	done := make(chan struct{})
+	go func() {
+		s.Scan(server.URL+"/?a=1&b=2&c=3", "<b>", "")
+		close(done)
+	}()
+
+	<-arrived
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Scan didn't return after the context was cancelled")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests sent, want none after the cancellation", n)
+	}
+	if n := len(engine.Navigations()); n != 0 {
+		t.Errorf("%d pages loaded after the cancellation", n)
+	}
+}
+
+func TestProbeRetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		Engine:       engine,
+		Filter:       ResponseFilter{MatchStatus: Ranges{{Min: 200, Max: 200}}},
+	})
+	s.Scan(server.URL+"/", "<b>", "")
+
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("%d attempts, want 3", n)
+	}
+	if n := len(engine.Navigations()); n != 1 {
+		t.Errorf("%d pages loaded, want the page once the probe got its 200", n)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDryRunSendsNothing(t *testing.T) {
+	var out strings.Builder
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{
+		Method:       "GET,POST",
+		IsParameters: true,
+		PathInject:   true,
+		Data:         "f=1",
+		DryRun:       true,
+		Engine:       engine,
+		Output:       &out,
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			t.Errorf("dry run sent %s %s", r.Method, r.URL)
+			return nil, io.EOF
+		}),
+	})
+	s.Scan("http://target.example/p?a=1&b=2", "<b>", "X-Test: v")
+
+	if n := len(engine.Navigations()); n != 0 {
+		t.Errorf("dry run loaded %d pages", n)
+	}
+
+	// Each method tests both parameters, along with the header, and both path
+	// injections, and POST the body field
+	printed := make(map[string]int)
+	for _, block := range strings.Split(out.String(), "--- Dry run (")[1:] {
+		label, request, _ := strings.Cut(block, ") ---\n")
+		method, _, _ := strings.Cut(request, " ")
+		if strings.HasPrefix(label, "query") && !strings.Contains(request, "X-Test: <b>") {
+			t.Errorf("%s request doesn't carry the header under test:\n%s", label, request)
+		}
+		if strings.HasPrefix(label, "path") {
+			label = "path"
+		}
+		printed[method+" "+label]++
+	}
+	want := map[string]int{
+		"GET query a": 1, "GET query b": 1, "GET path": 2,
+		"POST query a": 1, "POST query b": 1, "POST path": 2, "POST body f": 1,
+	}
+	for key, n := range want {
+		if printed[key] != n {
+			t.Errorf("%d dry runs of %s, want %d", printed[key], key, n)
+		}
+	}
+	if len(printed) != len(want) {
+		t.Errorf("dry runs printed for %v, want %v", printed, want)
+	}
+}
+
+func TestGlobalHeaders(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{
+		Method:  http.MethodGet,
+		Headers: http.Header{"Authorization": {"Bearer secret"}, "X-Marker": {"bxss"}},
+		Engine:  engine,
+	})
+	s.Scan(server.URL+"/", "<b>", "")
+	s.Scan(server.URL+"/", "<b>", "X-Marker: v")
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("%d requests, want 2", len(got))
+	}
+	for i, want := range []string{"bxss", "<b>"} {
+		if auth := got[i].Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("request %d Authorization = %q", i, auth)
+		}
+		if marker := got[i].Header.Values("X-Marker"); len(marker) != 1 || marker[0] != want {
+			t.Errorf("request %d X-Marker = %q, want only %q", i, marker, want)
+		}
+	}
+
+	navigations := engine.Navigations()
+	if len(navigations) != 2 {
+		t.Fatalf("%d navigations, want 2", len(navigations))
+	}
+	for i, want := range []string{"bxss", "<b>"} {
+		headers := navigations[i].Headers
+		if headers["Authorization"] != "Bearer secret" || headers["X-Marker"] != want {
+			t.Errorf("navigation %d headers = %v, want Authorization and X-Marker %q", i, headers, want)
+		}
+	}
+}
+
+func TestRedirectOutOfScopeNotFollowed(t *testing.T) {
+	outside, requests := recordServer(t, "ok")
+	inside := httptest.NewServer(http.RedirectHandler(strings.Replace(outside.URL, "127.0.0.1", "localhost", 1)+"/landing", http.StatusFound))
+	defer inside.Close()
+
+	allowed, err := scope.New([]string{"127.0.0.1"}, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, FollowRedirects: true, Scope: allowed})
+	s.Scan(inside.URL+"/", "<b>", "")
+
+	if got := requests(); len(got) != 0 {
+		t.Errorf("followed a redirect to an excluded host: %+v", got)
+	}
+}
+
+func TestFakeEngineRecordsNavigations(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Engine: engine})
+	s.Scan(server.URL+"/search?q=1&page=2", "<b>", "X-Test")
+
+	navigations := engine.Navigations()
+	if len(navigations) != 2 {
+		t.Fatalf("%d navigations, want one per parameter", len(navigations))
+	}
+	var injected []string
+	for _, nav := range navigations {
+		u, err := url.Parse(nav.URL)
+		if err != nil || u.Path != "/search" {
+			t.Errorf("navigated to %q", nav.URL)
+			continue
+		}
+		for name, values := range u.Query() {
+			if values[0] == "<b>" {
+				injected = append(injected, name)
+			}
+		}
+		if header := nav.Headers["X-Test"]; header != "<b>" {
+			t.Errorf("X-Test = %v on %s, want the payload", header, nav.URL)
+		}
+	}
+	slices.Sort(injected)
+	if !slices.Equal(injected, []string{"page", "q"}) {
+		t.Errorf("navigations injected %q, want page and q", injected)
+	}
+}
+
+func TestScriptedDialogConfirmsFinding(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		Payloads:     []string{"<b>"},
+		Engine:       &browser.FakeEngine{Fire: browser.FireOn("q=%3Cb%3E", "xss")},
+	})
+	findings, err := s.ScanURL(context.Background(), server.URL+"/?q=1&page=2")
+	if err != nil {
+		t.Fatalf("ScanURL: %v", err)
+	}
+
+	var confirmed []report.Finding
+	for _, f := range findings {
+		if f.Confirmed {
+			confirmed = append(confirmed, f)
+		}
+	}
+	if len(confirmed) != 1 {
+		t.Fatalf("%d confirmed findings, want 1: %+v", len(confirmed), findings)
+	}
+	if f := confirmed[0]; f.InjectionPoint != report.PointQuery || f.Param != "q" || !strings.Contains(f.Evidence, `"xss"`) {
+		t.Errorf("confirmed finding = %+v, want the alert on parameter q", f)
+	}
+}
+
+// timedServer returns a server recording when each request arrives
+func timedServer(t *testing.T) (*httptest.Server, func() []time.Time) {
+	t.Helper()
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Time(nil), times...)
+	}
+}
+
+func TestJitterSpacesRequests(t *testing.T) {
+	jitter := ratelimit.Jitter{Min: 40 * time.Millisecond, Max: 80 * time.Millisecond}
+	for _, hostLimit := range []float64{0, 10} {
+		server, times := timedServer(t)
+		config := &ScannerConfig{Method: http.MethodGet, IsParameters: true, Jitter: jitter}
+		if hostLimit > 0 {
+			config.HostLimiter = ratelimit.NewHostLimiter(hostLimit)
+		}
+		s := testScanner(t, config)
+		s.Scan(server.URL+"/?a=1&b=2&c=3&d=4&e=5", "<b>", "")
+
+		got := times()
+		if len(got) != 5 {
+			t.Fatalf("%d requests, want 5", len(got))
+		}
+		// The host limiter's 100ms interval dominates the jitter when set
+		lo, hi := jitter.Min, jitter.Max+40*time.Millisecond
+		if hostLimit > 0 {
+			lo, hi = 90*time.Millisecond, 100*time.Millisecond+jitter.Max
+		}
+		for i := 1; i < len(got); i++ {
+			if gap := got[i].Sub(got[i-1]); gap < lo || gap > hi {
+				t.Errorf("host limit %v: gap %s between requests, want %s-%s", hostLimit, gap, lo, hi)
+			}
+		}
+	}
+}
+
+func TestRotatedUserAgents(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	agents := []string{"agent-a", "agent-b", "agent-c"}
+	engine := &browser.FakeEngine{}
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		UserAgents:   useragent.NewPool(agents),
+		Engine:       engine,
+		Report:       findings,
+	})
+	s.Scan(server.URL+"/?a=1&b=2&c=3", "<b>", "")
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("%d requests, want 3", len(got))
+	}
+	var sent []string
+	probes := make(map[string]string)
+	for _, req := range got {
+		agent := req.Header.Get("User-Agent")
+		if !slices.Contains(agents, agent) {
+			t.Errorf("User-Agent %q isn't from the pool", agent)
+		}
+		sent = append(sent, agent)
+		for name, values := range req.Query {
+			if values[0] == "<b>" {
+				probes[name] = agent
+			}
+		}
+	}
+	if sent[0] == sent[1] || sent[1] == sent[2] || sent[0] == sent[2] {
+		t.Errorf("successive requests sent %q, want different agents", sent)
+	}
+
+	// The browser loads the page as the probe did, and the finding records it
+	for _, nav := range engine.Navigations() {
+		u, _ := url.Parse(nav.URL)
+		for name, values := range u.Query() {
+			if values[0] == "<b>" && nav.Headers["User-Agent"] != probes[name] {
+				t.Errorf("browser sent %v for %s, the probe %q", nav.Headers["User-Agent"], name, probes[name])
+			}
+		}
+	}
+	for _, f := range findings.Findings() {
+		if f.UserAgent != probes[f.Param] {
+			t.Errorf("finding for %s records %q, the probe sent %q", f.Param, f.UserAgent, probes[f.Param])
+		}
+	}
+}
+
+func TestEachMethodRequested(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	s := testScanner(t, &ScannerConfig{Method: "GET,POST,PURGE", IsParameters: true})
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+
+	var methods []string
+	for _, req := range requests() {
+		if q := req.Query["q"]; len(q) == 1 && q[0] == "<b>" {
+			methods = append(methods, req.Method)
+		}
+	}
+	slices.Sort(methods)
+	if want := []string{"GET", "POST", "PURGE"}; !slices.Equal(methods, want) {
+		t.Errorf("injected with %q, want one request per method %q", methods, want)
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	if s := testScanner(t, &ScannerConfig{}); s.Client.Timeout != DefaultHTTPTimeout {
+		t.Errorf("probe timeout = %s without HTTPTimeout, want %s", s.Client.Timeout, DefaultHTTPTimeout)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	engine := &browser.FakeEngine{}
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, ReflectCheck: true, HTTPTimeout: 50 * time.Millisecond, Engine: engine})
+	start := time.Now()
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe of a slow endpoint gave up after %s, want about 50ms", elapsed)
+	}
+	if n := len(engine.Navigations()); n != 0 {
+		t.Errorf("%d navigations after the probe timed out", n)
+	}
+}
+
+func TestFindingsRecordProtocol(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		http1 bool
+		want  string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	} {
+		rt, err := transport.New(transport.Options{Insecure: true, HTTP1: tt.http1})
+		if err != nil {
+			t.Fatalf("transport.New: %v", err)
+		}
+		findings := report.NewCollector()
+		s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Report: findings})
+		s.Client = &http.Client{Transport: rt}
+		s.Scan(server.URL+"/?q=1", "<b>", "")
+
+		got := findings.Findings()
+		if len(got) != 1 || got[0].Protocol != tt.want {
+			t.Errorf("HTTP1 %v: findings = %+v, want one recording %s", tt.http1, got, tt.want)
+		}
+	}
+}
+
+func TestJarCarriesCookiesAcrossProbes(t *testing.T) {
+	var mu sync.Mutex
+	var cookies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	}))
+	defer server.Close()
+
+	jar, err := transport.NewJar("")
+	if err != nil {
+		t.Fatalf("NewJar: %v", err)
+	}
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Jar: jar})
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+	s.Scan(server.URL+"/?q=2", "<b>", "")
+
+	if len(cookies) != 2 || cookies[0] != "" || cookies[1] != "session=abc" {
+		t.Errorf("cookies sent = %q, want none then the session set by the first response", cookies)
+	}
+}
+
+func TestConfirmedFindingCapturesExchange(t *testing.T) {
+	var sent received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = received{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone()}
+		w.Header().Set("X-Session", "server-secret")
+		w.Header().Set("X-Served-By", "test")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:        http.MethodGet,
+		IsParameters:  true,
+		Headers:       http.Header{"Authorization": {"Bearer abc"}, "X-Api-Key": {"k"}, "X-Trace": {"t"}},
+		RedactHeaders: []string{"x-api-key", "X-Session"},
+		Engine:        &browser.FakeEngine{Fire: browser.FireOn("q=", "1")},
+		Report:        findings,
+	})
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+
+	got := findings.Findings()
+	if len(got) != 1 || got[0].Request == nil || got[0].Response == nil {
+		t.Fatalf("findings = %+v, want one confirmed with its exchange", got)
+	}
+	req, resp := got[0].Request, got[0].Response
+	if req.Method != sent.Method || req.URL != server.URL+sent.Path {
+		t.Errorf("captured %s %s, sent %s %s", req.Method, req.URL, sent.Method, sent.Path)
+	}
+	for name, want := range map[string]string{"Authorization": report.Redacted, "X-Api-Key": report.Redacted, "X-Trace": "t"} {
+		if req.Header.Get(name) != want {
+			t.Errorf("captured %s = %q, want %q", name, req.Header.Get(name), want)
+		}
+	}
+	if sent.Header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("Authorization sent = %q, redaction must only apply to the capture", sent.Header.Get("Authorization"))
+	}
+	if resp.Status != http.StatusCreated || resp.Header.Get("X-Session") != report.Redacted || resp.Header.Get("X-Served-By") != "test" {
+		t.Errorf("captured response = %+v", resp)
+	}
+}
+
+func TestUnconfirmedFindingOmitsExchange(t *testing.T) {
+	server, _ := recordServer(t, "ok")
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{Method: http.MethodGet, IsParameters: true, Report: findings})
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+
+	if got := findings.Findings(); len(got) != 1 || got[0].Request != nil || got[0].Response != nil {
+		t.Errorf("findings = %+v, want one without the exchange", got)
+	}
+}
+
+func TestUnreachableHostsSkipped(t *testing.T) {
+	live, requests := recordServer(t, "ok")
+	liveURL, _ := url.Parse(live.URL)
+	failures := map[string]error{
+		"dns.example":     &net.DNSError{Err: "no such host", Name: "dns.example", IsNotFound: true},
+		"refused.example": &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+	}
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if err, ok := failures[r.URL.Host]; ok {
+			mu.Lock()
+			attempts[r.URL.Host]++
+			mu.Unlock()
+			return nil, err
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	unreachable := transport.NewUnreachable()
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		Transport:    rt,
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+		Unreachable:  unreachable,
+	})
+	for i := 0; i < 5; i++ {
+		for _, host := range []string{"dns.example", "refused.example", liveURL.Host} {
+			s.Scan("http://"+host+"/?q=1", "<b>", "")
+		}
+	}
+
+	// DNS failures aren't retried and give the host up at once, refused
+	// connections are retried until DefaultMaxFailures probes have failed
+	if attempts["dns.example"] != 1 || attempts["refused.example"] != transport.DefaultMaxFailures*2 {
+		t.Errorf("attempts = %v, want 1 for DNS and %d refused", attempts, transport.DefaultMaxFailures*2)
+	}
+	if got := len(requests()); got != 5 {
+		t.Errorf("%d requests to the live host, want the scan to carry on with all 5", got)
+	}
+	want := map[string]string{"dns.example": transport.ReasonDNS, "refused.example": transport.ReasonRefused}
+	if got := unreachable.Hosts(); !maps.Equal(got, want) {
+		t.Errorf("unreachable hosts = %v, want %v", got, want)
+	}
+}
+
+func TestPoolTimeouts(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		acquire, release time.Duration
+		wantAcquire      time.Duration
+		wantRelease      time.Duration
+	}{
+		{"zero keeps the defaults", 0, 0, browser.DefaultAcquireTimeout, browser.DefaultReleaseTimeout},
+		{"set", 2 * time.Second, 3 * time.Second, 2 * time.Second, 3 * time.Second},
+		{"negative waits indefinitely", -1, -1, 0, 0},
+	} {
+		s := testScanner(t, &ScannerConfig{AcquireTimeout: tt.acquire, ReleaseTimeout: tt.release})
+		if got := s.browserPool.AcquireTimeout; got != tt.wantAcquire {
+			t.Errorf("%s: AcquireTimeout = %v, want %v", tt.name, got, tt.wantAcquire)
+		}
+		if got := s.browserPool.ReleaseTimeout; got != tt.wantRelease {
+			t.Errorf("%s: ReleaseTimeout = %v, want %v", tt.name, got, tt.wantRelease)
+		}
+	}
+}
+
+func TestProgressCountsRequests(t *testing.T) {
+	server, requests := recordServer(t, "ok")
+	reporter := progress.New(10)
+	unreachable := transport.NewUnreachable()
+	unreachable.Failed("down.example", &net.DNSError{Err: "no such host", Name: "down.example", IsNotFound: true})
+	s := testScanner(t, &ScannerConfig{
+		Method:       "GET,POST",
+		IsParameters: true,
+		Data:         "a=1&b=2",
+		Unreachable:  unreachable,
+		Progress:     reporter,
+	})
+
+	// The query of both methods and the two body fields of the POST
+	s.Scan(server.URL+"/?q=1", "<b>", "")
+	if n := len(requests()); n != 4 {
+		t.Fatalf("%d requests sent, want 4", n)
+	}
+	for i := 0; i < 3; i++ {
+		reporter.Queued()
+	}
+	reporter.Done()
+
+	// Two URLs are left, each needing 4 requests at 10 a second
+	want := 800 * time.Millisecond
+	if eta, ok := reporter.ETA(time.Second); !ok || eta != want {
+		t.Errorf("ETA = %v, want %v", eta, want)
+	}
+
+	// A host given up on is skipped without sending anything
+	s.Scan("http://down.example/?q=1", "<b>", "")
+	if eta, _ := reporter.ETA(time.Second); eta != want {
+		t.Errorf("ETA = %v after skipping an unreachable host, want %v", eta, want)
+	}
+}
+
+func TestBodyIntactAfterTemporaryRedirect(t *testing.T) {
+	landing, requests := recordServer(t, "ok")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Redirect(w, r, landing.URL+"/new", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	s := testScanner(t, &ScannerConfig{Method: http.MethodPost, Data: "comment=hi&page=2", FollowRedirects: true})
+	s.Scan(server.URL+"/old", "<b>", "")
+
+	// The bare probe posts no body, so only the body injections are checked
+	var injected []received
+	for _, r := range requests() {
+		if r.Method != http.MethodPost {
+			t.Errorf("307 re-sent as %s", r.Method)
+		}
+		if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			injected = append(injected, r)
+		}
+	}
+	if len(injected) != 2 {
+		t.Fatalf("%d body injections redirected, want one per field", len(injected))
+	}
+	for _, r := range injected {
+		fields, err := url.ParseQuery(r.Body)
+		if err != nil || len(fields) != 2 || (fields.Get("comment") != "<b>" && fields.Get("page") != "<b>") {
+			t.Errorf("body after the 307 = %q, want both fields with one injected", r.Body)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// viewState remembers the dialog messages already seen on each view URL, so
+// a payload stored earlier firing again isn't credited to later injections
+type viewState struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newViewState() *viewState {
+	return &viewState{seen: make(map[string]bool)}
+}
+
+// checkViews loads each of the ViewURLs after the injection of finding and
+// reports it confirmed on those its payload executed on, as stored XSS. A
+// dialog carrying the token of another injection is left to that injection;
+// one without a token is only credited if its message is new to the view.
+func (s *Scanner) checkViews(finding report.Finding) {
+	if len(s.Config.ViewURLs) == 0 || s.context().Err() != nil {
+		return
+	}
+
+	for _, view := range s.Config.ViewURLs {
+		dialogs, err := s.viewDialogs(view)
+		if err != nil {
+			s.log.Error("Error loading view URL " + view + ": " + err.Error())
+			continue
+		}
+
+		// Every message is recorded as seen, not just the one credited
+		var credited *browser.Dialog
+		for i, dialog := range dialogs {
+			if s.credits(view, dialog, finding.Token) && credited == nil {
+				credited = &dialogs[i]
+			}
+		}
+		if credited == nil {
+			continue
+		}
+
+		s.log.Success(fmt.Sprintf("Stored XSS confirmed: %s dialog with message %q on %s", credited.Type, credited.Message, view))
+		atomic.AddInt64(s.confirmed, 1)
+		stored := finding
+		stored.View = view
+		stored.Confirmed = true
+		stored.Evidence = fmt.Sprintf("%s dialog with message %q on %s", credited.Type, credited.Message, view)
+		s.writeFinding(stored)
+	}
+}
+
+// credits reports whether dialog, opened on view, shows the injection of the
+// payload carrying token executed
+func (s *Scanner) credits(view string, dialog browser.Dialog, token string) bool {
+	if s.Config.Tokens != nil {
+		if inj, ok := s.Config.Tokens.Match(dialog.Message); ok {
+			return inj.Token == token
+		}
+	}
+
+	key := view + "\x00" + dialog.Message
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	if s.views.seen[key] {
+		return false
+	}
+	s.views.seen[key] = true
+	return true
+}
+
+// viewDialogs loads view in a browser context with the global headers and
+// returns the dialogs the page opened
+func (s *Scanner) viewDialogs(view string) ([]browser.Dialog, error) {
+	browserCtx, err := s.getBrowserContext()
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseBrowserContext(browserCtx)
+
+	ctx, cancel := context.WithCancel(browserCtx)
+	defer cancel()
+	stop := context.AfterFunc(s.context(), cancel)
+	defer stop()
+
+	// Discard dialogs left over from the context's previous user
+	driver := browser.DriverFromContext(ctx)
+	driver.DialogEvents()
+
+	var headers map[string]interface{}
+	if len(s.Config.Headers) > 0 {
+		headers = make(map[string]interface{})
+		for key := range s.Config.Headers {
+			headers[key] = s.Config.Headers.Get(key)
+		}
+	}
+	if err := s.navigate(ctx, view, headers); err != nil {
+		return nil, err
+	}
+	return driver.DialogEvents(), nil
+}
@@ -0,0 +1,126 @@
+package scan
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/browser"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// alertScript matches the alert calls a page would run
+var alertScript = regexp.MustCompile(`<script>alert\('([^']*)'\)</script>`)
+
+// commentServer stores the comments posted to /comment and renders them all at
+// /comments, the title and text fields as is and the author escaped
+func commentServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	var comments []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/comment":
+			r.ParseForm()
+			form := r.PostForm
+			comments = append(comments, "<h2>"+form.Get("title")+"</h2><p>"+form.Get("text")+" by "+html.EscapeString(form.Get("author"))+"</p>")
+		case "/comments":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, strings.Join(comments, "\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runScripts is a FakeEngine Fire loading the page and opening an alert for
+// every script on it, as a browser rendering it would
+func runScripts(url string, headers map[string]interface{}) []browser.Dialog {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	page, _ := io.ReadAll(resp.Body)
+
+	var dialogs []browser.Dialog
+	for _, match := range alertScript.FindAllStringSubmatch(string(page), -1) {
+		dialogs = append(dialogs, browser.Dialog{Type: "alert", Message: match[1]})
+	}
+	return dialogs
+}
+
+// storedFindings returns the findings confirmed on a view URL
+func storedFindings(findings *report.Collector) []report.Finding {
+	var stored []report.Finding
+	for _, f := range findings.Findings() {
+		if f.View != "" {
+			stored = append(stored, f)
+		}
+	}
+	return stored
+}
+
+func TestStoredPayloadDetectedOnView(t *testing.T) {
+	server := commentServer(t)
+	tokens := callback.NewIndex()
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:   http.MethodPost,
+		Data:     "text=hi&author=bob",
+		ViewURLs: []string{server.URL + "/comments"},
+		Tokens:   tokens,
+		Engine:   &browser.FakeEngine{Fire: runScripts},
+		Report:   findings,
+	})
+	s.Scan(server.URL+"/comment", "<script>alert('{{token}}')</script>", "")
+
+	// Once the author is posted the text payload runs on the view again, but
+	// only the text injection carries its token
+	stored := storedFindings(findings)
+	if len(stored) != 1 {
+		t.Fatalf("stored findings = %+v, want only the text field", stored)
+	}
+	f := stored[0]
+	if f.View != server.URL+"/comments" || f.Param != "text" || f.InjectionPoint != report.PointBody || !f.Confirmed {
+		t.Errorf("stored finding = %+v, want text confirmed on /comments", f)
+	}
+	inj, ok := tokens.Lookup(f.Token)
+	if !ok || inj.Param != "text" || inj.URL != server.URL+"/comment" {
+		t.Errorf("token %q indexed as %+v, want the text injection into /comment", f.Token, inj)
+	}
+	if inj.Payload != "<script>alert('"+f.Token+"')</script>" {
+		t.Errorf("injected payload %q doesn't carry the token %q", inj.Payload, f.Token)
+	}
+	if want := `alert dialog with message "` + f.Token + `" on ` + server.URL + "/comments"; f.Evidence != want {
+		t.Errorf("evidence = %q, want %q", f.Evidence, want)
+	}
+}
+
+func TestStoredPayloadWithoutTokenCreditedOnce(t *testing.T) {
+	server := commentServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:   http.MethodPost,
+		Data:     "title=t&text=hi",
+		ViewURLs: []string{server.URL + "/comments"},
+		Engine:   &browser.FakeEngine{Fire: runScripts},
+		Report:   findings,
+	})
+	s.Scan(server.URL+"/comment", "<script>alert('1')</script>", "")
+
+	// Both fields run the payload, but without a token the same message
+	// showing up again can't be told apart from the first injection still on
+	// the page
+	if stored := storedFindings(findings); len(stored) != 1 {
+		t.Errorf("stored findings = %+v, want the payload credited once", stored)
+	}
+}
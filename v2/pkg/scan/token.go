@@ -0,0 +1,100 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+// CallbackPlaceholder is left in payloads for the scanner when a collaborator
+// supplies the callback URL, which is then unique to each injection
+const CallbackPlaceholder = "{{callback}}"
+
+// InjectionPlaceholders matches the placeholders filled in differently for
+// every injection of a payload: {{param}}, {{token}} and {{callback}}
+var InjectionPlaceholders = regexp.MustCompile(`\{\{(?:param|token|callback)\}\}`)
+
+// Filler returns the value to inject into the named parameter, header, field
+// or cookie: the payload with its placeholders filled in, and the token
+// correlating callbacks to that injection, if the payload carries one
+type Filler func(name string) (value string, token string)
+
+// Fill returns a Filler filling in only the {{param}} placeholder of payload
+func Fill(payload string) Filler {
+	return func(name string) (string, string) {
+		return strings.ReplaceAll(payload, ParamPlaceholder, name), ""
+	}
+}
+
+// filler returns a Filler for the injections of payload into link at point,
+// see fill
+func (s *Scanner) filler(payload string, link string, point string) Filler {
+	return func(name string) (string, string) {
+		return s.fill(payload, link, injection{point: point, name: name})
+	}
+}
+
+// fill fills in the {{param}} placeholder of payload with the name injected
+// into, then gives the injection a token of its own, see withToken
+func (s *Scanner) fill(payload string, link string, at injection) (string, string) {
+	payload = strings.ReplaceAll(payload, ParamPlaceholder, at.name)
+	return s.withToken(payload, link, at)
+}
+
+// withToken replaces the {{token}} placeholder in payload with a fresh token,
+// and {{callback}} with a collaborator URL unique to it, and records the
+// injection of the result into link at the given point in the token index, so
+// hits can be traced back to it. Payloads without either placeholder are
+// returned as is, with no token.
+func (s *Scanner) withToken(payload string, link string, at injection) (string, string) {
+	injected, token := s.tokenFor(payload)
+	at.token = token
+	s.recordToken(injected, link, at)
+	return injected, token
+}
+
+// tokenFor fills in the {{token}} and collaborator {{callback}} placeholders
+// of payload with a fresh token, for withToken, returning no token when the
+// payload has neither. The injection is left for recordToken to record.
+func (s *Scanner) tokenFor(payload string) (string, string) {
+	collaborate := s.Config.Collaborator != nil && strings.Contains(payload, CallbackPlaceholder)
+	if s.Config.Tokens == nil || (!strings.Contains(payload, callback.TokenPlaceholder) && !collaborate) {
+		return payload, ""
+	}
+
+	token := callback.NewToken()
+	injected := strings.ReplaceAll(payload, callback.TokenPlaceholder, token)
+	if collaborate {
+		injected = strings.ReplaceAll(injected, CallbackPlaceholder, s.Config.Collaborator.URL(token))
+	}
+	return injected, token
+}
+
+// recordToken records the injection of payload, carrying at.token, into link
+// at the given point in the token index
+func (s *Scanner) recordToken(payload string, link string, at injection) {
+	if at.token == "" {
+		return
+	}
+
+	token := at.token
+	inj := callback.Injection{
+		Token:     token,
+		URL:       link,
+		Point:     at.point,
+		Payload:   payload,
+		Source:    s.Config.Source,
+		Encodings: s.Config.Encodings,
+	}
+	if at.point == report.PointHeader {
+		inj.Header = at.name
+	} else {
+		inj.Param = at.name
+	}
+	s.Config.Tokens.Add(inj)
+	if s.Config.Debug {
+		s.log.Debug("Token " + token + " assigned to " + link + " (" + at.point + " " + at.name + ")")
+	}
+}
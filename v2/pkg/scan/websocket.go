@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// WebSocket configures sending the payload in WebSocket messages instead of
+// HTTP requests, for input that only shows up later, e.g. in an admin panel
+type WebSocket struct {
+	Enabled bool
+
+	// Message is a JSON message template with the payload placed in each of
+	// its values in turn, as with -data. Empty sends the bare payload.
+	Message string
+
+	// Subprotocols are offered in the handshake
+	Subprotocols []string
+}
+
+// WebSocketMessages returns the messages carrying the payload: one per value
+// of the JSON template, or the payload itself when there is no template
+func WebSocketMessages(template string, fill Filler, mode Mode) ([]BodyInjection, error) {
+	if strings.TrimSpace(template) == "" {
+		value, token := fill("")
+		return []BodyInjection{{Body: value, Token: token}}, nil
+	}
+	return JSONInjections(template, fill, mode, nil)
+}
+
+// webSocketURL returns link with an http(s) scheme swapped for ws(s)
+func webSocketURL(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported WebSocket scheme '%s'", u.Scheme)
+	}
+	return u.String(), nil
+}
+
+// injectWebSocket connects to link and sends each message carrying the
+// payload over one connection
+func (s *Scanner) injectWebSocket(payload string, link string) {
+	target, err := webSocketURL(link)
+	if err != nil {
+		s.log.Error("Error parsing WebSocket URL: " + err.Error())
+		return
+	}
+	messages, err := WebSocketMessages(s.Config.WebSocket.Message, s.filler(payload, link, report.PointWebSocket), s.mode())
+	if err != nil {
+		s.log.Error("Error building WebSocket message: " + err.Error())
+		return
+	}
+
+	header := s.Config.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if userAgent := s.userAgent(); userAgent != "" {
+		header.Set("User-Agent", userAgent)
+	}
+	if len(s.Config.Cookies) > 0 {
+		request := &http.Request{Header: http.Header{}}
+		for _, cookie := range s.Config.Cookies {
+			request.AddCookie(cookie)
+		}
+		header.Set("Cookie", request.Header.Get("Cookie"))
+	}
+
+	if s.Config.DryRun {
+		for _, message := range messages {
+			s.log.Printf("WebSocket message to %s\n%s\n\n", target, message.Body)
+		}
+		return
+	}
+	if s.Config.Budget != nil {
+		if !s.Config.Budget.Take() {
+			return
+		}
+		defer s.Config.Budget.Finish()
+	}
+	s.pace(hostOf(target))
+
+	dialer := ws.Dialer{
+		Protocols: s.Config.WebSocket.Subprotocols,
+		Header:    ws.HandshakeHeaderHTTP(header),
+		Timeout:   s.Client.Timeout,
+	}
+	if t, ok := s.Config.Transport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		dialer.TLSConfig = t.TLSClientConfig.Clone()
+	} else {
+		dialer.TLSConfig = &tls.Config{}
+	}
+
+	conn, br, hs, err := dialer.Dial(s.context(), target)
+	if err != nil {
+		s.log.Error("Error connecting to WebSocket: " + err.Error())
+		return
+	}
+	defer conn.Close()
+	if br != nil {
+		ws.PutReader(br)
+	}
+	if hs.Protocol != "" {
+		s.log.Notice("WebSocket subprotocol: " + hs.Protocol)
+	}
+
+	for _, message := range messages {
+		if s.context().Err() != nil {
+			break
+		}
+		if message.Field != "" {
+			s.log.Notice("WebSocket field: " + message.Field)
+		}
+		if s.Config.Debug {
+			s.log.Debug("WebSocket message: " + message.Body)
+		}
+		if err := wsutil.WriteClientText(conn, []byte(message.Body)); err != nil {
+			s.log.Error("Error sending WebSocket message: " + err.Error())
+			return
+		}
+
+		s.writeFinding(report.Finding{
+			Target:         target,
+			Param:          message.Field,
+			Payload:        payload,
+			InjectionPoint: report.PointWebSocket,
+			Token:          message.Token,
+			UserAgent:      header.Get("User-Agent"),
+		})
+	}
+
+	closeBody := ws.NewCloseFrameBody(ws.StatusNormalClosure, "")
+	wsutil.WriteClientMessage(conn, ws.OpClose, closeBody)
+}
@@ -0,0 +1,147 @@
+package scan
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/callback"
+	"github.com/ethicalhackingplayground/bxss/v2/pkg/report"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// wsEchoServer is a loopback WebSocket server echoing every text message it
+// receives, and recording them along with the handshake
+type wsEchoServer struct {
+	*httptest.Server
+	closed chan struct{}
+
+	mu        sync.Mutex
+	messages  []string
+	protocol  string
+	userAgent string
+}
+
+func newWSEchoServer(t *testing.T) *wsEchoServer {
+	t.Helper()
+	e := &wsEchoServer{closed: make(chan struct{}, 1)}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := ws.HTTPUpgrader{Protocol: func(p string) bool { return p == "chat" }}
+		conn, rw, hs, err := upgrader.Upgrade(r, w)
+		if err != nil {
+			return
+		}
+		defer func() {
+			conn.Close()
+			e.closed <- struct{}{}
+		}()
+		e.mu.Lock()
+		e.protocol, e.userAgent = hs.Protocol, r.UserAgent()
+		e.mu.Unlock()
+
+		// Messages sent right after the handshake may already be buffered
+		rwc := struct {
+			io.Reader
+			io.Writer
+		}{rw.Reader, conn}
+		for {
+			data, op, err := wsutil.ReadClientData(rwc)
+			if err != nil || op == ws.OpClose {
+				return
+			}
+			e.mu.Lock()
+			e.messages = append(e.messages, string(data))
+			e.mu.Unlock()
+			wsutil.WriteServerMessage(rwc, op, data)
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// received waits for the client to close its connection and returns the
+// messages it sent
+func (e *wsEchoServer) received(t *testing.T) []string {
+	t.Helper()
+	select {
+	case <-e.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the WebSocket connection wasn't closed")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.messages...)
+}
+
+func TestWebSocketPayloadSent(t *testing.T) {
+	server := newWSEchoServer(t)
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		UserAgent: "bxss-test",
+		WebSocket: WebSocket{Enabled: true, Subprotocols: []string{"other", "chat"}},
+		Report:    findings,
+	})
+	s.Scan(server.URL+"/ws", "<b>", "")
+
+	if got := server.received(t); len(got) != 1 || got[0] != "<b>" {
+		t.Errorf("messages = %q, want the bare payload", got)
+	}
+	if server.protocol != "chat" || server.userAgent != "bxss-test" {
+		t.Errorf("handshake negotiated %q with User-Agent %q", server.protocol, server.userAgent)
+	}
+	got := findings.Findings()
+	if len(got) != 1 || got[0].InjectionPoint != report.PointWebSocket || !strings.HasPrefix(got[0].Target, "ws://") {
+		t.Errorf("findings = %+v, want one for the WebSocket", got)
+	}
+}
+
+func TestWebSocketMessageTemplateTokens(t *testing.T) {
+	server := newWSEchoServer(t)
+	tokens := callback.NewIndex()
+	findings := report.NewCollector()
+	s := testScanner(t, &ScannerConfig{
+		WebSocket: WebSocket{Enabled: true, Message: `{"room":"lobby","text":"hi"}`},
+		Tokens:    tokens,
+		Report:    findings,
+	})
+	s.Scan(server.URL+"/ws", `<img src=//cb.example/{{token}}>`, "")
+
+	messages := server.received(t)
+	if len(messages) != 2 {
+		t.Fatalf("messages = %q, want one per template value", messages)
+	}
+	for i, field := range []string{"room", "text"} {
+		var msg map[string]string
+		if err := json.Unmarshal([]byte(messages[i]), &msg); err != nil {
+			t.Fatalf("invalid JSON message %q: %v", messages[i], err)
+		}
+		inj, ok := tokens.Match(msg[field])
+		if !ok || inj.Point != report.PointWebSocket || inj.Param != field {
+			t.Errorf("%s = %q correlates to %+v, want its WebSocket injection", field, msg[field], inj)
+		}
+	}
+	if got := findings.Findings(); len(got) != 2 || got[0].Token == got[1].Token || got[0].Token == "" {
+		t.Errorf("findings = %+v, want one per message with a token of its own", got)
+	}
+}
+
+func TestWebSocketURL(t *testing.T) {
+	for link, want := range map[string]string{
+		"http://target.example/ws":  "ws://target.example/ws",
+		"https://target.example/ws": "wss://target.example/ws",
+		"wss://target.example/ws":   "wss://target.example/ws",
+	} {
+		if got, err := webSocketURL(link); err != nil || got != want {
+			t.Errorf("webSocketURL(%s) = %s, %v, want %s", link, got, err, want)
+		}
+	}
+	if _, err := webSocketURL("ftp://target.example/"); err == nil {
+		t.Error("ftp URL accepted")
+	}
+}
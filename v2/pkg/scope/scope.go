@@ -0,0 +1,117 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Scope decides which hosts may be sent requests. A host is in scope when it
+// matches an allow rule, or there are none, and matches no exclude rule.
+//
+// A rule is an exact host (example.com), a wildcard matching any subdomain
+// (*.example.com), or a regular expression between slashes searched for in
+// the host (/^api[0-9]+\.example\.com$/). Hosts are compared without their
+// port and case-insensitively.
+type Scope struct {
+	allow   []rule
+	exclude []rule
+}
+
+// rule matches a host by exact name, subdomain suffix or regular expression
+type rule struct {
+	host   string
+	suffix string
+	re     *regexp.Regexp
+}
+
+// New parses the allow and exclude rules, each may hold several comma separated rules
+func New(allow []string, exclude []string) (*Scope, error) {
+	s := &Scope{}
+	var err error
+	if s.allow, err = parseRules(allow); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = parseRules(exclude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Empty reports whether the scope has no rules and so allows every host
+func (s *Scope) Empty() bool {
+	return s == nil || (len(s.allow) == 0 && len(s.exclude) == 0)
+}
+
+// Allows reports whether requests may be sent to host, which may include a port
+func (s *Scope) Allows(host string) bool {
+	if s == nil {
+		return true
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, r := range s.exclude {
+		if r.match(host) {
+			return false
+		}
+	}
+	if len(s.allow) == 0 {
+		return true
+	}
+	for _, r := range s.allow {
+		if r.match(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsURL reports whether requests may be sent to the host of link
+func (s *Scope) AllowsURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return s.Allows(u.Host)
+}
+
+func (r rule) match(host string) bool {
+	switch {
+	case r.re != nil:
+		return r.re.MatchString(host)
+	case r.suffix != "":
+		return strings.HasSuffix(host, r.suffix)
+	default:
+		return host == r.host
+	}
+}
+
+func parseRules(values []string) ([]rule, error) {
+	var rules []rule
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			switch {
+			case v == "":
+				continue
+			case len(v) > 1 && strings.HasPrefix(v, "/") && strings.HasSuffix(v, "/"):
+				re, err := regexp.Compile(v[1 : len(v)-1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid scope rule '%s': %w", v, err)
+				}
+				rules = append(rules, rule{re: re})
+			case strings.HasPrefix(v, "*."):
+				rules = append(rules, rule{suffix: strings.ToLower(v[1:])})
+			default:
+				rules = append(rules, rule{host: strings.ToLower(v)})
+			}
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,54 @@
+package scope
+
+import "testing"
+
+func TestScope(t *testing.T) {
+	s, err := New([]string{"target.example, *.app.example", `/^api[0-9]+\.corp\.example$/`}, []string{"admin.app.example"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for host, want := range map[string]bool{
+		"target.example":         true,
+		"TARGET.example:8443":    true,
+		"target.example.":        true,
+		"www.target.example":     false,
+		"eviltarget.example":     false,
+		"shop.app.example":       true,
+		"a.b.app.example":        true,
+		"app.example":            false,
+		"evilapp.example":        false,
+		"admin.app.example":      false,
+		"admin.app.example:443":  false,
+		"api2.corp.example":      true,
+		"api.corp.example":       false,
+		"api2.corp.example.evil": false,
+		"other.example":          false,
+	} {
+		if got := s.Allows(host); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	if !s.AllowsURL("https://shop.app.example/cart?id=1") || s.AllowsURL("https://admin.app.example/") || s.AllowsURL("://bad") {
+		t.Error("AllowsURL disagrees with Allows")
+	}
+}
+
+func TestExcludeOnly(t *testing.T) {
+	s, err := New(nil, []string{"*.internal.example"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !s.Allows("target.example") || s.Allows("db.internal.example") || s.Empty() {
+		t.Error("exclude-only scope should allow everything but the excluded subdomains")
+	}
+
+	var none *Scope
+	if !none.Allows("anything.example") || !none.Empty() {
+		t.Error("a nil scope should allow every host")
+	}
+	if _, err := New([]string{"/[/"}, nil); err == nil {
+		t.Error("invalid regular expression accepted")
+	}
+}
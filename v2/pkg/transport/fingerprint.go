@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLS fingerprints the HTTP probes can present
+const (
+	// FingerprintDefault is Go's own ClientHello
+	FingerprintDefault = "default"
+
+	// FingerprintChrome mimics the ClientHello, and so the JA3, of a recent Chrome
+	FingerprintChrome = "chrome"
+
+	// FingerprintFirefox mimics the ClientHello of a recent Firefox
+	FingerprintFirefox = "firefox"
+)
+
+// Fingerprints lists the accepted Options.Fingerprint values
+var Fingerprints = []string{FingerprintDefault, FingerprintChrome, FingerprintFirefox}
+
+// helloIDs maps the fingerprints other than the default to their ClientHello
+var helloIDs = map[string]utls.ClientHelloID{
+	FingerprintChrome:  utls.HelloChrome_Auto,
+	FingerprintFirefox: utls.HelloFirefox_Auto,
+}
+
+// ValidFingerprint reports whether name is one of Fingerprints
+func ValidFingerprint(name string) bool {
+	_, ok := helloIDs[name]
+	return ok || name == "" || name == FingerprintDefault
+}
+
+// fingerprintDialer returns a DialTLSContext sending the ClientHello of the
+// fingerprint, verifying the server as tlsConfig would. Only HTTP/1.1 is
+// offered, as the transport can't speak HTTP/2 over a connection it didn't
+// set up itself.
+func fingerprintDialer(name string, tlsConfig *tls.Config) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	helloID, ok := helloIDs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS fingerprint '%s'", name)
+	}
+	// Check the profile once upfront rather than on every connection
+	if _, err := helloSpec(helloID); err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := &utls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+			RootCAs:            tlsConfig.RootCAs,
+		}
+		client := utls.UClient(conn, config, utls.HelloCustom)
+		spec, _ := helloSpec(helloID)
+		if err := client.ApplyPreset(&spec); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to apply TLS fingerprint: %w", err)
+		}
+		if err := client.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return client, nil
+	}, nil
+}
+
+// helloSpec returns a fresh ClientHello of helloID offering only HTTP/1.1
+func helloSpec(helloID utls.ClientHelloID) (utls.ClientHelloSpec, error) {
+	spec, err := utls.UTLSIdToSpec(helloID)
+	if err != nil {
+		return spec, fmt.Errorf("failed to load TLS fingerprint: %w", err)
+	}
+	for _, extension := range spec.Extensions {
+		if alpn, ok := extension.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = []string{"http/1.1"}
+		}
+	}
+	return spec, nil
+}
+
+// applyFingerprint makes t present the ClientHello of fingerprint on direct
+// HTTPS connections. Through a proxy the transport handshakes itself, and an
+// intercepting proxy presents its own ClientHello to the target anyway.
+func applyFingerprint(t *http.Transport, fingerprint string) error {
+	if fingerprint == "" || fingerprint == FingerprintDefault {
+		return nil
+	}
+	dial, err := fingerprintDialer(fingerprint, t.TLSClientConfig)
+	if err != nil {
+		return err
+	}
+	t.DialTLSContext = dial
+	t.ForceAttemptHTTP2 = false
+	return nil
+}
@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// clientHello is the part of a ClientHello a fingerprint is made of
+type clientHello struct {
+	ciphers    []uint16
+	extensions []uint16
+	alpn       []string
+}
+
+// helloServer returns a TLS server recording the ClientHello of the last
+// connection made to it
+func helloServer(t *testing.T) (*httptest.Server, func() clientHello) {
+	t.Helper()
+	var mu sync.Mutex
+	var last clientHello
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			last = clientHello{
+				ciphers:    slices.Clone(hello.CipherSuites),
+				extensions: slices.Clone(hello.Extensions),
+				alpn:       slices.Clone(hello.SupportedProtos),
+			}
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, func() clientHello {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+// grease reports whether values include a GREASE value (RFC 8701), which
+// browsers send and Go doesn't
+func grease(values []uint16) bool {
+	return slices.ContainsFunc(values, func(v uint16) bool {
+		return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+	})
+}
+
+func TestFingerprintChangesClientHello(t *testing.T) {
+	server, last := helloServer(t)
+
+	hellos := make(map[string]clientHello)
+	for _, fingerprint := range Fingerprints {
+		if err := get(t, Options{Insecure: true, Fingerprint: fingerprint}, server.URL); err != nil {
+			t.Fatalf("%s fingerprint: %v", fingerprint, err)
+		}
+		hellos[fingerprint] = last()
+	}
+
+	def, chrome, firefox := hellos[FingerprintDefault], hellos[FingerprintChrome], hellos[FingerprintFirefox]
+	if grease(def.ciphers) || grease(def.extensions) {
+		t.Errorf("Go's ClientHello carries GREASE: %+v", def)
+	}
+	if !grease(chrome.ciphers) || !grease(chrome.extensions) {
+		t.Errorf("the chrome ClientHello carries no GREASE: %+v", chrome)
+	}
+	if grease(firefox.ciphers) {
+		t.Errorf("the firefox ClientHello carries GREASE: %+v", firefox)
+	}
+
+	for _, pair := range [][2]string{
+		{FingerprintDefault, FingerprintChrome},
+		{FingerprintDefault, FingerprintFirefox},
+		{FingerprintChrome, FingerprintFirefox},
+	} {
+		a, b := hellos[pair[0]], hellos[pair[1]]
+		if slices.Equal(a.ciphers, b.ciphers) {
+			t.Errorf("%s and %s offer the same cipher suites %x", pair[0], pair[1], a.ciphers)
+		}
+		if slices.Equal(a.extensions, b.extensions) {
+			t.Errorf("%s and %s send the same extensions %x", pair[0], pair[1], a.extensions)
+		}
+	}
+
+	// The fingerprinted connections can't be handed to the HTTP/2 transport
+	for _, fingerprint := range []string{FingerprintChrome, FingerprintFirefox} {
+		if alpn := hellos[fingerprint].alpn; !slices.Equal(alpn, []string{"http/1.1"}) {
+			t.Errorf("the %s ClientHello offers %q, want only http/1.1", fingerprint, alpn)
+		}
+	}
+}
+
+func TestUnknownFingerprint(t *testing.T) {
+	if ValidFingerprint("safari") {
+		t.Error("safari accepted as a fingerprint")
+	}
+	for _, fingerprint := range append([]string{""}, Fingerprints...) {
+		if !ValidFingerprint(fingerprint) {
+			t.Errorf("%q rejected as a fingerprint", fingerprint)
+		}
+	}
+	if _, err := New(Options{Fingerprint: "safari"}); err == nil {
+		t.Error("New accepted an unknown fingerprint")
+	}
+}
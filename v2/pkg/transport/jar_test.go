@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJarCarriesSetCookie(t *testing.T) {
+	var cookies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		}
+	}))
+	defer server.Close()
+
+	jar, err := NewJar("")
+	if err != nil {
+		t.Fatalf("NewJar: %v", err)
+	}
+	client := &http.Client{Jar: jar}
+	for _, path := range []string{"/login", "/account"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	if cookies[0] != "" || cookies[1] != "session=abc" {
+		t.Errorf("cookies sent = %q, want none then session=abc", cookies)
+	}
+}
+
+func TestJarSeededFromFile(t *testing.T) {
+	var cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie = r.Header.Get("Cookie")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	content := "# Netscape HTTP Cookie File\n" +
+		"127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tseeded\n" +
+		"#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t0\tauth\tsecret\n" +
+		"other.example\tFALSE\t/\tFALSE\t0\tforeign\tx\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jar, err := NewJar(path)
+	if err != nil {
+		t.Fatalf("NewJar: %v", err)
+	}
+
+	resp, err := (&http.Client{Jar: jar}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if cookie != "session=seeded; auth=secret" {
+		t.Errorf("Cookie = %q, want the seeded cookies for the host only", cookie)
+	}
+}
+
+func TestJarFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewJar(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("missing cookie file accepted")
+	}
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("session=abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewJar(invalid); err == nil {
+		t.Error("cookie file without tab separated fields accepted")
+	}
+}
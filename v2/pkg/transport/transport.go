@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Options configures the network settings shared by the HTTP probes and the
+// browser, so both see the same proxy, TLS verification and User-Agent
+type Options struct {
+	// Insecure skips verification of server certificates entirely
+	Insecure bool
+
+	// CACert is a PEM file of extra certificate authorities to trust,
+	// alongside the system ones
+	CACert string
+
+	// HTTP1 forces HTTP/1.1 instead of negotiating HTTP/2 over TLS
+	HTTP1 bool
+
+	// Proxy is an http, https or socks5 URL to send every request through.
+	// Its certificate is trusted, so intercepting proxies such as Burp work
+	// without installing their CA.
+	Proxy string
+
+	// UserAgent is sent by the browser unless a request sets its own
+	UserAgent string
+
+	// Fingerprint is the TLS ClientHello the HTTP probes present, one of
+	// Fingerprints; empty keeps Go's own
+	Fingerprint string
+}
+
+// New returns an HTTP transport for the scan's HTTP probes and custom
+// requests, sharing its connection pool across every target
+func New(opts Options) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.CACert != "" {
+		pool, err := certPool(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	t.TLSClientConfig = tlsConfig
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", opts.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme '%s', expected http, https or socks5", proxyURL.Scheme)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if opts.HTTP1 {
+		// A non-nil empty TLSNextProto keeps the transport from upgrading to h2
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if err := applyFingerprint(t, opts.Fingerprint); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// BrowserFlags returns the Chrome command line flags applying the same
+// settings to the browser, to be merged under any user supplied flags
+func (opts Options) BrowserFlags() map[string]interface{} {
+	flags := make(map[string]interface{})
+	if opts.Proxy != "" {
+		flags["proxy-server"] = opts.Proxy
+	}
+	if opts.Proxy != "" || opts.Insecure {
+		flags["ignore-certificate-errors"] = true
+	}
+	if opts.UserAgent != "" {
+		flags["user-agent"] = opts.UserAgent
+	}
+	return flags
+}
+
+// certPool returns the system certificate pool with the certificates in path added
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no PEM certificates found in " + path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// get requests url over a transport built from opts
+func get(t *testing.T, opts Options, url string) error {
+	t.Helper()
+	transport, err := New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestSelfSignedServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCert, block, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := get(t, Options{}, server.URL); err == nil {
+		t.Error("self-signed certificate accepted without -insecure or -ca-cert")
+	}
+	if err := get(t, Options{Insecure: true}, server.URL); err != nil {
+		t.Errorf("-insecure: %v", err)
+	}
+	if err := get(t, Options{CACert: caCert}, server.URL); err != nil {
+		t.Errorf("-ca-cert: %v", err)
+	}
+	for _, fingerprint := range []string{FingerprintChrome, FingerprintFirefox} {
+		if err := get(t, Options{Fingerprint: fingerprint}, server.URL); err == nil {
+			t.Errorf("self-signed certificate accepted with the %s fingerprint", fingerprint)
+		}
+		if err := get(t, Options{CACert: caCert, Fingerprint: fingerprint}, server.URL); err != nil {
+			t.Errorf("-ca-cert with the %s fingerprint: %v", fingerprint, err)
+		}
+	}
+}
+
+func TestCACertErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(Options{CACert: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("missing CA file accepted")
+	}
+	notPEM := filepath.Join(dir, "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0o644)
+	if _, err := New(Options{CACert: notPEM}); err == nil {
+		t.Error("CA file without certificates accepted")
+	}
+}
+
+func TestForcedHTTP1(t *testing.T) {
+	protos := make(chan string, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protos <- r.Proto
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		http1 bool
+		want  string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	} {
+		if err := get(t, Options{Insecure: true, HTTP1: tt.http1}, server.URL); err != nil {
+			t.Fatalf("HTTP1 %v: %v", tt.http1, err)
+		}
+		if proto := <-protos; proto != tt.want {
+			t.Errorf("HTTP1 %v: server saw %s, want %s", tt.http1, proto, tt.want)
+		}
+	}
+}
+
+func TestProxySharedWithBrowser(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	opts := Options{Proxy: proxy.URL, UserAgent: "bxss-test"}
+	if err := get(t, opts, "http://target.example/probe"); err != nil {
+		t.Fatalf("GET through the proxy: %v", err)
+	}
+	if proxied != "http://target.example/probe" {
+		t.Errorf("proxy received %q, want the probe", proxied)
+	}
+
+	want := map[string]interface{}{
+		"proxy-server":              proxy.URL,
+		"ignore-certificate-errors": true,
+		"user-agent":                "bxss-test",
+	}
+	if flags := opts.BrowserFlags(); !reflect.DeepEqual(flags, want) {
+		t.Errorf("BrowserFlags = %v, want %v", flags, want)
+	}
+	if flags := (Options{}).BrowserFlags(); len(flags) != 0 {
+		t.Errorf("BrowserFlags = %v without settings, want none", flags)
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"127.0.0.1:8080", "ftp://127.0.0.1:21", "http://"} {
+		if _, err := New(Options{Proxy: proxy}); err == nil {
+			t.Errorf("proxy %q accepted", proxy)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// Reasons a request failed to reach its host, as returned by Classify
+const (
+	ReasonDNS     = "dns"
+	ReasonRefused = "connection refused"
+	ReasonTLS     = "tls"
+	ReasonTimeout = "timeout"
+	ReasonNetwork = "network"
+)
+
+// DefaultMaxFailures is how many requests in a row may fail to reach a host
+// before it is given up on, for failures that may be passing
+const DefaultMaxFailures = 3
+
+// Classify returns why err kept a request from reaching its host, or ""
+// when err is nil or the request was cancelled
+func Classify(err error) string {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ReasonTimeout
+		}
+		return ReasonDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ReasonRefused
+	}
+
+	var (
+		verifyErr  *tls.CertificateVerificationError
+		recordErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		unknownErr x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+	if errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &unknownErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+		return ReasonTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ReasonTimeout
+	}
+	return ReasonNetwork
+}
+
+// Permanent reports whether a failure for reason will keep happening, so the
+// host is given up on straight away rather than after MaxFailures
+func Permanent(reason string) bool {
+	return reason == ReasonDNS || reason == ReasonTLS
+}
+
+// hostState tracks the failures of requests to one host
+type hostState struct {
+	reason   string
+	failures int
+	down     bool
+}
+
+// Unreachable tracks the hosts requests fail to reach, so a scan skips them
+// instead of spending its requests and retries on them. A nil Unreachable
+// tracks nothing. It is safe for concurrent use.
+type Unreachable struct {
+	// MaxFailures is how many requests in a row may fail for a reason that
+	// isn't Permanent before the host is marked down
+	MaxFailures int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// NewUnreachable creates an empty tracker with DefaultMaxFailures
+func NewUnreachable() *Unreachable {
+	return &Unreachable{MaxFailures: DefaultMaxFailures, hosts: make(map[string]*hostState)}
+}
+
+// Failed records err for a request to host and returns its reason
+func (u *Unreachable) Failed(host string, err error) string {
+	reason := Classify(err)
+	if u == nil || reason == "" {
+		return reason
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	state, ok := u.hosts[host]
+	if !ok {
+		state = &hostState{}
+		u.hosts[host] = state
+	}
+	state.reason = reason
+	state.failures++
+	if Permanent(reason) || state.failures >= u.MaxFailures {
+		state.down = true
+	}
+	return reason
+}
+
+// Succeeded records that a request reached host, resetting its failures
+func (u *Unreachable) Succeeded(host string) {
+	if u == nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if state, ok := u.hosts[host]; ok && !state.down {
+		delete(u.hosts, host)
+	}
+}
+
+// Down reports whether host has been given up on, and why
+func (u *Unreachable) Down(host string) (string, bool) {
+	if u == nil {
+		return "", false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if state, ok := u.hosts[host]; ok && state.down {
+		return state.reason, true
+	}
+	return "", false
+}
+
+// Hosts returns the hosts given up on and the reason for each
+func (u *Unreachable) Hosts() map[string]string {
+	if u == nil {
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	hosts := make(map[string]string)
+	for host, state := range u.hosts {
+		if state.down {
+			hosts[host] = state.reason
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// closedAddr returns the address of a port nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestClassify(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	get := func(client *http.Client, url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	dnsErr := &url.Error{Op: "Get", URL: "http://missing.invalid/", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "missing.invalid", IsNotFound: true},
+	}}
+
+	for _, tt := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"dns", dnsErr, ReasonDNS},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, ReasonTimeout},
+		{"refused", get(http.DefaultClient, "http://"+closedAddr(t)), ReasonRefused},
+		{"tls", get(&http.Client{}, tlsServer.URL), ReasonTLS},
+		{"timeout", get(&http.Client{Timeout: 50 * time.Millisecond}, slow.URL), ReasonTimeout},
+		{"deadline", fmt.Errorf("probe: %w", context.DeadlineExceeded), ReasonTimeout},
+		{"other", errors.New("connection reset"), ReasonNetwork},
+		{"cancelled", fmt.Errorf("probe: %w", context.Canceled), ""},
+		{"none", nil, ""},
+	} {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("%s: Classify(%v) = %q, want %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUnreachableGivesUp(t *testing.T) {
+	u := NewUnreachable()
+	refused := &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+
+	// DNS and TLS failures won't pass, so the host is down after the first
+	u.Failed("dns.example", &net.DNSError{Err: "no such host", IsNotFound: true})
+	if reason, down := u.Down("dns.example"); !down || reason != ReasonDNS {
+		t.Errorf("Down(dns.example) = %q, %v after a DNS failure", reason, down)
+	}
+
+	// Others may, so only after MaxFailures in a row
+	for i := 1; i <= u.MaxFailures; i++ {
+		if _, down := u.Down("refused.example"); down {
+			t.Fatalf("down after %d refused connections, want %d", i-1, u.MaxFailures)
+		}
+		u.Failed("refused.example", refused)
+	}
+	if reason, down := u.Down("refused.example"); !down || reason != ReasonRefused {
+		t.Errorf("Down(refused.example) = %q, %v after %d refused connections", reason, down, u.MaxFailures)
+	}
+
+	// A success in between starts the count again
+	u.Failed("flaky.example", refused)
+	u.Succeeded("flaky.example")
+	for i := 1; i < u.MaxFailures; i++ {
+		u.Failed("flaky.example", refused)
+	}
+	if _, down := u.Down("flaky.example"); down {
+		t.Error("flaky.example down though a request reached it")
+	}
+
+	want := map[string]string{"dns.example": ReasonDNS, "refused.example": ReasonRefused}
+	if got := u.Hosts(); len(got) != len(want) || got["dns.example"] != ReasonDNS || got["refused.example"] != ReasonRefused {
+		t.Errorf("Hosts = %v, want %v", got, want)
+	}
+
+	var none *Unreachable
+	if none.Failed("x", refused) != ReasonRefused || none.Hosts() != nil {
+		t.Error("a nil Unreachable should only classify")
+	}
+	if _, down := none.Down("x"); down {
+		t.Error("a nil Unreachable reported a host down")
+	}
+}
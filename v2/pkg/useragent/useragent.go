@@ -0,0 +1,48 @@
+package useragent
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Defaults are current desktop and mobile browser User-Agents, used when no
+// list of its own is given to the pool
+var Defaults = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.7; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+}
+
+// Pool hands out User-Agents in turn, starting from a random one, so
+// successive requests carry different ones. It is safe for concurrent use.
+type Pool struct {
+	agents []string
+	next   atomic.Uint64
+}
+
+// NewPool creates a pool of agents, or of Defaults when agents is empty
+func NewPool(agents []string) *Pool {
+	if len(agents) == 0 {
+		agents = Defaults
+	}
+	p := &Pool{agents: agents}
+	p.next.Store(rand.Uint64N(uint64(len(agents))))
+	return p
+}
+
+// Next returns the next User-Agent of the pool
+func (p *Pool) Next() string {
+	return p.agents[(p.next.Add(1)-1)%uint64(len(p.agents))]
+}
+
+// Len returns the number of User-Agents in the pool
+func (p *Pool) Len() int {
+	return len(p.agents)
+}
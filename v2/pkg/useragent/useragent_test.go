@@ -0,0 +1,59 @@
+package useragent
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPoolCyclesThroughAgents(t *testing.T) {
+	agents := []string{"a", "b", "c"}
+	p := NewPool(agents)
+
+	seen := make(map[string]int)
+	previous := ""
+	for i := 0; i < 9; i++ {
+		agent := p.Next()
+		if agent == previous {
+			t.Errorf("request %d got %q again", i, agent)
+		}
+		previous = agent
+		seen[agent]++
+	}
+	for _, agent := range agents {
+		if seen[agent] != 3 {
+			t.Errorf("%q handed out %d times, want 3", agent, seen[agent])
+		}
+	}
+}
+
+func TestPoolDefaults(t *testing.T) {
+	p := NewPool(nil)
+	if p.Len() != len(Defaults) {
+		t.Fatalf("Len() = %d, want the %d defaults", p.Len(), len(Defaults))
+	}
+	if agent := p.Next(); !slices.Contains(Defaults, agent) {
+		t.Errorf("Next() = %q, not one of the defaults", agent)
+	}
+}
+
+func TestPoolConcurrentUse(t *testing.T) {
+	p := NewPool([]string{"a", "b"})
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			agent := p.Next()
+			mu.Lock()
+			counts[agent]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if counts["a"] != 50 || counts["b"] != 50 {
+		t.Errorf("counts = %v, want the agents handed out evenly", counts)
+	}
+}